@@ -0,0 +1,61 @@
+// Package main は user_question_best（ユーザー × 問題ごとの自己ベスト）を scores から計算し直すコマンドです。
+// 通常は回答の保存（ScoresRepository.Create）と再採点（cmd/rescore -apply）で自動的に更新されるため、
+// テーブルを導入する前の回答を取り込むときや、scores を SQL で直接編集した後に実行します。
+// 何度実行しても同じ結果になります。
+//
+// 使い方（backend ディレクトリで実行）:
+//
+//	go run ./cmd/backfill_best
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env ファイルが見つかりません。システムの環境変数に依存します。")
+	}
+
+	if err := run(context.Background()); err != nil {
+		log.Printf("自己ベストの再計算に失敗しました: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		return fmt.Errorf("DATABASE_URL 環境変数が設定されていません")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return fmt.Errorf("sql.DB の作成に失敗しました: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("failed to close database: %v", closeErr)
+		}
+	}()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("データベースへの接続に失敗しました: %w", err)
+	}
+
+	log.Println("scores から user_question_best を計算し直します")
+	start := time.Now()
+	n, err := repository.NewScoresRepository(db).RebuildQuestionBest(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d 行を書き込みました（%s）", n, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,323 @@
+// Package main は問題バンク（YAML / JSON）を検証・取り込み・書き出しするコマンドです。
+// 取り込みは slug をキーにした upsert で、同じファイルを何度流しても結果は変わりません。
+// 取り込む前に全ての問題を検証し、correct_answer を回答として採点して満点になるか（自己採点）を確認します。
+//
+// 使い方（backend ディレクトリで実行）:
+//
+//	go run ./cmd/questions check  -file bank.yaml            # DB を使わずに検証と自己採点だけを行う
+//	go run ./cmd/questions import -file bank.yaml -dry-run   # 何が作成・更新されるかを確認
+//	go run ./cmd/questions import -file bank.yaml            # 取り込む
+//	go run ./cmd/questions export -file backup.json          # 現在の問題を書き出す（-file を省略すると標準出力に YAML）
+//	go run ./cmd/questions dry-run -file bank.yaml -slug strawberry-r -runs 5   # AI に解かせて正答率と提案する level を見る
+//
+// dry-run は DB を使わず、DRYRUN_MODELS（未設定なら AI_MODEL_NAME）のモデルを呼び出します。結果は scores に保存しません。
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/authoring"
+	"github.com/shiv/CoT_game/backend/internal/questionbank"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	file := flags.String("file", "", "問題バンクのファイル（.yaml / .yml / .json）")
+	format := flags.String("format", "", "ファイル形式（yaml / json）。省略時は拡張子から判定")
+	dryRun := flags.Bool("dry-run", false, "import: 取り込み結果を表示するだけで DB に反映しない")
+	actor := flags.String("actor", defaultActor(), "import: 変更履歴に残す名前")
+	slug := flags.String("slug", "", "dry-run: 試走する問題の slug（省略時はファイル内の全問題）")
+	runs := flags.Int("runs", authoring.DefaultRuns, "dry-run: モデル・基準プロンプトごとに解かせる回数")
+	modelList := flags.String("models", "", "dry-run: 使うモデル名（カンマ区切り）。省略時は全モデル")
+	if err := flags.Parse(args); err != nil {
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env ファイルが見つかりません。システムの環境変数に依存します。")
+	}
+
+	ctx := context.Background()
+	var err error
+	switch command {
+	case "check":
+		err = runCheck(*file, *format)
+	case "import":
+		err = runImport(ctx, *file, *format, *actor, *dryRun)
+	case "export":
+		err = runExport(ctx, *file, *format)
+	case "dry-run":
+		err = runDryRun(ctx, *file, *format, *slug, *runs, authoring.ParseModelNames(*modelList))
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Printf("%s に失敗しました: %v", command, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: questions <check|import|export|dry-run> [-file path] [-format yaml|json] [-dry-run] [-actor name] [-slug slug] [-runs n] [-models a,b]")
+}
+
+// defaultActor は変更履歴に残す既定の名前です。誰がコマンドを実行したか分かるよう OS のユーザー名を使います。
+func defaultActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
+// resolveFormat は -format が指定されていればそれを、無ければ拡張子から形式を決めます。
+func resolveFormat(file, format string) (questionbank.Format, error) {
+	if format != "" {
+		return questionbank.ParseFormat(format)
+	}
+	return questionbank.FormatFromPath(file), nil
+}
+
+// loadBank は問題バンクのファイルを読み込みます。検証は呼び出し元で Bank.Validate を使って行います。
+func loadBank(file, format string) (questionbank.Bank, error) {
+	if file == "" {
+		return questionbank.Bank{}, errors.New("-file を指定してください")
+	}
+	f, err := resolveFormat(file, format)
+	if err != nil {
+		return questionbank.Bank{}, err
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		return questionbank.Bank{}, fmt.Errorf("ファイルを開けません: %w", err)
+	}
+	defer func() {
+		if closeErr := r.Close(); closeErr != nil {
+			log.Printf("failed to close %s: %v", file, closeErr)
+		}
+	}()
+
+	return questionbank.Decode(r, f)
+}
+
+func runCheck(file, format string) error {
+	bank, err := loadBank(file, format)
+	if err != nil {
+		return err
+	}
+	if _, err := bank.Validate(); err != nil {
+		return err
+	}
+	fmt.Printf("%d 問すべて検証と自己採点を通過しました\n", len(bank.Questions))
+	return nil
+}
+
+func runImport(ctx context.Context, file, format, actor string, dryRun bool) error {
+	bank, err := loadBank(file, format)
+	if err != nil {
+		return err
+	}
+	questions, err := bank.Validate()
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	results, err := repository.NewQuestionsRepository(db).Import(ctx, questions, actor, dryRun)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Result]++
+		switch r.Result {
+		case repository.ImportCreated:
+			fmt.Printf("  + %s (id=%d)\n", r.Slug, r.QuestionID)
+		case repository.ImportUpdated:
+			note := ""
+			if r.Deleted {
+				note = "（削除済みのまま）"
+			}
+			fmt.Printf("  ~ %s (id=%d) %v%s\n", r.Slug, r.QuestionID, r.Changed, note)
+		}
+	}
+	mode := "取り込みました"
+	if dryRun {
+		mode = "取り込みます（dry-run のため未反映）"
+	}
+	fmt.Printf("作成 %d / 更新 %d / 変更なし %d を%s\n",
+		counts[repository.ImportCreated], counts[repository.ImportUpdated], counts[repository.ImportUnchanged], mode)
+	return nil
+}
+
+func runExport(ctx context.Context, file, format string) error {
+	f := questionbank.FormatYAML
+	if file != "" || format != "" {
+		var err error
+		if f, err = resolveFormat(file, format); err != nil {
+			return err
+		}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	questions, err := repository.NewQuestionsRepository(db).FindAll(ctx, false)
+	if err != nil {
+		return err
+	}
+	bank, err := questionbank.BankFromQuestions(questions)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		out, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("ファイルを作成できません: %w", err)
+		}
+		defer func() {
+			if closeErr := out.Close(); closeErr != nil {
+				log.Printf("failed to close %s: %v", file, closeErr)
+			}
+		}()
+		w = out
+	}
+	if err := questionbank.Encode(w, bank, f); err != nil {
+		return err
+	}
+	if file != "" {
+		fmt.Printf("%d 問を %s に書き出しました\n", len(bank.Questions), file)
+	}
+	return nil
+}
+
+// runDryRun はファイル内の問題（-slug 指定時はその 1 問）を AI に解かせ、基準プロンプトごとの正答率と提案する level を表示します。
+func runDryRun(ctx context.Context, file, format, slug string, runs int, modelNames []string) error {
+	bank, err := loadBank(file, format)
+	if err != nil {
+		return err
+	}
+	questions, err := bank.Validate()
+	if err != nil {
+		return err
+	}
+	if slug != "" {
+		var matched []*models.Question
+		for _, q := range questions {
+			if *q.Slug == slug {
+				matched = append(matched, q)
+			}
+		}
+		if len(matched) == 0 {
+			return fmt.Errorf("slug %q の問題がファイルにありません", slug)
+		}
+		questions = matched
+	}
+
+	solveClient, err := ai.NewGeminiClientFromEnv()
+	if err != nil {
+		return fmt.Errorf("gemini クライアントの初期化に失敗しました: %w", err)
+	}
+	dryRunModels, err := authoring.ModelsFromEnv(solveClient)
+	if err != nil {
+		return err
+	}
+	runner := &authoring.Runner{Models: dryRunModels}
+	// 採点用クライアントが無くても、自由記述以外の問題は試走できます。
+	if judgeClient, err := ai.NewJudgeClientFromEnv(); err != nil {
+		log.Printf("採点用クライアントの初期化に失敗しました（自由記述問題は試走できません）: %v", err)
+	} else {
+		runner.JudgeClient = judgeClient
+	}
+
+	failed := 0
+	for _, q := range questions {
+		report, err := runner.Run(ctx, authoring.Request{Question: q, Runs: runs, Models: modelNames})
+		if err != nil {
+			log.Printf("%s: %v", *q.Slug, err)
+			failed++
+			continue
+		}
+		printReport(*q.Slug, report)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 問の試走を開始できませんでした", failed)
+	}
+	return nil
+}
+
+// printReport は試走の結果をモデル × 基準プロンプトの表として表示します。
+func printReport(slug string, report authoring.Report) {
+	fmt.Printf("%s: level %d → 提案 %d（正答率 %.0f%%, 平均 %.1f 点）\n",
+		slug, report.Level, report.SuggestedLevel, report.PassRate*100, report.AverageScore)
+	for _, cell := range report.Cells {
+		failures := 0
+		for _, a := range cell.Attempts {
+			if a.Error != "" {
+				failures++
+			}
+		}
+		note := ""
+		if failures > 0 {
+			note = fmt.Sprintf("（失敗 %d）", failures)
+		}
+		fmt.Printf("  %-24s %-28s %d/%d  平均 %5.1f 点%s\n",
+			cell.Model, cell.Baseline, cell.Passes, len(cell.Attempts), cell.AverageScore, note)
+	}
+	for _, w := range report.Warnings {
+		fmt.Printf("  ! %s\n", w)
+	}
+	fmt.Println(strings.Repeat("-", 40))
+}
+
+func openDB() (*sql.DB, error) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		return nil, errors.New("DATABASE_URL 環境変数が設定されていません")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("sql.DB の作成に失敗しました: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		closeDB(db)
+		return nil, fmt.Errorf("データベースへの接続に失敗しました: %w", err)
+	}
+	return db, nil
+}
+
+func closeDB(db *sql.DB) {
+	if err := db.Close(); err != nil {
+		log.Printf("failed to close database: %v", err)
+	}
+}
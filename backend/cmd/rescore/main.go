@@ -0,0 +1,289 @@
+// Package main は保存済みの scores.ai_response を指定した版の採点ロジックで再採点するコマンドです。
+// 既定は dry-run で、スコアが変わる行の差分と集計だけを表示します。-apply を付けるとバッチごとに書き戻します。
+// 書き戻すと同じトランザクションで user_question_best（自己ベスト）も計算し直すため、ランキングにはその時点で新しい採点結果が反映されます。
+//
+// 使い方（backend ディレクトリで実行）:
+//
+//	go run ./cmd/rescore                      # 現在の版より古いスコアを dry-run で確認
+//	go run ./cmd/rescore -question 3 -all     # 問題 3 の全スコアを確認（版を問わない）
+//	go run ./cmd/rescore -apply -batch 200    # 200 件ずつ書き戻す
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/internal/anticheat"
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// summary は再採点の集計結果です。
+type summary struct {
+	scanned   int
+	changed   int
+	raised    int
+	lowered   int
+	unchanged int
+	skipped   int
+	applied   int
+	perQ      map[int]*questionSummary
+}
+
+// questionSummary は問題ごとのスコア変化です。
+type questionSummary struct {
+	changed    int
+	totalDelta int
+}
+
+func main() {
+	version := flag.Int("version", int(eval.CurrentVersion), "再採点に使う採点ロジックの版")
+	questionID := flag.Int("question", 0, "対象の問題 ID（0 なら全問題）")
+	all := flag.Bool("all", false, "指定の版で採点済みのスコアも対象にする")
+	batchSize := flag.Int("batch", 500, "1 回に読み込み・書き戻す件数")
+	apply := flag.Bool("apply", false, "再採点結果を DB に書き戻す（指定しなければ dry-run）")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env ファイルが見つかりません。システムの環境変数に依存します。")
+	}
+
+	if err := run(context.Background(), eval.Version(*version), *questionID, *all, *batchSize, *apply); err != nil {
+		log.Printf("再採点に失敗しました: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, version eval.Version, questionID int, all bool, batchSize int, apply bool) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch は 1 以上を指定してください: %d", batchSize)
+	}
+	if !slices.Contains(eval.KnownVersions(), version) {
+		return fmt.Errorf("版 %d は登録されていません（登録済み: %v）", version, eval.KnownVersions())
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		return fmt.Errorf("DATABASE_URL 環境変数が設定されていません")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return fmt.Errorf("sql.DB の作成に失敗しました: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("failed to close database: %v", closeErr)
+		}
+	}()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("データベースへの接続に失敗しました: %w", err)
+	}
+
+	repo := repository.NewScoresRepository(db)
+
+	filter := repository.RescoreFilter{}
+	if questionID > 0 {
+		filter.QuestionID = &questionID
+	}
+	if !all {
+		below := int(version)
+		filter.BelowVersion = &below
+	}
+
+	mode := "dry-run"
+	if apply {
+		mode = "apply"
+	}
+	log.Printf("再採点を開始します（版: v%d, モード: %s, バッチ: %d 件）", version, mode, batchSize)
+
+	// 不正検出の減点もハンドラと同じ設定で掛け直す（再採点で減点が外れないようにする）。
+	cheat := anticheat.PolicyFromEnv()
+	// ゴルフモードの回答はハンドラと同じ曲線（GOLF_CURVES）でゴルフスコアも計算し直す。
+	golf, err := eval.ParseGolfCurves(os.Getenv("GOLF_CURVES"))
+	if err != nil {
+		return fmt.Errorf("GOLF_CURVES の読み込みに失敗しました: %w", err)
+	}
+
+	sum := &summary{perQ: make(map[int]*questionSummary)}
+	afterID := 0
+	for {
+		targets, err := repo.FindRescoreTargets(ctx, filter, afterID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			break
+		}
+		afterID = targets[len(targets)-1].ScoreID
+
+		updates := rescoreBatch(targets, version, cheat, golf, sum)
+		if apply && len(updates) > 0 {
+			if err := repo.UpdateEvaluations(ctx, updates); err != nil {
+				return fmt.Errorf("score_id %d までのバッチの書き戻しに失敗しました: %w", afterID, err)
+			}
+			sum.applied += len(updates)
+			log.Printf("%d 件を書き戻しました（score_id <= %d）", len(updates), afterID)
+		}
+	}
+
+	printSummary(sum, apply)
+	return nil
+}
+
+// rescoreBatch は 1 バッチ分を再採点し、スコアが変わった行を表示して書き戻し用の更新を返す。
+// スコアが変わらない行も版を更新するために更新対象に含める。
+// detail は採点し直した内容で置き換えるが、採点関数の外で付けた漏洩チェックの結果は元の detail から引き継ぎ、ゴルフスコアの内訳は計算し直す。
+func rescoreBatch(targets []repository.RescoreTarget, version eval.Version, cheat anticheat.Policy, golf eval.GolfCurves, sum *summary) []repository.EvaluationUpdate {
+	updates := make([]repository.EvaluationUpdate, 0, len(targets))
+	for _, t := range targets {
+		sum.scanned++
+
+		var expectedUnit string
+		if t.ExpectedUnit != nil {
+			expectedUnit = *t.ExpectedUnit
+		}
+		var unitPolicy eval.UnitPolicy
+		if t.UnitPolicy != nil {
+			unitPolicy = eval.UnitPolicy(*t.UnitPolicy)
+		}
+		if t.VariantAnswer != nil {
+			// テンプレート問題は問題側の代表値ではなく、実際に出題したバリアントの正解で採点し直す。
+			t.CorrectAnswer = *t.VariantAnswer
+			t.AnswerSpec = nil
+		}
+		spec, err := eval.ParseAnswerSpec(t.AnswerSpec, t.CorrectAnswer)
+		if err != nil {
+			// ハンドラと同じく、壊れた仕様は correct_answer による単一の正解として扱う。
+			log.Printf("answer_spec の読み込みに失敗したため correct_answer で採点します (question_id=%d): %v", t.QuestionID, err)
+			spec = eval.AnswerSpec{Type: eval.SpecValue, Value: t.CorrectAnswer}
+		}
+		scoring, err := eval.ParseScoringParams(t.ScoringParams)
+		if err != nil {
+			log.Printf("scoring_params の読み込みに失敗したため既定値で採点します (question_id=%d): %v", t.QuestionID, err)
+		}
+		if spec.Type == eval.SpecJudge && version >= eval.VersionV4 {
+			// LLM 採点は外部呼び出しが必要で結果も決定的ではないため、再採点の対象外とする。
+			sum.skipped++
+			continue
+		}
+
+		result, err := eval.EvaluateVersion(version, t.AIResponse, eval.AnswerKey{
+			CorrectAnswer: t.CorrectAnswer,
+			Spec:          spec,
+			ExpectedUnit:  expectedUnit,
+			UnitPolicy:    unitPolicy,
+			Scoring:       scoring,
+		})
+		if err != nil {
+			log.Printf("score_id %d の採点に失敗しました: %v", t.ScoreID, err)
+			sum.skipped++
+			continue
+		}
+
+		if cheat.Mode != anticheat.ModeOff {
+			result.Score = cheat.Apply(result.Score, anticheat.Inspect(t.Prompt, spec), result.Detail)
+		}
+
+		// 漏洩チェックは AI の出力と問題文だけで決まるため、採点し直しても結果は変わらない。
+		// 漏洩していた回答は、ハンドラと同じく AI の出力を持つキーを detail に戻さない。
+		if leak, ok := t.EvaluationDetail["leak_guard"].(map[string]any); ok {
+			if _, removed := leak["removed_fields"]; removed {
+				eval.StripAIText(result.Detail)
+			}
+			result.Detail["leak_guard"] = leak
+		}
+
+		// ゴルフスコアは正確さ（不正検出の減点後）から計算するため、スコアと一緒に計算し直す。
+		var golfScore *int
+		if t.ScoringMode == "golf" {
+			g, breakdown := eval.GolfScore(result.Score, t.Prompt, golf.For(t.Level))
+			breakdown["level"] = t.Level
+			result.Detail["golf"] = breakdown
+			golfScore = &g
+		}
+
+		// 再採点前の値を残しておき、後から変化の理由を追えるようにする。
+		result.Detail["rescored_from"] = map[string]any{
+			"score":             t.Score,
+			"evaluator_version": t.EvaluatorVersion,
+			"mode":              t.EvaluationDetail["mode"],
+		}
+
+		delta := result.Score - t.Score
+		if delta == 0 {
+			sum.unchanged++
+		} else {
+			sum.changed++
+			if delta > 0 {
+				sum.raised++
+			} else {
+				sum.lowered++
+			}
+			q, ok := sum.perQ[t.QuestionID]
+			if !ok {
+				q = &questionSummary{}
+				sum.perQ[t.QuestionID] = q
+			}
+			q.changed++
+			q.totalDelta += delta
+			fmt.Printf("score_id=%d question_id=%d score %d -> %d (%+d) mode %v -> %s (v%s -> v%d)\n",
+				t.ScoreID, t.QuestionID, t.Score, result.Score, delta,
+				modeOrDash(t.EvaluationDetail), result.Mode, versionOrDash(t.EvaluatorVersion), version)
+		}
+
+		updates = append(updates, repository.EvaluationUpdate{
+			ScoreID:          t.ScoreID,
+			Score:            result.Score,
+			AnswerNumber:     result.Extracted,
+			EvaluationDetail: result.Detail,
+			EvaluatorVersion: int(version),
+			GolfScore:        golfScore,
+		})
+	}
+	return updates
+}
+
+// printSummary は集計結果と問題ごとの変化量を表示する。
+func printSummary(sum *summary, apply bool) {
+	fmt.Println("---")
+	fmt.Printf("対象: %d 件 / 変化あり: %d 件（上昇 %d・低下 %d）/ 変化なし: %d 件 / スキップ: %d 件\n",
+		sum.scanned, sum.changed, sum.raised, sum.lowered, sum.unchanged, sum.skipped)
+
+	ids := make([]int, 0, len(sum.perQ))
+	for id := range sum.perQ {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		q := sum.perQ[id]
+		fmt.Printf("  question_id=%d: %d 件変化（平均 %+.1f 点）\n", id, q.changed, float64(q.totalDelta)/float64(q.changed))
+	}
+
+	if apply {
+		fmt.Printf("%d 件を書き戻しました。\n", sum.applied)
+	} else {
+		fmt.Println("dry-run のため DB は更新していません。書き戻すには -apply を指定してください。")
+	}
+}
+
+func modeOrDash(detail map[string]interface{}) any {
+	if mode, ok := detail["mode"]; ok {
+		return mode
+	}
+	return "-"
+}
+
+func versionOrDash(v *int) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(*v)
+}
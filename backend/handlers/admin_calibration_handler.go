@@ -0,0 +1,141 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// admin_calibration_handler.go は挑戦の記録から集計した難易度（solve rate・平均点・満点までの挑戦回数）と
+// 提案する level を管理者が確認し、受け入れるためのエンドポイントをまとめたファイルです。
+// 集計は main から定期的に走らせており（internal/calibration）、ここからも手動で走らせられます。
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// ListCalibrations は GET /api/v1/admin/calibrations のハンドラです。
+// 問題ごとの集計を今の level と提案する level を並べて返します。pending=true なら提案が今の level と異なる問題だけを返します。
+// ListCalibrations godoc
+// @Summary      List difficulty calibrations (admin)
+// @Description  Returns per-question solve statistics with the current and suggested level.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        pending  query  bool  false  "Only questions whose suggested level differs from the current level"
+// @Success      200  {array}   repository.QuestionCalibration
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/calibrations [get]
+func (h *AdminQuestionHandler) ListCalibrations(c *gin.Context) {
+	if !h.requireCalibrations(c) {
+		return
+	}
+
+	calibrations, err := h.Calibrations.FindAll(c.Request.Context())
+	if err != nil {
+		log.Printf("難易度の集計の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "難易度の集計の取得に失敗しました",
+		})
+		return
+	}
+	if c.Query("pending") == "true" {
+		pending := []repository.QuestionCalibration{}
+		for _, cal := range calibrations {
+			if cal.SuggestedLevel != nil && *cal.SuggestedLevel != cal.Level {
+				pending = append(pending, cal)
+			}
+		}
+		calibrations = pending
+	}
+	c.JSON(http.StatusOK, calibrations)
+}
+
+// RefreshCalibrations は POST /api/v1/admin/calibrations/refresh のハンドラです。
+// 定期実行を待たずに集計し直し、集計した問題の数を返します。
+// RefreshCalibrations godoc
+// @Summary      Recompute difficulty calibrations (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]int
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/calibrations/refresh [post]
+func (h *AdminQuestionHandler) RefreshCalibrations(c *gin.Context) {
+	if _, ok := requireAdmin(c); !ok {
+		return
+	}
+	if !h.requireCalibrations(c) {
+		return
+	}
+
+	n, err := h.Calibrations.Refresh(c.Request.Context())
+	if err != nil {
+		log.Printf("難易度の集計に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "難易度の集計に失敗しました",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"questions": n})
+}
+
+// AcceptLevel は POST /api/v1/admin/questions/:id/accept-level のハンドラです。
+// 問題の level を提案の値に書き換え、更新後の問題を返します。変更は calibrate として変更履歴に残ります。
+// AcceptLevel godoc
+// @Summary      Accept the suggested level (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Question ID"
+// @Success      200  {object}  models.Question
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/questions/{id}/accept-level [post]
+func (h *AdminQuestionHandler) AcceptLevel(c *gin.Context) {
+	actor, ok := requireAdmin(c)
+	if !ok {
+		return
+	}
+	if !h.requireCalibrations(c) {
+		return
+	}
+	id, ok := parseQuestionID(c)
+	if !ok {
+		return
+	}
+
+	q, err := h.Calibrations.AcceptSuggestion(c.Request.Context(), id, actor)
+	if errors.Is(err, repository.ErrNoLevelSuggestion) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "no_level_suggestion",
+			"message": "この問題にはまだ level の提案がありません",
+		})
+		return
+	}
+	if err != nil {
+		respondQuestionRepoError(c, err, "level の更新に失敗しました")
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+// requireCalibrations は集計のリポジトリが設定されているかを確認し、無ければ 503 を返します。
+func (h *AdminQuestionHandler) requireCalibrations(c *gin.Context) bool {
+	if h.Calibrations == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "calibration_unavailable",
+			"message": "難易度の集計は利用できません",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+// admin_calibration_handler_test.go は難易度の提案の一覧・手動集計・受け入れの入力検証と、リポジトリへ渡す actor を確認する単体テストです。
+// DB を使わないよう、CalibrationsRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/calibration"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// fakeCalibrationsRepo は問題 ID ごとの集計を持ち、受け入れた actor を記録します。
+type fakeCalibrationsRepo struct {
+	calibrations []repository.QuestionCalibration
+	refreshed    int
+	accepted     []string
+}
+
+func (f *fakeCalibrationsRepo) Refresh(context.Context) (int, error) {
+	f.refreshed++
+	return len(f.calibrations), nil
+}
+
+func (f *fakeCalibrationsRepo) FindAll(context.Context) ([]repository.QuestionCalibration, error) {
+	return f.calibrations, nil
+}
+
+func (f *fakeCalibrationsRepo) AcceptSuggestion(_ context.Context, questionID int, actor string) (*models.Question, error) {
+	for _, cal := range f.calibrations {
+		if cal.QuestionID != questionID {
+			continue
+		}
+		if cal.SuggestedLevel == nil {
+			return nil, repository.ErrNoLevelSuggestion
+		}
+		f.accepted = append(f.accepted, actor)
+		return &models.Question{ID: questionID, Level: *cal.SuggestedLevel}, nil
+	}
+	return nil, repository.ErrQuestionNotFound
+}
+
+func TestAdminQuestionHandler_Calibrations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	level := func(n int) *int { return &n }
+	repo := &fakeCalibrationsRepo{calibrations: []repository.QuestionCalibration{
+		{Stats: calibration.Stats{QuestionID: 1, Players: 10}, Level: 3, SuggestedLevel: level(1)},
+		{Stats: calibration.Stats{QuestionID: 2, Players: 10}, Level: 2, SuggestedLevel: level(2)},
+		{Stats: calibration.Stats{QuestionID: 3, Players: 1}, Level: 4},
+	}}
+	h := NewAdminQuestionHandler(newFakeQuestionsRepo())
+	h.Calibrations = repo
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(ContextKeyAdmin, "alice") })
+	router.GET("/admin/calibrations", h.ListCalibrations)
+	router.POST("/admin/calibrations/refresh", h.RefreshCalibrations)
+	router.POST("/admin/questions/:id/accept-level", h.AcceptLevel)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantCount  int // 一覧の件数。一覧以外では見ない
+	}{
+		{name: "一覧", method: http.MethodGet, path: "/admin/calibrations", wantStatus: http.StatusOK, wantCount: 3},
+		{name: "今の level と異なるものだけ", method: http.MethodGet, path: "/admin/calibrations?pending=true", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "手動で集計", method: http.MethodPost, path: "/admin/calibrations/refresh", wantStatus: http.StatusOK},
+		{name: "受け入れ", method: http.MethodPost, path: "/admin/questions/1/accept-level", wantStatus: http.StatusOK},
+		{name: "提案が無い", method: http.MethodPost, path: "/admin/questions/3/accept-level", wantStatus: http.StatusConflict},
+		{name: "存在しない問題", method: http.MethodPost, path: "/admin/questions/9/accept-level", wantStatus: http.StatusNotFound},
+		{name: "不正な ID", method: http.MethodPost, path: "/admin/questions/abc/accept-level", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.method != http.MethodGet {
+				return
+			}
+			var got []repository.QuestionCalibration
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("calibrations = %d, want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+
+	if repo.refreshed != 1 || len(repo.accepted) != 1 || repo.accepted[0] != "alice" {
+		t.Errorf("refreshed = %d, accepted = %v", repo.refreshed, repo.accepted)
+	}
+
+	// 集計のリポジトリが無ければ 503 です。
+	h.Calibrations = nil
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/calibrations", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without repository = %d, want 503", w.Code)
+	}
+}
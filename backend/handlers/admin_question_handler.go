@@ -0,0 +1,583 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// admin_question_handler.go は管理者向けの問題管理 API（登録・更新・論理削除・復元・変更履歴）をまとめたハンドラです。
+// 認証は middleware.AdminAuth が行い、ここでは入力の検証と、誰が変更したか（actor）をリポジトリに渡すことに集中します。
+// 問題を保存する前に AI に解かせて難易度を確かめる試走（dry-run）もここから呼び出します。
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/authoring"
+	"github.com/shiv/CoT_game/backend/internal/questionbank"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+const (
+	// maxAuditLogLimit は変更履歴を一度に返す件数の上限です。
+	maxAuditLogLimit = 200
+	// maxQuestionBankBytes は取り込む問題バンクの大きさの上限です。
+	maxQuestionBankBytes = 5 << 20
+)
+
+// AdminQuestionHandler は管理者向けの問題管理エンドポイントの依存関係を保持します。
+type AdminQuestionHandler struct {
+	QuestionRepo repository.QuestionsRepository
+	DryRunner    *authoring.Runner // 試走に使うモデル。nil の場合、試走のエンドポイントは 503 を返します。
+	// Calibrations は挑戦の記録から集計した難易度の提案です。nil の場合、難易度のエンドポイントは 503 を返します。
+	Calibrations repository.CalibrationsRepository
+}
+
+// NewAdminQuestionHandler は新しい AdminQuestionHandler を作成します。
+func NewAdminQuestionHandler(questionRepo repository.QuestionsRepository) *AdminQuestionHandler {
+	return &AdminQuestionHandler{QuestionRepo: questionRepo}
+}
+
+// QuestionInput は問題の登録・更新リクエストのボディです。更新は全項目の置き換えで、省略した任意項目は NULL になります。
+type QuestionInput struct {
+	Slug             *string         `json:"slug"` // 問題バンクで使う識別子。登録時に省略すると "q-<id>"、更新時に省略すると今の値のままです。
+	Level            int             `json:"level" binding:"required,min=1"`
+	ProblemStatement string          `json:"problem_statement" binding:"required"`
+	CorrectAnswer    string          `json:"correct_answer" binding:"required,max=255"`
+	AnswerSpec       json.RawMessage `json:"answer_spec"`    // eval.AnswerSpec の JSON。省略時は correct_answer を単一の正解にした value 仕様を保存します。
+	ExpectedUnit     *string         `json:"expected_unit"`  // 単位を問う問題のみ。eval が知っている単位である必要があります。
+	UnitPolicy       *string         `json:"unit_policy"`    // 次元の異なる単位の回答を "reject"（0 点）にするか "penalize"（減点）にするか。省略時は reject です。
+	GradingRubric    *string         `json:"grading_rubric"` // answer_spec.type が judge の問題では必須です。
+	TemplateKey      *string         `json:"template_key"`   // internal/variant に登録されたテンプレートのキー
+	ScoringParams    json.RawMessage `json:"scoring_params"` // eval.ScoringParams の JSON。省略時は既定の曲線です。
+	Tags             []string        `json:"tags"`           // models.TagDefinitions に定義されたタグ ID
+}
+
+// toQuestion は入力を検証し、保存する models.Question に変換します。
+// 検証の規則は問題バンクの取り込みと共通で、questionbank.Normalize にまとめています。
+func (in QuestionInput) toQuestion() (*models.Question, error) {
+	q := &models.Question{
+		Slug:             in.Slug,
+		Level:            in.Level,
+		ProblemStatement: in.ProblemStatement,
+		CorrectAnswer:    in.CorrectAnswer,
+		AnswerSpec:       in.AnswerSpec,
+		ExpectedUnit:     in.ExpectedUnit,
+		UnitPolicy:       in.UnitPolicy,
+		GradingRubric:    in.GradingRubric,
+		TemplateKey:      in.TemplateKey,
+		ScoringParams:    in.ScoringParams,
+		Tags:             in.Tags,
+	}
+	if err := questionbank.Normalize(q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// ListQuestions は GET /api/v1/admin/questions のハンドラです。
+// 問題文と正解を含む全項目を返します。include_deleted=true で論理削除した問題も含めます。
+// ListQuestions godoc
+// @Summary      List questions (admin)
+// @Description  Full question records including statements and answers
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        include_deleted  query  bool  false  "Include soft-deleted questions"
+// @Success      200  {array}   models.Question
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions [get]
+func (h *AdminQuestionHandler) ListQuestions(c *gin.Context) {
+	includeDeleted := c.Query("include_deleted") == "true"
+
+	questions, err := h.QuestionRepo.FindAll(c.Request.Context(), includeDeleted)
+	if err != nil {
+		log.Printf("問題一覧の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "問題一覧の取得に失敗しました",
+		})
+		return
+	}
+	if questions == nil {
+		questions = []models.Question{}
+	}
+	c.JSON(http.StatusOK, questions)
+}
+
+// GetQuestion は GET /api/v1/admin/questions/:id のハンドラです。論理削除した問題も取得できます。
+// GetQuestion godoc
+// @Summary      Get a question (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Question ID"
+// @Success      200  {object}  models.Question
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/questions/{id} [get]
+func (h *AdminQuestionHandler) GetQuestion(c *gin.Context) {
+	id, ok := parseQuestionID(c)
+	if !ok {
+		return
+	}
+
+	q, err := h.QuestionRepo.FindByID(c.Request.Context(), id, true)
+	if err != nil {
+		respondQuestionRepoError(c, err, "問題の取得に失敗しました")
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+// CreateQuestion は POST /api/v1/admin/questions のハンドラです。
+// CreateQuestion godoc
+// @Summary      Create a question (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  QuestionInput  true  "Question"
+// @Success      201  {object}  models.Question
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions [post]
+func (h *AdminQuestionHandler) CreateQuestion(c *gin.Context) {
+	actor, q, ok := h.bindQuestion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.QuestionRepo.Create(c.Request.Context(), q, actor); err != nil {
+		respondQuestionRepoError(c, err, "問題の登録に失敗しました")
+		return
+	}
+	c.JSON(http.StatusCreated, q)
+}
+
+// UpdateQuestion は PUT /api/v1/admin/questions/:id のハンドラです。論理削除した問題は先に復元する必要があります。
+// UpdateQuestion godoc
+// @Summary      Update a question (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int            true  "Question ID"
+// @Param        request  body  QuestionInput  true  "Question"
+// @Success      200  {object}  models.Question
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions/{id} [put]
+func (h *AdminQuestionHandler) UpdateQuestion(c *gin.Context) {
+	id, ok := parseQuestionID(c)
+	if !ok {
+		return
+	}
+	actor, q, ok := h.bindQuestion(c)
+	if !ok {
+		return
+	}
+	q.ID = id
+
+	if err := h.QuestionRepo.Update(c.Request.Context(), q, actor); err != nil {
+		respondQuestionRepoError(c, err, "問題の更新に失敗しました")
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+// DeleteQuestion は DELETE /api/v1/admin/questions/:id のハンドラです。
+// 過去のスコアが問題を参照しているため行は消さず、deleted_at を設定して一覧と出題から外します。
+// DeleteQuestion godoc
+// @Summary      Soft-delete a question (admin)
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Question ID"
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/questions/{id} [delete]
+func (h *AdminQuestionHandler) DeleteQuestion(c *gin.Context) {
+	h.setDeleted(c, true)
+}
+
+// RestoreQuestion は POST /api/v1/admin/questions/:id/restore のハンドラです。
+// RestoreQuestion godoc
+// @Summary      Restore a soft-deleted question (admin)
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Question ID"
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/questions/{id}/restore [post]
+func (h *AdminQuestionHandler) RestoreQuestion(c *gin.Context) {
+	h.setDeleted(c, false)
+}
+
+// GetAuditLogs は GET /api/v1/admin/questions/:id/audit-logs のハンドラです。変更履歴を新しい順に返します。
+// GetAuditLogs godoc
+// @Summary      Get question audit logs (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path   int  true   "Question ID"
+// @Param        limit  query  int  false  "Number of rows (default: 50, max: 200)"
+// @Success      200  {array}   repository.QuestionAuditLog
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions/{id}/audit-logs [get]
+func (h *AdminQuestionHandler) GetAuditLogs(c *gin.Context) {
+	id, ok := parseQuestionID(c)
+	if !ok {
+		return
+	}
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxAuditLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_limit",
+				"message": fmt.Sprintf("limit は 1〜%d の整数で指定してください", maxAuditLogLimit),
+			})
+			return
+		}
+		limit = n
+	}
+
+	logs, err := h.QuestionRepo.FindAuditLogs(c.Request.Context(), id, limit)
+	if err != nil {
+		respondQuestionRepoError(c, err, "変更履歴の取得に失敗しました")
+		return
+	}
+	if logs == nil {
+		logs = []repository.QuestionAuditLog{}
+	}
+	c.JSON(http.StatusOK, logs)
+}
+
+// ImportQuestions は POST /api/v1/admin/questions/import のハンドラです。
+// 問題バンク（Content-Type が yaml を含めば YAML、それ以外は JSON）を slug をキーに登録・更新します。
+// 検証（自己採点を含む）で 1 件でも誤りがあれば何も反映せず、全ての誤りを problems に並べて 400 を返します。
+// dry_run=true なら取り込み結果だけを返し、DB には反映しません。
+// ImportQuestions godoc
+// @Summary      Import a question bank (admin)
+// @Description  Idempotent upsert by slug. Every answer is self-checked through the evaluator before anything is written.
+// @Tags         admin
+// @Accept       json
+// @Accept       application/yaml
+// @Produce      json
+// @Security     BearerAuth
+// @Param        dry_run  query  bool               false  "Validate and report without writing"
+// @Param        request  body   questionbank.Bank  true   "Question bank"
+// @Success      200  {object}  ImportQuestionsResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions/import [post]
+func (h *AdminQuestionHandler) ImportQuestions(c *gin.Context) {
+	actor, ok := requireAdmin(c)
+	if !ok {
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	format := questionbank.FormatJSON
+	if strings.Contains(c.ContentType(), "yaml") {
+		format = questionbank.FormatYAML
+	}
+	bank, err := questionbank.Decode(http.MaxBytesReader(c.Writer, c.Request.Body, maxQuestionBankBytes), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "問題バンクを読み込めませんでした",
+			"detail":  err.Error(),
+		})
+		return
+	}
+
+	questions, err := bank.Validate()
+	if err != nil {
+		var verr *questionbank.ValidationError
+		if errors.As(err, &verr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    "invalid_question_bank",
+				"message":  fmt.Sprintf("%d 件の問題に誤りがあります", len(verr.Problems)),
+				"problems": verr.Problems,
+			})
+			return
+		}
+		respondQuestionRepoError(c, err, "問題バンクの検証に失敗しました")
+		return
+	}
+
+	results, err := h.QuestionRepo.Import(c.Request.Context(), questions, actor, dryRun)
+	if err != nil {
+		respondQuestionRepoError(c, err, "問題バンクの取り込みに失敗しました")
+		return
+	}
+
+	resp := ImportQuestionsResponse{DryRun: dryRun, Results: results}
+	for _, r := range results {
+		switch r.Result {
+		case repository.ImportCreated:
+			resp.Created++
+		case repository.ImportUpdated:
+			resp.Updated++
+		default:
+			resp.Unchanged++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ImportQuestionsResponse は問題バンクの取り込み結果です。
+type ImportQuestionsResponse struct {
+	DryRun    bool                      `json:"dry_run"`
+	Created   int                       `json:"created"`
+	Updated   int                       `json:"updated"`
+	Unchanged int                       `json:"unchanged"`
+	Results   []repository.ImportResult `json:"results"` // ファイルと同じ順序
+}
+
+// ExportQuestions は GET /api/v1/admin/questions/export のハンドラです。
+// 削除していない問題を、取り込みと同じ形式の問題バンクとして書き出します。バックアップや環境間の受け渡しに使います。
+// ExportQuestions godoc
+// @Summary      Export the question bank (admin)
+// @Tags         admin
+// @Produce      application/yaml
+// @Produce      json
+// @Security     BearerAuth
+// @Param        format  query  string  false  "yaml (default) or json"
+// @Success      200  {object}  questionbank.Bank
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/questions/export [get]
+func (h *AdminQuestionHandler) ExportQuestions(c *gin.Context) {
+	format, err := questionbank.ParseFormat(c.DefaultQuery("format", string(questionbank.FormatYAML)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_format",
+			"message": "format は yaml または json を指定してください",
+		})
+		return
+	}
+
+	questions, err := h.QuestionRepo.FindAll(c.Request.Context(), false)
+	if err != nil {
+		respondQuestionRepoError(c, err, "問題一覧の取得に失敗しました")
+		return
+	}
+	bank, err := questionbank.BankFromQuestions(questions)
+	if err != nil {
+		respondQuestionRepoError(c, err, "問題バンクの作成に失敗しました")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := questionbank.Encode(&buf, bank, format); err != nil {
+		respondQuestionRepoError(c, err, "問題バンクの書き出しに失敗しました")
+		return
+	}
+	contentType := "application/yaml; charset=utf-8"
+	if format == questionbank.FormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="questions.%s"`, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// DryRunInput は試走リクエストのボディです。保存済みの問題（question_id）か、保存前の下書き（question）のどちらかを指定します。
+type DryRunInput struct {
+	QuestionID int            `json:"question_id"`
+	Question   *QuestionInput `json:"question"`
+	Runs       int            `json:"runs"`   // モデル・基準プロンプトごとの回数。省略時は 3、上限は 10
+	Models     []string       `json:"models"` // 使うモデル名。省略時は DRYRUN_MODELS の全モデル
+}
+
+// DryRunQuestion は POST /api/v1/admin/questions/dry-run のハンドラです。
+// 問題を基準プロンプト（指示なし・ステップバイステップ・タグのヒント）で各モデルに複数回解かせ、正答率と提案する level を返します。
+// 結果は scores に保存しないため、ランキングや問題の統計には影響しません。
+// DryRunQuestion godoc
+// @Summary      Dry-run a question against the AI (admin)
+// @Description  Runs baseline prompts against the configured models several times and reports pass rates and a suggested level. Nothing is saved.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  DryRunInput  true  "Question to try"
+// @Success      200  {object}  authoring.Report
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/questions/dry-run [post]
+func (h *AdminQuestionHandler) DryRunQuestion(c *gin.Context) {
+	if _, ok := requireAdmin(c); !ok {
+		return
+	}
+	if h.DryRunner == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "dry_run_unavailable",
+			"message": "試走に使う AI モデルが設定されていません",
+		})
+		return
+	}
+
+	var in DryRunInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "リクエストボディが不正です",
+			"detail":  err.Error(),
+		})
+		return
+	}
+	if (in.QuestionID == 0) == (in.Question == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "question_id と question のどちらか一方を指定してください",
+		})
+		return
+	}
+
+	var q *models.Question
+	if in.Question != nil {
+		var err error
+		if q, err = in.Question.toQuestion(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_question",
+				"message": err.Error(),
+			})
+			return
+		}
+	} else {
+		var err error
+		if q, err = h.QuestionRepo.FindByID(c.Request.Context(), in.QuestionID, true); err != nil {
+			respondQuestionRepoError(c, err, "問題の取得に失敗しました")
+			return
+		}
+	}
+
+	// Run のエラーは AI を呼ぶ前の検証（回数・モデル名・採点者の有無）だけで、個々の試行の失敗はレポートに含まれます。
+	report, err := h.DryRunner.Run(c.Request.Context(), authoring.Request{Question: q, Runs: in.Runs, Models: in.Models})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_dry_run",
+			"message": "試走を開始できませんでした",
+			"detail":  err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// bindQuestion は管理者名とリクエストボディを取り出し、検証済みの問題に変換します。失敗時はレスポンスを書き込んで false を返します。
+func (h *AdminQuestionHandler) bindQuestion(c *gin.Context) (string, *models.Question, bool) {
+	actor, ok := requireAdmin(c)
+	if !ok {
+		return "", nil, false
+	}
+
+	var in QuestionInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "リクエストボディが不正です",
+			"detail":  err.Error(),
+		})
+		return "", nil, false
+	}
+	q, err := in.toQuestion()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_question",
+			"message": err.Error(),
+		})
+		return "", nil, false
+	}
+	return actor, q, true
+}
+
+// setDeleted は論理削除と復元の共通処理です。
+func (h *AdminQuestionHandler) setDeleted(c *gin.Context, deleted bool) {
+	actor, ok := requireAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := parseQuestionID(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	if deleted {
+		err = h.QuestionRepo.SoftDelete(c.Request.Context(), id, actor)
+	} else {
+		err = h.QuestionRepo.Restore(c.Request.Context(), id, actor)
+	}
+	if err != nil {
+		respondQuestionRepoError(c, err, "問題の削除状態の変更に失敗しました")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// requireAdmin は管理者名を取り出します。ミドルウェアを通らずに呼ばれた場合は 401 を返します。
+func requireAdmin(c *gin.Context) (string, bool) {
+	actor, ok := currentAdmin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "管理者として認証されていません",
+		})
+	}
+	return actor, ok
+}
+
+// parseQuestionID はパスの :id を正の整数として読み取ります。不正なら 400 を返します。
+func parseQuestionID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_question_id",
+			"message": "問題 ID は正の整数で指定してください",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondQuestionRepoError はリポジトリのエラーを 404・409・500 のレスポンスに変換します。
+func respondQuestionRepoError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrQuestionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "question_not_found",
+			"message": "指定された問題が見つかりません",
+		})
+		return
+	}
+	if errors.Is(err, repository.ErrQuestionSlugTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "slug_taken",
+			"message": "その slug は別の問題で使われています",
+		})
+		return
+	}
+	log.Printf("%s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "database_error",
+		"message": message,
+	})
+}
@@ -0,0 +1,312 @@
+// admin_question_handler_test.go は管理者向け問題管理 API の入力検証と、リポジトリへ渡す actor を確認する単体テストです。
+// DB を使わないよう、QuestionsRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/authoring"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// fakeQuestionsRepo は QuestionsRepository のメモリ上の実装です。呼び出された actor と action を記録します。
+type fakeQuestionsRepo struct {
+	questions map[int]*models.Question
+	actions   []string
+}
+
+func newFakeQuestionsRepo() *fakeQuestionsRepo {
+	return &fakeQuestionsRepo{questions: map[int]*models.Question{}}
+}
+
+func (f *fakeQuestionsRepo) Create(_ context.Context, q *models.Question, actor string) error {
+	q.ID = len(f.questions) + 1
+	f.questions[q.ID] = q
+	f.actions = append(f.actions, actor+":"+repository.AuditActionCreate)
+	return nil
+}
+
+func (f *fakeQuestionsRepo) FindByID(_ context.Context, id int, _ bool) (*models.Question, error) {
+	q, ok := f.questions[id]
+	if !ok {
+		return nil, repository.ErrQuestionNotFound
+	}
+	return q, nil
+}
+
+func (f *fakeQuestionsRepo) FindAll(context.Context, bool) ([]models.Question, error) {
+	return nil, nil
+}
+
+func (f *fakeQuestionsRepo) Update(_ context.Context, q *models.Question, actor string) error {
+	if _, ok := f.questions[q.ID]; !ok {
+		return repository.ErrQuestionNotFound
+	}
+	f.questions[q.ID] = q
+	f.actions = append(f.actions, actor+":"+repository.AuditActionUpdate)
+	return nil
+}
+
+func (f *fakeQuestionsRepo) SoftDelete(_ context.Context, id int, actor string) error {
+	if _, ok := f.questions[id]; !ok {
+		return repository.ErrQuestionNotFound
+	}
+	f.actions = append(f.actions, actor+":"+repository.AuditActionDelete)
+	return nil
+}
+
+func (f *fakeQuestionsRepo) Restore(_ context.Context, id int, actor string) error {
+	if _, ok := f.questions[id]; !ok {
+		return repository.ErrQuestionNotFound
+	}
+	f.actions = append(f.actions, actor+":"+repository.AuditActionRestore)
+	return nil
+}
+
+func (f *fakeQuestionsRepo) FindAuditLogs(context.Context, int, int) ([]repository.QuestionAuditLog, error) {
+	return nil, nil
+}
+
+func (f *fakeQuestionsRepo) Import(_ context.Context, questions []*models.Question, actor string, _ bool) ([]repository.ImportResult, error) {
+	results := make([]repository.ImportResult, 0, len(questions))
+	for _, q := range questions {
+		if err := f.Create(context.Background(), q, actor); err != nil {
+			return nil, err
+		}
+		results = append(results, repository.ImportResult{Slug: *q.Slug, QuestionID: q.ID, Result: repository.ImportCreated})
+	}
+	return results, nil
+}
+
+// newAdminRouter は管理者 alice として認証済みの状態でハンドラを呼ぶルーターを作ります。
+func newAdminRouter(repo repository.QuestionsRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewAdminQuestionHandler(repo)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(ContextKeyAdmin, "alice") })
+	router.POST("/admin/questions", h.CreateQuestion)
+	router.PUT("/admin/questions/:id", h.UpdateQuestion)
+	router.DELETE("/admin/questions/:id", h.DeleteQuestion)
+	return router
+}
+
+func TestAdminQuestionHandler_CreateQuestion(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "最小限", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","tags":["calculation"]}`, wantStatus: http.StatusCreated},
+		{name: "採点パラメータ付き", body: `{"level":2,"problem_statement":"円周率を小数第2位まで","correct_answer":"3.14","scoring_params":{"p":4}}`, wantStatus: http.StatusCreated},
+		{name: "レベル無し", body: `{"problem_statement":"1+1は？","correct_answer":"2"}`, wantStatus: http.StatusBadRequest},
+		{name: "未定義のタグ", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","tags":["unknown"]}`, wantStatus: http.StatusBadRequest},
+		{name: "タグの重複", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","tags":["calculation","calculation"]}`, wantStatus: http.StatusBadRequest},
+		{name: "不正な answer_spec", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","answer_spec":{"type":"set"}}`, wantStatus: http.StatusBadRequest},
+		{name: "採点基準の無い judge", body: `{"level":1,"problem_statement":"俳句を","correct_answer":"-","answer_spec":{"type":"judge"}}`, wantStatus: http.StatusBadRequest},
+		{name: "範囲外の採点パラメータ", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","scoring_params":{"p":20}}`, wantStatus: http.StatusBadRequest},
+		{name: "未登録のテンプレート", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","template_key":"nope"}`, wantStatus: http.StatusBadRequest},
+		{name: "未対応の単位", body: `{"level":1,"problem_statement":"距離は？","correct_answer":"3","expected_unit":"parsec2"}`, wantStatus: http.StatusBadRequest},
+		{name: "単位の食い違いは減点", body: `{"level":1,"problem_statement":"距離は？","correct_answer":"3","expected_unit":"km","unit_policy":"penalize"}`, wantStatus: http.StatusCreated},
+		{name: "未対応の unit_policy", body: `{"level":1,"problem_statement":"距離は？","correct_answer":"3","expected_unit":"km","unit_policy":"ignore"}`, wantStatus: http.StatusBadRequest},
+		{name: "期待単位の無い unit_policy", body: `{"level":1,"problem_statement":"1+1は？","correct_answer":"2","unit_policy":"penalize"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeQuestionsRepo()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/questions", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			newAdminRouter(repo).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(repo.actions) != 0 {
+					t.Errorf("repository should not be called, got %v", repo.actions)
+				}
+				return
+			}
+			if len(repo.actions) != 1 || repo.actions[0] != "alice:create" {
+				t.Errorf("actions = %v, want [alice:create]", repo.actions)
+			}
+			// answer_spec を省略しても value 仕様として保存されます。
+			var saved models.Question
+			if err := json.Unmarshal(w.Body.Bytes(), &saved); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !strings.Contains(string(saved.AnswerSpec), `"type":"value"`) {
+				t.Errorf("answer_spec = %s, want value spec", saved.AnswerSpec)
+			}
+		})
+	}
+}
+
+func TestAdminQuestionHandler_UpdateAndDelete(t *testing.T) {
+	repo := newFakeQuestionsRepo()
+	repo.questions[1] = &models.Question{ID: 1, Level: 1, ProblemStatement: "1+1は？", CorrectAnswer: "2"}
+	router := newAdminRouter(repo)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "更新", method: http.MethodPut, path: "/admin/questions/1", body: `{"level":2,"problem_statement":"2+2は？","correct_answer":"4"}`, wantStatus: http.StatusOK},
+		{name: "存在しない問題の更新", method: http.MethodPut, path: "/admin/questions/9", body: `{"level":2,"problem_statement":"2+2は？","correct_answer":"4"}`, wantStatus: http.StatusNotFound},
+		{name: "不正な ID", method: http.MethodPut, path: "/admin/questions/abc", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "削除", method: http.MethodDelete, path: "/admin/questions/1", wantStatus: http.StatusNoContent},
+		{name: "存在しない問題の削除", method: http.MethodDelete, path: "/admin/questions/9", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	want := []string{"alice:update", "alice:delete"}
+	if strings.Join(repo.actions, ",") != strings.Join(want, ",") {
+		t.Errorf("actions = %v, want %v", repo.actions, want)
+	}
+	if repo.questions[1].CorrectAnswer != "4" {
+		t.Errorf("question was not updated: %+v", repo.questions[1])
+	}
+}
+
+func TestAdminQuestionHandler_ImportQuestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCreated int
+	}{
+		{
+			name:        "YAML",
+			contentType: "application/yaml",
+			body:        "questions:\n  - slug: one-plus-one\n    level: 1\n    problem_statement: 1+1は？\n    correct_answer: \"2\"\n",
+			wantStatus:  http.StatusOK,
+			wantCreated: 1,
+		},
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"questions":[{"slug":"a","level":1,"problem_statement":"1+1は？","correct_answer":"2"},{"slug":"b","level":1,"problem_statement":"2+2は？","correct_answer":"4"}]}`,
+			wantStatus:  http.StatusOK,
+			wantCreated: 2,
+		},
+		{
+			name:        "自己採点で満点にならない",
+			contentType: "application/json",
+			body:        `{"questions":[{"slug":"a","level":1,"problem_statement":"1+1は？","correct_answer":"2","answer_spec":{"type":"value","value":"3"}}]}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{name: "壊れたファイル", contentType: "application/yaml", body: "questions: [", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeQuestionsRepo()
+			h := NewAdminQuestionHandler(repo)
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set(ContextKeyAdmin, "alice") })
+			router.POST("/admin/questions/import", h.ImportQuestions)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/questions/import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(repo.actions) != 0 {
+					t.Errorf("repository should not be called, got %v", repo.actions)
+				}
+				return
+			}
+			var resp ImportQuestionsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Created != tt.wantCreated || len(resp.Results) != tt.wantCreated {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestAdminQuestionHandler_DryRunQuestion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newFakeQuestionsRepo()
+	repo.questions[1] = &models.Question{ID: 1, Level: 1, ProblemStatement: "1+1は？", CorrectAnswer: "2"}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "保存済みの問題", body: `{"question_id":1,"runs":2}`, wantStatus: http.StatusOK},
+		{name: "下書き", body: `{"question":{"level":2,"problem_statement":"1+1は？","correct_answer":"2","tags":["calculation"]}}`, wantStatus: http.StatusOK},
+		{name: "どちらも無い", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "両方ある", body: `{"question_id":1,"question":{"level":1,"problem_statement":"1+1は？","correct_answer":"2"}}`, wantStatus: http.StatusBadRequest},
+		{name: "不正な下書き", body: `{"question":{"level":1,"problem_statement":"1+1は？","correct_answer":"2","tags":["unknown"]}}`, wantStatus: http.StatusBadRequest},
+		{name: "存在しない問題", body: `{"question_id":9}`, wantStatus: http.StatusNotFound},
+		{name: "回数が多すぎる", body: `{"question_id":1,"runs":100}`, wantStatus: http.StatusBadRequest},
+		{name: "未知のモデル", body: `{"question_id":1,"models":["other"]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAdminQuestionHandler(repo)
+			h.DryRunner = &authoring.Runner{Models: []authoring.Model{{Name: "mock", Client: &MockAIClient{Response: ai.Response{RawText: "最終回答: 2"}}}}}
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set(ContextKeyAdmin, "alice") })
+			router.POST("/admin/questions/dry-run", h.DryRunQuestion)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/questions/dry-run", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if len(repo.actions) != 0 {
+				t.Errorf("dry-run should not write, got %v", repo.actions)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			// 指示なしでも毎回正解するモックなので、最も易しい level が提案されます。
+			var report authoring.Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if report.PassRate != 1 || report.SuggestedLevel != 1 {
+				t.Errorf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
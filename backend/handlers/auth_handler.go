@@ -0,0 +1,401 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// auth_handler.go はサインアップ・ログインと、ログインセッションの更新・ログアウトのハンドラです。
+// フロントエンドの authService（/api/signup, /api/login）が期待する {token, user} の形で、アクセストークン（JWT）とユーザー情報を返します。
+// 短命なアクセストークンとは別に、ログインセッションごとのリフレッシュトークンを返し、/api/auth/refresh で使うたびに新しいものへ交換します。
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/auth"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// AuthHandler はサインアップ・ログイン・ログインセッションの依存関係を保持します。
+type AuthHandler struct {
+	UserRepo    repository.UsersRepository
+	SessionRepo repository.SessionsRepository
+	Tokens      *auth.TokenIssuer // nil の場合（JWT_SECRET 未設定）、サインアップ・ログインは 503 を返します。
+	RefreshTTL  time.Duration     // リフレッシュトークン（ログインセッション）の有効期間。使うたびにこの長さだけ延びます。
+}
+
+// NewAuthHandler は新しい AuthHandler を作成します。
+func NewAuthHandler(userRepo repository.UsersRepository, sessionRepo repository.SessionsRepository, tokens *auth.TokenIssuer) *AuthHandler {
+	return &AuthHandler{
+		UserRepo:    userRepo,
+		SessionRepo: sessionRepo,
+		Tokens:      tokens,
+		RefreshTTL:  auth.DefaultRefreshTTL,
+	}
+}
+
+// maxUserAgentLength は端末情報として保存する User-Agent の長さの上限です。
+const maxUserAgentLength = 512
+
+// SignupRequest はサインアップのリクエストボディです。
+type SignupRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginRequest はログインのリクエストボディです。
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest はリフレッシュトークンを送るリクエストボディです（更新・ログアウト）。
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// AuthResponse はサインアップ・ログイン・更新のレスポンスです。token は Authorization: Bearer で送ります。
+// refresh_token は 1 回しか使えません。更新のたびに返る新しい値に置き換えてください。
+type AuthResponse struct {
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+	User             models.User `json:"user"`
+}
+
+// LogoutAllResponse は全端末からのログアウトの結果です。
+type LogoutAllResponse struct {
+	Revoked int64 `json:"revoked"` // 失効させたセッションの数
+}
+
+// Signup は POST /api/signup のハンドラです。ユーザーを登録し、そのままログインした状態のトークンを返します。
+// Signup godoc
+// @Summary      Sign up
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  SignupRequest  true  "Signup"
+// @Success      201  {object}  AuthResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /signup [post]
+func (h *AuthHandler) Signup(c *gin.Context) {
+	if !h.requireTokens(c) {
+		return
+	}
+
+	var req SignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "ユーザー名（3〜50 文字）・メールアドレス・パスワードを指定してください",
+			"detail":  err.Error(),
+		})
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "weak_password",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("パスワードのハッシュ化に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": "ユーザーの登録に失敗しました",
+		})
+		return
+	}
+	user := &models.User{
+		Username:     strings.TrimSpace(req.Username),
+		Email:        strings.ToLower(strings.TrimSpace(req.Email)),
+		PasswordHash: hash,
+	}
+	if err := h.UserRepo.Create(c.Request.Context(), user); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "username_taken",
+				"message": "そのユーザー名は既に使われています",
+			})
+		case errors.Is(err, repository.ErrEmailTaken):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "email_taken",
+				"message": "そのメールアドレスは既に登録されています",
+			})
+		default:
+			log.Printf("ユーザーの登録に失敗しました: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "database_error",
+				"message": "ユーザーの登録に失敗しました",
+			})
+		}
+		return
+	}
+
+	h.startSession(c, http.StatusCreated, user)
+}
+
+// Login は POST /api/login のハンドラです。
+// メールアドレスが未登録でもパスワードが違っても同じ 401 を返し、登録済みのメールアドレスを推測されないようにします。
+// Login godoc
+// @Summary      Log in
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  LoginRequest  true  "Login"
+// @Success      200  {object}  AuthResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	if !h.requireTokens(c) {
+		return
+	}
+
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "メールアドレスとパスワードを指定してください",
+			"detail":  err.Error(),
+		})
+		return
+	}
+
+	user, err := h.UserRepo.FindByEmail(c.Request.Context(), strings.TrimSpace(req.Email))
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		err = auth.CheckPasswordMissingUser(req.Password)
+	case err != nil:
+		log.Printf("ユーザーの検索に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "ログインに失敗しました",
+		})
+		return
+	default:
+		err = auth.CheckPassword(user.PasswordHash, req.Password)
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_credentials",
+			"message": "メールアドレスまたはパスワードが正しくありません",
+		})
+		return
+	}
+
+	h.startSession(c, http.StatusOK, user)
+}
+
+// Refresh は POST /api/auth/refresh のハンドラです。
+// リフレッシュトークンを新しいものに交換し、新しいアクセストークンと一緒に返します。
+// 使用済みのリフレッシュトークンが送られた場合は盗まれたものとみなし、そのセッションを失効させて 401 を返します。
+// Refresh godoc
+// @Summary      Refresh the access token
+// @Description  Rotates the refresh token. Presenting an already-used refresh token revokes the whole session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  RefreshRequest  true  "Refresh token"
+// @Success      200  {object}  AuthResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	if !h.requireTokens(c) {
+		return
+	}
+	req, ok := bindRefreshRequest(c)
+	if !ok {
+		return
+	}
+
+	refreshToken, newHash, err := auth.NewRefreshToken()
+	if err != nil {
+		respondAuthInternalError(c, err, "リフレッシュトークンの作成に失敗しました")
+		return
+	}
+	session, err := h.SessionRepo.Rotate(c.Request.Context(), auth.HashRefreshToken(req.RefreshToken), newHash, time.Now().Add(h.RefreshTTL))
+	switch {
+	case errors.Is(err, repository.ErrRefreshTokenReused):
+		log.Printf("使用済みのリフレッシュトークンが使われたためセッションを失効させました (ip=%s)", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "refresh_token_reused",
+			"message": "このログインは無効になりました。もう一度ログインしてください",
+		})
+		return
+	case errors.Is(err, repository.ErrRefreshTokenInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_refresh_token",
+			"message": "ログインの有効期限が切れました。もう一度ログインしてください",
+		})
+		return
+	case err != nil:
+		respondAuthInternalError(c, err, "ログインの更新に失敗しました")
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(c.Request.Context(), session.UserID)
+	if err != nil {
+		respondAuthInternalError(c, err, "ログインの更新に失敗しました")
+		return
+	}
+	h.respondWithTokens(c, http.StatusOK, user, session, refreshToken)
+}
+
+// Logout は POST /api/auth/logout のハンドラです。リフレッシュトークンのセッションを失効させます。
+// 既にログアウト済み・未知のトークンでも 204 を返します。
+// Logout godoc
+// @Summary      Log out
+// @Tags         auth
+// @Accept       json
+// @Param        request  body  RefreshRequest  true  "Refresh token of the session to end"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	req, ok := bindRefreshRequest(c)
+	if !ok {
+		return
+	}
+	if err := h.SessionRepo.RevokeByToken(c.Request.Context(), auth.HashRefreshToken(req.RefreshToken), repository.RevokeReasonLogout); err != nil {
+		respondAuthInternalError(c, err, "ログアウトに失敗しました")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll は POST /api/auth/logout-all のハンドラです。ログイン中のユーザーの全てのセッション（他の端末を含む）を失効させます。
+// LogoutAll godoc
+// @Summary      Log out everywhere
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  LogoutAllResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	n, err := h.SessionRepo.RevokeAllForUser(c.Request.Context(), userID, repository.RevokeReasonLogoutAll)
+	if err != nil {
+		respondAuthInternalError(c, err, "ログアウトに失敗しました")
+		return
+	}
+	c.JSON(http.StatusOK, LogoutAllResponse{Revoked: n})
+}
+
+// requireTokens はトークンを発行できる状態か確認します。JWT_SECRET が未設定なら 503 を返します。
+func (h *AuthHandler) requireTokens(c *gin.Context) bool {
+	if h.Tokens == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "auth_unavailable",
+			"message": "認証が設定されていないため、現在ログインできません",
+		})
+		return false
+	}
+	return true
+}
+
+// startSession はログインセッションを作り、アクセストークンとリフレッシュトークンを返します。
+func (h *AuthHandler) startSession(c *gin.Context, status int, user *models.User) {
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		respondAuthInternalError(c, err, "ログインに失敗しました")
+		return
+	}
+	session := &repository.Session{
+		UserID:    user.ID,
+		UserAgent: userAgent(c),
+		ExpiresAt: time.Now().Add(h.RefreshTTL),
+	}
+	if ip := c.ClientIP(); ip != "" {
+		session.IPAddress = &ip
+	}
+	if err := h.SessionRepo.Create(c.Request.Context(), session, hash); err != nil {
+		respondAuthInternalError(c, err, "ログインに失敗しました")
+		return
+	}
+	h.respondWithTokens(c, status, user, session, refreshToken)
+}
+
+// respondWithTokens はセッションに対するアクセストークンを発行し、リフレッシュトークン・ユーザー情報と一緒に返します。
+func (h *AuthHandler) respondWithTokens(c *gin.Context, status int, user *models.User, session *repository.Session, refreshToken string) {
+	token, expiresAt, err := h.Tokens.Issue(user.ID, session.ID)
+	if err != nil {
+		respondAuthInternalError(c, err, "トークンの発行に失敗しました")
+		return
+	}
+	c.JSON(status, AuthResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             *user,
+	})
+}
+
+// bindRefreshRequest はリフレッシュトークンを含むリクエストボディを読み取ります。失敗時は 400 を返します。
+func bindRefreshRequest(c *gin.Context) (RefreshRequest, bool) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "refresh_token を指定してください",
+			"detail":  err.Error(),
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// userAgent は端末情報として保存する User-Agent を返します。長すぎる値は切り詰めます。
+func userAgent(c *gin.Context) *string {
+	ua := c.Request.UserAgent()
+	if ua == "" {
+		return nil
+	}
+	if len(ua) > maxUserAgentLength {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLength], "")
+	}
+	return &ua
+}
+
+// requireUser はログイン中のユーザー ID を取り出します。ゲストの場合は 401 を返します。
+func requireUser(c *gin.Context) (int, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "login_required",
+			"message": "ログインが必要です",
+		})
+	}
+	return userID, ok
+}
+
+// respondAuthInternalError は認証処理の予期しないエラーをログに残し、500 を返します。
+func respondAuthInternalError(c *gin.Context, err error, message string) {
+	log.Printf("%s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_error",
+		"message": message,
+	})
+}
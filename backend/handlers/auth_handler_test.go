@@ -0,0 +1,279 @@
+// auth_handler_test.go はサインアップ・ログインの入力検証、重複時の 409、誤ったパスワードの 401 と、返したトークンが検証できること、
+// リフレッシュトークンのローテーションとログアウトを確認する単体テストです。
+// DB を使わないよう、UsersRepository と SessionsRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/auth"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// fakeUsersRepo は UsersRepository のメモリ上の実装です。
+type fakeUsersRepo struct {
+	users []*models.User
+}
+
+func (f *fakeUsersRepo) Create(_ context.Context, user *models.User) error {
+	for _, u := range f.users {
+		if u.Username == user.Username {
+			return repository.ErrUsernameTaken
+		}
+		if u.Email == user.Email {
+			return repository.ErrEmailTaken
+		}
+	}
+	user.ID = len(f.users) + 1
+	user.CreatedAt = time.Now()
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeUsersRepo) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Email == strings.ToLower(email) {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (f *fakeUsersRepo) FindByID(_ context.Context, id int) (*models.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// fakeSessionsRepo は SessionsRepository のメモリ上の実装です。トークンのハッシュごとにセッションと使用済みかを持ちます。
+type fakeSessionsRepo struct {
+	sessions map[int64]*repository.Session
+	revoked  map[int64]string
+	tokens   map[string]int64
+	used     map[string]bool
+}
+
+func newFakeSessionsRepo() *fakeSessionsRepo {
+	return &fakeSessionsRepo{
+		sessions: map[int64]*repository.Session{},
+		revoked:  map[int64]string{},
+		tokens:   map[string]int64{},
+		used:     map[string]bool{},
+	}
+}
+
+func (f *fakeSessionsRepo) Create(_ context.Context, session *repository.Session, tokenHash string) error {
+	session.ID = int64(len(f.sessions) + 1)
+	f.sessions[session.ID] = session
+	f.tokens[tokenHash] = session.ID
+	return nil
+}
+
+func (f *fakeSessionsRepo) Rotate(_ context.Context, oldHash, newHash string, expiresAt time.Time) (*repository.Session, error) {
+	id, ok := f.tokens[oldHash]
+	if !ok || f.revoked[id] != "" {
+		return nil, repository.ErrRefreshTokenInvalid
+	}
+	if f.used[oldHash] {
+		f.revoked[id] = repository.RevokeReasonReuse
+		return nil, repository.ErrRefreshTokenReused
+	}
+	f.used[oldHash] = true
+	f.tokens[newHash] = id
+	f.sessions[id].ExpiresAt = expiresAt
+	return f.sessions[id], nil
+}
+
+func (f *fakeSessionsRepo) RevokeByToken(_ context.Context, tokenHash, reason string) error {
+	if id, ok := f.tokens[tokenHash]; ok && f.revoked[id] == "" {
+		f.revoked[id] = reason
+	}
+	return nil
+}
+
+func (f *fakeSessionsRepo) RevokeAllForUser(_ context.Context, userID int, reason string) (int64, error) {
+	var n int64
+	for id, s := range f.sessions {
+		if s.UserID == userID && f.revoked[id] == "" {
+			f.revoked[id] = reason
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeSessionsRepo) IsActive(_ context.Context, sessionID int64) (bool, error) {
+	_, ok := f.sessions[sessionID]
+	return ok && f.revoked[sessionID] == "", nil
+}
+
+func newTestTokens(t *testing.T) *auth.TokenIssuer {
+	t.Helper()
+	tokens, err := auth.NewTokenIssuer("0123456789abcdef0123456789abcdef", time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+	return tokens
+}
+
+func TestAuthHandler_SignupAndLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := newTestTokens(t)
+	h := NewAuthHandler(&fakeUsersRepo{}, newFakeSessionsRepo(), tokens)
+	router := gin.New()
+	router.POST("/api/signup", h.Signup)
+	router.POST("/api/login", h.Login)
+
+	// 順に実行し、最初のサインアップで作ったユーザーを後のケースで使います。
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "サインアップ", path: "/api/signup", body: `{"username":"alice","email":"Alice@Example.com","password":"password123"}`, wantStatus: http.StatusCreated},
+		{name: "ユーザー名の重複", path: "/api/signup", body: `{"username":"alice","email":"other@example.com","password":"password123"}`, wantStatus: http.StatusConflict},
+		{name: "メールアドレスの重複", path: "/api/signup", body: `{"username":"bob","email":"alice@example.com","password":"password123"}`, wantStatus: http.StatusConflict},
+		{name: "短いパスワード", path: "/api/signup", body: `{"username":"carol","email":"carol@example.com","password":"short"}`, wantStatus: http.StatusBadRequest},
+		{name: "不正なメールアドレス", path: "/api/signup", body: `{"username":"carol","email":"carol","password":"password123"}`, wantStatus: http.StatusBadRequest},
+		{name: "ログイン", path: "/api/login", body: `{"email":"ALICE@example.com","password":"password123"}`, wantStatus: http.StatusOK},
+		{name: "誤ったパスワード", path: "/api/login", body: `{"email":"alice@example.com","password":"wrong-password"}`, wantStatus: http.StatusUnauthorized},
+		{name: "未登録のメールアドレス", path: "/api/login", body: `{"email":"nobody@example.com","password":"password123"}`, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code >= 300 {
+				return
+			}
+			var resp AuthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.User.Email != "alice@example.com" || strings.Contains(w.Body.String(), "password") {
+				t.Errorf("unexpected user in response: %s", w.Body.String())
+			}
+			claims, err := tokens.Verify(resp.Token)
+			if err != nil || claims.UserID != resp.User.ID {
+				t.Errorf("token does not identify the user: %+v, %v", claims, err)
+			}
+			if resp.RefreshToken == "" {
+				t.Error("refresh_token should be returned")
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Unavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthHandler(&fakeUsersRepo{}, newFakeSessionsRepo(), nil)
+	router := gin.New()
+	router.POST("/api/login", h.Login)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"email":"a@example.com","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+// TestAuthHandler_RefreshAndLogout はリフレッシュトークンの交換、使用済みトークンの再利用による失効、ログアウトを順に確認します。
+func TestAuthHandler_RefreshAndLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := newTestTokens(t)
+	sessions := newFakeSessionsRepo()
+	users := &fakeUsersRepo{}
+	h := NewAuthHandler(users, sessions, tokens)
+	router := gin.New()
+	router.POST("/api/signup", h.Signup)
+	router.POST("/api/auth/refresh", h.Refresh)
+	router.POST("/api/auth/logout", h.Logout)
+	router.POST("/api/auth/logout-all", func(c *gin.Context) { c.Set(ContextKeyUserID, 1) }, h.LogoutAll)
+
+	post := func(path, body string) (*httptest.ResponseRecorder, AuthResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var resp AuthResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	refreshBody := func(token string) string { return `{"refresh_token":"` + token + `"}` }
+
+	w, first := post("/api/signup", `{"username":"alice","email":"alice@example.com","password":"password123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d (body=%s)", w.Code, w.Body.String())
+	}
+
+	// 交換すると新しいリフレッシュトークンと、同じセッションのアクセストークンが返ります。
+	w, second := post("/api/auth/refresh", refreshBody(first.RefreshToken))
+	if w.Code != http.StatusOK || second.RefreshToken == first.RefreshToken || second.User.ID != first.User.ID {
+		t.Fatalf("refresh = %d %+v", w.Code, second)
+	}
+	claims, err := tokens.Verify(second.Token)
+	if err != nil || claims.SessionID != 1 {
+		t.Errorf("refreshed token = %+v, %v", claims, err)
+	}
+
+	// 使用済みのトークンを再び使うとセッションが失効し、最新のトークンも使えなくなります。
+	if w, _ := post("/api/auth/refresh", refreshBody(first.RefreshToken)); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "refresh_token_reused") {
+		t.Errorf("reused refresh = %d %s", w.Code, w.Body.String())
+	}
+	if sessions.revoked[1] != repository.RevokeReasonReuse {
+		t.Errorf("session should be revoked for reuse: %v", sessions.revoked)
+	}
+	if w, _ := post("/api/auth/refresh", refreshBody(second.RefreshToken)); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after revoke = %d, want 401", w.Code)
+	}
+
+	// ログアウトは未知のトークンでも 204 を返します。
+	_, third := post("/api/signup", `{"username":"bob","email":"bob@example.com","password":"password123"}`)
+	if w, _ := post("/api/auth/logout", refreshBody(third.RefreshToken)); w.Code != http.StatusNoContent {
+		t.Errorf("logout = %d, want 204", w.Code)
+	}
+	if w, _ := post("/api/auth/logout", refreshBody("unknown")); w.Code != http.StatusNoContent {
+		t.Errorf("logout(unknown) = %d, want 204", w.Code)
+	}
+	if active, _ := sessions.IsActive(context.Background(), 2); active {
+		t.Error("logged out session should be inactive")
+	}
+	if w, _ := post("/api/auth/logout", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("logout without token = %d, want 400", w.Code)
+	}
+
+	// 全端末からのログアウトはユーザーの有効なセッションだけを失効させます。
+	users.users[0].ID = 1
+	sessions.sessions[3] = &repository.Session{ID: 3, UserID: 1}
+	sessions.sessions[4] = &repository.Session{ID: 4, UserID: 2}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/auth/logout-all", nil))
+	var all LogoutAllResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil || w.Code != http.StatusOK || all.Revoked != 1 {
+		t.Errorf("logout-all = %d %s", w.Code, w.Body.String())
+	}
+	if active, _ := sessions.IsActive(context.Background(), 4); !active {
+		t.Error("other user's session should stay active")
+	}
+}
@@ -0,0 +1,33 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// context.go はミドルウェアとハンドラの間で gin.Context に載せる値のキーと、その取り出し方をまとめます。
+package handlers
+
+import "github.com/gin-gonic/gin"
+
+// ContextKeyUserID はログイン中のユーザー ID（int）を gin.Context に保存するキーです。
+// 認証ミドルウェアがセットし、ハンドラは currentUserID で取り出します。
+const ContextKeyUserID = "user_id"
+
+// currentUserID はリクエストしたユーザーの ID を返します。未ログイン（ゲスト）の場合は false です。
+func currentUserID(c *gin.Context) (int, bool) {
+	v, ok := c.Get(ContextKeyUserID)
+	if !ok {
+		return 0, false
+	}
+	id, ok := v.(int)
+	return id, ok
+}
+
+// ContextKeyAdmin は管理 API を呼び出した管理者の名前（string）を gin.Context に保存するキーです。
+// 管理者認証ミドルウェアがセットし、ハンドラは currentAdmin で取り出して変更履歴の actor に使います。
+const ContextKeyAdmin = "admin"
+
+// currentAdmin はリクエストした管理者の名前を返します。管理者認証を通っていない場合は false です。
+func currentAdmin(c *gin.Context) (string, bool) {
+	v, ok := c.Get(ContextKeyAdmin)
+	if !ok {
+		return "", false
+	}
+	name, ok := v.(string)
+	return name, ok && name != ""
+}
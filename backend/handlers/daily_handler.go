@@ -0,0 +1,201 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// daily_handler.go は今日の一問（デイリーチャレンジ）の出題・日付ごとのランキング・過去の日の勝者を返すエンドポイントをまとめたハンドラです。
+// 挑戦そのものは POST /api/v1/solve に daily: true を付けて行い、回数の上限と成績の記録は SolveHandler が行います。
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/daily"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// DailyHandler は今日の一問のエンドポイントの依存関係を保持します。
+type DailyHandler struct {
+	Daily  repository.DailyRepository
+	Config daily.Config // 日付を区切るタイムゾーンと 1 日の挑戦回数
+}
+
+// NewDailyHandler は新しい DailyHandler を作成します。
+func NewDailyHandler(repo repository.DailyRepository, config daily.Config) *DailyHandler {
+	return &DailyHandler{Daily: repo, Config: config}
+}
+
+// DailyResponse は GET /api/v1/daily のレスポンスです。問題文と正解は含めません。
+type DailyResponse struct {
+	repository.DailyChallenge
+	AttemptsLimit int                    `json:"attempts_limit"` // 1 日に挑戦できる回数
+	ResetsAt      time.Time              `json:"resets_at"`      // 次の問題に切り替わり、ランキングがリセットされる時刻
+	Me            *repository.DailyEntry `json:"me"`             // ログイン中のユーザーの今日の成績。ゲストや未挑戦なら null です。
+}
+
+// DailyLeaderboardResponse は GET /api/v1/daily/leaderboard のレスポンスです。
+type DailyLeaderboardResponse struct {
+	Date       string                  `json:"date"`
+	QuestionID int                     `json:"question_id"`
+	Entries    []repository.DailyEntry `json:"entries"`
+	Me         *repository.DailyEntry  `json:"me"` // ログイン中のユーザーのその日の成績。上位に入っていなくても返します。
+}
+
+// GetDaily は GET /api/v1/daily のハンドラです。
+// 今日の出題（問題 ID・レベル・タグ）と挑戦できる回数、次にリセットされる時刻を返します。
+// GetDaily godoc
+// @Summary      Get today's daily challenge
+// @Description  Returns today's question (no problem statement/answer), the attempt limit, the reset time and the caller's result so far
+// @Tags         daily
+// @Produce      json
+// @Success      200  {object}  DailyResponse
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /daily [get]
+func (h *DailyHandler) GetDaily(c *gin.Context) {
+	ctx := c.Request.Context()
+	today := h.Config.Today()
+	challenge, err := h.Daily.FindOrCreate(ctx, today)
+	if err != nil {
+		respondDailyError(c, err, "今日の一問の取得に失敗しました")
+		return
+	}
+
+	resp := DailyResponse{
+		DailyChallenge: *challenge,
+		AttemptsLimit:  h.Config.Attempts,
+		ResetsAt:       h.Config.NextReset(),
+	}
+	if userID, ok := currentUserID(c); ok {
+		if resp.Me, err = h.Daily.FindEntry(ctx, today, userID); err != nil {
+			respondDailyError(c, err, "今日の成績の取得に失敗しました")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetDailyLeaderboard は GET /api/v1/daily/leaderboard のハンドラです。
+// date（既定は今日）の最高点のランキングを返します。日付ごとに独立しているため、日付が変わると新しいランキングになります。
+// GetDailyLeaderboard godoc
+// @Summary      Get the daily challenge leaderboard
+// @Description  Ranking of one day's daily challenge by best score (earlier first on ties), with the caller's own entry
+// @Tags         daily
+// @Produce      json
+// @Param        date   query  string  false  "YYYY-MM-DD (default: today in DAILY_TIMEZONE)"
+// @Param        limit  query  int     false  "Number of rows (default: 10, max: 100)"
+// @Success      200  {object}  DailyLeaderboardResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /daily/leaderboard [get]
+func (h *DailyHandler) GetDailyLeaderboard(c *gin.Context) {
+	date, ok := parseDailyDate(c, "date", h.Config.Today())
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var challenge *repository.DailyChallenge
+	var err error
+	if date == h.Config.Today() {
+		challenge, err = h.Daily.FindOrCreate(ctx, date)
+	} else {
+		challenge, err = h.Daily.Find(ctx, date)
+	}
+	if err != nil {
+		respondDailyError(c, err, "今日の一問の取得に失敗しました")
+		return
+	}
+
+	entries, err := h.Daily.FindLeaderboard(ctx, date, limit)
+	if err != nil {
+		respondDailyError(c, err, "ランキングの取得に失敗しました")
+		return
+	}
+	resp := DailyLeaderboardResponse{Date: date, QuestionID: challenge.QuestionID, Entries: entries}
+	// 該当者がいない場合も null ではなく空配列を返します。
+	if resp.Entries == nil {
+		resp.Entries = []repository.DailyEntry{}
+	}
+	if userID, ok := currentUserID(c); ok {
+		if resp.Me, err = h.Daily.FindEntry(ctx, date, userID); err != nil {
+			respondDailyError(c, err, "ランキングの自分の順位の取得に失敗しました")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetDailyHistory は GET /api/v1/daily/history のハンドラです。
+// before（既定は今日。その日は含めません）より前の日の出題と勝者を新しい順に返します。続きは最後の日付を before に指定して取得します。
+// GetDailyHistory godoc
+// @Summary      Browse past daily challenges
+// @Description  Past days' questions and winners, newest first
+// @Tags         daily
+// @Produce      json
+// @Param        before  query  string  false  "YYYY-MM-DD, exclusive (default: today in DAILY_TIMEZONE)"
+// @Param        limit   query  int     false  "Number of days (default: 10, max: 100)"
+// @Success      200  {array}   repository.DailyArchive
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /daily/history [get]
+func (h *DailyHandler) GetDailyHistory(c *gin.Context) {
+	before, ok := parseDailyDate(c, "before", h.Config.Today())
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.Daily.FindHistory(c.Request.Context(), before, limit)
+	if err != nil {
+		respondDailyError(c, err, "過去の今日の一問の取得に失敗しました")
+		return
+	}
+	if history == nil {
+		history = []repository.DailyArchive{}
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// parseDailyDate はクエリパラメータ name を日付として読み取ります。未指定なら defaultDate を使い、不正な値の場合は 400 を返して false を返します。
+func parseDailyDate(c *gin.Context, name, defaultDate string) (string, bool) {
+	raw := c.DefaultQuery(name, defaultDate)
+	if _, err := daily.ParseDate(raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_date",
+			"message": name + " は YYYY-MM-DD の形式で指定してください",
+		})
+		return "", false
+	}
+	return raw, true
+}
+
+// respondDailyError はリポジトリのエラーを 404・503・500 のレスポンスに変換します。
+func respondDailyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrDailyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "daily_not_found",
+			"message": "指定された日の今日の一問はありません",
+		})
+	case errors.Is(err, repository.ErrNoDailyQuestion):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "no_daily_question",
+			"message": "出題できる問題がありません",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": message,
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// daily_handler_test.go は今日の一問のエンドポイントが日付の区切り・自分の成績・日付の検証・過去の日の扱いを正しく返すかを確認する単体テストです。
+// DB を使わないよう、DailyRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/daily"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// fakeDailyRepo は日付ごとの出題と成績をメモリ上に持ちます。FindOrCreate はまだ無い日付に問題 1 を出題します。
+// ReleaseAttempt と RecordResult は本物の DB と同じく、キャンセル済みの context ではエラーを返します。
+type fakeDailyRepo struct {
+	challenges map[string]*repository.DailyChallenge
+	entries    map[string]map[int]*repository.DailyEntry
+	history    []repository.DailyArchive
+	released   int   // ReleaseAttempt で戻した回数
+	recorded   []int // RecordResult で記録したスコア
+}
+
+func newFakeDailyRepo() *fakeDailyRepo {
+	return &fakeDailyRepo{
+		challenges: map[string]*repository.DailyChallenge{},
+		entries:    map[string]map[int]*repository.DailyEntry{},
+	}
+}
+
+func (f *fakeDailyRepo) FindOrCreate(_ context.Context, date string) (*repository.DailyChallenge, error) {
+	if _, ok := f.challenges[date]; !ok {
+		f.challenges[date] = &repository.DailyChallenge{Date: date, QuestionID: 1, Level: 1, Tags: []string{}}
+	}
+	return f.challenges[date], nil
+}
+
+func (f *fakeDailyRepo) Find(_ context.Context, date string) (*repository.DailyChallenge, error) {
+	c, ok := f.challenges[date]
+	if !ok {
+		return nil, repository.ErrDailyNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeDailyRepo) ReserveAttempt(_ context.Context, date string, userID, limit int) (int, error) {
+	if f.entries[date] == nil {
+		f.entries[date] = map[int]*repository.DailyEntry{}
+	}
+	e, ok := f.entries[date][userID]
+	if !ok {
+		e = &repository.DailyEntry{UserID: userID}
+		f.entries[date][userID] = e
+	}
+	if e.Attempts >= limit {
+		return 0, repository.ErrDailyAttemptsExhausted
+	}
+	e.Attempts++
+	return e.Attempts, nil
+}
+
+func (f *fakeDailyRepo) ReleaseAttempt(ctx context.Context, date string, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if e, ok := f.entries[date][userID]; ok && e.Attempts > 0 {
+		e.Attempts--
+		f.released++
+	}
+	return nil
+}
+
+func (f *fakeDailyRepo) RecordResult(ctx context.Context, date string, userID, score int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.recorded = append(f.recorded, score)
+	if e, ok := f.entries[date][userID]; ok && (e.BestScore == nil || score > *e.BestScore) {
+		e.BestScore = &score
+	}
+	return nil
+}
+
+func (f *fakeDailyRepo) FindEntry(_ context.Context, date string, userID int) (*repository.DailyEntry, error) {
+	return f.entries[date][userID], nil
+}
+
+func (f *fakeDailyRepo) FindLeaderboard(_ context.Context, date string, _ int) ([]repository.DailyEntry, error) {
+	var entries []repository.DailyEntry
+	for _, e := range f.entries[date] {
+		if e.BestScore != nil {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeDailyRepo) FindHistory(_ context.Context, before string, _ int) ([]repository.DailyArchive, error) {
+	var history []repository.DailyArchive
+	for _, a := range f.history {
+		if a.Date < before {
+			history = append(history, a)
+		}
+	}
+	return history, nil
+}
+
+// newDailyTestRouter は X-Test-User ヘッダーがあればユーザー 7 としてログインした扱いにするルーターを作ります。
+// 現在時刻は UTC の 2025-11-15 15:30（日本時間では 11-16）に固定します。
+func newDailyTestRouter(t *testing.T, repo repository.DailyRepository) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	tokyo, err := time.LoadLocation(daily.DefaultTimezone)
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	h := NewDailyHandler(repo, daily.Config{
+		Location: tokyo,
+		Attempts: 3,
+		Now:      func() time.Time { return time.Date(2025, 11, 15, 15, 30, 0, 0, time.UTC) },
+	})
+
+	router := gin.New()
+	api := router.Group("/api/v1", func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set(ContextKeyUserID, 7)
+		}
+	})
+	api.GET("/daily", h.GetDaily)
+	api.GET("/daily/leaderboard", h.GetDailyLeaderboard)
+	api.GET("/daily/history", h.GetDailyHistory)
+	return router
+}
+
+func TestDailyHandler_GetDaily(t *testing.T) {
+	repo := newFakeDailyRepo()
+	score := 80
+	repo.entries["2025-11-16"] = map[int]*repository.DailyEntry{7: {UserID: 7, Attempts: 1, BestScore: &score}}
+	router := newDailyTestRouter(t, repo)
+
+	tests := []struct {
+		name     string
+		loggedIn bool
+		wantMe   bool
+	}{
+		{name: "ログイン中は自分の成績を返す", loggedIn: true, wantMe: true},
+		{name: "ゲスト", loggedIn: false, wantMe: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/daily", nil)
+			if tt.loggedIn {
+				req.Header.Set("X-Test-User", "1")
+			}
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body=%s)", w.Code, w.Body.String())
+			}
+
+			var resp DailyResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			// 日付は日本時間で区切ります。
+			if resp.Date != "2025-11-16" || resp.AttemptsLimit != 3 {
+				t.Errorf("date = %s, attempts_limit = %d", resp.Date, resp.AttemptsLimit)
+			}
+			if want := time.Date(2025, 11, 16, 15, 0, 0, 0, time.UTC); !resp.ResetsAt.Equal(want) {
+				t.Errorf("resets_at = %s, want %s", resp.ResetsAt, want)
+			}
+			if (resp.Me != nil) != tt.wantMe {
+				t.Errorf("me = %+v, want present=%v", resp.Me, tt.wantMe)
+			}
+		})
+	}
+}
+
+func TestDailyHandler_GetDailyLeaderboard(t *testing.T) {
+	repo := newFakeDailyRepo()
+	repo.challenges["2025-11-10"] = &repository.DailyChallenge{Date: "2025-11-10", QuestionID: 5}
+	score := 100
+	repo.entries["2025-11-10"] = map[int]*repository.DailyEntry{3: {UserID: 3, Attempts: 2, BestScore: &score}}
+	router := newDailyTestRouter(t, repo)
+
+	tests := []struct {
+		name           string
+		query          string
+		wantStatus     int
+		wantQuestionID int
+		wantEntries    int
+	}{
+		{name: "今日（まだ誰も挑戦していない）", query: "", wantStatus: http.StatusOK, wantQuestionID: 1, wantEntries: 0},
+		{name: "過去の日", query: "?date=2025-11-10", wantStatus: http.StatusOK, wantQuestionID: 5, wantEntries: 1},
+		{name: "出題の無い過去の日", query: "?date=2025-11-01", wantStatus: http.StatusNotFound},
+		{name: "不正な日付", query: "?date=2025/11/10", wantStatus: http.StatusBadRequest},
+		{name: "不正な件数", query: "?limit=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/daily/leaderboard"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DailyLeaderboardResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.QuestionID != tt.wantQuestionID || len(resp.Entries) != tt.wantEntries {
+				t.Errorf("question_id = %d, entries = %+v", resp.QuestionID, resp.Entries)
+			}
+			// 該当者がいなくても null ではなく空配列を返します。
+			if resp.Entries == nil {
+				t.Error("entries should be an empty array, not null")
+			}
+		})
+	}
+}
+
+func TestDailyHandler_GetDailyHistory(t *testing.T) {
+	repo := newFakeDailyRepo()
+	repo.history = []repository.DailyArchive{
+		{Date: "2025-11-15", QuestionID: 2, Players: 1, Winner: &repository.DailyEntry{UserID: 3}},
+		{Date: "2025-11-14", QuestionID: 4},
+	}
+	router := newDailyTestRouter(t, repo)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantDays   int
+	}{
+		{name: "今日より前", query: "", wantStatus: http.StatusOK, wantDays: 2},
+		{name: "before より前", query: "?before=2025-11-15", wantStatus: http.StatusOK, wantDays: 1},
+		{name: "不正な日付", query: "?before=yesterday", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/daily/history"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var history []repository.DailyArchive
+			if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(history) != tt.wantDays {
+				t.Errorf("history = %+v, want %d days", history, tt.wantDays)
+			}
+		})
+	}
+}
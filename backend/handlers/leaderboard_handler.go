@@ -0,0 +1,231 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// leaderboard_handler.go はスコアの集計結果（ランキング）を返すエンドポイントをまとめたハンドラです。
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+const (
+	// defaultLeaderboardLimit はランキングの既定の件数です。
+	defaultLeaderboardLimit = 10
+	// maxLeaderboardLimit はランキングで一度に返す件数の上限です。
+	maxLeaderboardLimit = 100
+)
+
+// LeaderboardHandler はランキング系エンドポイントの依存関係を保持します。
+type LeaderboardHandler struct {
+	ScoreRepo repository.ScoresRepository // 集計クエリはリポジトリ層に任せ、ハンドラは入力の検証とレスポンスに集中します。
+}
+
+// NewLeaderboardHandler は新しい LeaderboardHandler を作成します。
+func NewLeaderboardHandler(scoreRepo repository.ScoresRepository) *LeaderboardHandler {
+	return &LeaderboardHandler{ScoreRepo: scoreRepo}
+}
+
+// LeaderboardResponse は GET /api/v1/leaderboard のレスポンスです。
+type LeaderboardResponse struct {
+	Period     string                      `json:"period"`
+	QuestionID *int                        `json:"question_id,omitempty"`
+	Tag        *string                     `json:"tag,omitempty"`
+	Weighted   bool                        `json:"weighted"` // true なら total_score は自己ベストに問題のレベルを掛けた合計です。
+	Entries    []repository.LeaderboardRow `json:"entries"`
+	Me         *repository.LeaderboardRow  `json:"me"` // ログイン中のユーザー自身の順位。上位に入っていなくても返します。ゲストや対象期間にスコアが無い場合は null です。
+}
+
+// GetLeaderboard は GET /api/v1/leaderboard のハンドラです。
+// 期間内の問題ごとの自己ベストの合計で並べたランキングを返します。question_id で問題ごと、tag でタグごとのランキングに絞り込めます。
+// weighted=true を指定すると、自己ベストに問題のレベルを掛けてから合計します。
+// GetLeaderboard godoc
+// @Summary      Get leaderboard
+// @Description  Ranking by the sum of each user's best score per question, optionally per question or per tag, with the caller's own rank
+// @Tags         leaderboard
+// @Produce      json
+// @Param        period       query  string  false  "day, week or all (default: day)"
+// @Param        question_id  query  int     false  "Restrict to a single question"
+// @Param        tag          query  string  false  "Restrict to questions with this tag"
+// @Param        weighted     query  bool    false  "Weight each best score by the question level (default: false)"
+// @Param        limit        query  int     false  "Number of rows (default: 10, max: 100)"
+// @Success      200  {object}  LeaderboardResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /leaderboard [get]
+func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
+	// 通常のランキングは「今日の順位」を見せるのが目的なので、period の既定値は day です（docs/task/13）。
+	period, ok := parsePeriod(c, "day")
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+	questionID, ok := parseQuestionIDFilter(c)
+	if !ok {
+		return
+	}
+
+	var tag *string
+	if raw := c.Query("tag"); raw != "" {
+		if _, found := models.GetTagByID(raw); !found {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_tag",
+				"message": "tag " + raw + " は定義されていません",
+			})
+			return
+		}
+		tag = &raw
+	}
+
+	var weighted bool
+	if raw := c.Query("weighted"); raw != "" {
+		var err error
+		weighted, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_weighted",
+				"message": "weighted は true または false を指定してください",
+			})
+			return
+		}
+	}
+
+	scope := repository.LeaderboardScope{QuestionID: questionID, Tag: tag, WeightByLevel: weighted}
+	rows, err := h.ScoreRepo.FindLeaderboard(c.Request.Context(), period, scope, limit)
+	if err != nil {
+		log.Printf("ランキング取得エラー: %v", err)
+		respondLeaderboardError(c)
+		return
+	}
+
+	resp := LeaderboardResponse{Period: period, QuestionID: questionID, Tag: tag, Weighted: weighted, Entries: rows}
+	// 該当者がいない場合も null ではなく空配列を返します。
+	if resp.Entries == nil {
+		resp.Entries = []repository.LeaderboardRow{}
+	}
+
+	if userID, ok := currentUserID(c); ok {
+		// 上位に入っていれば一覧の行をそのまま使い、余計なクエリを省きます。
+		for i := range resp.Entries {
+			if e := resp.Entries[i]; e.UserID != nil && *e.UserID == userID {
+				resp.Me = &resp.Entries[i]
+				break
+			}
+		}
+		if resp.Me == nil {
+			resp.Me, err = h.ScoreRepo.FindLeaderboardRank(c.Request.Context(), period, scope, userID)
+			if err != nil {
+				log.Printf("ランキングの自分の順位の取得エラー (user_id=%d): %v", userID, err)
+				respondLeaderboardError(c)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGolfLeaderboard は GET /api/v1/leaderboard/golf のハンドラです。
+// ゴルフモード（scoring_mode=golf）の回答だけを対象に、問題ごとのゴルフスコアの自己ベストの合計で並べたランキングを返します。
+// GetGolfLeaderboard godoc
+// @Summary      Get prompt golf leaderboard
+// @Description  Ranking by the sum of per-question best golf scores (correctness combined with prompt length)
+// @Tags         leaderboard
+// @Produce      json
+// @Param        period       query  string  false  "day, week or all (default: all)"
+// @Param        question_id  query  int     false  "Restrict to a single question"
+// @Param        limit        query  int     false  "Number of rows (default: 10, max: 100)"
+// @Success      200  {array}   repository.GolfLeaderboardRow
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /leaderboard/golf [get]
+func (h *LeaderboardHandler) GetGolfLeaderboard(c *gin.Context) {
+	period, ok := parsePeriod(c, "all")
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	questionID, ok := parseQuestionIDFilter(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.ScoreRepo.FindGolfLeaderboard(c.Request.Context(), period, questionID, limit)
+	if err != nil {
+		log.Printf("ゴルフランキング取得エラー: %v", err)
+		respondLeaderboardError(c)
+		return
+	}
+
+	// 該当者がいない場合も null ではなく空配列を返します。
+	if rows == nil {
+		rows = []repository.GolfLeaderboardRow{}
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// parseLimit はクエリパラメータ limit を読み取ります。不正な値の場合は 400 を返して false を返します。
+func parseLimit(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLeaderboardLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 || limit > maxLeaderboardLimit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_limit",
+			"message": "limit は 1〜100 の整数で指定してください",
+		})
+		return 0, false
+	}
+	return limit, true
+}
+
+// parsePeriod はクエリパラメータ period を読み取ります。未指定なら defaultPeriod を使い、不正な値の場合は 400 を返して false を返します。
+func parsePeriod(c *gin.Context, defaultPeriod string) (string, bool) {
+	period := c.DefaultQuery("period", defaultPeriod)
+	if period != "day" && period != "week" && period != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_period",
+			"message": "period は day, week, all のいずれかを指定してください",
+		})
+		return "", false
+	}
+	return period, true
+}
+
+// parseQuestionIDFilter はクエリパラメータ question_id を読み取ります。未指定なら nil、不正な値の場合は 400 を返して false を返します。
+func parseQuestionIDFilter(c *gin.Context) (*int, bool) {
+	raw := c.Query("question_id")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_question_id",
+			"message": "question_id は正の整数で指定してください",
+		})
+		return nil, false
+	}
+	return &id, true
+}
+
+// respondLeaderboardError はランキングの集計に失敗したときの 500 を返します。
+func respondLeaderboardError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "database_error",
+		"message": "ランキングの取得に失敗しました",
+	})
+}
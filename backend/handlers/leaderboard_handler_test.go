@@ -0,0 +1,131 @@
+// leaderboard_handler_test.go は GET /api/v1/leaderboard の入力検証と、上位に入っていないユーザーにも自分の順位を返すことを確認する単体テストです。
+// DB を使わないよう、ScoresRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// fakeLeaderboardRepo は順位付きのランキング全体を持ち、FindLeaderboard と FindLeaderboardRank だけを実装します。
+// それ以外のメソッドはインターフェースの埋め込みに任せ、呼ばれたら panic させます。
+type fakeLeaderboardRepo struct {
+	repository.ScoresRepository
+	rows      []repository.LeaderboardRow
+	gotPeriod string
+	gotScope  repository.LeaderboardScope
+	rankCalls int
+}
+
+func (f *fakeLeaderboardRepo) FindLeaderboard(_ context.Context, period string, scope repository.LeaderboardScope, limit int) ([]repository.LeaderboardRow, error) {
+	f.gotPeriod, f.gotScope = period, scope
+	if limit > len(f.rows) {
+		limit = len(f.rows)
+	}
+	return f.rows[:limit], nil
+}
+
+func (f *fakeLeaderboardRepo) FindLeaderboardRank(_ context.Context, _ string, _ repository.LeaderboardScope, userID int) (*repository.LeaderboardRow, error) {
+	f.rankCalls++
+	for _, row := range f.rows {
+		if row.UserID != nil && *row.UserID == userID {
+			return &row, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestLeaderboardHandler_GetLeaderboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ids := []int{1, 2, 3}
+	repo := &fakeLeaderboardRepo{rows: []repository.LeaderboardRow{
+		{Rank: 1, UserID: &ids[0], Username: "alice", TotalScore: 300, BestScore: 100},
+		{Rank: 2, UserID: &ids[1], Username: "bob", TotalScore: 240, BestScore: 80},
+		{Rank: 3, UserID: &ids[2], Username: "carol", TotalScore: 60, BestScore: 60},
+	}}
+	h := NewLeaderboardHandler(repo)
+
+	// ログイン済みを再現するため、ヘッダー X-Test-User のユーザー ID をそのままセットします。
+	router := gin.New()
+	router.GET("/api/v1/leaderboard", func(c *gin.Context) {
+		switch c.GetHeader("X-Test-User") {
+		case "2":
+			c.Set(ContextKeyUserID, 2)
+		case "3":
+			c.Set(ContextKeyUserID, 3)
+		case "9":
+			c.Set(ContextKeyUserID, 9)
+		}
+	}, h.GetLeaderboard)
+
+	tests := []struct {
+		name          string
+		query         string
+		user          string
+		wantStatus    int
+		wantEntries   int
+		wantMeRank    int // 0 なら me は null
+		wantRankCalls int
+	}{
+		{name: "ゲスト", query: "", wantStatus: http.StatusOK, wantEntries: 3},
+		{name: "上位に入っている", query: "?limit=2", user: "2", wantStatus: http.StatusOK, wantEntries: 2, wantMeRank: 2},
+		{name: "上位の外", query: "?limit=2", user: "3", wantStatus: http.StatusOK, wantEntries: 2, wantMeRank: 3, wantRankCalls: 1},
+		{name: "スコアが無い", query: "?limit=2", user: "9", wantStatus: http.StatusOK, wantEntries: 2, wantRankCalls: 1},
+		{name: "問題とタグで絞り込み", query: "?period=week&question_id=4&tag=calculation", wantStatus: http.StatusOK, wantEntries: 3},
+		{name: "不正な period", query: "?period=month", wantStatus: http.StatusBadRequest},
+		{name: "不正な limit", query: "?limit=101", wantStatus: http.StatusBadRequest},
+		{name: "不正な question_id", query: "?question_id=0", wantStatus: http.StatusBadRequest},
+		{name: "未定義のタグ", query: "?tag=no_such_tag", wantStatus: http.StatusBadRequest},
+		{name: "不正な weighted", query: "?weighted=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.rankCalls = 0
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard"+tt.query, nil)
+			if tt.user != "" {
+				req.Header.Set("X-Test-User", tt.user)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp LeaderboardResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Entries) != tt.wantEntries {
+				t.Errorf("entries = %d, want %d", len(resp.Entries), tt.wantEntries)
+			}
+			gotMeRank := 0
+			if resp.Me != nil {
+				gotMeRank = resp.Me.Rank
+			}
+			if gotMeRank != tt.wantMeRank {
+				t.Errorf("me.rank = %d, want %d", gotMeRank, tt.wantMeRank)
+			}
+			if repo.rankCalls != tt.wantRankCalls {
+				t.Errorf("FindLeaderboardRank calls = %d, want %d", repo.rankCalls, tt.wantRankCalls)
+			}
+		})
+	}
+
+	// 既定の期間は day で、絞り込み条件はそのままリポジトリに渡ります。
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard?question_id=4&tag=calculation&weighted=true", nil))
+	if repo.gotPeriod != "day" || repo.gotScope.QuestionID == nil || *repo.gotScope.QuestionID != 4 ||
+		repo.gotScope.Tag == nil || *repo.gotScope.Tag != "calculation" || !repo.gotScope.WeightByLevel {
+		t.Errorf("repository called with period=%s scope=%+v", repo.gotPeriod, repo.gotScope)
+	}
+}
@@ -0,0 +1,79 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// progress_handler.go はレベルの解放（プログレッション）の進捗を返すエンドポイントと、
+// 問題一覧・回答で「挑戦できるレベル」を調べる共通処理をまとめたファイルです。解放の条件は internal/progression にあります。
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/progression"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// ProgressHandler は進捗エンドポイントの依存関係を保持します。
+type ProgressHandler struct {
+	Progress repository.ProgressRepository // nil の場合はプログレッションを使わない設定で、エンドポイントは 503 を返します。
+}
+
+// NewProgressHandler は新しい ProgressHandler を作成します。
+func NewProgressHandler(progress repository.ProgressRepository) *ProgressHandler {
+	return &ProgressHandler{Progress: progress}
+}
+
+// GetMyProgress は GET /api/v1/me/progress のハンドラです。
+// ログイン中のユーザーが挑戦できるレベル、レベルごとのクリア数、次のレベルの解放条件を返します。
+// GetMyProgress godoc
+// @Summary      Get my level progression
+// @Description  Returns the highest unlocked level, per-level clears and what is needed to unlock the next level. Requires login.
+// @Tags         progress
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  progression.Progress
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /me/progress [get]
+func (h *ProgressHandler) GetMyProgress(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	if h.Progress == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "progression_disabled",
+			"message": "レベルの解放は無効に設定されています",
+		})
+		return
+	}
+
+	progress, err := h.Progress.Sync(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("進捗の取得に失敗しました (user_id=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "進捗の取得に失敗しました",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// unlockedLevel は呼び出したユーザーが挑戦できる最も高いレベルを返します。
+// repo が nil（プログレッションを使わない設定）なら enabled は false で、全ての問題に挑戦できます。
+// ゲストは進捗を保存できないため progression.GuestLevel までです。
+func unlockedLevel(c *gin.Context, repo repository.ProgressRepository) (level int, enabled bool, err error) {
+	if repo == nil {
+		return 0, false, nil
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return progression.GuestLevel, true, nil
+	}
+	progress, err := repo.Sync(c.Request.Context(), userID)
+	if err != nil {
+		return 0, true, err
+	}
+	return progress.UnlockedLevel, true, nil
+}
@@ -0,0 +1,114 @@
+// progress_handler_test.go は進捗エンドポイントの認証・無効設定の扱いと、ゲスト・ログインユーザーが挑戦できるレベルの判定を確認する単体テストです。
+// DB を使わないよう、ProgressRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/progression"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// fakeProgressRepo はユーザーごとの解放レベルを持ち、Sync で呼ばれたユーザーを記録します。
+type fakeProgressRepo struct {
+	unlocked map[int]int
+	synced   []int
+}
+
+func (f *fakeProgressRepo) Sync(_ context.Context, userID int) (*progression.Progress, error) {
+	f.synced = append(f.synced, userID)
+	level, ok := f.unlocked[userID]
+	if !ok {
+		level = progression.GuestLevel
+	}
+	return &progression.Progress{UnlockedLevel: level}, nil
+}
+
+func TestProgressHandler_GetMyProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeProgressRepo{unlocked: map[int]int{7: 3}}
+
+	newRouter := func(progress repository.ProgressRepository) *gin.Engine {
+		router := gin.New()
+		router.GET("/api/v1/me/progress", func(c *gin.Context) {
+			if c.GetHeader("X-Test-User") != "" {
+				c.Set(ContextKeyUserID, 7)
+			}
+		}, NewProgressHandler(progress).GetMyProgress)
+		return router
+	}
+
+	tests := []struct {
+		name       string
+		repo       repository.ProgressRepository
+		loggedIn   bool
+		wantStatus int
+	}{
+		{name: "ログイン中", repo: repo, loggedIn: true, wantStatus: http.StatusOK},
+		{name: "ゲスト", repo: repo, wantStatus: http.StatusUnauthorized},
+		{name: "無効な設定", repo: nil, loggedIn: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/me/progress", nil)
+			if tt.loggedIn {
+				req.Header.Set("X-Test-User", "1")
+			}
+			newRouter(tt.repo).ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var progress progression.Progress
+			if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if progress.UnlockedLevel != 3 {
+				t.Errorf("unlocked_level = %d, want 3", progress.UnlockedLevel)
+			}
+		})
+	}
+}
+
+func TestUnlockedLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeProgressRepo{unlocked: map[int]int{7: 4}}
+
+	tests := []struct {
+		name        string
+		repo        repository.ProgressRepository
+		userID      int // 0 ならゲスト
+		wantLevel   int
+		wantEnabled bool
+	}{
+		{name: "無効な設定", repo: nil, userID: 7},
+		{name: "ゲスト", repo: repo, wantLevel: progression.GuestLevel, wantEnabled: true},
+		{name: "ログイン中", repo: repo, userID: 7, wantLevel: 4, wantEnabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.userID != 0 {
+				c.Set(ContextKeyUserID, tt.userID)
+			}
+			level, enabled, err := unlockedLevel(c, tt.repo)
+			if err != nil {
+				t.Fatalf("unlockedLevel() error = %v", err)
+			}
+			if level != tt.wantLevel || enabled != tt.wantEnabled {
+				t.Errorf("unlockedLevel() = %d, %v, want %d, %v", level, enabled, tt.wantLevel, tt.wantEnabled)
+			}
+		})
+	}
+}
@@ -2,12 +2,15 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shiv/CoT_game/backend/internal/repository"
 	"github.com/shiv/CoT_game/backend/models"
 )
 
@@ -16,6 +19,8 @@ import (
 // ハンドラのテスト容易性や再利用性を高めます。
 type QuestionHandler struct {
 	DB *pgxpool.Pool
+	// Progress はレベルの解放の進捗です。nil の場合はプログレッションを使わず、全ての問題を解放済みとして返します。
+	Progress repository.ProgressRepository
 }
 
 // NewQuestionHandler は question に関連するルートの新しいハンドラを作成します。
@@ -24,24 +29,61 @@ func NewQuestionHandler(db *pgxpool.Pool) *QuestionHandler {
 	return &QuestionHandler{DB: db}
 }
 
-// GetQuestions はデータベースから全ての問題を取得します。
+// GetQuestions はデータベースから問題の一覧を取得します。
+// クエリパラメータでレベル・タグ・解いたかどうかの絞り込み、並び替え、カーソルによるページングができます（parseQuestionListParams を参照）。
+// 次のページのカーソルはレスポンスヘッダー X-Next-Cursor、条件に一致する全件数は X-Total-Count で返します。
 // データベースのクエリやデータスキャン中に発生しうるエラーをハンドリングし、
 // 適切なHTTPステータスコードとエラーメッセージを返します。
 // セキュリティ上、問題文と正解はクライアントに送信されません。
 // GetQuestions godoc
 // @Summary      Get list of questions
-// @Description  Get all questions with limited details (no problem statement/answer)
+// @Description  Get questions with limited details (no problem statement/answer), with filters, sorting and cursor pagination
 // @Tags         questions
 // @Accept       json
 // @Produce      json
+// @Param        level_min  query  int     false  "Minimum level (inclusive)"
+// @Param        level_max  query  int     false  "Maximum level (inclusive)"
+// @Param        tags       query  string  false  "Comma separated tag IDs"
+// @Param        tag_match  query  string  false  "any (default) or all"
+// @Param        solved     query  bool    false  "Solved (score 100) by the current user. Requires login"
+// @Param        sort       query  string  false  "level (default), created_at or id. Prefix with - for descending"
+// @Param        cursor     query  string  false  "X-Next-Cursor of the previous page"
+// @Param        limit      query  int     false  "Page size (default: 100, max: 100)"
 // @Success      200  {array}   models.QuestionResponse
+// @Header       200  {string}  X-Next-Cursor  "Cursor of the next page (absent on the last page)"
+// @Header       200  {int}     X-Total-Count  "Number of questions matching the filters"
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /questions [get]
 func (h *QuestionHandler) GetQuestions(c *gin.Context) {
-	// 全ての問題を選択するクエリ。一貫性を保つためにlevelで並び替えます。
-	query := "SELECT id, level, problem_statement, correct_answer, tags, created_at FROM questions ORDER BY level"
+	params, err := parseQuestionListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// 絞り込み条件に一致する全件数。カーソルの位置に関係なく同じ値になります。
+	where, countArgs := params.buildWhere()
+	var total int
+	if err := h.DB.QueryRow(ctx, "SELECT COUNT(*) FROM questions q WHERE "+where, countArgs...).Scan(&total); err != nil {
+		log.Printf("問題数の集計中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースからの質問の取得に失敗しました。"})
+		return
+	}
+
+	// 未解放のレベルの問題も一覧には出し、locked を付けて挑戦できないことを伝えます。
+	unlocked, locking, err := unlockedLevel(c, h.Progress)
+	if err != nil {
+		log.Printf("進捗の取得中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "進捗の取得に失敗しました。"})
+		return
+	}
 
-	rows, err := h.DB.Query(context.Background(), query)
+	// 問題文と正解はクライアントに返さないため、一覧に必要な列だけを選択します。
+	query, args := params.buildListQuery()
+	rows, err := h.DB.Query(ctx, query, args...)
 	if err != nil {
 		// サーバー側のデバッグ目的で詳細なエラーをログに出力します。
 		log.Printf("質問のクエリ実行中にエラーが発生しました: %v", err)
@@ -51,17 +93,19 @@ func (h *QuestionHandler) GetQuestions(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	// 質問データを保持するためのスライスを作成します（内部用の完全なデータ）。
-	var questions []models.Question
+	// データベースに質問が見つからない場合、フロントエンドの互換性のために
+	// `null` の代わりに空のリスト `[]` を返します。
+	responses := []models.QuestionResponse{}
 	for rows.Next() {
-		var q models.Question
-		// 行データをQuestion構造体にスキャンします。
-		if err := rows.Scan(&q.ID, &q.Level, &q.ProblemStatement, &q.CorrectAnswer, &q.Tags, &q.CreatedAt); err != nil {
+		var q models.QuestionResponse
+		// 行データをQuestionResponse構造体にスキャンします。
+		if err := rows.Scan(&q.ID, &q.Level, &q.Tags, &q.CreatedAt, &q.Rating, &q.RatingRD, &q.SuggestedLevel); err != nil {
 			log.Printf("質問行のスキャン中にエラーが発生しました: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "質問データの処理に失敗しました。"})
 			return
 		}
-		questions = append(questions, q)
+		q.Locked = locking && q.Level > unlocked
+		responses = append(responses, q)
 	}
 
 	// 行のイテレーション中に発生したエラーを確認します。
@@ -71,25 +115,107 @@ func (h *QuestionHandler) GetQuestions(c *gin.Context) {
 		return
 	}
 
-	// データベースに質問が見つからない場合、フロントエンドの互換性のために
-	// `null` の代わりに空のリスト `[]` を返します。
-	if len(questions) == 0 {
-		c.JSON(http.StatusOK, []models.QuestionResponse{})
+	// limit+1 件目が取れていれば次のページがあります。余分な 1 件は返さず、カーソルの作成に最後の行を使います。
+	if len(responses) > params.Limit {
+		responses = responses[:params.Limit]
+		c.Header(HeaderNextCursor, params.nextCursor(responses[len(responses)-1]))
+	}
+	c.Header(HeaderTotalCount, strconv.Itoa(total))
+
+	// 200 OKステータスと共に質問のリスト（機密情報を除外）を返します。
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetQuestion は 1 問の詳細を返します。
+// 一覧と同じく問題文と正解は返さず、タグの表示情報・挑戦の統計・ログイン中のユーザーの自己ベストを付け加えます。
+// GetQuestion godoc
+// @Summary      Get question detail
+// @Description  Get a question with tag metadata, attempt statistics and the caller's best score (no problem statement/answer)
+// @Tags         questions
+// @Produce      json
+// @Param        id   path      int  true  "Question ID"
+// @Success      200  {object}  models.QuestionDetailResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /questions/{id} [get]
+func (h *QuestionHandler) GetQuestion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "問題IDは正の整数で指定してください。"})
 		return
 	}
 
-	// 内部データをクライアント用のレスポンスに変換します。
-	// 問題文と正解を除外し、IDとレベルとタグと作成日時のみを返します。
-	responses := make([]models.QuestionResponse, len(questions))
-	for i, q := range questions {
-		responses[i] = models.QuestionResponse{
-			ID:        q.ID,
-			Level:     q.Level,
-			Tags:      q.Tags,
-			CreatedAt: q.CreatedAt,
+	ctx := c.Request.Context()
+
+	// 問題文と正解はクライアントに返さないため、最初から選択しません。
+	var resp models.QuestionDetailResponse
+	err = h.DB.QueryRow(ctx, `
+		SELECT q.id, q.level, q.tags, q.created_at, qr.rating, qr.rd, qc.suggested_level
+		FROM questions q
+		LEFT JOIN question_ratings qr ON qr.question_id = q.id
+		LEFT JOIN question_calibrations qc ON qc.question_id = q.id
+		WHERE q.id = $1 AND q.deleted_at IS NULL
+	`, id).
+		Scan(&resp.ID, &resp.Level, &resp.Tags, &resp.CreatedAt, &resp.Rating, &resp.RatingRD, &resp.SuggestedLevel)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された問題が見つかりません。"})
+			return
 		}
+		log.Printf("問題詳細のクエリ実行中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースからの問題の取得に失敗しました。"})
+		return
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
 	}
 
-	// 200 OKステータスと共に質問のリスト（機密情報を除外）を返します。
-	c.JSON(http.StatusOK, responses)
+	unlocked, locking, err := unlockedLevel(c, h.Progress)
+	if err != nil {
+		log.Printf("進捗の取得中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "進捗の取得に失敗しました。"})
+		return
+	}
+	resp.Locked = locking && resp.Level > unlocked
+
+	// タグ ID を表示用の情報に解決します。定義に無いタグは ID をそのままラベルにして返します。
+	resp.TagDetails = make([]models.Tag, 0, len(resp.Tags))
+	for _, tagID := range resp.Tags {
+		tag, ok := models.GetTagByID(tagID)
+		if !ok {
+			tag = models.Tag{ID: tagID, Label: tagID}
+		}
+		resp.TagDetails = append(resp.TagDetails, tag)
+	}
+
+	// 挑戦の統計。挑戦が無い問題では平均・最高・正答率が NULL になります。
+	statsQuery := `
+		SELECT
+			COUNT(*),
+			COUNT(DISTINCT user_id),
+			AVG(score)::float8,
+			MAX(score),
+			(COUNT(*) FILTER (WHERE score = 100))::float8 / NULLIF(COUNT(*), 0)
+		FROM scores
+		WHERE question_id = $1
+	`
+	stats := &resp.Stats
+	if err := h.DB.QueryRow(ctx, statsQuery, id).Scan(&stats.Attempts, &stats.Players, &stats.AverageScore, &stats.BestScore, &stats.SolveRate); err != nil {
+		log.Printf("問題の統計の取得中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "問題の統計の取得に失敗しました。"})
+		return
+	}
+
+	// ログイン中であれば自己ベストを付けます。ゲストの場合や未挑戦の場合は null のままです。
+	// 自己ベストは user_question_best の主キーで引けるので、scores を集計し直す必要はありません。
+	if userID, ok := currentUserID(c); ok {
+		if err := h.DB.QueryRow(ctx, "SELECT MAX(best_score) FROM user_question_best WHERE question_id = $1 AND user_id = $2", id, userID).Scan(&resp.MyBestScore); err != nil {
+			log.Printf("自己ベストの取得中にエラーが発生しました: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "自己ベストの取得に失敗しました。"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
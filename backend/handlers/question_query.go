@@ -0,0 +1,243 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// question_query.go は GET /questions の絞り込み・並び替え・カーソルページングのパラメータを読み取り、SQL を組み立てます。
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+const (
+	// defaultQuestionLimit は 1 ページの既定の件数です。
+	defaultQuestionLimit = 100
+	// maxQuestionLimit は 1 ページの件数の上限です。
+	maxQuestionLimit = 100
+
+	// HeaderNextCursor は次のページのカーソルを返すレスポンスヘッダーです。最後のページでは付きません。
+	HeaderNextCursor = "X-Next-Cursor"
+	// HeaderTotalCount は絞り込み条件に一致する全件数を返すレスポンスヘッダーです。
+	HeaderTotalCount = "X-Total-Count"
+)
+
+// questionSort は並び替えの指定です。id を第 2 キーにして、同じ値の行でも順序とカーソルが一意に決まるようにします。
+type questionSort struct {
+	column string // 並び替えに使う列
+	cast   string // カーソルの値を SQL に渡すときの型
+	desc   bool
+}
+
+// questionSorts は sort パラメータに指定できる値です。先頭に - を付けると降順になります。
+var questionSorts = map[string]questionSort{
+	"level":       {column: "q.level", cast: "int"},
+	"-level":      {column: "q.level", cast: "int", desc: true},
+	"created_at":  {column: "q.created_at", cast: "timestamptz"},
+	"-created_at": {column: "q.created_at", cast: "timestamptz", desc: true},
+	"id":          {column: "q.id", cast: "int"},
+	"-id":         {column: "q.id", cast: "int", desc: true},
+}
+
+// questionCursor は前のページの最後の行の位置です。クライアントには base64 の文字列として渡します。
+type questionCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func (c questionCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeQuestionCursor(raw string) (questionCursor, error) {
+	var cursor questionCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return cursor, err
+	}
+	return cursor, nil
+}
+
+// questionListParams は GET /questions のクエリパラメータを読み取った結果です。
+type questionListParams struct {
+	LevelMin *int
+	LevelMax *int
+	Tags     []string
+	MatchAll bool  // true ならタグを全て含む問題、false ならいずれかを含む問題
+	Solved   *bool // 呼び出したユーザーが満点を取った（true）・取っていない（false）問題に絞る
+	UserID   int
+	Sort     string
+	Cursor   *questionCursor
+	Limit    int
+}
+
+// parseQuestionListParams はクエリパラメータを検証して読み取ります。不正な値があればクライアント向けのメッセージをエラーで返します。
+//
+//	level_min, level_max : レベルの範囲（両端を含む）
+//	tags                 : カンマ区切りのタグ ID
+//	tag_match            : any（既定。いずれかを含む）または all（全て含む）
+//	solved               : true / false。ログインが必要
+//	sort                 : level（既定）, created_at, id。先頭に - で降順
+//	cursor               : 前のページのレスポンスヘッダー X-Next-Cursor の値
+//	limit                : 1〜100（既定 100）
+func parseQuestionListParams(c *gin.Context) (questionListParams, error) {
+	p := questionListParams{Sort: c.DefaultQuery("sort", "level"), Limit: defaultQuestionLimit}
+
+	levels := []struct {
+		name string
+		dst  **int
+	}{{"level_min", &p.LevelMin}, {"level_max", &p.LevelMax}}
+	for _, l := range levels {
+		if raw := c.Query(l.name); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 1 {
+				return p, fmt.Errorf("%s は 1 以上の整数で指定してください。", l.name)
+			}
+			*l.dst = &v
+		}
+	}
+	if p.LevelMin != nil && p.LevelMax != nil && *p.LevelMin > *p.LevelMax {
+		return p, fmt.Errorf("level_min は level_max 以下で指定してください。")
+	}
+
+	if raw := c.Query("tags"); raw != "" {
+		for _, tag := range strings.Split(raw, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if _, ok := models.GetTagByID(tag); !ok {
+				return p, fmt.Errorf("タグ %s は定義されていません。", tag)
+			}
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		p.MatchAll = true
+	default:
+		return p, fmt.Errorf("tag_match は any または all を指定してください。")
+	}
+
+	if raw := c.Query("solved"); raw != "" {
+		solved, err := strconv.ParseBool(raw)
+		if err != nil {
+			return p, fmt.Errorf("solved は true または false を指定してください。")
+		}
+		userID, ok := currentUserID(c)
+		if !ok {
+			return p, fmt.Errorf("solved で絞り込むにはログインが必要です。")
+		}
+		p.Solved = &solved
+		p.UserID = userID
+	}
+
+	if _, ok := questionSorts[p.Sort]; !ok {
+		return p, fmt.Errorf("sort は level, created_at, id のいずれか（降順は先頭に -）を指定してください。")
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeQuestionCursor(raw)
+		if err != nil || cursor.Sort != p.Sort {
+			return p, fmt.Errorf("cursor が不正です。並び順を変えた場合は最初のページから取得し直してください。")
+		}
+		p.Cursor = &cursor
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxQuestionLimit {
+			return p, fmt.Errorf("limit は 1〜100 の整数で指定してください。")
+		}
+		p.Limit = limit
+	}
+	return p, nil
+}
+
+// buildWhere は絞り込み条件の WHERE 句とバインド変数を組み立てます。カーソル条件は含みません（件数の集計にも使うため）。
+func (p questionListParams) buildWhere() (string, []any) {
+	// 論理削除した問題は一覧に出しません。
+	conds := []string{"q.deleted_at IS NULL"}
+	var args []any
+	bind := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if p.LevelMin != nil {
+		conds = append(conds, "q.level >= "+bind(*p.LevelMin))
+	}
+	if p.LevelMax != nil {
+		conds = append(conds, "q.level <= "+bind(*p.LevelMax))
+	}
+	if len(p.Tags) > 0 {
+		// && と @> はどちらも GIN インデックス idx_questions_tags を使えます。
+		op := "&&"
+		if p.MatchAll {
+			op = "@>"
+		}
+		conds = append(conds, fmt.Sprintf("q.tags %s %s::text[]", op, bind(p.Tags)))
+	}
+	if p.Solved != nil {
+		not := ""
+		if !*p.Solved {
+			not = "NOT "
+		}
+		conds = append(conds, fmt.Sprintf(
+			"%sEXISTS (SELECT 1 FROM user_question_best b WHERE b.question_id = q.id AND b.user_id = %s AND b.best_score = 100)",
+			not, bind(p.UserID)))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// buildListQuery は 1 ページ分を取得する SQL を組み立てます。次のページがあるか判定するため limit+1 件を取得します。
+func (p questionListParams) buildListQuery() (string, []any) {
+	where, args := p.buildWhere()
+	sort := questionSorts[p.Sort]
+
+	cmp, dir := ">", "ASC"
+	if sort.desc {
+		cmp, dir = "<", "DESC"
+	}
+	if p.Cursor != nil {
+		// カーソルの値は文字列で受け取り、SQL 側で列の型に変換します。
+		args = append(args, p.Cursor.Value, p.Cursor.ID)
+		where += fmt.Sprintf(" AND (%s, q.id) %s ($%d::text::%s, $%d)", sort.column, cmp, len(args)-1, sort.cast, len(args))
+	}
+	args = append(args, p.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT q.id, q.level, q.tags, q.created_at, qr.rating, qr.rd, qc.suggested_level
+		FROM questions q
+		LEFT JOIN question_ratings qr ON qr.question_id = q.id
+		LEFT JOIN question_calibrations qc ON qc.question_id = q.id
+		WHERE %s
+		ORDER BY %s %s, q.id %s
+		LIMIT $%d
+	`, where, sort.column, dir, dir, len(args))
+	return query, args
+}
+
+// nextCursor はページの最後の行から次のページのカーソルを作ります。
+func (p questionListParams) nextCursor(last models.QuestionResponse) string {
+	var value string
+	switch questionSorts[p.Sort].column {
+	case "q.level":
+		value = strconv.Itoa(last.Level)
+	case "q.created_at":
+		value = last.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		value = strconv.Itoa(last.ID)
+	}
+	return questionCursor{Sort: p.Sort, Value: value, ID: last.ID}.encode()
+}
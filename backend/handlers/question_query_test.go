@@ -0,0 +1,87 @@
+// question_query_test.go は GET /questions のクエリパラメータの検証と SQL の組み立てを、DB 無しで確認する単体テストです。
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// newQueryContext はクエリ文字列だけを持つ gin.Context を作ります。userID が 0 以外ならログイン中として扱います。
+func newQueryContext(rawQuery string, userID int) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/questions?"+rawQuery, nil)
+	if userID != 0 {
+		c.Set(ContextKeyUserID, userID)
+	}
+	return c
+}
+
+func TestParseQuestionListParams(t *testing.T) {
+	validCursor := questionCursor{Sort: "level", Value: "3", ID: 7}.encode()
+
+	tests := []struct {
+		name     string
+		query    string
+		userID   int
+		wantErr  bool
+		wantCond string // 組み立てた SQL に含まれるべき断片
+	}{
+		{name: "指定なし", query: "", wantCond: "ORDER BY q.level ASC, q.id ASC"},
+		{name: "レベル範囲", query: "level_min=2&level_max=4", wantCond: "q.level >= $1 AND q.level <= $2"},
+		{name: "レベル範囲が逆", query: "level_min=4&level_max=2", wantErr: true},
+		{name: "タグのいずれか", query: "tags=calculation,text_problem", wantCond: "q.tags && $1::text[]"},
+		{name: "タグを全て", query: "tags=calculation,text_problem&tag_match=all", wantCond: "q.tags @> $1::text[]"},
+		{name: "未定義のタグ", query: "tags=unknown", wantErr: true},
+		{name: "tag_match が不正", query: "tags=calculation&tag_match=some", wantErr: true},
+		{name: "未解決（ログイン中）", query: "solved=false", userID: 5, wantCond: "NOT EXISTS"},
+		{name: "solved はログインが必要", query: "solved=true", wantErr: true},
+		{name: "降順", query: "sort=-created_at", wantCond: "ORDER BY q.created_at DESC, q.id DESC"},
+		{name: "並び替えが不正", query: "sort=score", wantErr: true},
+		{name: "カーソル", query: "cursor=" + validCursor, wantCond: "(q.level, q.id) > ($1::text::int, $2)"},
+		{name: "並び順の違うカーソル", query: "sort=id&cursor=" + validCursor, wantErr: true},
+		{name: "壊れたカーソル", query: "cursor=!!!", wantErr: true},
+		{name: "limit が大きすぎる", query: "limit=101", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseQuestionListParams(newQueryContext(tt.query, tt.userID))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQuestionListParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			query, args := params.buildListQuery()
+			if !strings.Contains(query, tt.wantCond) {
+				t.Errorf("query does not contain %q:\n%s", tt.wantCond, query)
+			}
+			// 最後のバインド変数は次ページ判定用の limit+1 です。
+			if got := args[len(args)-1]; got != params.Limit+1 {
+				t.Errorf("last arg = %v, want %d", got, params.Limit+1)
+			}
+		})
+	}
+}
+
+func TestQuestionCursorRoundTrip(t *testing.T) {
+	last := models.QuestionResponse{ID: 12, Level: 3, CreatedAt: time.Date(2025, 11, 7, 9, 30, 0, 123, time.UTC)}
+
+	for _, sort := range []string{"level", "-created_at", "id"} {
+		params := questionListParams{Sort: sort}
+		cursor, err := decodeQuestionCursor(params.nextCursor(last))
+		if err != nil {
+			t.Fatalf("%s: decode error = %v", sort, err)
+		}
+		if cursor.Sort != sort || cursor.ID != last.ID || cursor.Value == "" {
+			t.Errorf("%s: unexpected cursor %+v", sort, cursor)
+		}
+	}
+}
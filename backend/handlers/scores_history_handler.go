@@ -0,0 +1,362 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// scores_history_handler.go はログイン中のユーザー自身の挑戦履歴と、1 回の挑戦の詳細を返すエンドポイントをまとめたハンドラです。
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/leakguard"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/internal/variant"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+const (
+	// defaultScoreHistoryLimit はスコア履歴の 1 ページの既定の件数です（docs/task/14）。
+	defaultScoreHistoryLimit = 50
+	// maxScoreHistoryLimit はスコア履歴の 1 ページの件数の上限です。
+	maxScoreHistoryLimit = 100
+)
+
+// publicDetailKeys は本人以外にも返す evaluation_detail のキーです。
+// 採点の方式・判定・一致の有無・点数の内訳だけを許可し、正解（correct_raw・expected_items・rubric など）や AI の出力を含むキーは返しません。
+// キーを追加するときは、正解や AI の出力を推測できる値でないことを確認してください。
+var publicDetailKeys = map[string]bool{
+	"mode":              true,
+	"evaluator_version": true,
+	"answer_spec_type":  true,
+	"mode_reason":       true,
+	"normalized_score":  true,
+	"scale_type":        true,
+	"curve_mode":        true,
+	"judge_verdict":     true,
+	"judge_score":       true,
+	"judge_cached":      true,
+	"matched_count":     true,
+	"denominator":       true,
+	"partial_credit":    true,
+	"unit_assumed":      true,
+	"unit_penalty":      true,
+	"cheat_flagged":     true,
+	"golf":              true,
+	"leak_guard":        true,
+}
+
+// ScoreHistoryHandler はスコア履歴系エンドポイントの依存関係を保持します。
+type ScoreHistoryHandler struct {
+	ScoreRepo repository.ScoresRepository
+}
+
+// NewScoreHistoryHandler は新しい ScoreHistoryHandler を作成します。
+func NewScoreHistoryHandler(scoreRepo repository.ScoresRepository) *ScoreHistoryHandler {
+	return &ScoreHistoryHandler{ScoreRepo: scoreRepo}
+}
+
+// ScoreHistoryItem はスコア履歴の一覧の 1 行です。プロンプト本文や AI の出力は詳細エンドポイントで返します。
+type ScoreHistoryItem struct {
+	ID          int       `json:"id"`
+	QuestionID  int       `json:"question_id"`
+	Score       int       `json:"score"`
+	GolfScore   *int      `json:"golf_score"`
+	ScoringMode string    `json:"scoring_mode"`
+	ModelName   *string   `json:"model_name"`
+	PromptLen   int       `json:"prompt_len"` // プロンプトの文字数（バイト数ではありません）。
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ScoreDetailResponse は GET /api/v1/scores/:id のレスポンスです。
+// 問題文はプレイヤーに見せない方針のため、バリアントの変数や正解は含めません。
+type ScoreDetailResponse struct {
+	ID               int                    `json:"id"`
+	UserID           *int                   `json:"user_id"`
+	QuestionID       int                    `json:"question_id"`
+	Prompt           string                 `json:"prompt"` // 本人以外には、不正検出で疑いなしと判定された挑戦だけ返します。
+	Score            int                    `json:"score"`
+	GolfScore        *int                   `json:"golf_score"`
+	ScoringMode      string                 `json:"scoring_mode"`
+	ModelName        *string                `json:"model_name"`
+	AnswerNumber     *float64               `json:"answer_number"` // 満点の挑戦では正解そのものなので、本人にだけ返します。
+	LatencyMs        int                    `json:"latency_ms"`
+	EvaluationDetail map[string]interface{} `json:"evaluation_detail"` // 本人以外には publicDetailKeys のキーだけを返します。
+	EvaluatorVersion *int                   `json:"evaluator_version"`
+	CreatedAt        time.Time              `json:"created_at"`
+	// AIOutput は挑戦したユーザー本人にだけ返す AI の出力です。solve のレスポンスと同じく「最終回答」以降だけを、問題文を伏せ字にして返します。
+	AIOutput *string `json:"ai_output,omitempty"`
+}
+
+// scoreCursor は前のページの最後の行の位置です。クライアントには base64 の文字列として渡します。
+type scoreCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+func (c scoreCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeScoreCursor(raw string) (scoreCursor, error) {
+	var cursor scoreCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return cursor, err
+	}
+	return cursor, nil
+}
+
+// GetMyScores は GET /api/v1/me/scores のハンドラです。
+// ログイン中のユーザーの挑戦履歴を新しい順に返します。次のページがある場合はレスポンスヘッダー X-Next-Cursor にカーソルを付けます。
+//
+// クエリパラメータ:
+//
+//	question_id : 問題 ID
+//	tag         : タグ ID
+//	model       : 使用したモデル名（scores.model_name）
+//	from, to    : 回答日時の範囲。RFC 3339 か YYYY-MM-DD（UTC の日付。to はその日を含みます）
+//	cursor      : 前のページのレスポンスヘッダー X-Next-Cursor の値
+//	limit       : 1 ページの件数（既定 50、最大 100）
+//
+// GetMyScores godoc
+// @Summary      Get my score history
+// @Description  The logged-in user's attempts, newest first, with cursor pagination
+// @Tags         scores
+// @Produce      json
+// @Param        question_id  query  int     false  "Restrict to a single question"
+// @Param        tag          query  string  false  "Restrict to questions with this tag"
+// @Param        model        query  string  false  "Restrict to a model name"
+// @Param        from         query  string  false  "RFC 3339 or YYYY-MM-DD (inclusive)"
+// @Param        to           query  string  false  "RFC 3339 (exclusive) or YYYY-MM-DD (inclusive)"
+// @Param        cursor       query  string  false  "X-Next-Cursor of the previous page"
+// @Param        limit        query  int     false  "Number of rows (default: 50, max: 100)"
+// @Success      200  {array}   ScoreHistoryItem
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /me/scores [get]
+func (h *ScoreHistoryHandler) GetMyScores(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+
+	filter, limit, err := parseScoreHistoryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_parameter",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 次のページがあるか判定するため limit+1 件を取得します。
+	scores, err := h.ScoreRepo.FindUserScores(c.Request.Context(), userID, filter, limit+1)
+	if err != nil {
+		log.Printf("スコア履歴取得エラー (user_id=%d): %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "スコア履歴の取得に失敗しました",
+		})
+		return
+	}
+
+	if len(scores) > limit {
+		scores = scores[:limit]
+		last := scores[len(scores)-1]
+		c.Header(HeaderNextCursor, scoreCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode())
+	}
+
+	items := make([]ScoreHistoryItem, 0, len(scores))
+	for _, s := range scores {
+		items = append(items, ScoreHistoryItem{
+			ID:          s.ID,
+			QuestionID:  s.QuestionID,
+			Score:       s.Score,
+			GolfScore:   s.GolfScore,
+			ScoringMode: s.ScoringMode,
+			ModelName:   s.ModelName,
+			PromptLen:   utf8.RuneCountInString(s.Prompt),
+			CreatedAt:   s.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// GetScore は GET /api/v1/scores/:id のハンドラです。
+// 1 回の挑戦のプロンプトと採点の詳細を返します。AI の出力と採点の詳細の全体は、挑戦したユーザー本人がログインしている場合だけ含めます。
+// 本人以外（ゲストを含む）には、正解や AI の出力を含まない採点の内訳だけを返します。AI が読み取った回答の数値も返さず、
+// プロンプトは不正検出で正解の書き込みなどの疑いが無いと判定された挑戦のものだけを返します。
+// GetScore godoc
+// @Summary      Get a single attempt
+// @Description  Prompt and evaluation detail of one attempt; the full detail and the redacted AI output are included for its owner only
+// @Tags         scores
+// @Produce      json
+// @Param        id   path  int  true  "Score ID"
+// @Success      200  {object}  ScoreDetailResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /scores/{id} [get]
+func (h *ScoreHistoryHandler) GetScore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_score_id",
+			"message": "id は正の整数で指定してください",
+		})
+		return
+	}
+
+	detail, err := h.ScoreRepo.FindScoreDetail(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrScoreNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "score_not_found",
+			"message": "指定されたスコアが見つかりません",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("スコア詳細取得エラー (score_id=%d): %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "database_error",
+			"message": "スコアの取得に失敗しました",
+		})
+		return
+	}
+
+	score := detail.Score
+	resp := ScoreDetailResponse{
+		ID:               score.ID,
+		UserID:           score.UserID,
+		QuestionID:       score.QuestionID,
+		Prompt:           publicPrompt(score),
+		Score:            score.Score,
+		GolfScore:        score.GolfScore,
+		ScoringMode:      score.ScoringMode,
+		ModelName:        score.ModelName,
+		LatencyMs:        score.LatencyMs,
+		EvaluationDetail: publicEvaluationDetail(score.EvaluationDetail),
+		EvaluatorVersion: score.EvaluatorVersion,
+		CreatedAt:        score.CreatedAt,
+	}
+	if userID, ok := currentUserID(c); ok && score.UserID != nil && *score.UserID == userID {
+		output := visibleAIOutput(detail)
+		resp.AIOutput = &output
+		resp.Prompt = score.Prompt
+		resp.AnswerNumber = score.AnswerNumber
+		resp.EvaluationDetail = score.EvaluationDetail
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// publicEvaluationDetail は採点の詳細から publicDetailKeys のキーだけを取り出します。
+func publicEvaluationDetail(detail map[string]interface{}) map[string]interface{} {
+	public := make(map[string]interface{}, len(publicDetailKeys))
+	for key, value := range detail {
+		if publicDetailKeys[key] {
+			public[key] = value
+		}
+	}
+	return public
+}
+
+// publicPrompt は本人以外に見せるプロンプトを返します。
+// 不正検出を通って detail["cheat_flagged"] が false の挑戦だけを返し、疑いがある挑戦や検査していない挑戦は空文字にします。
+func publicPrompt(score repository.Score) string {
+	if flagged, ok := score.EvaluationDetail["cheat_flagged"].(bool); ok && !flagged {
+		return score.Prompt
+	}
+	return ""
+}
+
+// visibleAIOutput は保存した AI の出力から、solve のレスポンスと同じ形（「最終回答」以降、問題文は伏せ字）の文字列を作ります。
+// テンプレート問題は保存したシードから出題した問題文を作り直して照合します。作り直せない場合は代表例の問題文で照合します。
+func visibleAIOutput(detail *repository.ScoreDetail) string {
+	statement := detail.ProblemStatement
+	if detail.TemplateKey != nil {
+		if seed, ok := detail.VariantParams["seed"].(float64); ok {
+			if v, err := variant.Instantiate(*detail.TemplateKey, int64(seed)); err == nil {
+				statement = v.ProblemStatement
+			}
+		}
+	}
+	return leakguard.Check(extractFinalAnswer(detail.AIResponse), statement).Redacted
+}
+
+// parseScoreHistoryParams は GET /me/scores のクエリパラメータを読み取ります。
+func parseScoreHistoryParams(c *gin.Context) (repository.UserScoresFilter, int, error) {
+	var filter repository.UserScoresFilter
+	limit := defaultScoreHistoryLimit
+
+	if raw := c.Query("question_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			return filter, 0, errors.New("question_id は正の整数で指定してください")
+		}
+		filter.QuestionID = &id
+	}
+	if raw := c.Query("tag"); raw != "" {
+		if _, ok := models.GetTagByID(raw); !ok {
+			return filter, 0, errors.New("tag " + raw + " は定義されていません")
+		}
+		filter.Tag = &raw
+	}
+	if raw := c.Query("model"); raw != "" {
+		filter.ModelName = &raw
+	}
+	if raw := c.Query("from"); raw != "" {
+		from, _, err := parseHistoryTime(raw)
+		if err != nil {
+			return filter, 0, errors.New("from は RFC 3339 か YYYY-MM-DD で指定してください")
+		}
+		filter.From = &from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, dateOnly, err := parseHistoryTime(raw)
+		if err != nil {
+			return filter, 0, errors.New("to は RFC 3339 か YYYY-MM-DD で指定してください")
+		}
+		if dateOnly {
+			// 日付だけの指定はその日の終わりまでを含めます。
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, 0, errors.New("from は to より前の日時を指定してください")
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeScoreCursor(raw)
+		if err != nil || cursor.ID <= 0 {
+			return filter, 0, errors.New("cursor が不正です")
+		}
+		filter.Before = &repository.ScoreCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxScoreHistoryLimit {
+			return filter, 0, errors.New("limit は 1〜100 の整数で指定してください")
+		}
+		limit = n
+	}
+	return filter, limit, nil
+}
+
+// parseHistoryTime は RFC 3339 の日時か YYYY-MM-DD の日付（UTC の 0 時）を読み取ります。2 つ目の戻り値は日付だけの指定だったかどうかです。
+func parseHistoryTime(raw string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	return t, false, err
+}
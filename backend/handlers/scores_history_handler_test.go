@@ -0,0 +1,286 @@
+// scores_history_handler_test.go はスコア履歴の入力検証・カーソルでのページ送りと、詳細で AI の出力を本人にだけ伏せ字付きで返し、正解を含む採点の詳細を本人以外に返さないことを確認する単体テストです。
+// DB を使わないよう、ScoresRepository はメモリ上の偽実装に差し替えます。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/internal/leakguard"
+	"github.com/shiv/CoT_game/backend/internal/repository"
+)
+
+// fakeScoreHistoryRepo は新しい順に並んだスコアを持ち、FindUserScores と FindScoreDetail だけを実装します。
+type fakeScoreHistoryRepo struct {
+	repository.ScoresRepository
+	scores    []repository.Score
+	statement string
+	gotFilter repository.UserScoresFilter
+}
+
+func (f *fakeScoreHistoryRepo) FindUserScores(_ context.Context, userID int, filter repository.UserScoresFilter, limit int) ([]repository.Score, error) {
+	f.gotFilter = filter
+	var out []repository.Score
+	for _, s := range f.scores {
+		if s.UserID == nil || *s.UserID != userID {
+			continue
+		}
+		if filter.Before != nil && s.ID >= filter.Before.ID {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (f *fakeScoreHistoryRepo) FindScoreDetail(_ context.Context, scoreID int) (*repository.ScoreDetail, error) {
+	for _, s := range f.scores {
+		if s.ID == scoreID {
+			return &repository.ScoreDetail{Score: s, ProblemStatement: f.statement}, nil
+		}
+	}
+	return nil, repository.ErrScoreNotFound
+}
+
+func newScoreHistoryRouter(h *ScoreHistoryHandler) *gin.Engine {
+	router := gin.New()
+	// ログイン済みを再現するため、ヘッダー X-Test-User があればユーザー 7 としてセットします。
+	setUser := func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set(ContextKeyUserID, 7)
+		}
+	}
+	router.GET("/api/v1/me/scores", setUser, h.GetMyScores)
+	router.GET("/api/v1/scores/:id", setUser, h.GetScore)
+	return router
+}
+
+func TestScoreHistoryHandler_GetMyScores(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	me, other := 7, 8
+	repo := &fakeScoreHistoryRepo{}
+	base := time.Date(2025, 11, 12, 0, 0, 0, 0, time.UTC)
+	for id := 5; id >= 1; id-- {
+		owner := &me
+		if id == 3 {
+			owner = &other
+		}
+		repo.scores = append(repo.scores, repository.Score{ID: id, UserID: owner, QuestionID: 1, Prompt: "日本語", Score: 10 * id, CreatedAt: base.Add(time.Duration(id) * time.Minute)})
+	}
+	router := newScoreHistoryRouter(NewScoreHistoryHandler(repo))
+
+	get := func(query string, loggedIn bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/scores"+query, nil)
+		if loggedIn {
+			req.Header.Set("X-Test-User", "1")
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("", false); w.Code != http.StatusUnauthorized {
+		t.Errorf("guest status = %d, want 401", w.Code)
+	}
+
+	// 2 件ずつページを送ると、他人のスコアを除いた 4 件を 2 ページで読み切れます。
+	var ids []int
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		w := get("?limit=2"+cursor, true)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d (body=%s)", w.Code, w.Body.String())
+		}
+		var items []ScoreHistoryItem
+		if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		for _, item := range items {
+			ids = append(ids, item.ID)
+			if item.PromptLen != 3 {
+				t.Errorf("prompt_len = %d, want 3", item.PromptLen)
+			}
+		}
+		next := w.Header().Get(HeaderNextCursor)
+		if next == "" {
+			break
+		}
+		cursor = "&cursor=" + next
+	}
+	if len(ids) != 4 || ids[0] != 5 || ids[3] != 1 {
+		t.Errorf("paged ids = %v, want [5 4 2 1]", ids)
+	}
+
+	// 日付だけの to はその日の終わりまでを含めます。
+	if w := get("?from=2025-11-01&to=2025-11-12&model=gemini-2.5-pro&tag=calculation&question_id=3", true); w.Code != http.StatusOK {
+		t.Fatalf("status = %d (body=%s)", w.Code, w.Body.String())
+	}
+	f := repo.gotFilter
+	if f.From == nil || !f.From.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) ||
+		f.To == nil || !f.To.Equal(time.Date(2025, 11, 13, 0, 0, 0, 0, time.UTC)) ||
+		f.ModelName == nil || *f.ModelName != "gemini-2.5-pro" || f.Tag == nil || f.QuestionID == nil || *f.QuestionID != 3 {
+		t.Errorf("filter = %+v", f)
+	}
+
+	for _, query := range []string{
+		"?limit=0",
+		"?limit=101",
+		"?question_id=abc",
+		"?tag=no_such_tag",
+		"?from=yesterday",
+		"?from=2025-11-12&to=2025-11-01",
+		"?cursor=bm90LWpzb24",
+	} {
+		if w := get(query, true); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestScoreHistoryHandler_GetScore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	me := 7
+	statement := "すもももももももものうちの右から３番目の文字は何？"
+	repo := &fakeScoreHistoryRepo{
+		statement: statement,
+		scores: []repository.Score{{
+			ID:               1,
+			UserID:           &me,
+			QuestionID:       4,
+			Prompt:           "問題を繰り返してから答えて",
+			AIResponse:       "考え方: ...\n最終回答: " + statement + " 答えは「の」",
+			Score:            100,
+			EvaluationDetail: map[string]interface{}{"mode": "exact"},
+		}},
+	}
+	router := newScoreHistoryRouter(NewScoreHistoryHandler(repo))
+
+	get := func(path string, loggedIn bool) (*httptest.ResponseRecorder, ScoreDetailResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if loggedIn {
+			req.Header.Set("X-Test-User", "1")
+		}
+		router.ServeHTTP(w, req)
+		var resp ScoreDetailResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, owner := get("/api/v1/scores/1", true)
+	if w.Code != http.StatusOK || owner.Prompt == "" || owner.EvaluationDetail["mode"] != "exact" {
+		t.Fatalf("owner = %d %+v", w.Code, owner)
+	}
+	// 本人には「最終回答」以降だけを、問題文を伏せ字にして返します。
+	if owner.AIOutput == nil || strings.Contains(*owner.AIOutput, statement) ||
+		!strings.Contains(*owner.AIOutput, leakguard.Redaction) || strings.Contains(*owner.AIOutput, "考え方") {
+		t.Errorf("owner ai_output = %v", owner.AIOutput)
+	}
+
+	if w, guest := get("/api/v1/scores/1", false); w.Code != http.StatusOK || guest.AIOutput != nil || strings.Contains(w.Body.String(), "ai_output") {
+		t.Errorf("guest = %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := get("/api/v1/scores/2", true); w.Code != http.StatusNotFound {
+		t.Errorf("missing score status = %d, want 404", w.Code)
+	}
+	if w, _ := get("/api/v1/scores/abc", true); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid id status = %d, want 400", w.Code)
+	}
+}
+
+func TestScoreHistoryHandler_GetScore_HidesAnswerKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	me, other := 7, 8
+	detail := map[string]interface{}{
+		"mode":               "exact",
+		"normalized_score":   100,
+		"mode_reason":        "正規化後の最終回答が正解と一致",
+		"answer_raw":         "最終回答: 42",
+		"correct_raw":        "42",
+		"correct_trimmed":    "42",
+		"expected_items":     []string{"赤", "青"},
+		"correct_expression": "x^2+1",
+		"interval_min":       40,
+		"interval_max":       44,
+		"rubric":             "42 と答えていれば正解",
+		"golf":               map[string]interface{}{"golf_score": 80},
+		"cheat_flagged":      true,
+	}
+	answer := 42.0
+	repo := &fakeScoreHistoryRepo{scores: []repository.Score{
+		{ID: 1, UserID: &me, QuestionID: 4, Prompt: "答えは 42 です", AIResponse: "最終回答: 42", Score: 100, AnswerNumber: &answer, EvaluationDetail: detail},
+		{ID: 2, UserID: &other, QuestionID: 4, Prompt: "答えは 42 です", AIResponse: "最終回答: 42", Score: 100, AnswerNumber: &answer, EvaluationDetail: detail},
+		{ID: 3, UserID: &other, QuestionID: 4, Prompt: "順を追って考えて", AIResponse: "最終回答: 42", Score: 100, AnswerNumber: &answer,
+			EvaluationDetail: map[string]interface{}{"mode": "exact", "cheat_flagged": false}},
+	}}
+	router := newScoreHistoryRouter(NewScoreHistoryHandler(repo))
+	answerKeys := []string{"answer_raw", "correct_raw", "correct_trimmed", "expected_items", "correct_expression", "interval_min", "interval_max", "rubric"}
+
+	tests := []struct {
+		name     string
+		path     string
+		loggedIn bool
+		wantFull bool
+	}{
+		{name: "本人", path: "/api/v1/scores/1", loggedIn: true, wantFull: true},
+		{name: "他のユーザー", path: "/api/v1/scores/2", loggedIn: true},
+		{name: "ゲスト", path: "/api/v1/scores/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.loggedIn {
+				req.Header.Set("X-Test-User", "1")
+			}
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body=%s)", w.Code, w.Body.String())
+			}
+			var resp ScoreDetailResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			// 採点の内訳は誰にでも返します。
+			if resp.EvaluationDetail["mode"] != "exact" || resp.EvaluationDetail["golf"] == nil {
+				t.Errorf("evaluation_detail = %v, want mode and golf breakdown", resp.EvaluationDetail)
+			}
+			for _, key := range answerKeys {
+				if _, ok := resp.EvaluationDetail[key]; ok != tt.wantFull {
+					t.Errorf("evaluation_detail[%q] present = %v, want %v", key, ok, tt.wantFull)
+				}
+			}
+			if !tt.wantFull && strings.Contains(w.Body.String(), "x^2+1") {
+				t.Errorf("answer key leaked in body: %s", w.Body.String())
+			}
+			// 満点の挑戦の answer_number は正解そのもの、不正検出で引っかかったプロンプトは正解を含むため本人にだけ返します。
+			if (resp.AnswerNumber != nil) != tt.wantFull {
+				t.Errorf("answer_number = %v, want present = %v", resp.AnswerNumber, tt.wantFull)
+			}
+			if (resp.Prompt != "") != tt.wantFull {
+				t.Errorf("prompt = %q, want present = %v", resp.Prompt, tt.wantFull)
+			}
+		})
+	}
+
+	// 不正検出で疑いなしと判定された挑戦のプロンプトは本人以外にも返します。
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/scores/3", nil))
+	var clean ScoreDetailResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &clean); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if clean.Prompt != "順を追って考えて" || clean.AnswerNumber != nil {
+		t.Errorf("clean attempt for guest = prompt %q, answer_number %v", clean.Prompt, clean.AnswerNumber)
+	}
+}
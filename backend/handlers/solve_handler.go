@@ -5,6 +5,7 @@ package handlers
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,15 +14,29 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/anticheat"
+	"github.com/shiv/CoT_game/backend/internal/daily"
 	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/internal/leakguard"
+	"github.com/shiv/CoT_game/backend/internal/rating"
 	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/internal/variant"
 )
 
 // SolveHandler は solve エンドポイントの依存関係を保持します。
 type SolveHandler struct {
-	AIClient  ai.Client                   // AI へ質問を投げるための依存。モック可能にするため interface で受け取ります。
-	ScoreRepo repository.ScoresRepository // スコア保存・取得を担うリポジトリ。DB 直書きよりテストしやすい構造です。
-	DB        *sql.DB                     // 正解を問い合わせるための生 SQL 接続。将来的に専用リポジトリを切り出す余地があります。
+	AIClient  ai.Client                     // AI へ質問を投げるための依存。モック可能にするため interface で受け取ります。
+	ScoreRepo repository.ScoresRepository   // スコア保存・取得を担うリポジトリ。DB 直書きよりテストしやすい構造です。
+	DB        *sql.DB                       // 正解を問い合わせるための生 SQL 接続。将来的に専用リポジトリを切り出す余地があります。
+	Judge     eval.Judge                    // 自由記述問題（answer_spec.type=judge）の採点者。nil の場合その問題は採点できません。
+	Cheat     anticheat.Policy              // プロンプトに正解を書き込む・出力を指定するなどの不正を検出したときの扱い。
+	Golf      eval.GolfCurves               // プロンプトゴルフモードのレベルごとの長さスコアの曲線。
+	NewSeed   func() int64                  // テンプレート問題のバリアントを決めるシードの生成関数。テストで固定できるよう差し替え可能にしています。
+	Ratings   repository.RatingsRepository  // プレイヤーと問題の Glicko-2 レーティング。nil の場合は更新しません。
+	Progress  repository.ProgressRepository // レベルの解放の進捗。nil の場合は全ての問題に挑戦できます。
+	// Daily は今日の一問の出題と成績です。nil の場合、daily: true の挑戦は 503 になります。
+	Daily       repository.DailyRepository
+	DailyConfig daily.Config // 今日の一問の日付の区切りと 1 日の挑戦回数
 }
 
 // NewSolveHandler は新しい SolveHandler を作成します。
@@ -30,6 +45,9 @@ func NewSolveHandler(aiClient ai.Client, scoreRepo repository.ScoresRepository,
 		AIClient:  aiClient,
 		ScoreRepo: scoreRepo,
 		DB:        db,
+		Cheat:     anticheat.DefaultPolicy(),
+		Golf:      eval.DefaultGolfCurves(),
+		NewSeed:   variant.NewSeed,
 	}
 }
 
@@ -38,20 +56,41 @@ type SolveRequest struct {
 	QuestionID int    `json:"question_id" binding:"required"`
 	Prompt     string `json:"prompt" binding:"required"`
 	Model      string `json:"model"`
+	// ScoringMode は採点モード。"standard"（既定）は正確さだけ、"golf" はプロンプトの短さも加味したゴルフスコアを付けます。
+	ScoringMode string `json:"scoring_mode"`
+	// Daily が true なら今日の一問として挑戦します。ログインが必要で、question_id は今日の出題と一致している必要があります。
+	// 1 日の挑戦回数に上限があり、結果は今日の一問のランキングにも反映されます。
+	Daily bool `json:"daily"`
 }
 
+// 採点モード。
+const (
+	ScoringModeStandard = "standard"
+	ScoringModeGolf     = "golf"
+)
+
+// dailyWriteTimeout は今日の一問の挑戦回数の取り消しと成績の記録を、リクエストから切り離して行うときの上限時間です。
+const dailyWriteTimeout = 5 * time.Second
+
 // SolveResponse は /api/v1/solve のレスポンスを表します。
 type SolveResponse struct {
 	QuestionID   int                    `json:"question_id"`
 	Prompt       string                 `json:"prompt"`
 	ModelVendor  string                 `json:"model_vendor"`
 	ModelName    string                 `json:"model_name"`
-	AIOutput     string                 `json:"ai_output"`     // AI が出力したテキスト全文。クライアントで表示します。
-	AnswerNumber *float64               `json:"answer_number"` // 数値回答が抽出できた場合のみ値が入ります（例: 算数の答え）。
-	Score        int                    `json:"score"`         // 評価ロジックで決まった点数。100 点満点を想定。
-	Evaluation   map[string]interface{} `json:"evaluation"`    // 評価モードなどの補足情報。UI の詳細表示に役立ちます。
-	ElapsedMs    int64                  `json:"elapsed_ms"`    // AI 応答までにかかった時間（ミリ秒）。
-	Saved        bool                   `json:"saved"`         // DB 保存が成功したかどうか。false でもスコア自体は返します。
+	AIOutput     string                 `json:"ai_output"`            // AI が出力したテキスト全文。クライアントで表示します。
+	AnswerNumber *float64               `json:"answer_number"`        // 数値回答が抽出できた場合のみ値が入ります（例: 算数の答え）。
+	Score        int                    `json:"score"`                // 評価ロジックで決まった点数。100 点満点を想定。
+	Evaluation   map[string]interface{} `json:"evaluation"`           // 評価モードなどの補足情報。UI の詳細表示に役立ちます。
+	ElapsedMs    int64                  `json:"elapsed_ms"`           // AI 応答までにかかった時間（ミリ秒）。
+	Saved        bool                   `json:"saved"`                // DB 保存が成功したかどうか。false でもスコア自体は返します。
+	ScoringMode  string                 `json:"scoring_mode"`         // 採点モード（standard / golf）。
+	GolfScore    *int                   `json:"golf_score,omitempty"` // ゴルフモードのみ。正確さとプロンプトの短さを合成したスコア。内訳は evaluation.golf に入ります。
+	// UnlockedLevel はこの挑戦を反映した後に挑戦できる最も高いレベルです。ログイン中かつプログレッションを使う設定の場合のみ返します。
+	// 挑戦前より上がっていれば、新しいレベルが解放されたことを表示できます。
+	UnlockedLevel *int `json:"unlocked_level,omitempty"`
+	// DailyAttemptsLeft は今日の一問として挑戦した場合の、今日の残りの挑戦回数です。
+	DailyAttemptsLeft *int `json:"daily_attempts_left,omitempty"`
 }
 
 // PostSolve は POST /api/v1/solve のハンドラです。
@@ -66,8 +105,12 @@ type SolveResponse struct {
 // @Param        request body handlers.SolveRequest true "Solve Request"
 // @Success      200  {object}  handlers.SolveResponse
 // @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
 // @Router       /solve [post]
 func (h *SolveHandler) PostSolve(c *gin.Context) {
 	var req SolveRequest
@@ -99,6 +142,19 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		return
 	}
 
+	// 採点モードの確認
+	switch req.ScoringMode {
+	case "":
+		req.ScoringMode = ScoringModeStandard
+	case ScoringModeStandard, ScoringModeGolf:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_scoring_mode",
+			"message": "scoring_mode は standard または golf を指定してください",
+		})
+		return
+	}
+
 	// デフォルトモデルを設定
 	if req.Model == "" {
 		// モデル指定が無いケースでも使いやすいよう、サービス側でデフォルト値を決めています。
@@ -107,9 +163,42 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
+	// 今日の一問としての挑戦
+	// 挑戦回数を数えるためログインが必要で、今日の出題と異なる問題は受け付けません。
+	// 日付はここで一度だけ決め、挑戦中に日付が変わっても同じ日の挑戦として扱います。
+	var challenge *repository.DailyChallenge
+	var dailyUserID int
+	if req.Daily {
+		if h.Daily == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "daily_unavailable",
+				"message": "今日の一問は利用できません",
+			})
+			return
+		}
+		userID, ok := requireUser(c)
+		if !ok {
+			return
+		}
+		dailyUserID = userID
+		ch, err := h.Daily.FindOrCreate(ctx, h.DailyConfig.Today())
+		if err != nil {
+			respondDailyError(c, err, "今日の一問の取得に失敗しました")
+			return
+		}
+		if ch.QuestionID != req.QuestionID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "not_daily_question",
+				"message": fmt.Sprintf("今日の一問は問題 %d です", ch.QuestionID),
+			})
+			return
+		}
+		challenge = ch
+	}
+
 	// 問題の存在確認と正解の取得
 	// 問題が存在しない場合は 404 を返してフロントに伝えます。
-	correctAnswer, err := h.getCorrectAnswer(ctx, req.QuestionID)
+	key, err := h.getAnswerKey(ctx, req.QuestionID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -126,6 +215,30 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		return
 	}
 
+	// レベルの解放の確認
+	// 未解放のレベルの問題は AI を呼ぶ前に断り、どのレベルまで挑戦できるかを返します。
+	// 今日の一問は全員が同じ問題に挑戦できるよう、解放の確認をしません。
+	if !req.Daily {
+		unlocked, locking, err := unlockedLevel(c, h.Progress)
+		if err != nil {
+			log.Printf("進捗取得エラー: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "database_error",
+				"message": "進捗の取得に失敗しました",
+			})
+			return
+		}
+		if locking && key.Level > unlocked {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":          "question_locked",
+				"message":        fmt.Sprintf("この問題（レベル %d）はまだ解放されていません。挑戦できるのはレベル %d までです", key.Level, unlocked),
+				"level":          key.Level,
+				"unlocked_level": unlocked,
+			})
+			return
+		}
+	}
+
 	// 問題文の取得
 	// AIに問題文を含めたプロンプトを送信するために必要です。
 	problemStatement, err := h.getProblemStatement(ctx, req.QuestionID)
@@ -145,9 +258,44 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		return
 	}
 
+	// テンプレート問題のバリアント生成
+	// template_key が設定された問題は挑戦ごとに問題文と正解を作り直し、実際に出題したバリアントの正解で採点します。
+	// 今日の一問は日付から決まるシードを使い、その日は全員に同じバリアントを出します。
+	var served *variant.Variant
+	if key.TemplateKey != "" {
+		var seed int64
+		if challenge != nil {
+			seed = challenge.Seed
+		} else {
+			seed = h.NewSeed()
+		}
+		v, err := variant.Instantiate(key.TemplateKey, seed)
+		if err != nil {
+			log.Printf("バリアント生成エラー (question_id=%d): %v", req.QuestionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "variant_error",
+				"message": "問題の生成に失敗しました",
+				"detail":  err.Error(),
+			})
+			return
+		}
+		served = &v
+		problemStatement = v.ProblemStatement
+		key.CorrectAnswer = v.CorrectAnswer
+		key.Spec = eval.AnswerSpec{Type: eval.SpecValue, Value: v.CorrectAnswer}
+	}
+
+	// 不正検出
+	// プロンプトに正解が書かれていないか、AI に特定の出力を指示していないかを採点前に調べます。
+	// 結果は採点後にポリシー（記録のみ・減点・失格）に従ってスコアへ反映します。
+	finding := anticheat.Finding{}
+	if h.Cheat.Mode != anticheat.ModeOff {
+		finding = anticheat.Inspect(req.Prompt, key.Spec)
+	}
+
 	// システムプロンプトとユーザープロンプトを結合
 	// 問題文はユーザーには見せませんが、AIには送信する必要があります。
-	combinedPrompt := buildCombinedPrompt(problemStatement, req.Prompt)
+	combinedPrompt := ai.BuildSolvePrompt(problemStatement, req.Prompt)
 
 	// デバッグ: 送信されるプロンプトを確認
 	log.Printf("=== プロンプト確認 ===")
@@ -157,6 +305,37 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 	log.Printf("結合後のプロンプト:\n%s", combinedPrompt)
 	log.Printf("===================")
 
+	// 今日の一問の挑戦回数
+	// AI を呼ぶ前に 1 回分を使い、上限に達していれば断ります。AI の呼び出しや採点に失敗した場合は releaseDaily で戻します。
+	var dailyUsed int
+	if challenge != nil {
+		used, err := h.Daily.ReserveAttempt(ctx, challenge.Date, dailyUserID, h.DailyConfig.Attempts)
+		if errors.Is(err, repository.ErrDailyAttemptsExhausted) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":     "daily_attempts_exhausted",
+				"message":   fmt.Sprintf("今日の一問の挑戦回数（%d 回）を使い切りました", h.DailyConfig.Attempts),
+				"resets_at": h.DailyConfig.NextReset(),
+			})
+			return
+		}
+		if err != nil {
+			respondDailyError(c, err, "挑戦回数の記録に失敗しました")
+			return
+		}
+		dailyUsed = used
+	}
+	releaseDaily := func() {
+		if challenge == nil {
+			return
+		}
+		// クライアントの切断で AI の呼び出しが失敗した場合も戻せるよう、リクエストのキャンセルを引き継がない context を使います。
+		releaseCtx, cancel := detachedContext(ctx)
+		defer cancel()
+		if err := h.Daily.ReleaseAttempt(releaseCtx, challenge.Date, dailyUserID); err != nil {
+			log.Printf("今日の一問の挑戦回数の取り消しエラー (user_id=%d): %v", dailyUserID, err)
+		}
+	}
+
 	// AI呼び出し開始時刻
 	// time.Since と組み合わせることでレイテンシを簡単に測定できます。
 	startTime := time.Now()
@@ -166,6 +345,7 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 	aiResp, err := h.AIClient.Generate(ctx, combinedPrompt)
 	if err != nil {
 		log.Printf("AI呼び出しエラー: %v", err)
+		releaseDaily()
 		statusCode := http.StatusBadGateway
 		if ai.IsKind(err, ai.ErrorKindClientError) {
 			statusCode = http.StatusBadRequest
@@ -190,9 +370,73 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 	// 問題文が推測されないよう、説明部分を除外します
 	clientResponse := extractFinalAnswer(aiResp.RawText)
 
+	// 漏洩チェック
+	// マーカーが無い場合や「問題をそのまま繰り返して」と指示された場合に、問題文がクライアントへ漏れないよう伏せ字にします。
+	leak := leakguard.Check(clientResponse, problemStatement)
+	if leak.Flagged {
+		log.Printf("問題文の漏洩を検出しました (question_id=%d, coverage=%.2f)", req.QuestionID, leak.Coverage)
+		clientResponse = leak.Redacted
+	}
+
 	// 評価ロジック実行
 	// eval パッケージに責務を分離することで、ハンドラは「AI の結果をどう扱うか」に集中できます。
-	score, answerNumber, mode, detail := eval.Evaluate(fullAIResponse, correctAnswer)
+	// 採点は常に現在の版（eval.CurrentVersion）で行い、版をスコアと一緒に保存して後から再採点できるようにします。
+	result, err := eval.EvaluateVersion(eval.CurrentVersion, fullAIResponse, key.evalKey())
+	if err != nil {
+		log.Printf("採点エラー: %v", err)
+		releaseDaily()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "evaluation_error",
+			"message": "回答の採点に失敗しました",
+			"detail":  err.Error(),
+		})
+		return
+	}
+	score, answerNumber, mode, detail := result.Score, result.Extracted, result.Mode, result.Detail
+	if key.Spec.Type == eval.SpecJudge {
+		// 自由記述問題は別途設定した採点用モデルに採点させます。
+		// 採点者の障害でプレイヤーが 0 点にならないよう、失敗時は保存せずエラーを返します。
+		var judgeErr error
+		score, mode, detail, judgeErr = eval.EvaluateWithJudge(ctx, h.Judge, eval.JudgeRequest{
+			QuestionID:       req.QuestionID,
+			ProblemStatement: problemStatement,
+			Rubric:           key.Rubric,
+			ReferenceAnswer:  key.Spec.Value,
+			Answer:           fullAIResponse,
+		})
+		if judgeErr != nil {
+			log.Printf("採点者呼び出しエラー: %v", judgeErr)
+			releaseDaily()
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "judge_error",
+				"message": "回答の採点に失敗しました",
+				"detail":  judgeErr.Error(),
+			})
+			return
+		}
+		eval.StampVersion(detail, eval.CurrentVersion, mode)
+	}
+	score = h.Cheat.Apply(score, finding, detail)
+	leakDetail := leak.Detail()
+	// 採点の詳細には AI の出力（説明部分を含む全文や抜き出した文字列）がそのまま入ります。
+	// 最終回答か出力全体に問題文が含まれていれば、伏せ字にした ai_output を迂回して漏れないよう、レスポンスと保存する詳細の両方から取り除きます。
+	// AI の出力の全文は scores.ai_response に残るため、運営の確認には困りません。
+	if leak.Flagged || leakguard.Check(fullAIResponse, problemStatement).Flagged {
+		leakDetail["removed_fields"] = eval.StripAIText(detail)
+	}
+	detail["leak_guard"] = leakDetail
+
+	// ゴルフモードでは、正確さ（不正検出の減点後）にプロンプトの短さを掛け合わせたスコアを別に付けます。
+	// score 自体は正確さのまま残し、通常のランキングと混ざらないようにします。
+	var golfScore *int
+	var golfBreakdown map[string]any
+	if req.ScoringMode == ScoringModeGolf {
+		g, breakdown := eval.GolfScore(score, req.Prompt, h.Golf.For(key.Level))
+		breakdown["level"] = key.Level
+		golfScore = &g
+		golfBreakdown = breakdown
+		detail["golf"] = breakdown
+	}
 
 	// 評価メタデータを構築
 	// detail 全体は JSONB に保存しますが、レスポンスに最低限の情報を添えておくと UI 側で扱いやすくなります。
@@ -200,11 +444,20 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		"mode":   mode,
 		"detail": detail,
 	}
+	if golfBreakdown != nil {
+		evaluationMeta["golf"] = golfBreakdown
+	}
 
 	// スコアレコードをDBに保存
 	// 完全な回答をDBに保存（デバッグ・分析用）
+	evaluatorVersion := int(eval.CurrentVersion)
+	// ログイン中ならユーザーの挑戦として記録し、ゲストなら user_id は NULL のままにします。
+	var userID *int
+	if id, ok := currentUserID(c); ok {
+		userID = &id
+	}
 	scoreRecord := &repository.Score{
-		UserID:           nil, // ゲストユーザー（認証未実装のため）
+		UserID:           userID,
 		QuestionID:       req.QuestionID,
 		Prompt:           req.Prompt,
 		AIResponse:       fullAIResponse,
@@ -214,6 +467,20 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		AnswerNumber:     answerNumber,
 		LatencyMs:        int(elapsedMs),
 		EvaluationDetail: detail,
+		EvaluatorVersion: &evaluatorVersion,
+		ScoringMode:      req.ScoringMode,
+		GolfScore:        golfScore,
+	}
+	if leak.Flagged {
+		// 漏洩が疑われる回答は運営が確認できるよう印を付けて保存します。
+		reason := leakguard.ReviewReason
+		scoreRecord.NeedsReview = true
+		scoreRecord.ReviewReason = &reason
+	}
+	if served != nil {
+		// 再採点や問い合わせ対応のため、出題したバリアントの変数と正解を一緒に保存します。
+		scoreRecord.VariantParams = served.Record()
+		scoreRecord.VariantAnswer = &served.CorrectAnswer
 	}
 
 	// 保存は可能な限り試みますが、失敗しても回答自体はクライアントに返せるようにします。
@@ -225,41 +492,126 @@ func (h *SolveHandler) PostSolve(c *gin.Context) {
 		// 保存失敗しても結果は返す（クライアントには成功を伝える）
 	}
 
+	// レーティング更新
+	// ログイン中の挑戦を「プレイヤー対問題」の対戦として記録します。保存できなかった挑戦やゲストの挑戦は数えません。
+	// レーティングは付加的な情報なので、失敗してもログに残すだけで回答は返します。
+	if saved && userID != nil && h.Ratings != nil {
+		if _, _, err := h.Ratings.RecordMatch(ctx, *userID, req.QuestionID, rating.Outcome(score)); err != nil {
+			log.Printf("レーティング更新エラー (user_id=%d, question_id=%d): %v", *userID, req.QuestionID, err)
+		}
+	}
+
+	// 今日の一問の成績の更新
+	// 挑戦回数は使っているため、スコアの保存に失敗しても今日の一問の成績には反映します。
+	var dailyLeft *int
+	if challenge != nil {
+		// 挑戦回数は使い終えているため、採点後にクライアントが切断していても成績は記録します。
+		recordCtx, cancel := detachedContext(ctx)
+		defer cancel()
+		if err := h.Daily.RecordResult(recordCtx, challenge.Date, dailyUserID, score); err != nil {
+			log.Printf("今日の一問の成績の更新エラー (user_id=%d): %v", dailyUserID, err)
+		}
+		left := h.DailyConfig.Attempts - dailyUsed
+		dailyLeft = &left
+	}
+
+	// 進捗の更新
+	// 保存した挑戦で条件を満たしていれば次のレベルを解放します。失敗してもログに残すだけで回答は返します（次の挑戦や一覧の取得で改めて計算されます）。
+	var unlockedAfter *int
+	if saved && userID != nil && h.Progress != nil {
+		if progress, err := h.Progress.Sync(ctx, *userID); err != nil {
+			log.Printf("進捗更新エラー (user_id=%d): %v", *userID, err)
+		} else {
+			unlockedAfter = &progress.UnlockedLevel
+		}
+	}
+
 	// レスポンス生成
 	// フロントエンドには「最終回答: 」以降のみを返して、問題文の推測を防ぎます
 	resp := SolveResponse{
-		QuestionID:   req.QuestionID,
-		Prompt:       req.Prompt,
-		ModelVendor:  "gemini",
-		ModelName:    req.Model,
-		AIOutput:     clientResponse, // 最終回答のみ
-		AnswerNumber: answerNumber,
-		Score:        score,
-		Evaluation:   evaluationMeta,
-		ElapsedMs:    elapsedMs,
-		Saved:        saved,
+		QuestionID:        req.QuestionID,
+		Prompt:            req.Prompt,
+		ModelVendor:       "gemini",
+		ModelName:         req.Model,
+		AIOutput:          clientResponse, // 最終回答のみ
+		AnswerNumber:      answerNumber,
+		Score:             score,
+		Evaluation:        evaluationMeta,
+		ElapsedMs:         elapsedMs,
+		Saved:             saved,
+		ScoringMode:       req.ScoringMode,
+		GolfScore:         golfScore,
+		UnlockedLevel:     unlockedAfter,
+		DailyAttemptsLeft: dailyLeft,
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
 
+// detachedContext はリクエストがキャンセルされても止まらない、dailyWriteTimeout で打ち切る context を返します。
+// 使った挑戦回数の取り消しや成績の記録のように、途中で止めるとプレイヤーが損をする書き込みに使います。
+func detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), dailyWriteTimeout)
+}
+
 // getProblemStatement は question_id から問題文を取得します。
 // システムプロンプトの構築に使用されます。
 func (h *SolveHandler) getProblemStatement(ctx context.Context, questionID int) (string, error) {
-	query := "SELECT problem_statement FROM questions WHERE id = $1"
+	query := "SELECT problem_statement FROM questions WHERE id = $1 AND deleted_at IS NULL"
 	var problemStatement string
 	// PostgreSQLのバインド変数を使用してSQLインジェクションを防ぎます。
 	err := h.DB.QueryRowContext(ctx, query, questionID).Scan(&problemStatement)
 	return problemStatement, err
 }
 
-// getCorrectAnswer はquestion_idから正解を取得します。
-func (h *SolveHandler) getCorrectAnswer(ctx context.Context, questionID int) (string, error) {
-	query := "SELECT correct_answer FROM questions WHERE id = $1"
-	var correctAnswer string
+// answerKey は採点に必要な問題側の情報（正解と評価条件）をまとめたものです。
+type answerKey struct {
+	CorrectAnswer string
+	Spec          eval.AnswerSpec    // answer_spec 列。NULL の場合は CorrectAnswer から組み立てます。
+	ExpectedUnit  string             // 単位を問わない問題では空文字
+	UnitPolicy    eval.UnitPolicy    // unit_policy 列。NULL の場合は空文字（reject）
+	Rubric        string             // LLM 採点用の採点基準。judge 以外の問題では空文字
+	Level         int                // 問題のレベル。ゴルフモードの曲線の選択に使います。
+	TemplateKey   string             // 問題テンプレートのキー。固定の問題では空文字
+	Scoring       eval.ScoringParams // scoring_params 列。NULL の場合はゼロ値（既定の曲線）
+}
+
+// evalKey は採点関数に渡す問題側の情報へ変換します。
+func (k answerKey) evalKey() eval.AnswerKey {
+	return eval.AnswerKey{CorrectAnswer: k.CorrectAnswer, Spec: k.Spec, ExpectedUnit: k.ExpectedUnit, UnitPolicy: k.UnitPolicy, Scoring: k.Scoring}
+}
+
+// getAnswerKey はquestion_idから正解・正解仕様・期待単位・単位の食い違いの扱い・採点基準・レベル・テンプレートキー・採点曲線のパラメータを取得します。
+// 削除済みの問題は sql.ErrNoRows になり、存在しない問題と同じく 404 を返します。
+func (h *SolveHandler) getAnswerKey(ctx context.Context, questionID int) (answerKey, error) {
+	query := "SELECT correct_answer, answer_spec, expected_unit, unit_policy, grading_rubric, level, template_key, scoring_params FROM questions WHERE id = $1 AND deleted_at IS NULL"
+	var key answerKey
+	var specJSON, scoringJSON []byte
+	var expectedUnit, unitPolicy, rubric, templateKey sql.NullString
 	// 実環境では questionID をバインドして SQL インジェクションを防ぎます。QueryRowContext → Scan の流れは DB 操作の基本形です。
-	err := h.DB.QueryRowContext(ctx, query, questionID).Scan(&correctAnswer)
-	return correctAnswer, err
+	if err := h.DB.QueryRowContext(ctx, query, questionID).Scan(&key.CorrectAnswer, &specJSON, &expectedUnit, &unitPolicy, &rubric, &key.Level, &templateKey, &scoringJSON); err != nil {
+		return key, err
+	}
+	key.ExpectedUnit = expectedUnit.String
+	key.UnitPolicy = eval.UnitPolicy(unitPolicy.String)
+	key.Rubric = rubric.String
+	key.TemplateKey = templateKey.String
+
+	spec, err := eval.ParseAnswerSpec(specJSON, key.CorrectAnswer)
+	if err != nil {
+		// 仕様が壊れていても採点を止めないよう、correct_answer による単一の正解に戻します。
+		log.Printf("answer_spec の読み込みに失敗したため correct_answer で採点します (question_id=%d): %v", questionID, err)
+		spec = eval.AnswerSpec{Type: eval.SpecValue, Value: key.CorrectAnswer}
+	}
+	key.Spec = spec
+
+	scoring, err := eval.ParseScoringParams(scoringJSON)
+	if err != nil {
+		// パラメータが壊れていても採点を止めないよう、既定の曲線で採点します。
+		log.Printf("scoring_params の読み込みに失敗したため既定値で採点します (question_id=%d): %v", questionID, err)
+	}
+	key.Scoring = scoring
+	return key, nil
 }
 
 // extractFinalAnswer はAIの完全な回答から「最終回答: 」以降の部分のみを抽出します。
@@ -280,19 +632,3 @@ func extractFinalAnswer(fullResponse string) string {
 	// マーカーが見つからない場合は全文を返す（フォールバック）
 	return fullResponse
 }
-
-// buildCombinedPrompt はシステムプロンプトとユーザープロンプトを結合します。
-// 問題文はユーザーには見せませんが、AIが問題を解くために必要です。
-// 返されるプロンプトには、問題文、ユーザーの指示が含まれます。
-// ユーザーのプロンプトを最大限尊重しつつ、最終回答を明確にするよう促します。
-func buildCombinedPrompt(problemStatement, userPrompt string) string {
-	return fmt.Sprintf(`以下の問題について、ユーザーの指示に従って回答してください。
-
-【問題】
-%s
-
-【ユーザーの指示】
-%s
-
-【重要】回答の最後に、必ず「最終回答: 」に続けて答えを明記してください。`, problemStatement, userPrompt)
-}
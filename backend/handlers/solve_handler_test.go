@@ -7,15 +7,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/daily"
+	"github.com/shiv/CoT_game/backend/internal/eval"
 	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/internal/variant"
 )
 
 // MockAIClient はテスト用のAIクライアントモックです。
@@ -157,6 +162,70 @@ func TestSolveHandler_PostSolve_Success(t *testing.T) {
 	}
 }
 
+// TestSolveHandler_PostSolve_TemplateVariant はテンプレート問題で、実際に出題したバリアントの正解で採点・保存されることを確認します。
+func TestSolveHandler_PostSolve_TemplateVariant(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	var questionID int
+	err := db.QueryRow("SELECT id FROM questions WHERE template_key = 'letter_count' ORDER BY id LIMIT 1").Scan(&questionID)
+	if err != nil {
+		t.Skip("テンプレート問題（letter_count）が存在しないためスキップ")
+	}
+	defer cleanupTestScores(t, db, questionID)
+
+	// シードを固定して、出題されるバリアントを先に求めておきます。
+	const seed = 42
+	expected, err := variant.Instantiate("letter_count", seed)
+	if err != nil {
+		t.Fatalf("failed to instantiate variant: %v", err)
+	}
+
+	mockAI := &MockAIClient{
+		Response: ai.Response{RawText: "1文字ずつ数えました。\n最終回答: " + expected.CorrectAnswer},
+	}
+	scoreRepo := repository.NewScoresRepository(db)
+	handler := NewSolveHandler(mockAI, scoreRepo, db)
+	handler.NewSeed = func() int64 { return seed }
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/solve", handler.PostSolve)
+
+	bodyBytes, _ := json.Marshal(SolveRequest{QuestionID: questionID, Prompt: "1文字ずつ数えてください"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/solve", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp SolveResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Score != 100 {
+		t.Errorf("expected score 100 for the served variant, got %d", resp.Score)
+	}
+
+	var variantAnswer sql.NullString
+	err = db.QueryRow("SELECT variant_answer FROM scores WHERE question_id = $1 ORDER BY id DESC LIMIT 1", questionID).Scan(&variantAnswer)
+	if err != nil {
+		t.Fatalf("failed to read saved score: %v", err)
+	}
+	if variantAnswer.String != expected.CorrectAnswer {
+		t.Errorf("expected variant_answer=%s, got %q", expected.CorrectAnswer, variantAnswer.String)
+	}
+}
+
 func TestSolveHandler_PostSolve_ValidationError(t *testing.T) {
 	db := setupTestDB(t)
 	if db == nil {
@@ -199,6 +268,15 @@ func TestSolveHandler_PostSolve_ValidationError(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "採点モードが不正",
+			reqBody: SolveRequest{
+				QuestionID:  1,
+				Prompt:      "test",
+				ScoringMode: "speedrun",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "question_idが不正",
 			reqBody: map[string]interface{}{
@@ -315,3 +393,250 @@ func TestSolveHandler_PostSolve_AIError(t *testing.T) {
 		t.Errorf("expected status 502, got %d", w.Code)
 	}
 }
+
+func TestSolveHandler_PostSolve_QuestionLocked(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	// level 2 以上の問題を、ゲスト（level 1 まで）として解こうとします。
+	var questionID, level int
+	if err := db.QueryRow("SELECT id, level FROM questions WHERE level > 1 AND deleted_at IS NULL ORDER BY id LIMIT 1").Scan(&questionID, &level); err != nil {
+		t.Skipf("level 2 以上の問題が用意されていません（スキップ）: %v", err)
+	}
+
+	mockAI := &MockAIClient{Err: errors.New("AI must not be called")}
+	handler := NewSolveHandler(mockAI, repository.NewScoresRepository(db), db)
+	handler.Progress = &fakeProgressRepo{}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/solve", handler.PostSolve)
+
+	bodyBytes, _ := json.Marshal(SolveRequest{QuestionID: questionID, Prompt: "test prompt"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/solve", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d (body=%s)", w.Code, w.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["error"] != "question_locked" || body["unlocked_level"] != float64(1) || body["level"] != float64(level) {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+// TestStripAIText は問題文を繰り返した AI の出力をどの正解仕様で採点しても、AI の出力を持つキーを取り除いた後の詳細に問題文が残らないことを確認します。
+func TestStripAIText(t *testing.T) {
+	statement := "すもももももももものうちの右から３番目の文字は何？"
+	output := "問題: " + statement + "\n最終回答: " + statement + " 答えは 2 です"
+	lo, hi := 1.0, 3.0
+
+	specs := []eval.AnswerSpec{
+		{Type: eval.SpecValue, Value: "2"},
+		{Type: eval.SpecAlternatives, Alternatives: []string{"2", "二"}},
+		{Type: eval.SpecRegex, Pattern: `^2$`},
+		{Type: eval.SpecSet, Items: []string{"2", "3"}, PartialCredit: true},
+		{Type: eval.SpecList, Items: []string{"2", "3"}},
+		{Type: eval.SpecInterval, Min: &lo, Max: &hi},
+		{Type: eval.SpecExpression, Value: "1+1"},
+	}
+	for _, spec := range specs {
+		t.Run(string(spec.Type), func(t *testing.T) {
+			result, err := eval.EvaluateVersion(eval.CurrentVersion, output, eval.AnswerKey{CorrectAnswer: spec.Value, Spec: spec})
+			if err != nil {
+				t.Fatalf("EvaluateVersion() error = %v", err)
+			}
+			eval.StripAIText(result.Detail)
+			body, err := json.Marshal(result.Detail)
+			if err != nil {
+				t.Fatalf("failed to encode detail: %v", err)
+			}
+			if strings.Contains(string(body), statement) {
+				t.Errorf("detail still contains the problem statement: %s", body)
+			}
+		})
+	}
+}
+
+// TestSolveHandler_PostSolve_LeakRemovedFromDetail は AI が問題文を繰り返したとき、レスポンスのどこにも問題文が含まれないことを確認します。
+func TestSolveHandler_PostSolve_LeakRemovedFromDetail(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	var statement string
+	if err := db.QueryRow("SELECT problem_statement FROM questions WHERE id = 1 AND deleted_at IS NULL").Scan(&statement); err != nil {
+		t.Skip("テスト用の問題（ID=1）が存在しないためスキップ")
+	}
+	defer cleanupTestScores(t, db, 1)
+
+	mockAI := &MockAIClient{Response: ai.Response{RawText: "問題: " + statement + "\n最終回答: " + statement}}
+	handler := NewSolveHandler(mockAI, repository.NewScoresRepository(db), db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/solve", handler.PostSolve)
+
+	bodyBytes, _ := json.Marshal(SolveRequest{QuestionID: 1, Prompt: "問題をそのまま繰り返して"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/solve", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (body=%s)", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), statement) {
+		t.Errorf("response contains the problem statement: %s", w.Body.String())
+	}
+
+	// 保存した採点の詳細にも残しません。
+	var stored string
+	if err := db.QueryRow("SELECT evaluation_detail::text FROM scores WHERE question_id = 1 ORDER BY id DESC LIMIT 1").Scan(&stored); err != nil {
+		t.Fatalf("failed to read stored detail: %v", err)
+	}
+	if strings.Contains(stored, statement) {
+		t.Errorf("stored evaluation_detail contains the problem statement: %s", stored)
+	}
+}
+
+// cancelingAIClient は呼ばれるとリクエストの context をキャンセルして失敗し、AI の応答待ちの間にクライアントが切断した状況を再現します。
+type cancelingAIClient struct {
+	cancel context.CancelFunc
+}
+
+func (m *cancelingAIClient) Generate(ctx context.Context, _ string) (ai.Response, error) {
+	m.cancel()
+	return ai.Response{}, ctx.Err()
+}
+
+func (m *cancelingAIClient) GenerateAnswer(ctx context.Context, prompt string) (string, error) {
+	_, err := m.Generate(ctx, prompt)
+	return "", err
+}
+
+// TestDetachedContext はリクエストがキャンセルされても、挑戦回数の取り消しに使う context は有効で、期限が付いていることを確認します。
+func TestDetachedContext(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	cancelParent()
+
+	ctx, cancel := detachedContext(parent)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		t.Errorf("detached context error = %v, want nil", err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("detached context should have a deadline")
+	}
+}
+
+// TestSolveHandler_PostSolve_Daily は今日の一問の挑戦で、回数の上限・失敗時の取り消し・成績の記録が行われることを確認します。
+func TestSolveHandler_PostSolve_Daily(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM questions WHERE id = 1 AND deleted_at IS NULL)").Scan(&exists); err != nil || !exists {
+		t.Skip("テスト用の問題（ID=1）が存在しないためスキップ")
+	}
+	defer cleanupTestScores(t, db, 1)
+
+	const userID = 7
+	config := daily.Config{Location: time.UTC, Attempts: 2}
+	today := config.Today()
+
+	// post は fakeDailyRepo（今日の一問は問題 1）を使ったハンドラに、ユーザー 7 として daily: true の挑戦を送ります。
+	post := func(t *testing.T, ctx context.Context, client ai.Client, repo *fakeDailyRepo) *httptest.ResponseRecorder {
+		t.Helper()
+		handler := NewSolveHandler(client, repository.NewScoresRepository(db), db)
+		handler.Daily = repo
+		handler.DailyConfig = config
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/api/v1/solve", func(c *gin.Context) { c.Set(ContextKeyUserID, userID) }, handler.PostSolve)
+
+		bodyBytes, _ := json.Marshal(SolveRequest{QuestionID: 1, Prompt: "test prompt", Daily: true})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/solve", bytes.NewReader(bodyBytes)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("回数を使い切ると 429", func(t *testing.T) {
+		repo := newFakeDailyRepo()
+		repo.entries[today] = map[int]*repository.DailyEntry{userID: {UserID: userID, Attempts: 2}}
+		w := post(t, context.Background(), &MockAIClient{Err: errors.New("AI must not be called")}, repo)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d (body=%s)", w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "daily_attempts_exhausted") || !strings.Contains(w.Body.String(), "resets_at") {
+			t.Errorf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("AI の失敗で回数を戻す", func(t *testing.T) {
+		repo := newFakeDailyRepo()
+		w := post(t, context.Background(), &MockAIClient{Err: &ai.Error{Kind: ai.ErrorKindServerError, Code: 500, Message: "AI server error", Temp: true}}, repo)
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("expected status 502, got %d (body=%s)", w.Code, w.Body.String())
+		}
+		if repo.released != 1 || repo.entries[today][userID].Attempts != 0 {
+			t.Errorf("released = %d, attempts = %d, want 1, 0", repo.released, repo.entries[today][userID].Attempts)
+		}
+	})
+
+	t.Run("クライアントが切断しても回数を戻す", func(t *testing.T) {
+		repo := newFakeDailyRepo()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		post(t, ctx, &cancelingAIClient{cancel: cancel}, repo)
+		if repo.released != 1 || repo.entries[today][userID].Attempts != 0 {
+			t.Errorf("released = %d, attempts = %d, want 1, 0", repo.released, repo.entries[today][userID].Attempts)
+		}
+	})
+
+	t.Run("採点した成績を記録する", func(t *testing.T) {
+		repo := newFakeDailyRepo()
+		w := post(t, context.Background(), &MockAIClient{Response: ai.Response{RawText: "最終回答: 2"}}, repo)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d (body=%s)", w.Code, w.Body.String())
+		}
+		var resp SolveResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(repo.recorded) != 1 || repo.recorded[0] != resp.Score {
+			t.Errorf("recorded = %v, want [%d]", repo.recorded, resp.Score)
+		}
+		if resp.DailyAttemptsLeft == nil || *resp.DailyAttemptsLeft != 1 {
+			t.Errorf("daily_attempts_left = %v, want 1", resp.DailyAttemptsLeft)
+		}
+	})
+}
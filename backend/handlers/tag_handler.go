@@ -0,0 +1,162 @@
+// Package handlers は HTTP リクエストを処理しレスポンスを生成するコントローラ層を提供します。
+// tag_handler.go は models.TagDefinitions のタグ定義を、問題数や平均点の集計と一緒に返すハンドラです。
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// TagHandler はタグ API の依存関係を保持します。
+type TagHandler struct {
+	DB *pgxpool.Pool
+}
+
+// NewTagHandler は新しい TagHandler を作成します。
+func NewTagHandler(db *pgxpool.Pool) *TagHandler {
+	return &TagHandler{DB: db}
+}
+
+// tagStats はタグごとの集計値です。
+type tagStats struct {
+	questionCount int
+	attempts      int
+	averageScore  *float64
+}
+
+// GetTags は全てのタグを models.TagOrder の順で、問題数・挑戦回数・平均点と一緒に返します。
+// 問題が 1 つも無いタグも件数 0 で返します。
+// GetTags godoc
+// @Summary      Get list of tags
+// @Description  Get all tag definitions with question counts and average scores
+// @Tags         tags
+// @Produce      json
+// @Success      200  {array}   models.TagSummary
+// @Failure      500  {object}  map[string]string
+// @Router       /tags [get]
+func (h *TagHandler) GetTags(c *gin.Context) {
+	stats, err := h.loadTagStats(c.Request.Context())
+	if err != nil {
+		log.Printf("タグの集計中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "タグの集計に失敗しました。"})
+		return
+	}
+
+	tags := models.GetAllTags()
+	responses := make([]models.TagSummary, len(tags))
+	for i, tag := range tags {
+		responses[i] = newTagSummary(tag, stats[tag.ID])
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetTag は 1 つのタグの定義と集計を返します。定義に無いタグは 404 です。
+// GetTag godoc
+// @Summary      Get tag detail
+// @Description  Get a tag definition with its question count and average score
+// @Tags         tags
+// @Produce      json
+// @Param        id   path      string  true  "Tag ID"
+// @Success      200  {object}  models.TagSummary
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /tags/{id} [get]
+func (h *TagHandler) GetTag(c *gin.Context) {
+	tag, ok := models.GetTagByID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定されたタグが見つかりません。"})
+		return
+	}
+
+	stats, err := h.loadTagStats(c.Request.Context())
+	if err != nil {
+		log.Printf("タグの集計中にエラーが発生しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "タグの集計に失敗しました。"})
+		return
+	}
+	c.JSON(http.StatusOK, newTagSummary(tag, stats[tag.ID]))
+}
+
+func newTagSummary(tag models.Tag, s tagStats) models.TagSummary {
+	return models.TagSummary{
+		Tag:           tag,
+		QuestionCount: s.questionCount,
+		Attempts:      s.attempts,
+		AverageScore:  s.averageScore,
+	}
+}
+
+// loadTagStats は questions.tags を展開して、タグごとの問題数・挑戦回数・平均点を集計します。
+func (h *TagHandler) loadTagStats(ctx context.Context) (map[string]tagStats, error) {
+	query := `
+		SELECT t.tag, COUNT(DISTINCT q.id), COUNT(s.id), AVG(s.score)::float8
+		FROM questions q
+		CROSS JOIN LATERAL unnest(q.tags) AS t(tag)
+		LEFT JOIN scores s ON s.question_id = q.id
+		WHERE q.deleted_at IS NULL
+		GROUP BY t.tag
+	`
+	rows, err := h.DB.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]tagStats)
+	for rows.Next() {
+		var tag string
+		var s tagStats
+		if err := rows.Scan(&tag, &s.questionCount, &s.attempts, &s.averageScore); err != nil {
+			return nil, fmt.Errorf("failed to scan tag stats: %w", err)
+		}
+		stats[tag] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tag stats rows iteration error: %w", err)
+	}
+	return stats, nil
+}
+
+// CheckQuestionTags は questions.tags に models.TagDefinitions に無いタグが使われていないかを確認します。
+// 未定義のタグがあれば、タグごとに使っている問題 ID を並べたエラーを返します。起動時の確認に使います。
+func CheckQuestionTags(ctx context.Context, db *pgxpool.Pool) error {
+	rows, err := db.Query(ctx, `
+		SELECT t.tag, array_agg(q.id ORDER BY q.id)
+		FROM questions q
+		CROSS JOIN LATERAL unnest(q.tags) AS t(tag)
+		GROUP BY t.tag
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query question tags: %w", err)
+	}
+	defer rows.Close()
+
+	var unknown []string
+	for rows.Next() {
+		var tag string
+		var questionIDs []int32
+		if err := rows.Scan(&tag, &questionIDs); err != nil {
+			return fmt.Errorf("failed to scan question tags: %w", err)
+		}
+		if _, ok := models.GetTagByID(tag); !ok {
+			unknown = append(unknown, fmt.Sprintf("%s (question_id=%v)", tag, questionIDs))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("question tags rows iteration error: %w", err)
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("undefined tags in questions.tags: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// tag_handler_test.go は TagHandler が定義どおりの順序でタグを返し、未定義のタグを検出できるかを検証します。
+// テスト用の PostgreSQL が無ければスキップします。
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+func TestTagHandler_GetTags(t *testing.T) {
+	pool := setupTestPool(t)
+	if pool == nil {
+		return
+	}
+	defer pool.Close()
+
+	handler := NewTagHandler(pool)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/tags", handler.GetTags)
+	router.GET("/api/v1/tags/:id", handler.GetTag)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tags", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var tags []models.TagSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &tags); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// 並び順は models.TagOrder に固定されています。
+	if len(tags) != len(models.TagOrder) {
+		t.Fatalf("expected %d tags, got %d", len(models.TagOrder), len(tags))
+	}
+	for i, tag := range tags {
+		if tag.ID != models.TagOrder[i] {
+			t.Errorf("tags[%d] = %s, want %s", i, tag.ID, models.TagOrder[i])
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tags/no_such_tag", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown tag, got %d", w.Code)
+	}
+
+	// シードデータのタグは全て定義済みのはずです。
+	if err := CheckQuestionTags(context.Background(), pool); err != nil {
+		t.Errorf("CheckQuestionTags() error = %v", err)
+	}
+}
@@ -22,6 +22,8 @@ const (
 	// defaultTimeout は API 呼び出し 1 件あたりの目安タイムアウト。
 	// Gemini APIのレスポンスが15秒程度かかることがあるため、余裕を持って30秒に設定。
 	defaultTimeout = 30 * time.Second
+	// defaultJudgeModel は採点用（LLM-as-judge）に使うモデル名。回答生成とは別に設定できる。
+	defaultJudgeModel = "gemini-2.0-flash"
 )
 
 // GeminiClient は Gemini REST API を利用してテキスト生成を行うクライアントです。
@@ -74,8 +76,13 @@ func NewGeminiClient(cfg Config, client *http.Client) (*GeminiClient, error) {
 //	text, err := client.GenerateAnswer(ctx, "1+1=?")
 //	// text には Gemini からの回答が格納される
 func NewGeminiClientFromEnv() (*GeminiClient, error) {
+	return NewGeminiClientForModel(readEnv("AI_MODEL_NAME"))
+}
+
+// NewGeminiClientForModel は NewGeminiClientFromEnv と同じ設定で、モデルだけを指定したクライアントを生成します。
+// model が空なら既定のモデルを使います。問題作成時の試走（internal/authoring）で複数のモデルを比べるときに使います。
+func NewGeminiClientForModel(model string) (*GeminiClient, error) {
 	baseURL := readEnv("GEMINI_API_BASE")
-	model := readEnv("AI_MODEL_NAME")
 	cfg := Config{
 		APIKey:     readEnv("GEMINI_API_KEY"),
 		BaseURL:    defaultBaseURL,
@@ -92,6 +99,29 @@ func NewGeminiClientFromEnv() (*GeminiClient, error) {
 	return NewGeminiClient(cfg, nil)
 }
 
+// NewJudgeClientFromEnv は採点用の Gemini クライアントを環境変数から生成します。
+// JUDGE_API_KEY / JUDGE_API_BASE / JUDGE_MODEL_NAME を優先し、未設定の項目は回答生成用の設定（GEMINI_API_KEY など）にフォールバックします。
+// 採点は低温度・短い出力で十分なため、リトライは回答生成と同じく 1 回に留めます。
+func NewJudgeClientFromEnv() (*GeminiClient, error) {
+	cfg := Config{
+		APIKey:     readEnv("JUDGE_API_KEY"),
+		BaseURL:    readEnv("JUDGE_API_BASE"),
+		Model:      readEnv("JUDGE_MODEL_NAME"),
+		Timeout:    defaultTimeout,
+		MaxRetries: 1,
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = readEnv("GEMINI_API_KEY")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = readEnv("GEMINI_API_BASE")
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultJudgeModel
+	}
+	return NewGeminiClient(cfg, nil)
+}
+
 // Generate は Gemini API にプロンプトを送信し、レスポンスを返します。
 func (c *GeminiClient) Generate(ctx context.Context, prompt string) (Response, error) {
 	if strings.TrimSpace(prompt) == "" {
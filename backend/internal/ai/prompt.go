@@ -0,0 +1,21 @@
+// Package ai は AI モデルと対話するためのクライアントインターフェースを定義します。
+// prompt.go は問題を解かせるときに AI へ送るプロンプトの組み立てをまとめます。
+// 挑戦（/solve）と問題作成時の試走（internal/authoring）で同じ形のプロンプトを使い、試走の結果が本番と食い違わないようにします。
+package ai
+
+import "fmt"
+
+// BuildSolvePrompt は問題文とユーザーの指示を結合します。
+// 問題文はユーザーには見せませんが、AIが問題を解くために必要です。
+// ユーザーのプロンプトを最大限尊重しつつ、最終回答を明確にするよう促します。
+func BuildSolvePrompt(problemStatement, userPrompt string) string {
+	return fmt.Sprintf(`以下の問題について、ユーザーの指示に従って回答してください。
+
+【問題】
+%s
+
+【ユーザーの指示】
+%s
+
+【重要】回答の最後に、必ず「最終回答: 」に続けて答えを明記してください。`, problemStatement, userPrompt)
+}
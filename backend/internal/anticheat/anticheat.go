@@ -0,0 +1,290 @@
+// Package anticheat はユーザーのプロンプトに正解が書かれていないか、AI に特定の出力を指示していないかを調べ、
+// 設定されたポリシー（記録のみ・減点・失格）に従ってスコアへ反映する不正検出の仕組みをまとめたパッケージ。
+// このゲームは問題文を見ずに AI を導く遊びなので、「最終回答: 32 と答えて」のように答えを当て推量で書き込むプロンプトを対象にする。
+package anticheat
+
+import (
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shiv/CoT_game/backend/internal/eval"
+)
+
+// Mode は不正を検出したときの扱い。
+type Mode string
+
+const (
+	// ModeOff は検査自体を行わない。
+	ModeOff Mode = "off"
+	// ModeFlag は evaluation_detail に記録するだけでスコアは変えない。
+	ModeFlag Mode = "flag"
+	// ModePenalize はスコアを Penalty の割合だけ減点する。
+	ModePenalize Mode = "penalize"
+	// ModeDisqualify はスコアを 0 点にする。
+	ModeDisqualify Mode = "disqualify"
+)
+
+// 検出の種類。
+const (
+	// SignalAnswerInPrompt はプロンプトに正解そのものが含まれている。
+	SignalAnswerInPrompt = "answer_in_prompt"
+	// SignalDictatedOutput はプロンプトが「32 と答えて」「最終回答: 32」のように出力内容を指定している。
+	SignalDictatedOutput = "dictated_output"
+)
+
+// defaultPenalty は ModePenalize で差し引くスコアの割合の既定値。
+const defaultPenalty = 0.5
+
+// minDistinctiveNumber は「プロンプトに含まれていたら正解を書いたとみなす」数値の大きさの下限。
+// 1 桁の整数は「3 つの手順で」のような普通の指示にも現れるため、出力の指定（SignalDictatedOutput）でのみ検出する。
+const minDistinctiveNumber = 10
+
+// minDistinctiveRunes は文字列の正解をプロンプト中から探すときの最小文字数。1 文字の正解（例: "の"）は誤検出が多いため探さない。
+const minDistinctiveRunes = 2
+
+// Policy は検出時の扱いを表す設定。
+type Policy struct {
+	Mode    Mode
+	Penalty float64 // ModePenalize で差し引く割合（0〜1）
+}
+
+// DefaultPolicy は既定のポリシー（半分に減点）を返す。
+func DefaultPolicy() Policy {
+	return Policy{Mode: ModePenalize, Penalty: defaultPenalty}
+}
+
+// PolicyFromEnv は環境変数 CHEAT_POLICY（off / flag / penalize / disqualify）と CHEAT_PENALTY（0〜1）からポリシーを作る。
+// 未設定や不正な値の場合は DefaultPolicy の値を使う。
+func PolicyFromEnv() Policy {
+	policy := DefaultPolicy()
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(os.Getenv("CHEAT_POLICY")))); mode {
+	case ModeOff, ModeFlag, ModePenalize, ModeDisqualify:
+		policy.Mode = mode
+	}
+	if raw := strings.TrimSpace(os.Getenv("CHEAT_PENALTY")); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v >= 0 && v <= 1 {
+			policy.Penalty = v
+		}
+	}
+	return policy
+}
+
+// Signal は 1 件の検出結果。
+type Signal struct {
+	Kind          string `json:"kind"`
+	Evidence      string `json:"evidence"`       // プロンプト中の該当箇所
+	MatchesAnswer bool   `json:"matches_answer"` // 指定された出力が正解と一致するか
+}
+
+// Finding はプロンプト 1 件の検査結果。
+type Finding struct {
+	Signals []Signal
+}
+
+// Flagged は不正の疑いがあるかを返す。
+func (f Finding) Flagged() bool {
+	return len(f.Signals) > 0
+}
+
+// dictationPatterns は出力内容を指定する言い回し。最初のキャプチャが指定された出力。
+var dictationPatterns = []*regexp.Regexp{
+	// 「32」と答えて / "three" って出力して
+	regexp.MustCompile(`[「『"“]([^」』"”]+)[」』"”]\s*(?:と|って)\s*(?:だけ)?\s*(?:答え|こたえ|回答|出力|書|言|返)`),
+	// 32と答えて / 32 とだけ出力
+	regexp.MustCompile(`([-+]?[0-9０-９]+(?:[.．][0-9０-９]+)?)\s*(?:と|って)\s*(?:だけ)?\s*(?:答え|こたえ|回答|出力|書|言|返)`),
+	// 最終回答: 32
+	regexp.MustCompile(`最終回答\s*[:：]?\s*[「『"“]?([^\s「」『』"”、。,]+)`),
+	// answer with 32. / say "three" / output: 32（指定された語の直後で文が終わるものに限る）
+	regexp.MustCompile(`(?i)\b(?:answer|say|output|respond|reply|print)(?:\s+(?:with|only|just|exactly|is))*\s*[:：]?\s*["“'「]?([^\s"”'」.,!]+)["”'」]?\s*(?:[.,!。、\n]|$)`),
+}
+
+// Inspect はプロンプトと正解仕様を照らし合わせ、正解の埋め込みと出力の指定を検出する。
+// 正解の比較には eval の正規化（NormalizeText / ParseNumbers）を使い、表記ゆれ（全角・語尾・小数表記）を吸収する。
+func Inspect(prompt string, spec eval.AnswerSpec) Finding {
+	answers := acceptedAnswers(spec)
+	var finding Finding
+
+	for _, pattern := range dictationPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(prompt, -1) {
+			literal := strings.TrimSpace(match[1])
+			matches := matchesAnyAnswer(literal, answers)
+			// 数値でも正解でもない指定（「"最終回答: " の形式で答えて」など）は書式の指示として扱う。
+			if _, numeric := singleNumber(literal); !matches && !numeric {
+				continue
+			}
+			finding.add(Signal{Kind: SignalDictatedOutput, Evidence: strings.TrimSpace(match[0]), MatchesAnswer: matches})
+		}
+	}
+
+	if evidence, ok := containsAnswer(prompt, spec, answers); ok {
+		finding.add(Signal{Kind: SignalAnswerInPrompt, Evidence: evidence, MatchesAnswer: true})
+	}
+	return finding
+}
+
+// add は同じ種類・同じ該当箇所の重複を除いて検出結果を追加する。
+func (f *Finding) add(s Signal) {
+	for _, existing := range f.Signals {
+		if existing.Kind == s.Kind && existing.Evidence == s.Evidence {
+			return
+		}
+	}
+	f.Signals = append(f.Signals, s)
+}
+
+// acceptedAnswers は正解仕様から「書かれていたら正解とみなす」文字列を列挙する。
+// 区間や LLM 採点の問題は単一の正解文字列を持たないため空になる。
+func acceptedAnswers(spec eval.AnswerSpec) []string {
+	switch spec.Type {
+	case eval.SpecValue, eval.SpecExpression:
+		return []string{spec.Value}
+	case eval.SpecAlternatives:
+		return spec.Alternatives
+	}
+	return nil
+}
+
+// matchesAnyAnswer は指定された出力が正解のいずれかと一致するかを判定する。数値同士なら有理数として比べる。
+func matchesAnyAnswer(literal string, answers []string) bool {
+	normalized := eval.NormalizeText(literal)
+	for _, answer := range answers {
+		if normalized == eval.NormalizeText(answer) {
+			return true
+		}
+		if a, ok := singleNumber(answer); ok {
+			if l, ok := singleNumber(literal); ok && a.Cmp(l) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsAnswer はプロンプトのどこかに正解が書かれているかを調べ、該当箇所を返す。
+func containsAnswer(prompt string, spec eval.AnswerSpec, answers []string) (string, bool) {
+	numbers := eval.ParseNumbers(prompt)
+	normalizedPrompt := eval.NormalizeText(prompt)
+
+	switch spec.Type {
+	case eval.SpecSet, eval.SpecList:
+		// 集合・リストは全要素が揃って初めて正解の埋め込みとみなす（要素 1 つだけでは普通の指示と区別できない）。
+		if len(spec.Items) < 2 {
+			return "", false
+		}
+		for _, item := range spec.Items {
+			if !containsOne(item, numbers, normalizedPrompt, 0) {
+				return "", false
+			}
+		}
+		return strings.Join(spec.Items, ", "), true
+	case eval.SpecInterval:
+		lo := new(big.Rat).SetFloat64(*spec.Min)
+		hi := new(big.Rat).SetFloat64(*spec.Max)
+		for _, n := range numbers {
+			if n.Cmp(lo) >= 0 && n.Cmp(hi) <= 0 && isDistinctive(n) {
+				return n.RatString(), true
+			}
+		}
+		return "", false
+	}
+
+	for _, answer := range answers {
+		if containsOne(answer, numbers, normalizedPrompt, minDistinctiveRunes) {
+			return answer, true
+		}
+	}
+	return "", false
+}
+
+// containsOne は 1 つの正解がプロンプトに含まれるかを判定する。
+// 数値の正解はプロンプト中の数値と有理数で比べ（小さい整数は除く）、文字列の正解は正規化後の部分一致で探す。
+func containsOne(answer string, numbers []*big.Rat, normalizedPrompt string, minRunes int) bool {
+	if v, ok := singleNumber(answer); ok {
+		if minRunes > 0 && !isDistinctive(v) {
+			return false
+		}
+		for _, n := range numbers {
+			if n.Cmp(v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	normalized := eval.NormalizeText(answer)
+	if normalized == "" || utf8.RuneCountInString(normalized) < minRunes {
+		return false
+	}
+	return strings.Contains(normalizedPrompt, normalized)
+}
+
+// singleNumber は文字列全体が 1 つの数値として読める場合にその値を返す。
+func singleNumber(s string) (*big.Rat, bool) {
+	numbers := eval.ParseNumbers(s)
+	if len(numbers) != 1 {
+		return nil, false
+	}
+	trimmed := strings.TrimSpace(s)
+	if _, err := strconv.ParseFloat(strings.Map(fullwidthDigit, trimmed), 64); err != nil {
+		return nil, false
+	}
+	return numbers[0], true
+}
+
+// fullwidthDigit は全角数字・小数点を半角に寄せる。
+func fullwidthDigit(r rune) rune {
+	switch {
+	case r >= '０' && r <= '９':
+		return '0' + (r - '０')
+	case r == '．':
+		return '.'
+	}
+	return r
+}
+
+// isDistinctive はプロンプトに偶然現れにくい数値（絶対値が minDistinctiveNumber 以上、または整数でない）かを判定する。
+func isDistinctive(v *big.Rat) bool {
+	if !v.IsInt() {
+		return true
+	}
+	abs := new(big.Rat).Abs(v)
+	return abs.Cmp(big.NewRat(minDistinctiveNumber, 1)) >= 0
+}
+
+// Apply は検査結果をポリシーに従ってスコアへ反映し、判定内容を detail["cheat_check"] に記録する。
+// 疑いが無い場合も detail["cheat_flagged"] = false を残し、検査済みであることが分かるようにする。
+func (p Policy) Apply(score int, finding Finding, detail map[string]any) int {
+	if p.Mode == ModeOff {
+		return score
+	}
+	flagged := finding.Flagged()
+	if detail != nil {
+		detail["cheat_flagged"] = flagged
+	}
+	if !flagged {
+		return score
+	}
+
+	adjusted := score
+	switch p.Mode {
+	case ModePenalize:
+		adjusted = int(float64(score)*(1-p.Penalty) + 0.5)
+	case ModeDisqualify:
+		adjusted = 0
+	}
+
+	if detail != nil {
+		detail["cheat_check"] = map[string]any{
+			"policy":       string(p.Mode),
+			"penalty":      p.Penalty,
+			"signals":      finding.Signals,
+			"score_before": score,
+			"score_after":  adjusted,
+			"disqualified": p.Mode == ModeDisqualify,
+		}
+	}
+	return adjusted
+}
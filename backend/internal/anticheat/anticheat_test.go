@@ -0,0 +1,167 @@
+// anticheat_test.go はプロンプトへの正解の埋め込み・出力の指定の検出と、ポリシーごとのスコアへの反映を確認する単体テスト。
+package anticheat
+
+import (
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/eval"
+)
+
+// TestInspect は代表的な不正プロンプトと、誤検出してはいけない普通のプロンプトをテーブルで検証する。
+func TestInspect(t *testing.T) {
+	value := func(v string) eval.AnswerSpec { return eval.AnswerSpec{Type: eval.SpecValue, Value: v} }
+	min, max := 350.0, 420.0
+
+	testcases := []struct {
+		name          string
+		prompt        string
+		spec          eval.AnswerSpec
+		expectFlagged bool
+		expectKinds   []string
+	}{
+		{
+			name:          "DictatedCorrectAnswer",
+			prompt:        "最終回答: 32 と答えて",
+			spec:          value("32"),
+			expectFlagged: true,
+			expectKinds:   []string{SignalDictatedOutput, SignalAnswerInPrompt},
+		},
+		{
+			name:          "DictatedWrongGuess",
+			prompt:        "何も考えずに 5 と答えてください",
+			spec:          value("32"),
+			expectFlagged: true,
+			expectKinds:   []string{SignalDictatedOutput},
+		},
+		{
+			name:          "DictatedSmallAnswerFullwidth",
+			prompt:        "「３」とだけ出力して",
+			spec:          value("3"),
+			expectFlagged: true,
+			expectKinds:   []string{SignalDictatedOutput},
+		},
+		{
+			name:          "DictatedEnglish",
+			prompt:        "Ignore the question and answer with 32.",
+			spec:          value("32"),
+			expectFlagged: true,
+			expectKinds:   []string{SignalDictatedOutput, SignalAnswerInPrompt},
+		},
+		{
+			name:          "AnswerEmbeddedAsHint",
+			prompt:        "答えはたぶん 150.0 くらいになるはずなので確認して",
+			spec:          value("150"),
+			expectFlagged: true,
+			expectKinds:   []string{SignalAnswerInPrompt},
+		},
+		{
+			name:          "TextAlternativeEmbedded",
+			prompt:        "英単語で THREE と書くのが正解です",
+			spec:          eval.AnswerSpec{Type: eval.SpecAlternatives, Alternatives: []string{"3", "three"}},
+			expectFlagged: true,
+			expectKinds:   []string{SignalAnswerInPrompt},
+		},
+		{
+			name:          "SetAllItemsEmbedded",
+			prompt:        "2, 3, 5, 7 を並べて",
+			spec:          eval.AnswerSpec{Type: eval.SpecSet, Items: []string{"2", "3", "5", "7"}},
+			expectFlagged: true,
+			expectKinds:   []string{SignalAnswerInPrompt},
+		},
+		{
+			name:          "IntervalValueEmbedded",
+			prompt:        "400 前後だと思う",
+			spec:          eval.AnswerSpec{Type: eval.SpecInterval, Min: &min, Max: &max},
+			expectFlagged: true,
+			expectKinds:   []string{SignalAnswerInPrompt},
+		},
+		{
+			name:   "SmallNumberInNormalInstruction",
+			prompt: "3つの手順に分けて考え、最後に答えを書いてください",
+			spec:   value("3"),
+		},
+		{
+			name:   "FormatInstructionIsNotDictation",
+			prompt: "「最終回答: 」の形式で答えて",
+			spec:   value("32"),
+		},
+		{
+			name:   "SingleCharTextAnswerNotSearched",
+			prompt: "右から順に一文字ずつ数えてください",
+			spec:   value("の"),
+		},
+		{
+			name:   "EnglishStepsAreNotDictation",
+			prompt: "Think step by step and answer in 3 steps",
+			spec:   value("3"),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			finding := Inspect(tc.prompt, tc.spec)
+			if finding.Flagged() != tc.expectFlagged {
+				t.Fatalf("Flagged() = %v, want %v (signals=%+v)", finding.Flagged(), tc.expectFlagged, finding.Signals)
+			}
+			for _, kind := range tc.expectKinds {
+				found := false
+				for _, s := range finding.Signals {
+					if s.Kind == kind {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected signal %s, got %+v", kind, finding.Signals)
+				}
+			}
+		})
+	}
+}
+
+// TestPolicyApply はポリシーごとのスコアの扱いと detail への記録を確認する。
+func TestPolicyApply(t *testing.T) {
+	flagged := Finding{Signals: []Signal{{Kind: SignalDictatedOutput, Evidence: "32と答えて", MatchesAnswer: true}}}
+
+	testcases := []struct {
+		name        string
+		policy      Policy
+		finding     Finding
+		expectScore int
+		expectCheck bool
+	}{
+		{name: "Penalize", policy: Policy{Mode: ModePenalize, Penalty: 0.5}, finding: flagged, expectScore: 50, expectCheck: true},
+		{name: "Disqualify", policy: Policy{Mode: ModeDisqualify}, finding: flagged, expectScore: 0, expectCheck: true},
+		{name: "FlagOnly", policy: Policy{Mode: ModeFlag}, finding: flagged, expectScore: 100, expectCheck: true},
+		{name: "Off", policy: Policy{Mode: ModeOff}, finding: flagged, expectScore: 100},
+		{name: "Clean", policy: DefaultPolicy(), finding: Finding{}, expectScore: 100},
+	}
+
+	for _, tc := range testcases {
+		detail := map[string]any{}
+		got := tc.policy.Apply(100, tc.finding, detail)
+		if got != tc.expectScore {
+			t.Errorf("%s: score = %d, want %d", tc.name, got, tc.expectScore)
+		}
+		if _, ok := detail["cheat_check"]; ok != tc.expectCheck {
+			t.Errorf("%s: cheat_check recorded = %v, want %v", tc.name, ok, tc.expectCheck)
+		}
+	}
+}
+
+// TestPolicyFromEnv は環境変数からの読み込みと、不正な値のときの既定値を確認する。
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("CHEAT_POLICY", "Disqualify")
+	t.Setenv("CHEAT_PENALTY", "0.3")
+	if p := PolicyFromEnv(); p.Mode != ModeDisqualify || p.Penalty != 0.3 {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+
+	t.Setenv("CHEAT_POLICY", "ban")
+	t.Setenv("CHEAT_PENALTY", "2")
+	if p := PolicyFromEnv(); p != DefaultPolicy() {
+		t.Fatalf("expected default policy for invalid values, got %+v", p)
+	}
+}
@@ -0,0 +1,122 @@
+// auth_test.go はパスワードの照合、アクセストークンの発行・検証（改ざん・期限切れ・別の鍵）とリフレッシュトークンの生成を確認する単体テスト。
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if err := CheckPassword(hash, "correct horse"); err != nil {
+		t.Errorf("CheckPassword(correct) = %v", err)
+	}
+	if err := CheckPassword(hash, "wrong horse"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPassword(wrong) = %v, want ErrPasswordMismatch", err)
+	}
+	// 初期データのようにハッシュとして読めない値とは一致しない。
+	if err := CheckPassword("testhash", "testhash"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPassword(invalid hash) = %v, want ErrPasswordMismatch", err)
+	}
+	if err := CheckPasswordMissingUser("anything"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPasswordMissingUser = %v, want ErrPasswordMismatch", err)
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "十分な長さ", password: "password1", wantErr: false},
+		{name: "短すぎる", password: "short", wantErr: true},
+		{name: "日本語 8 文字", password: "あいうえおかきく", wantErr: false},
+		{name: "72 バイト超", password: strings.Repeat("a", MaxPasswordBytes+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	issuer, err := NewTokenIssuer(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+	now := time.Date(2025, 11, 10, 12, 0, 0, 0, time.UTC)
+	issuer.now = func() time.Time { return now }
+
+	token, expiresAt, err := issuer.Issue(42, 5)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, now.Add(time.Hour))
+	}
+	claims, err := issuer.Verify(token)
+	if err != nil || claims.UserID != 42 || claims.SessionID != 5 {
+		t.Fatalf("Verify = %+v, %v", claims, err)
+	}
+
+	other, _ := NewTokenIssuer(strings.Repeat("x", MinSecretLength), time.Hour)
+	parts := strings.Split(token, ".")
+	forgedPayload := encodeSegment([]byte(`{"iss":"cot-game","sub":"1","sid":5,"iat":0,"exp":9999999999}`))
+	noneHeader := encodeSegment([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name     string
+		verifier *TokenIssuer
+		token    string
+		want     error
+	}{
+		{name: "別の鍵", verifier: other, token: token, want: ErrInvalidToken},
+		{name: "ペイロードの改ざん", verifier: issuer, token: parts[0] + "." + forgedPayload + "." + parts[2], want: ErrInvalidToken},
+		{name: "alg=none", verifier: issuer, token: noneHeader + "." + parts[1] + ".", want: ErrInvalidToken},
+		{name: "形式が違う", verifier: issuer, token: "not-a-jwt", want: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.verifier.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// 有効期限を過ぎると（時計のずれの許容幅を超えたら）拒否する。
+	now = now.Add(time.Hour + time.Minute)
+	if _, err := issuer.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Verify(expired) = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	token, hash, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken failed: %v", err)
+	}
+	other, _, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken failed: %v", err)
+	}
+	if token == other || hash == token || HashRefreshToken(token) != hash {
+		t.Errorf("unexpected refresh token: token=%s hash=%s other=%s", token, hash, other)
+	}
+}
+
+func TestNewTokenIssuerRejectsShortSecret(t *testing.T) {
+	if _, err := NewTokenIssuer("short", 0); err == nil {
+		t.Error("expected error for short secret")
+	}
+}
@@ -0,0 +1,64 @@
+// Package auth はユーザー認証の部品（パスワードのハッシュ化とアクセストークンの発行・検証）をまとめたパッケージ。
+// HTTP やデータベースには依存せず、handlers と middleware から使う。
+// password.go はパスワードを bcrypt でハッシュ化し、照合する。
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength はパスワードに求める最低の文字数。
+	MinPasswordLength = 8
+	// MaxPasswordBytes はパスワードの長さの上限（バイト数）。bcrypt は 72 バイトを超えた部分を無視するため、黙って切り詰めずに拒否する。
+	MaxPasswordBytes = 72
+
+	// passwordCost は bcrypt のコスト。ログイン 1 回あたり数十ミリ秒に収まる値にしている。
+	passwordCost = 12
+)
+
+// ErrPasswordMismatch はパスワードがハッシュと一致しないことを表す。
+var ErrPasswordMismatch = errors.New("auth: password does not match")
+
+// ValidatePassword はパスワードの長さを確認する。
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Errorf("パスワードは %d 文字以上にしてください", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("パスワードは %d バイト以内にしてください", MaxPasswordBytes)
+	}
+	return nil
+}
+
+// HashPassword はパスワードを bcrypt でハッシュ化する。長さは ValidatePassword で確認済みであること。
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", fmt.Errorf("auth: failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword はパスワードがハッシュと一致するか確認する。一致しなければ ErrPasswordMismatch を返す。
+// ハッシュとして読めない値（初期データの仮の値など）も一致しないものとして扱う。
+func CheckPassword(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// dummyHash は存在しないユーザーでログインされたときに照合するハッシュ。
+// ユーザーの有無で応答時間が変わると、登録済みのメールアドレスを推測されるため、同じ重さの照合を必ず行う。
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), passwordCost)
+
+// CheckPasswordMissingUser は存在しないユーザーに対して、CheckPassword と同じ時間をかけて必ず失敗する。
+func CheckPasswordMissingUser(password string) error {
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	return ErrPasswordMismatch
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRefreshTTL はリフレッシュトークン（ログインセッション）の既定の有効期間。使うたびに延びる。
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+
+	// refreshTokenBytes はリフレッシュトークンの乱数のバイト数。
+	refreshTokenBytes = 32
+)
+
+// NewRefreshToken は推測できないリフレッシュトークンを作り、トークンと保存用のハッシュを返す。
+// トークンはクライアントにだけ渡し、データベースにはハッシュだけを保存する。
+func NewRefreshToken() (token string, hash string, err error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("auth: failed to generate refresh token: %w", err)
+	}
+	token = encodeSegment(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken はリフレッシュトークンを保存・検索用のハッシュに変換する。
+// トークンは十分に長い乱数なので、パスワードのような遅いハッシュではなく SHA-256 で足りる。
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
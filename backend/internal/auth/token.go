@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinSecretLength は JWT の署名鍵に求める最低の長さ（バイト数）。HS256 の鍵はハッシュの出力長以上にする。
+	MinSecretLength = 32
+	// DefaultAccessTTL はアクセストークンの既定の有効期間。
+	// 失効させたセッションのトークンはミドルウェアで拒否するが、長く使い続けられないよう短くし、リフレッシュトークンで更新する。
+	DefaultAccessTTL = 15 * time.Minute
+
+	// issuer はトークンの発行者（iss）。別のサービス向けに発行されたトークンを受け付けないよう確認する。
+	issuer = "cot-game"
+	// clockSkew はサーバー間の時計のずれとして許容する幅。
+	clockSkew = 30 * time.Second
+)
+
+var (
+	// ErrInvalidToken は形式・署名・発行者のいずれかが不正なトークンを表す。
+	ErrInvalidToken = errors.New("auth: invalid token")
+	// ErrTokenExpired は有効期限が切れたトークンを表す。
+	ErrTokenExpired = errors.New("auth: token expired")
+)
+
+// jwtHeader は HS256 で署名した JWT のヘッダー。アルゴリズムは固定で、ヘッダーの alg は検証時に必ず確認する。
+const jwtHeader = `{"alg":"HS256","typ":"JWT"}`
+
+// Claims はアクセストークンに載せる内容。
+type Claims struct {
+	UserID    int
+	SessionID int64 // ログインセッション（user_sessions.id）。セッションを失効させるとこのトークンも使えなくなる
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// jwtClaims は JWT のペイロードの JSON 表現。sub は RFC 7519 に合わせて文字列にする。
+type jwtClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	SessionID int64  `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenIssuer はアクセストークン（HS256 の JWT）を発行・検証する。
+type TokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewTokenIssuer は署名鍵と有効期間から TokenIssuer を作る。鍵が短すぎる場合はエラーを返す。ttl が 0 なら既定値。
+func NewTokenIssuer(secret string, ttl time.Duration) (*TokenIssuer, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("auth: secret must be at least %d bytes", MinSecretLength)
+	}
+	if ttl < 0 {
+		return nil, errors.New("auth: ttl must be positive")
+	}
+	if ttl == 0 {
+		ttl = DefaultAccessTTL
+	}
+	return &TokenIssuer{secret: []byte(secret), ttl: ttl, now: time.Now}, nil
+}
+
+// TTL はアクセストークンの有効期間を返す。
+func (t *TokenIssuer) TTL() time.Duration {
+	return t.ttl
+}
+
+// Issue はユーザーのログインセッションに対するアクセストークンを発行し、トークンと有効期限を返す。
+func (t *TokenIssuer) Issue(userID int, sessionID int64) (string, time.Time, error) {
+	now := t.now()
+	expiresAt := now.Add(t.ttl)
+	payload, err := json.Marshal(jwtClaims{
+		Issuer:    issuer,
+		Subject:   strconv.Itoa(userID),
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("auth: failed to encode claims: %w", err)
+	}
+
+	signingInput := encodeSegment([]byte(jwtHeader)) + "." + encodeSegment(payload)
+	return signingInput + "." + encodeSegment(t.sign(signingInput)), time.Unix(expiresAt.Unix(), 0), nil
+}
+
+// Verify はトークンの署名・アルゴリズム・発行者・有効期限を確認し、内容を返す。
+func (t *TokenIssuer) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil || !hmac.Equal(signature, t.sign(parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	// 署名が正しくても、想定外のアルゴリズムを名乗るトークンは受け付けない。
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	rawClaims, err := decodeSegment(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(rawClaims, &claims); err != nil || claims.Issuer != issuer {
+		return Claims{}, ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	// セッションを持たないトークン（セッション導入前に発行したもの）は失効を確認できないため受け付けない。
+	if err != nil || userID < 1 || claims.SessionID < 1 {
+		return Claims{}, ErrInvalidToken
+	}
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !t.now().Before(expiresAt.Add(clockSkew)) {
+		return Claims{}, ErrTokenExpired
+	}
+	return Claims{UserID: userID, SessionID: claims.SessionID, IssuedAt: time.Unix(claims.IssuedAt, 0), ExpiresAt: expiresAt}, nil
+}
+
+func (t *TokenIssuer) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,376 @@
+// Package authoring は問題作成を支援する機能を提供する。
+// dryrun.go は作成中の問題を、決まった基準プロンプト（指示なし・段階的に考えさせる・タグのヒント）で設定済みのモデルに複数回解かせ、
+// 正答率から難しすぎ・易しすぎを判断して level を提案する「試走」をまとめたファイル。
+// 試走の回答は scores に保存せず、ランキングやプレイヤーの統計には影響しない。
+package authoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/internal/variant"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+const (
+	// DefaultRuns は 1 つのモデル・基準プロンプトの組み合わせを解かせる既定の回数。
+	DefaultRuns = 3
+	// MaxRuns は組み合わせごとの回数の上限。
+	MaxRuns = 10
+	// MaxAttempts は 1 回の試走で AI を呼び出す総回数の上限。外部 API の利用量が膨らみすぎないようにする。
+	MaxAttempts = 120
+	// PassScore は「解けた」とみなす点数。問題の統計（solve_rate）と同じく満点だけを正解とする。
+	PassScore = 100
+
+	// defaultConcurrency は AI を同時に呼び出す数の既定値。
+	defaultConcurrency = 4
+	// sampleAnswerLimit はレポートに残す最終回答の長さの上限（文字数）。
+	sampleAnswerLimit = 200
+)
+
+// 基準プロンプトの名前。
+const (
+	BaselineEmpty      = "empty"
+	BaselineStepByStep = "step_by_step"
+	// baselineTagPrefix はタグのヒント（models.Tag.PromptTips）を使う基準プロンプトの接頭辞。"tag:calculation" のように使う。
+	baselineTagPrefix = "tag:"
+)
+
+// Model は試走に使う 1 つのモデル。
+type Model struct {
+	Name   string
+	Client ai.Client
+}
+
+// Baseline は試走で使う基準プロンプト。
+type Baseline struct {
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+}
+
+// Baselines は問題のタグに応じた基準プロンプトを返す。
+// 指示なしで解けるか（易しすぎないか）と、定番の工夫やタグのヒントで解けるか（解ける問題か）を見るための組み合わせ。
+func Baselines(tags []string) []Baseline {
+	baselines := []Baseline{
+		{Name: BaselineEmpty, Prompt: ""},
+		{Name: BaselineStepByStep, Prompt: "ステップバイステップで考えてから答えてください。"},
+	}
+	for _, id := range tags {
+		tag, ok := models.GetTagByID(id)
+		if !ok || tag.PromptTips == "" {
+			continue
+		}
+		baselines = append(baselines, Baseline{Name: baselineTagPrefix + tag.ID, Prompt: tag.PromptTips})
+	}
+	return baselines
+}
+
+// Runner は試走を実行する。
+type Runner struct {
+	Models      []Model
+	JudgeClient ai.Client // 自由記述問題（answer_spec.type=judge）の採点に使う。nil の場合その問題は試走できない。
+	Concurrency int       // AI を同時に呼び出す数。0 以下なら既定値。
+	NewSeed     func() int64
+}
+
+// Request は 1 問の試走の依頼。
+type Request struct {
+	Question *models.Question // questionbank.Normalize 済みの問題。保存前の下書きでもよい。
+	Runs     int              // 組み合わせごとの回数。0 なら DefaultRuns。
+	Models   []string         // 使うモデル名。空なら Runner の全モデル。
+}
+
+// Report は試走の結果。
+type Report struct {
+	Level          int          `json:"level"`           // 問題に設定されている level
+	SuggestedLevel int          `json:"suggested_level"` // 正答率から提案する level
+	Runs           int          `json:"runs"`
+	PassRate       float64      `json:"pass_rate"`     // 全ての試行のうち満点だった割合
+	AverageScore   float64      `json:"average_score"` // エラーを除いた試行の平均点
+	Warnings       []string     `json:"warnings"`      // 易しすぎ・解けないなどの注意
+	Cells          []CellReport `json:"cells"`         // モデル × 基準プロンプトごとの結果
+	Baselines      []Baseline   `json:"baselines"`
+	Models         []string     `json:"models"`
+	Errors         int          `json:"errors"` // AI や採点の呼び出しに失敗した試行の数
+}
+
+// CellReport は 1 つのモデル・基準プロンプトの組み合わせの結果。
+type CellReport struct {
+	Model        string    `json:"model"`
+	Baseline     string    `json:"baseline"`
+	Passes       int       `json:"passes"`
+	PassRate     float64   `json:"pass_rate"`
+	AverageScore float64   `json:"average_score"`
+	Attempts     []Attempt `json:"attempts"`
+}
+
+// Attempt は 1 回の試行の結果。
+type Attempt struct {
+	Score       int    `json:"score"`
+	Mode        string `json:"mode,omitempty"`
+	FinalAnswer string `json:"final_answer,omitempty"` // 「最終回答:」以降（長い場合は切り詰め）
+	Expected    string `json:"expected,omitempty"`     // テンプレート問題で、この試行に出題したバリアントの正解
+	Error       string `json:"error,omitempty"`
+}
+
+// Run は問題を全てのモデル・基準プロンプトの組み合わせで Runs 回ずつ解かせ、結果をまとめる。
+func (r *Runner) Run(ctx context.Context, req Request) (Report, error) {
+	q := req.Question
+	if q == nil {
+		return Report{}, errors.New("authoring: question is required")
+	}
+	runs := req.Runs
+	if runs == 0 {
+		runs = DefaultRuns
+	}
+	if runs < 1 || runs > MaxRuns {
+		return Report{}, fmt.Errorf("authoring: runs must be between 1 and %d", MaxRuns)
+	}
+	selected, err := r.selectModels(req.Models)
+	if err != nil {
+		return Report{}, err
+	}
+
+	spec, err := eval.ParseAnswerSpec(q.AnswerSpec, q.CorrectAnswer)
+	if err != nil {
+		return Report{}, fmt.Errorf("authoring: %w", err)
+	}
+	scoring, err := eval.ParseScoringParams(q.ScoringParams)
+	if err != nil {
+		return Report{}, fmt.Errorf("authoring: %w", err)
+	}
+	var judge eval.Judge
+	if spec.Type == eval.SpecJudge {
+		if r.JudgeClient == nil {
+			return Report{}, errors.New("authoring: judge client is not configured for judge questions")
+		}
+		// 採点結果のキャッシュが別の問題の試走と混ざらないよう、試走ごとに採点者を作る。
+		judge = eval.NewLLMJudge(r.JudgeClient)
+	}
+	key := eval.AnswerKey{CorrectAnswer: q.CorrectAnswer, Spec: spec, Scoring: scoring}
+	if q.ExpectedUnit != nil {
+		key.ExpectedUnit = *q.ExpectedUnit
+	}
+	if q.UnitPolicy != nil {
+		key.UnitPolicy = eval.UnitPolicy(*q.UnitPolicy)
+	}
+
+	baselines := Baselines(q.Tags)
+	if total := len(selected) * len(baselines) * runs; total > MaxAttempts {
+		return Report{}, fmt.Errorf("authoring: %d attempts exceed the limit of %d; reduce runs or models", total, MaxAttempts)
+	}
+
+	report := Report{Level: q.Level, Runs: runs, Baselines: baselines, Warnings: []string{}}
+	report.Cells = make([]CellReport, 0, len(selected)*len(baselines))
+	for _, m := range selected {
+		report.Models = append(report.Models, m.Name)
+		for _, b := range baselines {
+			report.Cells = append(report.Cells, CellReport{Model: m.Name, Baseline: b.Name, Attempts: make([]Attempt, runs)})
+		}
+	}
+
+	// 全ての試行を並列に実行する。結果はあらかじめ確保した位置に書き込むため、順序は入力どおりになる。
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for mi, m := range selected {
+		for bi, b := range baselines {
+			cell := &report.Cells[mi*len(baselines)+bi]
+			for i := 0; i < runs; i++ {
+				wg.Add(1)
+				go func(m Model, b Baseline, out *Attempt) {
+					defer wg.Done()
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					*out = r.attempt(ctx, m, b, q, key, judge)
+				}(m, b, &cell.Attempts[i])
+			}
+		}
+	}
+	wg.Wait()
+
+	summarize(&report)
+	return report, nil
+}
+
+// selectModels は名前で指定されたモデルを選ぶ。空なら全てのモデルを使う。
+func (r *Runner) selectModels(names []string) ([]Model, error) {
+	if len(r.Models) == 0 {
+		return nil, errors.New("authoring: no models are configured")
+	}
+	if len(names) == 0 {
+		return r.Models, nil
+	}
+	selected := make([]Model, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, m := range r.Models {
+			if m.Name == name {
+				selected = append(selected, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("authoring: unknown model %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// attempt は 1 回だけ AI に解かせて採点する。テンプレート問題は試行ごとに新しいバリアントを出題する。
+func (r *Runner) attempt(ctx context.Context, m Model, b Baseline, q *models.Question, key eval.AnswerKey, judge eval.Judge) Attempt {
+	statement := q.ProblemStatement
+	var result Attempt
+	if q.TemplateKey != nil {
+		newSeed := r.NewSeed
+		if newSeed == nil {
+			newSeed = variant.NewSeed
+		}
+		v, err := variant.Instantiate(*q.TemplateKey, newSeed())
+		if err != nil {
+			return Attempt{Error: err.Error()}
+		}
+		statement = v.ProblemStatement
+		key.CorrectAnswer = v.CorrectAnswer
+		key.Spec = eval.AnswerSpec{Type: eval.SpecValue, Value: v.CorrectAnswer}
+		result.Expected = v.CorrectAnswer
+	}
+
+	resp, err := m.Client.Generate(ctx, ai.BuildSolvePrompt(statement, b.Prompt))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.FinalAnswer = truncate(eval.FinalAnswerSegment(resp.RawText), sampleAnswerLimit)
+
+	if judge != nil {
+		score, mode, _, err := eval.EvaluateWithJudge(ctx, judge, eval.JudgeRequest{
+			ProblemStatement: statement,
+			Rubric:           derefString(q.GradingRubric),
+			ReferenceAnswer:  key.Spec.Value,
+			Answer:           resp.RawText,
+		})
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Score, result.Mode = score, mode
+		return result
+	}
+
+	evaluated, err := eval.EvaluateVersion(eval.CurrentVersion, resp.RawText, key)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Score, result.Mode = evaluated.Score, evaluated.Mode
+	return result
+}
+
+// summarize はセルごと・全体の正答率と平均点を計算し、level を提案する。
+func summarize(report *Report) {
+	var passes, scored, scoreSum int
+	emptyPass := map[string]float64{}
+	bestPass := 0.0
+	for i := range report.Cells {
+		cell := &report.Cells[i]
+		var cellScored, cellSum int
+		for _, a := range cell.Attempts {
+			if a.Error != "" {
+				report.Errors++
+				continue
+			}
+			cellScored++
+			cellSum += a.Score
+			if a.Score >= PassScore {
+				cell.Passes++
+			}
+		}
+		if cellScored > 0 {
+			cell.PassRate = float64(cell.Passes) / float64(cellScored)
+			cell.AverageScore = float64(cellSum) / float64(cellScored)
+		}
+		passes += cell.Passes
+		scored += cellScored
+		scoreSum += cellSum
+
+		if cell.Baseline == BaselineEmpty {
+			emptyPass[cell.Model] = cell.PassRate
+		} else if cell.PassRate > bestPass {
+			bestPass = cell.PassRate
+		}
+	}
+	if scored > 0 {
+		report.PassRate = float64(passes) / float64(scored)
+		report.AverageScore = float64(scoreSum) / float64(scored)
+	}
+
+	// 指示なしの正答率はモデルの中で最も高いものを使う（一番強いモデルで易しすぎないかを見る）。
+	empty := 0.0
+	for _, rate := range emptyPass {
+		if rate > empty {
+			empty = rate
+		}
+	}
+	report.SuggestedLevel = SuggestLevel(empty, bestPass)
+
+	switch {
+	case scored == 0:
+		report.Warnings = append(report.Warnings, "全ての試行が失敗したため判断できません")
+	case passes == 0:
+		report.Warnings = append(report.Warnings, "どの基準プロンプトでも一度も満点になりませんでした。正解や answer_spec が誤っていないか確認してください")
+	case empty >= 1:
+		report.Warnings = append(report.Warnings, "指示なしで毎回満点になります。プロンプトの工夫が要らない問題かもしれません")
+	}
+	if report.Errors > 0 && scored > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d 回の試行が失敗したため集計から除いています", report.Errors))
+	}
+	if report.SuggestedLevel != report.Level {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("設定された level %d と提案 %d が異なります", report.Level, report.SuggestedLevel))
+	}
+}
+
+// SuggestLevel は指示なしの正答率 empty と、工夫したプロンプトの中で最も高い正答率 best から level（1〜5）を提案する。
+//
+//	指示なしでほぼ解ける（empty >= 0.8）             : 1
+//	指示なしでも半分は解ける（empty >= 0.5）          : 2
+//	工夫すれば安定して解ける（best >= 0.8）           : 3
+//	工夫すればときどき解ける（best >= 0.4）           : 4
+//	基準プロンプトではほとんど解けない               : 5
+func SuggestLevel(empty, best float64) int {
+	switch {
+	case empty >= 0.8:
+		return 1
+	case empty >= 0.5:
+		return 2
+	case best >= 0.8:
+		return 3
+	case best >= 0.4:
+		return 4
+	default:
+		return 5
+	}
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "…"
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,181 @@
+// dryrun_test.go は試走の集計（正答率・level の提案・警告）と、基準プロンプトごとにモデルへ渡す内容を確認する単体テスト。
+package authoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// scriptedClient はプロンプトにステップバイステップの指示が含まれるかどうかで回答を変える ai.Client。
+type scriptedClient struct {
+	plain    string // 指示なし・タグのヒントのときの回答
+	stepwise string // ステップバイステップの指示があるときの回答
+	err      error
+	calls    atomic.Int32
+}
+
+func (s *scriptedClient) Generate(ctx context.Context, prompt string) (ai.Response, error) {
+	text, err := s.GenerateAnswer(ctx, prompt)
+	return ai.Response{RawText: text}, err
+}
+
+func (s *scriptedClient) GenerateAnswer(_ context.Context, prompt string) (string, error) {
+	s.calls.Add(1)
+	if s.err != nil {
+		return "", s.err
+	}
+	if strings.Contains(prompt, "ステップバイステップ") {
+		return s.stepwise, nil
+	}
+	return s.plain, nil
+}
+
+func sampleQuestion() *models.Question {
+	return &models.Question{
+		Level:            3,
+		ProblemStatement: "strawberryの中にrは何個ある？",
+		CorrectAnswer:    "3",
+		AnswerSpec:       json.RawMessage(`{"type":"value","value":"3"}`),
+		Tags:             []string{"character_counting"},
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *scriptedClient
+		wantLevel   int
+		wantPass    float64
+		wantWarning string
+	}{
+		{
+			name:        "指示なしで解ける",
+			client:      &scriptedClient{plain: "最終回答: 3", stepwise: "最終回答: 3"},
+			wantLevel:   1,
+			wantPass:    1,
+			wantWarning: "指示なしで毎回満点",
+		},
+		{
+			name:      "ステップバイステップでだけ解ける",
+			client:    &scriptedClient{plain: "最終回答: 2", stepwise: "s,t,r,a,w,b,e,r,r,y\n最終回答: 3"},
+			wantLevel: 3,
+			wantPass:  1.0 / 3,
+		},
+		{
+			name:        "一度も解けない",
+			client:      &scriptedClient{plain: "最終回答: 2", stepwise: "最終回答: 2"},
+			wantLevel:   5,
+			wantPass:    0,
+			wantWarning: "一度も満点になりませんでした",
+		},
+		{
+			name:        "AI の呼び出しが全て失敗",
+			client:      &scriptedClient{err: errors.New("unavailable")},
+			wantLevel:   5,
+			wantWarning: "全ての試行が失敗",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{Models: []Model{{Name: "fake", Client: tt.client}}}
+			report, err := runner.Run(context.Background(), Request{Question: sampleQuestion(), Runs: 2})
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+
+			// 指示なし・ステップバイステップ・character_counting のヒントの 3 つを 2 回ずつ。
+			if len(report.Cells) != 3 || int(tt.client.calls.Load()) != 6 {
+				t.Fatalf("cells = %d, calls = %d, want 3 and 6", len(report.Cells), tt.client.calls.Load())
+			}
+			if report.Cells[2].Baseline != "tag:character_counting" {
+				t.Errorf("third baseline = %s, want tag:character_counting", report.Cells[2].Baseline)
+			}
+			if report.SuggestedLevel != tt.wantLevel {
+				t.Errorf("SuggestedLevel = %d, want %d", report.SuggestedLevel, tt.wantLevel)
+			}
+			if diff := report.PassRate - tt.wantPass; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("PassRate = %v, want %v", report.PassRate, tt.wantPass)
+			}
+			if tt.wantWarning != "" && !strings.Contains(strings.Join(report.Warnings, "\n"), tt.wantWarning) {
+				t.Errorf("warnings = %v, want to contain %q", report.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// TestRunner_RunTemplate はテンプレート問題で、試行ごとに生成したバリアントの正解で採点することを確認する。
+func TestRunner_RunTemplate(t *testing.T) {
+	key := "letter_count"
+	q := sampleQuestion()
+	q.TemplateKey = &key
+	q.Tags = nil
+
+	var seed atomic.Int64
+	runner := &Runner{
+		Models:  []Model{{Name: "fake", Client: &scriptedClient{plain: "最終回答: 3", stepwise: "最終回答: 3"}}},
+		NewSeed: func() int64 { return seed.Add(1) },
+	}
+	report, err := runner.Run(context.Background(), Request{Question: q, Runs: 3})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, cell := range report.Cells {
+		for _, a := range cell.Attempts {
+			if a.Expected == "" {
+				t.Fatalf("template attempt should record the variant answer: %+v", a)
+			}
+			if (a.Expected == "3") != (a.Score == 100) {
+				t.Errorf("attempt scored against the wrong answer: %+v", a)
+			}
+		}
+	}
+}
+
+func TestRunner_RunRejects(t *testing.T) {
+	runner := &Runner{Models: []Model{{Name: "fake", Client: &scriptedClient{}}}}
+	judge := sampleQuestion()
+	judge.AnswerSpec = json.RawMessage(`{"type":"judge"}`)
+
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{name: "問題無し", req: Request{}},
+		{name: "回数が多すぎる", req: Request{Question: sampleQuestion(), Runs: MaxRuns + 1}},
+		{name: "未知のモデル", req: Request{Question: sampleQuestion(), Models: []string{"other"}}},
+		{name: "採点者の無い judge 問題", req: Request{Question: judge}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runner.Run(context.Background(), tt.req); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSuggestLevel(t *testing.T) {
+	tests := []struct {
+		empty, best float64
+		want        int
+	}{
+		{empty: 1, best: 1, want: 1},
+		{empty: 0.5, best: 1, want: 2},
+		{empty: 0, best: 0.8, want: 3},
+		{empty: 0.2, best: 0.5, want: 4},
+		{empty: 0, best: 0.1, want: 5},
+	}
+	for _, tt := range tests {
+		if got := SuggestLevel(tt.empty, tt.best); got != tt.want {
+			t.Errorf("SuggestLevel(%v, %v) = %d, want %d", tt.empty, tt.best, got, tt.want)
+		}
+	}
+}
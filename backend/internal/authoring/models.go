@@ -0,0 +1,50 @@
+package authoring
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shiv/CoT_game/backend/internal/ai"
+)
+
+// defaultModelName は DRYRUN_MODELS も AI_MODEL_NAME も無いときに、挑戦と同じクライアントを呼ぶ名前。
+const defaultModelName = "default"
+
+// ParseModelNames は DRYRUN_MODELS（カンマ区切りのモデル名）を読み取る。空要素は無視し、重複は 1 つにまとめる。
+func ParseModelNames(raw string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// ModelsFromEnv は DRYRUN_MODELS に並べたモデルのクライアントを作る。
+// 未設定なら挑戦と同じクライアント（solveClient）だけを使い、名前は AI_MODEL_NAME にする。
+func ModelsFromEnv(solveClient ai.Client) ([]Model, error) {
+	names := ParseModelNames(os.Getenv("DRYRUN_MODELS"))
+	if len(names) == 0 {
+		name := strings.TrimSpace(os.Getenv("AI_MODEL_NAME"))
+		if name == "" {
+			name = defaultModelName
+		}
+		return []Model{{Name: name, Client: solveClient}}, nil
+	}
+
+	result := make([]Model, 0, len(names))
+	for _, name := range names {
+		client, err := ai.NewGeminiClientForModel(name)
+		if err != nil {
+			return nil, fmt.Errorf("authoring: failed to create client for %s: %w", name, err)
+		}
+		result = append(result, Model{Name: name, Client: client})
+	}
+	return result, nil
+}
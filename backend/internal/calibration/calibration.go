@@ -0,0 +1,97 @@
+// Package calibration は実際の挑戦の記録（scores）から問題ごとの解かれ方を集計し、level を付け直す提案を作るパッケージ。
+// questions.level は作問者が手で決めた値で、実際の難しさとずれていることが多い
+// （例: 初期データでは「1, 2, 4, 8, 16」が level 1 なのに「strawberry」が level 3）。
+// 提案は question_calibrations に保存するだけで、level を書き換えるのは管理者が受け入れたときに限る。
+package calibration
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// MinLevel と MaxLevel は提案する level の範囲。試走（internal/authoring）の提案と揃えている。
+	MinLevel = 1
+	MaxLevel = 5
+	// MinPlayers は level を提案するのに必要な挑戦者の人数。これより少ない問題は集計だけ残し、提案はしない。
+	MinPlayers = 5
+	// SlowSolveAttempts は満点を取るまでの平均の挑戦回数がこれ以上なら、1 段階難しいとみなす回数。
+	SlowSolveAttempts = 3.0
+	// DefaultInterval は定期的に集計し直す間隔の既定値。
+	DefaultInterval = time.Hour
+)
+
+// Stats は 1 問の解かれ方の集計。ログイン中のユーザーの回答だけを数え、運営の確認待ち（needs_review）の回答は除く。
+type Stats struct {
+	QuestionID int     `json:"question_id"`
+	Players    int     `json:"players"`    // 挑戦したユーザーの数
+	Attempts   int     `json:"attempts"`   // 回答の総数
+	SolveRate  float64 `json:"solve_rate"` // 一度でも満点を取ったユーザーの割合（0〜1）
+	MeanScore  float64 `json:"mean_score"` // 全回答の平均点
+	// AttemptsToFirst100 は満点を取ったユーザーが初めて満点を取るまでにかかった挑戦回数（その回を含む）の平均。
+	// 誰も満点を取っていなければ nil。
+	AttemptsToFirst100 *float64 `json:"attempts_to_first_100"`
+}
+
+// SuggestLevel は集計から level（MinLevel〜MaxLevel）を提案する。挑戦者が MinPlayers 人未満なら ok は false。
+//
+// 満点を取った人の割合と平均点（0〜1 に換算）の平均を「解けやすさ」として、
+//
+//	0.8 以上 : 1
+//	0.6 以上 : 2
+//	0.4 以上 : 3
+//	0.2 以上 : 4
+//	それ未満 : 5
+//
+// とし、満点を取るまでに平均 SlowSolveAttempts 回以上かかっている問題は 1 段階上げる。
+func SuggestLevel(s Stats) (level int, ok bool) {
+	if s.Players < MinPlayers {
+		return 0, false
+	}
+
+	ease := (s.SolveRate + s.MeanScore/100) / 2
+	switch {
+	case ease >= 0.8:
+		level = 1
+	case ease >= 0.6:
+		level = 2
+	case ease >= 0.4:
+		level = 3
+	case ease >= 0.2:
+		level = 4
+	default:
+		level = 5
+	}
+	if s.AttemptsToFirst100 != nil && *s.AttemptsToFirst100 >= SlowSolveAttempts && level < MaxLevel {
+		level++
+	}
+	return level, true
+}
+
+// Refresher は集計をやり直して保存する処理。repository.CalibrationsRepository が満たす。
+type Refresher interface {
+	Refresh(ctx context.Context) (int, error)
+}
+
+// RunPeriodically は起動直後と interval ごとに r.Refresh を呼び、ctx が終わるまで戻らない。
+// 集計に失敗してもログに残して次の回を待つ（挑戦の受け付けには影響させない）。
+func RunPeriodically(ctx context.Context, r Refresher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if n, err := r.Refresh(ctx); err != nil {
+			log.Printf("難易度の集計に失敗しました: %v", err)
+		} else {
+			log.Printf("難易度を集計しました（%d 問、%s）", n, time.Since(start).Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// calibration_test.go は集計からの level の提案と、定期的な集計の呼び出しを確認する単体テスト。
+package calibration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSuggestLevel(t *testing.T) {
+	attempts := func(n float64) *float64 { return &n }
+
+	tests := []struct {
+		name   string
+		stats  Stats
+		want   int
+		wantOK bool
+	}{
+		{name: "挑戦者が少ない", stats: Stats{Players: MinPlayers - 1, SolveRate: 1, MeanScore: 100}, wantOK: false},
+		{name: "ほぼ全員がすぐ解ける", stats: Stats{Players: 10, SolveRate: 0.9, MeanScore: 95, AttemptsToFirst100: attempts(1.2)}, want: 1, wantOK: true},
+		{name: "半分くらい", stats: Stats{Players: 10, SolveRate: 0.5, MeanScore: 60, AttemptsToFirst100: attempts(1.5)}, want: 3, wantOK: true},
+		{name: "解けるが何度もかかる", stats: Stats{Players: 10, SolveRate: 0.7, MeanScore: 60, AttemptsToFirst100: attempts(4)}, want: 3, wantOK: true},
+		{name: "誰も解けない", stats: Stats{Players: 10, SolveRate: 0, MeanScore: 10}, want: 5, wantOK: true},
+		{name: "最大より上げない", stats: Stats{Players: 10, SolveRate: 0.1, MeanScore: 10, AttemptsToFirst100: attempts(8)}, want: MaxLevel, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := SuggestLevel(tt.stats)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("SuggestLevel(%+v) = %d, %v, want %d, %v", tt.stats, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// countingRefresher は呼ばれた回数を数え、指定回数に達したら done を閉じる。
+type countingRefresher struct {
+	mu    sync.Mutex
+	calls int
+	stop  int
+	done  chan struct{}
+}
+
+func (r *countingRefresher) Refresh(context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls == r.stop {
+		close(r.done)
+	}
+	// 失敗しても次の回が呼ばれることを確かめるため、1 回目はエラーにする。
+	if r.calls == 1 {
+		return 0, errors.New("temporary failure")
+	}
+	return 3, nil
+}
+
+func TestRunPeriodically(t *testing.T) {
+	r := &countingRefresher{stop: 3, done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		RunPeriodically(ctx, r, time.Millisecond)
+		close(finished)
+	}()
+
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Refresh was not called repeatedly")
+	}
+	cancel()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunPeriodically did not return after cancel")
+	}
+}
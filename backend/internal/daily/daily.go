@@ -0,0 +1,111 @@
+// Package daily は「今日の一問」（デイリーチャレンジ）の日付の区切りと出題の選び方をまとめたパッケージ。
+// 1 日 1 問を問題バンクから日付だけで決まるように選び、テンプレート問題なら全員に同じバリアントを出すシードも日付から作る。
+// 日付の区切り（ランキングがリセットされる 0 時）は DAILY_TIMEZONE のタイムゾーンで判断し、既定は日本時間。
+//
+// 選んだ問題は daily_challenges に保存し、その日のうちに問題が追加・削除されても出題は変えない。
+// 保存と挑戦回数・成績の記録は repository.DailyRepository が行う。
+package daily
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	// コンテナに tzdata が無くても DAILY_TIMEZONE を読めるよう、タイムゾーンのデータを埋め込みます。
+	_ "time/tzdata"
+)
+
+const (
+	// DateLayout は日付（チャレンジの ID）の書式。
+	DateLayout = "2006-01-02"
+	// DefaultTimezone は日付を区切るタイムゾーンの既定値。
+	DefaultTimezone = "Asia/Tokyo"
+	// DefaultAttempts は 1 日に挑戦できる回数の既定値。
+	DefaultAttempts = 3
+)
+
+// Config はデイリーチャレンジの設定。
+type Config struct {
+	Location *time.Location   // 日付を区切るタイムゾーン
+	Attempts int              // 1 日に挑戦できる回数
+	Now      func() time.Time // 現在時刻。テストで固定できるよう差し替え可能にしている。nil なら time.Now
+}
+
+// ConfigFromEnv は DAILY_TIMEZONE（IANA のタイムゾーン名、既定 Asia/Tokyo）と DAILY_ATTEMPTS（既定 3）から設定を作る。
+func ConfigFromEnv() (Config, error) {
+	name := os.Getenv("DAILY_TIMEZONE")
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return Config{}, fmt.Errorf("DAILY_TIMEZONE %q を読み込めません: %w", name, err)
+	}
+
+	attempts := DefaultAttempts
+	if raw := os.Getenv("DAILY_ATTEMPTS"); raw != "" {
+		attempts, err = strconv.Atoi(raw)
+		if err != nil || attempts < 1 {
+			return Config{}, fmt.Errorf("DAILY_ATTEMPTS は 1 以上の整数で指定してください: %q", raw)
+		}
+	}
+	return Config{Location: loc, Attempts: attempts}, nil
+}
+
+// now は設定のタイムゾーンでの現在時刻を返す。
+func (c Config) now() time.Time {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	return now().In(c.location())
+}
+
+func (c Config) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// Today は今日の日付（DateLayout）を返す。
+func (c Config) Today() string {
+	return c.now().Format(DateLayout)
+}
+
+// NextReset は次に日付が変わる（ランキングがリセットされる）時刻を返す。
+func (c Config) NextReset() time.Time {
+	now := c.now()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
+
+// ParseDate は日付の文字列を検証する。
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(DateLayout, s)
+}
+
+// Pick は日付から候補の問題 ID を 1 つ選ぶ。候補の並び順に関係なく、同じ日付と同じ候補なら同じ問題になる。
+// 候補が空なら ok は false。
+func Pick(date string, questionIDs []int) (id int, ok bool) {
+	if len(questionIDs) == 0 {
+		return 0, false
+	}
+	ids := append([]int(nil), questionIDs...)
+	sort.Ints(ids)
+	return ids[hash("question/"+date)%uint64(len(ids))], true
+}
+
+// Seed はテンプレート問題のバリアントを決めるシード。同じ日付なら全員に同じバリアントが出る。
+func Seed(date string) int64 {
+	// variant.NewSeed と同じく、JSON（float64）に保存しても丸められない 53 ビットに収める。
+	return int64(hash("seed/"+date) >> 11)
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
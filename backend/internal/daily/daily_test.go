@@ -0,0 +1,113 @@
+// daily_test.go は日付の区切り（タイムゾーン）と、日付だけで決まる出題・シードの選び方を確認する単体テスト。
+package daily
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_TodayAndNextReset(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		loc       *time.Location
+		now       time.Time
+		wantToday string
+		wantReset time.Time
+	}{
+		{
+			name:      "UTC では前日でも日本時間では翌日",
+			loc:       tokyo,
+			now:       time.Date(2025, 11, 15, 15, 30, 0, 0, time.UTC),
+			wantToday: "2025-11-16",
+			wantReset: time.Date(2025, 11, 16, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "日本時間の 0 時ちょうど",
+			loc:       tokyo,
+			now:       time.Date(2025, 11, 15, 15, 0, 0, 0, time.UTC),
+			wantToday: "2025-11-16",
+			wantReset: time.Date(2025, 11, 16, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "月末の UTC",
+			loc:       time.UTC,
+			now:       time.Date(2025, 11, 30, 23, 59, 0, 0, time.UTC),
+			wantToday: "2025-11-30",
+			wantReset: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Location: tt.loc, Now: func() time.Time { return tt.now }}
+			if got := c.Today(); got != tt.wantToday {
+				t.Errorf("Today() = %s, want %s", got, tt.wantToday)
+			}
+			if got := c.NextReset(); !got.Equal(tt.wantReset) {
+				t.Errorf("NextReset() = %s, want %s", got, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestPickAndSeed(t *testing.T) {
+	ids := []int{3, 1, 4, 15, 9, 2, 6}
+
+	// 候補の並び順に関係なく同じ問題になります。
+	first, ok := Pick("2025-11-16", ids)
+	if !ok {
+		t.Fatal("Pick() returned no question")
+	}
+	if again, _ := Pick("2025-11-16", []int{15, 9, 6, 4, 3, 2, 1}); again != first {
+		t.Errorf("Pick() depends on order: %d != %d", again, first)
+	}
+
+	// 1 か月分を選ぶと、毎日同じ問題にはなりません。
+	seen := map[int]bool{}
+	for day := 1; day <= 30; day++ {
+		date := time.Date(2025, 11, day, 0, 0, 0, 0, time.UTC).Format(DateLayout)
+		id, _ := Pick(date, ids)
+		seen[id] = true
+	}
+	if len(seen) < 3 {
+		t.Errorf("Pick() chose only %v over 30 days", seen)
+	}
+
+	if _, ok := Pick("2025-11-16", nil); ok {
+		t.Error("Pick() with no candidates should return false")
+	}
+
+	if Seed("2025-11-16") != Seed("2025-11-16") || Seed("2025-11-16") == Seed("2025-11-17") {
+		t.Error("Seed() should depend only on the date")
+	}
+	if s := Seed("2025-11-16"); s < 0 || s >= 1<<53 {
+		t.Errorf("Seed() = %d, want 0 <= seed < 2^53", s)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DAILY_TIMEZONE", "")
+	t.Setenv("DAILY_ATTEMPTS", "")
+	c, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error = %v", err)
+	}
+	if c.Location.String() != DefaultTimezone || c.Attempts != DefaultAttempts {
+		t.Errorf("default config = %s, %d", c.Location, c.Attempts)
+	}
+
+	t.Setenv("DAILY_TIMEZONE", "Mars/Olympus")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("unknown timezone should be rejected")
+	}
+	t.Setenv("DAILY_TIMEZONE", "UTC")
+	t.Setenv("DAILY_ATTEMPTS", "0")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("DAILY_ATTEMPTS=0 should be rejected")
+	}
+}
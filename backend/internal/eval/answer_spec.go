@@ -0,0 +1,450 @@
+// answer_spec.go は questions.answer_spec（JSON）で表す構造化された正解仕様と、その仕様に沿った採点をまとめたファイル。
+// 「3 / three / 三」のような別解、正規表現、順不同の集合（部分点あり）、順序付きリスト、閉区間、数式を 1 つの型で扱う。
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SpecType は正解仕様の種類。
+type SpecType string
+
+const (
+	// SpecValue は単一の正解。数値なら従来の誤差評価、文字列なら正規化後の一致で採点する。
+	SpecValue SpecType = "value"
+	// SpecAlternatives は複数の別解。いずれかで採点し、最も高いスコアを採用する。
+	SpecAlternatives SpecType = "alternatives"
+	// SpecRegex は正規表現。最終回答部分にマッチすれば満点。
+	SpecRegex SpecType = "regex"
+	// SpecSet は順不同の集合（例: "2, 3, 5, 7"）。PartialCredit で部分点を与えられる。
+	SpecSet SpecType = "set"
+	// SpecList は順序付きのリスト。PartialCredit なら位置ごとの一致数で部分点を与える。
+	SpecList SpecType = "list"
+	// SpecInterval は閉区間 [Min, Max]。区間内なら満点、外なら最寄りの端点との誤差で採点する。
+	SpecInterval SpecType = "interval"
+	// SpecExpression は数式（例: "2√3", "π/2", "(x+1)^2"）。Value の式と同じ値・同じ関数になる式なら正解とする。
+	SpecExpression SpecType = "expression"
+	// SpecJudge は LLM 採点者（Judge）による採点。採点基準は questions.grading_rubric に置き、Value は任意の参考解答。
+	// 外部呼び出しが必要なため EvaluateSpec では採点せず、EvaluateWithJudge を使う。
+	SpecJudge SpecType = "judge"
+)
+
+// AnswerSpec は questions.answer_spec に保存される正解仕様。
+// Type に応じて使うフィールドが変わり、使わないフィールドは JSON から省略される。
+type AnswerSpec struct {
+	Type          SpecType `json:"type"`
+	Value         string   `json:"value,omitempty"`          // SpecValue / SpecExpression / SpecJudge（参考解答）
+	Alternatives  []string `json:"alternatives,omitempty"`   // SpecAlternatives
+	Pattern       string   `json:"pattern,omitempty"`        // SpecRegex
+	Items         []string `json:"items,omitempty"`          // SpecSet / SpecList
+	PartialCredit bool     `json:"partial_credit,omitempty"` // SpecSet / SpecList
+	Min           *float64 `json:"min,omitempty"`            // SpecInterval
+	Max           *float64 `json:"max,omitempty"`            // SpecInterval
+}
+
+// itemSeparator は集合・リスト回答の区切り文字。"New York" のように空白を含む要素があるため、空白だけでは区切らない。
+var itemSeparator = regexp.MustCompile(`\s*(?:[,、，;；/／・]|\sand\s)\s*`)
+
+// ParseAnswerSpec は answer_spec 列の JSON を読み込む。
+// 列が NULL（raw が空）の場合は correct_answer を単一の正解とみなした仕様を返すため、未移行の行もそのまま採点できる。
+func ParseAnswerSpec(raw []byte, fallback string) (AnswerSpec, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return AnswerSpec{Type: SpecValue, Value: fallback}, nil
+	}
+	var spec AnswerSpec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return AnswerSpec{}, fmt.Errorf("failed to unmarshal answer_spec: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return AnswerSpec{}, err
+	}
+	return spec, nil
+}
+
+// Validate は Type ごとの必須項目が揃っているかを確認する。問題登録時のバリデーションにも使う。
+func (s AnswerSpec) Validate() error {
+	switch s.Type {
+	case SpecValue:
+		if strings.TrimSpace(s.Value) == "" {
+			return errors.New("answer_spec: value is required")
+		}
+	case SpecAlternatives:
+		if len(s.Alternatives) == 0 {
+			return errors.New("answer_spec: alternatives must not be empty")
+		}
+	case SpecRegex:
+		if s.Pattern == "" {
+			return errors.New("answer_spec: pattern is required")
+		}
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("answer_spec: invalid pattern: %w", err)
+		}
+	case SpecSet, SpecList:
+		if len(s.Items) == 0 {
+			return errors.New("answer_spec: items must not be empty")
+		}
+	case SpecExpression:
+		if strings.TrimSpace(s.Value) == "" {
+			return errors.New("answer_spec: value is required")
+		}
+		if _, err := parseExpr(s.Value, exprVariables(s.Value)); err != nil {
+			return fmt.Errorf("answer_spec: invalid expression: %w", err)
+		}
+	case SpecJudge:
+		// 採点基準は問題側の grading_rubric に保存するため、仕様側の必須項目は無い。
+	case SpecInterval:
+		if s.Min == nil || s.Max == nil {
+			return errors.New("answer_spec: min and max are required")
+		}
+		if *s.Min > *s.Max {
+			return errors.New("answer_spec: min must not exceed max")
+		}
+	default:
+		return fmt.Errorf("answer_spec: unknown type %q", s.Type)
+	}
+	return nil
+}
+
+// EvaluateSpec は正解仕様に従って AI 回答を採点する。戻り値の形は Evaluate と同じ。
+// SpecValue の数値問題は EvaluateWithOptions と同じ結果になるため、既存の問題の採点は変わらない。
+func EvaluateSpec(answerText string, spec AnswerSpec, opts Options) (score int, extracted *float64, mode string, detail map[string]any) {
+	switch spec.Type {
+	case SpecValue:
+		score, extracted, mode, detail = evaluateValue(answerText, spec.Value, opts)
+	case SpecAlternatives:
+		score, extracted, mode, detail = evaluateAlternatives(answerText, spec.Alternatives, opts)
+	case SpecRegex:
+		score, mode, detail = evaluateRegex(answerText, spec.Pattern)
+	case SpecSet:
+		score, mode, detail = evaluateCollection(answerText, spec.Items, spec.PartialCredit, false)
+	case SpecList:
+		score, mode, detail = evaluateCollection(answerText, spec.Items, spec.PartialCredit, true)
+	case SpecInterval:
+		score, extracted, mode, detail = evaluateInterval(answerText, *spec.Min, *spec.Max, opts)
+	case SpecExpression:
+		score, extracted, mode, detail = evaluateExpression(answerText, spec.Value, opts)
+	case SpecJudge:
+		mode = "judge_required"
+		detail = map[string]any{
+			"answer_raw":  answerText,
+			"mode_reason": "LLM 採点が必要な問題のため EvaluateWithJudge で採点する",
+		}
+	default:
+		mode = "invalid_spec"
+		detail = map[string]any{
+			"answer_raw":  answerText,
+			"mode_reason": fmt.Sprintf("未知の正解仕様 %q", spec.Type),
+		}
+	}
+	detail["answer_spec_type"] = string(spec.Type)
+	return
+}
+
+// evaluateValue は単一の正解で採点する。
+// 正解が数値なら従来どおり EvaluateWithOptions、文字列なら最終回答部分を正規化して比較する。
+func evaluateValue(answerText, correct string, opts Options) (int, *float64, string, map[string]any) {
+	if !isNumericAnswer(correct, opts) {
+		score, mode, detail := evaluateTextValue(answerText, correct)
+		return score, nil, mode, detail
+	}
+	return EvaluateWithOptions(answerText, correct, opts)
+}
+
+// isNumericAnswer は正解文字列を数値として採点すべきかを判定する。
+// 期待単位がある問題では "2時間30分" のような単位付きの正解も数値扱いにする。
+func isNumericAnswer(correct string, opts Options) bool {
+	trimmed := strings.TrimSpace(correct)
+	if _, err := strconv.ParseFloat(trimmed, 64); err == nil {
+		return true
+	}
+	return opts.ExpectedUnit != "" && len(parseQuantities(trimmed)) == 1
+}
+
+// evaluateTextValue は文字列の正解と回答を正規化して比較する。
+func evaluateTextValue(answerText, correct string) (score int, mode string, detail map[string]any) {
+	normalizedCorrect := NormalizeText(correct)
+	candidates := textCandidates(answerText)
+	detail = map[string]any{
+		"answer_raw":         answerText,
+		"correct_raw":        correct,
+		"correct_normalized": normalizedCorrect,
+		"answer_candidates":  candidates,
+	}
+	for _, candidate := range candidates {
+		if candidate == normalizedCorrect {
+			score = 100
+			mode = "text_match"
+			detail["matched_text"] = candidate
+			detail["mode_reason"] = "正規化後の最終回答が正解と一致"
+			detail["normalized_score"] = score
+			return
+		}
+	}
+	mode = "text_mismatch"
+	detail["mode_reason"] = "正規化後の最終回答が正解と一致しない"
+	return
+}
+
+// evaluateAlternatives は別解ごとに採点し、最も高いスコアの結果を採用する。
+// 試した別解とスコアは alternatives_tried に残す。
+func evaluateAlternatives(answerText string, alternatives []string, opts Options) (score int, extracted *float64, mode string, detail map[string]any) {
+	tried := make([]map[string]any, 0, len(alternatives))
+	best := -1
+	for _, alt := range alternatives {
+		s, e, m, d := evaluateValue(answerText, alt, opts)
+		tried = append(tried, map[string]any{"alternative": alt, "score": s, "mode": m})
+		if s > best {
+			best = s
+			score, extracted, mode, detail = s, e, m, d
+			detail["matched_alternative"] = alt
+		}
+	}
+	detail["alternatives_tried"] = tried
+	return
+}
+
+// evaluateRegex は最終回答部分（正規化前後の両方）に正規表現がマッチするかで採点する。
+func evaluateRegex(answerText, pattern string) (score int, mode string, detail map[string]any) {
+	segment := FinalAnswerSegment(answerText)
+	detail = map[string]any{
+		"answer_raw":     answerText,
+		"answer_segment": segment,
+		"pattern":        pattern,
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		mode = "invalid_spec"
+		detail["mode_reason"] = "正規表現のコンパイルに失敗"
+		detail["parse_error"] = err.Error()
+		return
+	}
+	if re.MatchString(segment) || re.MatchString(NormalizeText(segment)) {
+		score = 100
+		mode = "regex_match"
+		detail["mode_reason"] = "最終回答が正規表現にマッチ"
+		detail["normalized_score"] = score
+		return
+	}
+	mode = "regex_no_match"
+	detail["mode_reason"] = "最終回答が正規表現にマッチしない"
+	return
+}
+
+// evaluateCollection は集合（ordered=false）またはリスト（ordered=true）として回答を採点する。
+// 部分点は「一致した要素数 / max(正解の要素数, 回答の要素数)」で、余計な要素を書き並べるほど下がる。
+func evaluateCollection(answerText string, items []string, partial bool, ordered bool) (score int, mode string, detail map[string]any) {
+	given := splitAnswerItems(FinalAnswerSegment(answerText), allNumeric(items))
+	detail = map[string]any{
+		"answer_raw":     answerText,
+		"expected_items": items,
+		"answer_items":   given,
+		"partial_credit": partial,
+	}
+
+	var hits int
+	if ordered {
+		for i := 0; i < len(items) && i < len(given); i++ {
+			if itemsEqual(items[i], given[i]) {
+				hits++
+			}
+		}
+	} else {
+		given = uniqueItems(given)
+		detail["answer_items"] = given
+		var missing []string
+		for _, want := range items {
+			found := false
+			for _, got := range given {
+				if itemsEqual(want, got) {
+					found = true
+					break
+				}
+			}
+			if found {
+				hits++
+			} else {
+				missing = append(missing, want)
+			}
+		}
+		detail["missing_items"] = missing
+	}
+
+	total := len(items)
+	if len(given) > total {
+		total = len(given)
+	}
+	detail["matched_count"] = hits
+	detail["denominator"] = total
+
+	kind := "set"
+	if ordered {
+		kind = "list"
+	}
+	switch {
+	case hits == len(items) && len(given) == len(items):
+		score = 100
+		mode = kind + "_exact"
+		detail["mode_reason"] = "全ての要素が一致"
+	case partial && hits > 0:
+		score = int(math.Round(100 * float64(hits) / float64(total)))
+		mode = kind + "_partial"
+		detail["mode_reason"] = "一部の要素が一致したため部分点"
+	default:
+		score = 0
+		mode = kind + "_mismatch"
+		detail["mode_reason"] = "要素が一致しない"
+	}
+	detail["normalized_score"] = score
+	return
+}
+
+// splitAnswerItems は最終回答部分を区切り文字で分割する。
+// 正解がすべて数値なら、説明文の混入に強いよう数値だけを順に拾う。
+func splitAnswerItems(segment string, numericOnly bool) []string {
+	if numericOnly {
+		return numberPattern.FindAllString(normalizeDigits(segment), -1)
+	}
+	var items []string
+	for _, part := range itemSeparator.Split(segment, -1) {
+		if normalized := NormalizeText(part); normalized != "" {
+			items = append(items, normalized)
+		}
+	}
+	return items
+}
+
+// allNumeric は全要素が数値として読めるかを返す。
+func allNumeric(items []string) bool {
+	for _, item := range items {
+		if _, ok := new(big.Rat).SetString(strings.TrimSpace(item)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// itemsEqual は要素同士を比較する。両方数値なら有理数として、それ以外は正規化後の文字列として比べる。
+func itemsEqual(want, got string) bool {
+	wantRat, okWant := new(big.Rat).SetString(strings.TrimSpace(want))
+	gotRat, okGot := new(big.Rat).SetString(strings.TrimSpace(got))
+	if okWant && okGot {
+		return wantRat.Cmp(gotRat) == 0
+	}
+	return NormalizeText(want) == NormalizeText(got)
+}
+
+// uniqueItems は同じ要素の重複を取り除く（集合として扱うため）。
+func uniqueItems(items []string) []string {
+	var result []string
+	for _, item := range items {
+		duplicate := false
+		for _, seen := range result {
+			if itemsEqual(seen, item) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// evaluateInterval は回答の数値が閉区間 [lo, hi] に入っているかで採点する。
+// 区間外の場合は最寄りの端点を正解とみなして scoreNumeric の曲線で減点する。
+func evaluateInterval(answerText string, lo, hi float64, opts Options) (score int, extracted *float64, mode string, detail map[string]any) {
+	loStr := strconv.FormatFloat(lo, 'f', -1, 64)
+	hiStr := strconv.FormatFloat(hi, 'f', -1, 64)
+	detail = map[string]any{
+		"answer_raw":   answerText,
+		"interval_min": lo,
+		"interval_max": hi,
+	}
+
+	value, mismatch, ok := extractAnswerValue(answerText, opts, detail)
+	if !ok {
+		mode = "no_numeric"
+		detail["mode_reason"] = "回答から区間と比較できる数値を取り出せない"
+		return
+	}
+	valueF, _ := value.Float64()
+	detail["extracted_numeric"] = valueF
+	valueCopy := valueF
+	extracted = &valueCopy
+
+	if mismatch && opts.policy() == UnitPolicyReject {
+		mode = "unit_mismatch"
+		detail["mode_reason"] = "回答の単位が期待単位と次元が異なるため不正解"
+		detail["normalized_score"] = score
+		return
+	}
+
+	loRat, _ := new(big.Rat).SetString(loStr)
+	hiRat, _ := new(big.Rat).SetString(hiStr)
+	if value.Cmp(loRat) >= 0 && value.Cmp(hiRat) <= 0 {
+		score = 100
+		mode = "interval_inside"
+		detail["mode_reason"] = "回答が区間内"
+		detail["normalized_score"] = score
+	} else {
+		boundStr, boundVal := loStr, lo
+		if value.Cmp(hiRat) > 0 {
+			boundStr, boundVal = hiStr, hi
+		}
+		detail["nearest_bound"] = boundVal
+		var curveMode string
+		score, curveMode = scoreNumeric(boundStr, boundVal, value.RatString(), valueF, opts.Scoring.withDefaults(), detail)
+		mode = "interval_outside"
+		detail["curve_mode"] = curveMode
+		detail["mode_reason"] = "区間外のため最寄りの端点との誤差で評価"
+	}
+	if mismatch {
+		score = penalizeUnitMismatch(score, detail)
+	}
+	return
+}
+
+// extractAnswerValue は回答から採点対象の数値を 1 つ（最後に現れたもの）取り出す。
+// 期待単位があれば parseQuantities で期待単位へ換算する。次元が異なる単位は換算できないため、
+// 元の単位のまま読んだ値を mismatch = true で返し、扱いは呼び出し側が UnitPolicy に従って決める。
+func extractAnswerValue(answerText string, opts Options, detail map[string]any) (value *big.Rat, mismatch bool, ok bool) {
+	if expected, found := lookupUnit(opts.ExpectedUnit); found {
+		quantities := parseQuantities(answerText)
+		if len(quantities) == 0 {
+			return nil, false, false
+		}
+		q := quantities[len(quantities)-1]
+		detail["extracted_text"] = q.text
+		detail["unit_conversions"] = q.parts
+		switch {
+		case q.dim == "":
+			detail["unit_assumed"] = true
+			return q.value, false, true
+		case q.dim != expected.dim:
+			detail["unit_mismatch"] = map[string]any{
+				"expected_dimension": string(expected.dim),
+				"answer_dimension":   string(q.dim),
+				"policy":             string(opts.policy()),
+			}
+			return q.raw, true, true
+		}
+		return toUnit(q.value, expected), false, true
+	}
+
+	matches := numberPattern.FindAllString(normalizeDigits(answerText), -1)
+	if len(matches) == 0 {
+		return nil, false, false
+	}
+	matched := matches[len(matches)-1]
+	detail["extracted_text"] = matched
+	value, ok = new(big.Rat).SetString(matched)
+	return value, false, ok
+}
@@ -0,0 +1,268 @@
+// answer_spec_test.go は構造化された正解仕様（別解・正規表現・集合・リスト・区間）の採点と、JSON の読み込み・検証を確認する単体テスト。
+package eval
+
+import (
+	"testing"
+)
+
+// TestEvaluateSpec は仕様の種類ごとの代表ケースをテーブルで検証する。
+func TestEvaluateSpec(t *testing.T) {
+	testcases := []struct {
+		name        string
+		answer      string
+		spec        AnswerSpec
+		opts        Options
+		expectScore int
+		expectMode  string
+	}{
+		{
+			name:        "ValueNumericKeepsLegacyBehavior",
+			answer:      "First 11 then 10",
+			spec:        AnswerSpec{Type: SpecValue, Value: "10"},
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "ValueTextAfterMarker",
+			answer:      "右から数えると…\n最終回答: の",
+			spec:        AnswerSpec{Type: SpecValue, Value: "の"},
+			expectScore: 100,
+			expectMode:  "text_match",
+		},
+		{
+			name:        "ValueTextQuotedWithCopula",
+			answer:      "答えは「の」です。",
+			spec:        AnswerSpec{Type: SpecValue, Value: "の"},
+			expectScore: 100,
+			expectMode:  "text_match",
+		},
+		{
+			name:        "ValueTextMismatch",
+			answer:      "最終回答: も",
+			spec:        AnswerSpec{Type: SpecValue, Value: "の"},
+			expectScore: 0,
+			expectMode:  "text_mismatch",
+		},
+		{
+			name:        "AlternativesWord",
+			answer:      "最終回答: Three.",
+			spec:        AnswerSpec{Type: SpecAlternatives, Alternatives: []string{"3", "three", "三"}},
+			expectScore: 100,
+			expectMode:  "text_match",
+		},
+		{
+			name:        "AlternativesKanji",
+			answer:      "最終回答：三",
+			spec:        AnswerSpec{Type: SpecAlternatives, Alternatives: []string{"3", "three", "三"}},
+			expectScore: 100,
+			expectMode:  "text_match",
+		},
+		{
+			name:        "AlternativesNumericPicksBest",
+			answer:      "最終回答: 4",
+			spec:        AnswerSpec{Type: SpecAlternatives, Alternatives: []string{"three", "3"}},
+			expectScore: computeIntegerScaleScore(1, 3),
+			expectMode:  "numeric_score_integer",
+		},
+		{
+			name:        "RegexMatch",
+			answer:      "最終回答: Tokyo Tower",
+			spec:        AnswerSpec{Type: SpecRegex, Pattern: `(?i)^tokyo\s*tower$`},
+			expectScore: 100,
+			expectMode:  "regex_match",
+		},
+		{
+			name:        "RegexNoMatch",
+			answer:      "最終回答: Skytree",
+			spec:        AnswerSpec{Type: SpecRegex, Pattern: `(?i)tower`},
+			expectScore: 0,
+			expectMode:  "regex_no_match",
+		},
+		{
+			name:        "SetUnorderedExact",
+			answer:      "最終回答: 7, 5, 3, 2",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"2", "3", "5", "7"}},
+			expectScore: 100,
+			expectMode:  "set_exact",
+		},
+		{
+			name:        "SetPartialCredit",
+			answer:      "最終回答: 2、3、5",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"2", "3", "5", "7"}, PartialCredit: true},
+			expectScore: 75,
+			expectMode:  "set_partial",
+		},
+		{
+			name:        "SetExtraItemsLowerCredit",
+			answer:      "最終回答: 1, 2, 3, 5, 7",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"2", "3", "5", "7"}, PartialCredit: true},
+			expectScore: 80,
+			expectMode:  "set_partial",
+		},
+		{
+			name:        "SetWithoutPartialCredit",
+			answer:      "最終回答: 2, 3, 5",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"2", "3", "5", "7"}},
+			expectScore: 0,
+			expectMode:  "set_mismatch",
+		},
+		{
+			name:        "SetTextItems",
+			answer:      "最終回答: みかん・りんご",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"りんご", "みかん"}},
+			expectScore: 100,
+			expectMode:  "set_exact",
+		},
+		{
+			name:        "SetMultiWordItems",
+			answer:      "最終回答: carbon dioxide, New York and water",
+			spec:        AnswerSpec{Type: SpecSet, Items: []string{"New York", "water", "Carbon Dioxide"}},
+			expectScore: 100,
+			expectMode:  "set_exact",
+		},
+		{
+			name:        "ListMultiWordItems",
+			answer:      "最終回答: New York / Los Angeles / Chicago",
+			spec:        AnswerSpec{Type: SpecList, Items: []string{"New York", "Los Angeles", "Chicago"}},
+			expectScore: 100,
+			expectMode:  "list_exact",
+		},
+		{
+			name:        "ListOrderMatters",
+			answer:      "最終回答: 3, 2, 1",
+			spec:        AnswerSpec{Type: SpecList, Items: []string{"1", "2", "3"}, PartialCredit: true},
+			expectScore: 33,
+			expectMode:  "list_partial",
+		},
+		{
+			name:        "ListExact",
+			answer:      "最終回答: 1 2 3",
+			spec:        AnswerSpec{Type: SpecList, Items: []string{"1", "2", "3"}},
+			expectScore: 100,
+			expectMode:  "list_exact",
+		},
+		{
+			name:        "IntervalInside",
+			answer:      "およそ 380 km",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(350), Max: floatPtr(420)},
+			opts:        Options{ExpectedUnit: "km"},
+			expectScore: 100,
+			expectMode:  "interval_inside",
+		},
+		{
+			name:        "IntervalInsideAfterUnitConversion",
+			answer:      "400000 m",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(350), Max: floatPtr(420)},
+			opts:        Options{ExpectedUnit: "km"},
+			expectScore: 100,
+			expectMode:  "interval_inside",
+		},
+		{
+			name:        "IntervalOutside",
+			answer:      "最終回答: 12",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(5), Max: floatPtr(10)},
+			expectScore: computeIntegerScaleScore(2, 10),
+			expectMode:  "interval_outside",
+		},
+		{
+			name:        "IntervalUnitMismatchRejected",
+			answer:      "380分",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(350), Max: floatPtr(420)},
+			opts:        Options{ExpectedUnit: "km"},
+			expectScore: 0,
+			expectMode:  "unit_mismatch",
+		},
+		{
+			name:        "IntervalUnitMismatchPenalized",
+			answer:      "3.8万分",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(35000), Max: floatPtr(42000)},
+			opts:        Options{ExpectedUnit: "km", UnitPolicy: UnitPolicyPenalize},
+			expectScore: 50,
+			expectMode:  "interval_inside",
+		},
+		{
+			name:        "IntervalNoNumber",
+			answer:      "わかりません",
+			spec:        AnswerSpec{Type: SpecInterval, Min: floatPtr(5), Max: floatPtr(10)},
+			expectScore: 0,
+			expectMode:  "no_numeric",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, _, mode, detail := EvaluateSpec(tc.answer, tc.spec, tc.opts)
+			if score != tc.expectScore {
+				t.Fatalf("score mismatch: got %d, want %d (detail=%v)", score, tc.expectScore, detail)
+			}
+			if mode != tc.expectMode {
+				t.Fatalf("mode mismatch: got %s, want %s (detail=%v)", mode, tc.expectMode, detail)
+			}
+			if detail["answer_spec_type"] != string(tc.spec.Type) {
+				t.Fatalf("answer_spec_type mismatch: got %v", detail["answer_spec_type"])
+			}
+		})
+	}
+}
+
+// TestParseAnswerSpec は JSON の読み込み、NULL 列のフォールバック、不正な仕様の検出を確認する。
+func TestParseAnswerSpec(t *testing.T) {
+	testcases := []struct {
+		name       string
+		raw        string
+		fallback   string
+		expectType SpecType
+		wantErr    bool
+	}{
+		{name: "NullFallsBackToValue", raw: "", fallback: "32", expectType: SpecValue},
+		{name: "JSONNullFallsBackToValue", raw: "null", fallback: "32", expectType: SpecValue},
+		{name: "Alternatives", raw: `{"type":"alternatives","alternatives":["3","three"]}`, expectType: SpecAlternatives},
+		{name: "Interval", raw: `{"type":"interval","min":1,"max":2}`, expectType: SpecInterval},
+		{name: "UnknownType", raw: `{"type":"magic"}`, wantErr: true},
+		{name: "EmptyItems", raw: `{"type":"set","items":[]}`, wantErr: true},
+		{name: "InvalidRegex", raw: `{"type":"regex","pattern":"("}`, wantErr: true},
+		{name: "Expression", raw: `{"type":"expression","value":"(x+1)^2"}`, expectType: SpecExpression},
+		{name: "InvalidExpression", raw: `{"type":"expression","value":"(1+"}`, wantErr: true},
+		{name: "ReversedInterval", raw: `{"type":"interval","min":3,"max":2}`, wantErr: true},
+		{name: "BrokenJSON", raw: `{"type":`, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec, err := ParseAnswerSpec([]byte(tc.raw), tc.fallback)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseAnswerSpec() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if spec.Type != tc.expectType {
+				t.Fatalf("type mismatch: got %s, want %s", spec.Type, tc.expectType)
+			}
+			if tc.raw == "" && spec.Value != tc.fallback {
+				t.Fatalf("fallback value mismatch: got %s, want %s", spec.Value, tc.fallback)
+			}
+		})
+	}
+}
+
+// TestNormalizeText は比較前の正規化（全角→半角・小文字化・語尾や句読点の除去）を確認する。
+func TestNormalizeText(t *testing.T) {
+	testcases := map[string]string{
+		"　ＴＨＲＥＥ。":       "three",
+		"「の」です。":        "の",
+		"Tokyo   Tower": "tokyo tower",
+		"です":            "です",
+	}
+	for input, want := range testcases {
+		if got := NormalizeText(input); got != want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
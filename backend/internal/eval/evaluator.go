@@ -39,12 +39,37 @@ const (
 // 複数の数値がある場合は、最後に出現するものを最終回答として採用する。
 var numberPattern = regexp.MustCompile(`[-+]?\d+(?:\.\d+)?`)
 
+// Options は問題ごとに変わる評価条件をまとめた構造体。ゼロ値は従来どおりの数値評価を意味する。
+type Options struct {
+	// ExpectedUnit は正解が表す単位（例: "km", "分", "円"）。空なら単位を考慮しない。
+	ExpectedUnit string
+	// UnitPolicy は回答の単位が期待単位と次元ごと異なる場合の扱い。空なら UnitPolicyReject。
+	UnitPolicy UnitPolicy
+	// Scoring は数値の誤差からスコアを決める曲線のパラメータ。ゼロ値の項目は既定値を使う。
+	Scoring ScoringParams
+}
+
+// policy は UnitPolicy の既定値を補完して返す。
+func (o Options) policy() UnitPolicy {
+	if o.UnitPolicy == "" {
+		return UnitPolicyReject
+	}
+	return o.UnitPolicy
+}
+
 // Evaluate は AI 回答を正解と比較し、数値の一致度に基づくスコア・抽出値・メタ情報を返す。
 // 完全一致なら 100 点、それ以外は数値誤差に応じて連続的に減点し、必要な場合はモード名と理由も detail に記録する。
 func Evaluate(answerText string, correct string) (score int, extracted *float64, mode string, detail map[string]any) {
+	return EvaluateWithOptions(answerText, correct, Options{})
+}
+
+// EvaluateWithOptions は Evaluate に問題ごとの評価条件を加えた版。
+// ExpectedUnit が設定されていれば、回答と正解を期待単位へ換算してから誤差を計算する。
+func EvaluateWithOptions(answerText string, correct string, opts Options) (score int, extracted *float64, mode string, detail map[string]any) {
 	// 回答と正解の前後スペースを除去し、純粋な値として比較しやすくする。
 	trimmedAnswer := strings.TrimSpace(answerText)
 	trimmedCorrect := strings.TrimSpace(correct)
+	params := opts.Scoring.withDefaults()
 
 	// detail は評価過程の情報を溜め込むメタデータ。
 	// デバッグや可視化で使えるよう、生の入力・前処理結果・スコア計算式を格納している。
@@ -54,8 +79,11 @@ func Evaluate(answerText string, correct string) (score int, extracted *float64,
 		"answer_trimmed":  trimmedAnswer,
 		"correct_trimmed": trimmedCorrect,
 		"score_strategy":  "v3: スケール適応型（整数問題は絶対誤差、大きな数は相対誤差）",
-		"r0":              r0,
-		"p":               p,
+		"r0":              params.R0,
+		"p":               params.P,
+	}
+	if !opts.Scoring.IsZero() {
+		detail["scoring_params"] = params
 	}
 
 	if trimmedAnswer == trimmedCorrect {
@@ -76,6 +104,17 @@ func Evaluate(answerText string, correct string) (score int, extracted *float64,
 		return
 	}
 
+	if opts.ExpectedUnit != "" {
+		score, extracted, mode = evaluateWithUnit(trimmedAnswer, trimmedCorrect, opts, detail)
+		return
+	}
+
+	score, extracted, mode = evaluatePlainNumeric(trimmedAnswer, trimmedCorrect, params, detail)
+	return
+}
+
+// evaluatePlainNumeric は単位を考慮せず、回答中の最後の数値と正解を比較する従来の評価経路。
+func evaluatePlainNumeric(trimmedAnswer, trimmedCorrect string, params ScoringParams, detail map[string]any) (score int, extracted *float64, mode string) {
 	// 正解文字列が数値として読めるか先に調べ、後続の誤差計算に備える。
 	correctVal, correctErr := strconv.ParseFloat(trimmedCorrect, 64)
 	if correctErr == nil {
@@ -115,44 +154,8 @@ func Evaluate(answerText string, correct string) (score int, extracted *float64,
 	extracted = &valueCopy
 
 	if correctErr == nil {
-		// 正解も数値なら calculatePreciseDiff で高精度に差分を算出し、連続スコアを決定する。
-		diff, precise := calculatePreciseDiff(trimmedCorrect, matched)
-		detail["diff_precision"] = "float"
-		if precise {
-			detail["diff_precision"] = "rational"
-		} else {
-			diff = math.Abs(parsed - correctVal)
-		}
-
-		detail["absolute_diff"] = diff
-
-		if diff == 0 {
-			mode = "numeric_exact"
-			score = 100
-			detail["mode_reason"] = "数値比較で誤差が0"
-			detail["normalized_score"] = score
-			return
-		}
-
-		// 正解の大きさに応じてスコアリング方式を選択
-		absCorrect := math.Abs(correctVal)
-		if absCorrect <= integerScaleThreshold {
-			// 整数スケール問題：絶対誤差ベースでスコアリング
-			score = computeIntegerScaleScore(diff, absCorrect)
-			mode = "numeric_score_integer"
-			detail["mode_reason"] = "整数スケール問題として絶対誤差ベースで評価（v3）"
-			detail["scale_type"] = "integer"
-			detail["base_error"] = integerBaseError
-		} else {
-			// 大きな数の問題：相対誤差ベースでスコアリング
-			relativeError := calculateRelativeError(diff, correctVal)
-			detail["relative_error"] = relativeError
-			score = computeScore(relativeError)
-			mode = "numeric_score_relative"
-			detail["mode_reason"] = "大規模数値問題として相対誤差ベースで評価（v3）"
-			detail["scale_type"] = "relative"
-		}
-		detail["normalized_score"] = score
+		// 正解も数値なら scoreNumeric で高精度に差分を算出し、連続スコアを決定する。
+		score, mode = scoreNumeric(trimmedCorrect, correctVal, matched, parsed, params, detail)
 		return
 	}
 
@@ -163,6 +166,50 @@ func Evaluate(answerText string, correct string) (score int, extracted *float64,
 	return
 }
 
+// scoreNumeric は正解と抽出値の差分を calculatePreciseDiff で求め、正解の大きさに応じた曲線でスコアを決める。
+// 文字列は big.Rat が解釈できる形式（"3.14" や "22/7"）で渡し、失敗時は float の差分にフォールバックする。
+// 曲線の形は params（既定値を補完済みのもの）で決まる。
+func scoreNumeric(correctStr string, correctVal float64, extractedStr string, extractedVal float64, params ScoringParams, detail map[string]any) (score int, mode string) {
+	diff, precise := calculatePreciseDiff(correctStr, extractedStr)
+	detail["diff_precision"] = "float"
+	if precise {
+		detail["diff_precision"] = "rational"
+	} else {
+		diff = math.Abs(extractedVal - correctVal)
+	}
+
+	detail["absolute_diff"] = diff
+
+	if diff == 0 {
+		mode = "numeric_exact"
+		score = 100
+		detail["mode_reason"] = "数値比較で誤差が0"
+		detail["normalized_score"] = score
+		return
+	}
+
+	// 正解の大きさに応じてスコアリング方式を選択
+	absCorrect := math.Abs(correctVal)
+	if absCorrect <= integerScaleThreshold {
+		// 整数スケール問題：絶対誤差ベースでスコアリング
+		score = params.integerScore(diff, absCorrect)
+		mode = "numeric_score_integer"
+		detail["mode_reason"] = "整数スケール問題として絶対誤差ベースで評価（v3）"
+		detail["scale_type"] = "integer"
+		detail["base_error"] = params.BaseError
+	} else {
+		// 大きな数の問題：相対誤差ベースでスコアリング
+		relativeError := params.relativeError(diff, correctVal)
+		detail["relative_error"] = relativeError
+		score = params.relativeScore(relativeError)
+		mode = "numeric_score_relative"
+		detail["mode_reason"] = "大規模数値問題として相対誤差ベースで評価（v3）"
+		detail["scale_type"] = "relative"
+	}
+	detail["normalized_score"] = score
+	return
+}
+
 // calculatePreciseDiff は文字列表現を用いた高精度計算で誤差を算出する。
 // 変換に失敗した場合は (0, false) を返し、呼び出し側でフォールバックしてもらう。
 func calculatePreciseDiff(correctStr string, extractedStr string) (diff float64, ok bool) {
@@ -189,8 +236,7 @@ func calculatePreciseDiff(correctStr string, extractedStr string) (diff float64,
 // calculateRelativeError は絶対誤差と正解値から相対誤差を計算する。
 // 正解が0に近い場合は tolAbsHint を使用して分母が0にならないようにする。
 func calculateRelativeError(absoluteDiff float64, correctVal float64) float64 {
-	denominator := math.Max(math.Abs(correctVal), tolAbsHint)
-	return absoluteDiff / denominator
+	return DefaultScoringParams().relativeError(absoluteDiff, correctVal)
 }
 
 // computeIntegerScaleScore は整数スケールの問題に対して絶対誤差ベースでスコアを計算する。
@@ -206,51 +252,15 @@ func calculateRelativeError(absoluteDiff float64, correctVal float64) float64 {
 //
 // 数式: score = 100 / (1 + (diff/base)^p)
 // ここで base = integerBaseError (デフォルト: 2.0)
+// 問題ごとのパラメータを使う場合は ScoringParams.integerScore を使う。
 func computeIntegerScaleScore(absoluteDiff float64, correctVal float64) int {
-	if absoluteDiff <= 0 {
-		return 100
-	}
-
-	// 正解が極小（絶対値1未満）の場合は、より厳しい評価
-	base := integerBaseError
-	if math.Abs(correctVal) < 1.0 {
-		base = smallValueBaseError
-	}
-
-	// ロジスティック関数: score = 100 / (1 + (diff/base)^p)
-	ratio := absoluteDiff / base
-	raw := 100.0 / (1.0 + math.Pow(ratio, p))
-
-	// スコアを0-100の範囲に制限
-	if raw < 0 {
-		raw = 0
-	}
-	if raw > 100 {
-		raw = 100
-	}
-
-	return int(math.Round(raw))
+	return DefaultScoringParams().integerScore(absoluteDiff, correctVal)
 }
 
 // computeScore は相対誤差に基づいてロジスティック関数でスコアを計算する。
 // rel が 0 なら 100 点、r0(5%)で 50 点、それ以上は急速に減少する。
 // スコア式: score = 100 / (1 + (rel/r0)^p)
+// 問題ごとのパラメータを使う場合は ScoringParams.relativeScore を使う。
 func computeScore(relativeError float64) int {
-	if relativeError <= 0 {
-		return 100
-	}
-
-	// ロジスティック関数: score = 100 / (1 + (rel/r0)^p)
-	ratio := relativeError / r0
-	raw := 100.0 / (1.0 + math.Pow(ratio, p))
-
-	// スコアを0-100の範囲に制限
-	if raw < 0 {
-		raw = 0
-	}
-	if raw > 100 {
-		raw = 100
-	}
-
-	return int(math.Round(raw))
+	return DefaultScoringParams().relativeScore(relativeError)
 }
@@ -0,0 +1,684 @@
+// expr.go は "2√3" や "π/2"、"(x+1)^2" のような数式の回答を解析し、math/big の精度で値を求める評価器をまとめたファイル。
+// 定数式は求めた値を scoreNumeric に渡して既存の誤差曲線で採点し、変数を含む式は複数の代入点で値を比べて同値性を判定する。
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// exprPrec は式の計算に使う big.Float の精度（ビット数）。
+	exprPrec = 256
+
+	// exprMaxIntExponent は累乗を正確に計算する整数指数の上限。これを超える指数や小数の指数は float64 で近似する。
+	exprMaxIntExponent = 1024
+
+	// exprExactTolerance は精度 exprPrec で計算した値同士を「等しい」とみなす相対誤差。
+	// √12 と 2√3 のように計算順序の違いで末尾の桁だけずれるケースを吸収する。
+	exprExactTolerance = 1e-40
+
+	// exprApproxTolerance は float64 の近似を含む計算（小数の指数など）で等しいとみなす相対誤差。
+	exprApproxTolerance = 1e-9
+
+	// exprMinSamplePoints は変数を含む式の比較で、計算できた代入点がこれ未満なら判定しない。
+	exprMinSamplePoints = 3
+)
+
+// piDigits は π の 80 桁。exprPrec（約 77 桁）を満たす。
+const piDigits = "3.1415926535897932384626433832795028841971693993751058209749445923078164062862089"
+
+// exprSamplePoints は変数に代入する値。0 や整数を避け、偶然の一致が起きにくい値を選んでいる。
+var exprSamplePoints = []float64{0.37, 1.61, 2.83, -1.29, 4.05, -3.17}
+
+// exprFunctions は関数として扱う識別子。
+var exprFunctions = map[string]bool{"sqrt": true}
+
+// exprConstants は定数として扱う識別子。e は変数名と衝突しやすいため含めない。
+var exprConstants = map[string]bool{"pi": true, "π": true}
+
+// errExprNotConstant は変数を含む式の値を求めようとしたときのエラー。
+var errExprNotConstant = errors.New("expression: contains variables")
+
+// errExprOverflow は計算途中の値が big.Float でも表せない大きさ（±Inf）になったときのエラー。
+// ±Inf のまま計算を続けると Inf-Inf や 0×Inf で big.Float が panic するため、その時点で打ち切る。
+var errExprOverflow = errors.New("expression: value is too large")
+
+// exprNode は解析済みの数式の構文木。
+type exprNode interface {
+	eval(env map[string]*big.Float, st *exprState) (*big.Float, error)
+}
+
+// exprState は 1 回の評価中に float64 の近似を使ったかを記録する。
+type exprState struct {
+	approx bool
+}
+
+type exprNumber struct{ value *big.Float }
+
+type exprVariable struct{ name string }
+
+type exprConstPi struct{}
+
+type exprUnary struct {
+	neg     bool
+	operand exprNode
+}
+
+type exprBinary struct {
+	op          rune
+	left, right exprNode
+}
+
+type exprSqrt struct{ operand exprNode }
+
+func (n exprNumber) eval(_ map[string]*big.Float, _ *exprState) (*big.Float, error) {
+	return new(big.Float).SetPrec(exprPrec).Set(n.value), nil
+}
+
+func (n exprVariable) eval(env map[string]*big.Float, _ *exprState) (*big.Float, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, errExprNotConstant
+	}
+	return new(big.Float).SetPrec(exprPrec).Set(v), nil
+}
+
+func (exprConstPi) eval(_ map[string]*big.Float, _ *exprState) (*big.Float, error) {
+	pi, _, err := big.ParseFloat(piDigits, 10, exprPrec, big.ToNearestEven)
+	return pi, err
+}
+
+func (n exprUnary) eval(env map[string]*big.Float, st *exprState) (*big.Float, error) {
+	v, err := n.operand.eval(env, st)
+	if err != nil {
+		return nil, err
+	}
+	if n.neg {
+		v.Neg(v)
+	}
+	return v, nil
+}
+
+func (n exprSqrt) eval(env map[string]*big.Float, st *exprState) (*big.Float, error) {
+	v, err := n.operand.eval(env, st)
+	if err != nil {
+		return nil, err
+	}
+	if v.Sign() < 0 {
+		return nil, errors.New("expression: square root of a negative number")
+	}
+	return new(big.Float).SetPrec(exprPrec).Sqrt(v), nil
+}
+
+func (n exprBinary) eval(env map[string]*big.Float, st *exprState) (*big.Float, error) {
+	l, err := n.left.eval(env, st)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env, st)
+	if err != nil {
+		return nil, err
+	}
+	result := new(big.Float).SetPrec(exprPrec)
+	switch n.op {
+	case '+':
+		return exprFinite(result.Add(l, r))
+	case '-':
+		return exprFinite(result.Sub(l, r))
+	case '*':
+		return exprFinite(result.Mul(l, r))
+	case '/':
+		if r.Sign() == 0 {
+			return nil, errors.New("expression: division by zero")
+		}
+		return exprFinite(result.Quo(l, r))
+	case '^':
+		return exprPow(l, r, st)
+	}
+	return nil, fmt.Errorf("expression: unknown operator %q", n.op)
+}
+
+// exprPow は累乗を計算する。整数の指数は繰り返し二乗法で正確に、1/2 は Sqrt で、それ以外は float64 で近似する。
+func exprPow(base, exponent *big.Float, st *exprState) (*big.Float, error) {
+	if exponent.IsInt() {
+		n, acc := exponent.Int64()
+		if acc == big.Exact && n >= -exprMaxIntExponent && n <= exprMaxIntExponent {
+			if n < 0 && base.Sign() == 0 {
+				return nil, errors.New("expression: division by zero")
+			}
+			result := new(big.Float).SetPrec(exprPrec).SetInt64(1)
+			b := new(big.Float).SetPrec(exprPrec).Set(base)
+			for k := absInt64(n); k > 0; k >>= 1 {
+				if k&1 == 1 {
+					result.Mul(result, b)
+				}
+				b.Mul(b, b)
+			}
+			if result.IsInf() {
+				return nil, errExprOverflow
+			}
+			if n < 0 {
+				result.Quo(new(big.Float).SetPrec(exprPrec).SetInt64(1), result)
+			}
+			return result, nil
+		}
+	}
+	if exponent.Cmp(big.NewFloat(0.5)) == 0 {
+		if base.Sign() < 0 {
+			return nil, errors.New("expression: square root of a negative number")
+		}
+		return new(big.Float).SetPrec(exprPrec).Sqrt(base), nil
+	}
+
+	b, _ := base.Float64()
+	e, _ := exponent.Float64()
+	v := math.Pow(b, e)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, errors.New("expression: power is not a finite real number")
+	}
+	st.approx = true
+	return new(big.Float).SetPrec(exprPrec).SetFloat64(v), nil
+}
+
+// exprFinite は演算結果が ±Inf なら errExprOverflow を返す。
+func exprFinite(v *big.Float) (*big.Float, error) {
+	if v.IsInf() {
+		return nil, errExprOverflow
+	}
+	return v, nil
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// exprToken は字句解析の結果。kind は 'n'（数値）・'i'（識別子）・演算子や括弧そのもの・0（終端）のいずれか。
+type exprToken struct {
+	kind rune
+	text string
+}
+
+// normalizeExprRune は全角や数学記号の表記ゆれを ASCII の演算子へ寄せる。
+func normalizeExprRune(r rune) rune {
+	switch {
+	case r >= '！' && r <= '～':
+		return r - 0xFEE0
+	case r == '−' || r == '－' || r == '–':
+		return '-'
+	case r == '×' || r == '·' || r == '⋅' || r == '∙':
+		return '*'
+	case r == '÷' || r == '∕' || r == '⁄':
+		return '/'
+	}
+	return r
+}
+
+// tokenizeExpr は式を字句に分解する。² と ³ は "^2" "^3" に展開する。
+func tokenizeExpr(s string) ([]exprToken, error) {
+	runes := []rune(s)
+	var tokens []exprToken
+	for i := 0; i < len(runes); {
+		r := normalizeExprRune(runes[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r >= '0' && r <= '9' || r == '.':
+			start := i
+			for i < len(runes) {
+				c := normalizeExprRune(runes[i])
+				if !(c >= '0' && c <= '9' || c == '.') {
+					break
+				}
+				i++
+			}
+			text := strings.Map(normalizeExprRune, string(runes[start:i]))
+			tokens = append(tokens, exprToken{kind: 'n', text: text})
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			start := i
+			for i < len(runes) {
+				c := normalizeExprRune(runes[i])
+				if !(c < unicode.MaxASCII && unicode.IsLetter(c)) {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: 'i', text: strings.ToLower(strings.Map(normalizeExprRune, string(runes[start:i])))})
+		case r == 'π':
+			tokens = append(tokens, exprToken{kind: 'i', text: "pi"})
+			i++
+		case r == '²' || r == '³':
+			exp := "2"
+			if r == '³' {
+				exp = "3"
+			}
+			tokens = append(tokens, exprToken{kind: '^'}, exprToken{kind: 'n', text: exp})
+			i++
+		case strings.ContainsRune("+-*/^()√", r):
+			tokens = append(tokens, exprToken{kind: r})
+			i++
+		default:
+			return nil, fmt.Errorf("expression: unexpected character %q", runes[i])
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser は再帰下降で式を構文木にする。
+//
+//	expr    := term (('+' | '-') term)*
+//	term    := unary (('*' | '/' | 暗黙の積) unary)*
+//	unary   := ('+' | '-') unary | power
+//	power   := primary ('^' unary)?
+//	primary := 数値 | 識別子 | π | '√' primary | sqrt '(' expr ')' | '(' expr ')'
+//
+// "2√3" "2π" "3x" "(x+1)(x-1)" のように並べて書いた項は積として扱う。ただし数値同士（"2 3"）は積にしない。
+type exprParser struct {
+	tokens    []exprToken
+	pos       int
+	variables map[string]bool
+}
+
+// parseExpr は式を解析する。variables に無い識別子（関数・定数を除く）はエラーにする。
+func parseExpr(s string, variables map[string]bool) (exprNode, error) {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("expression: empty")
+	}
+	p := &exprParser{tokens: tokens, variables: variables}
+	node, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != 0 {
+		return nil, fmt.Errorf("expression: unexpected token %q", p.peek().display())
+	}
+	return node, nil
+}
+
+func (t exprToken) display() string {
+	if t.text != "" {
+		return t.text
+	}
+	return string(t.kind)
+}
+
+func (p *exprParser) peek() exprToken {
+	if p.pos >= len(p.tokens) {
+		return exprToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().kind
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == '*' || tok.kind == '/':
+			p.next()
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			left = exprBinary{op: tok.kind, left: left, right: right}
+		case p.startsImplicitFactor(tok):
+			right, err := p.parsePower()
+			if err != nil {
+				return nil, err
+			}
+			left = exprBinary{op: '*', left: left, right: right}
+		default:
+			return left, nil
+		}
+	}
+}
+
+// startsImplicitFactor は直前の項との間に演算子の無い因子が始まるかを判定する。
+func (p *exprParser) startsImplicitFactor(tok exprToken) bool {
+	switch tok.kind {
+	case 'i', '(', '√':
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	switch p.peek().kind {
+	case '-':
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{neg: true, operand: operand}, nil
+	case '+':
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePower() (exprNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != '^' {
+		return base, nil
+	}
+	p.next()
+	// 指数は右結合（2^3^2 = 2^9）で、"2^-1" のような符号付きも受け付ける。
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return exprBinary{op: '^', left: base, right: exponent}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case 'n':
+		v, _, err := big.ParseFloat(tok.text, 10, exprPrec, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("expression: invalid number %q", tok.text)
+		}
+		return exprNumber{value: v}, nil
+	case 'i':
+		switch {
+		case exprConstants[tok.text]:
+			return exprConstPi{}, nil
+		case exprFunctions[tok.text]:
+			if p.peek().kind != '(' {
+				return nil, fmt.Errorf("expression: %s requires parentheses", tok.text)
+			}
+			operand, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return exprSqrt{operand: operand}, nil
+		case p.variables[tok.text]:
+			return exprVariable{name: tok.text}, nil
+		}
+		return nil, fmt.Errorf("expression: unknown identifier %q", tok.text)
+	case '√':
+		operand, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return exprSqrt{operand: operand}, nil
+	case '(':
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != ')' {
+			return nil, errors.New("expression: missing closing parenthesis")
+		}
+		return inner, nil
+	case 0:
+		return nil, errors.New("expression: unexpected end")
+	}
+	return nil, fmt.Errorf("expression: unexpected token %q", tok.display())
+}
+
+// exprVariables は正解の式に現れる識別子のうち、関数・定数以外を変数として返す。
+func exprVariables(s string) map[string]bool {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil
+	}
+	vars := make(map[string]bool)
+	for _, t := range tokens {
+		if t.kind == 'i' && !exprFunctions[t.text] && !exprConstants[t.text] {
+			vars[t.text] = true
+		}
+	}
+	return vars
+}
+
+// expressionCandidates は最終回答部分から式として読めそうな区間を、後ろに現れたものから順に返す。
+// 日本語や "=" などで区切り、英字は関数名・定数名・正解の変数名だけを式の一部として認める。
+func expressionCandidates(segment string, variables map[string]bool) []string {
+	var runs []string
+	var current strings.Builder
+	flush := func() {
+		run := strings.TrimRight(strings.TrimSpace(current.String()), ".")
+		if strings.ContainsAny(run, "0123456789０１２３４５６７８９π") || containsIdentifier(run) {
+			runs = append(runs, run)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(segment)
+	for i := 0; i < len(runes); {
+		r := normalizeExprRune(runes[i])
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			start := i
+			for i < len(runes) {
+				c := normalizeExprRune(runes[i])
+				if !(c < unicode.MaxASCII && unicode.IsLetter(c)) {
+					break
+				}
+				i++
+			}
+			word := strings.ToLower(strings.Map(normalizeExprRune, string(runes[start:i])))
+			if exprFunctions[word] || exprConstants[word] || variables[word] {
+				current.WriteString(word)
+			} else {
+				flush()
+			}
+			continue
+		}
+		if unicode.IsSpace(r) || r >= '0' && r <= '9' || strings.ContainsRune(".+-*/^()√π²³", r) {
+			current.WriteRune(runes[i])
+		} else {
+			flush()
+		}
+		i++
+	}
+	flush()
+
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs
+}
+
+func containsIdentifier(s string) bool {
+	for _, r := range s {
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateExpression は正解の式と同じ値（変数を含む場合は同じ関数）になる式が回答に書かれているかを採点する。
+// 定数式は両者の値を精度 exprPrec で求めて scoreNumeric に渡すため、"3.46" のような近似値も従来の曲線で部分点になる。
+func evaluateExpression(answerText, correct string, opts Options) (score int, extracted *float64, mode string, detail map[string]any) {
+	segment := FinalAnswerSegment(answerText)
+	detail = map[string]any{
+		"answer_raw":          answerText,
+		"answer_segment":      segment,
+		"correct_expression":  correct,
+		"expression_strategy": fmt.Sprintf("big.Float（%d bit）で評価し、変数は代入点で比較", exprPrec),
+	}
+
+	variables := exprVariables(correct)
+	correctNode, err := parseExpr(correct, variables)
+	if err != nil {
+		mode = "invalid_spec"
+		detail["mode_reason"] = "正解の式を解析できない"
+		detail["parse_error"] = err.Error()
+		return
+	}
+
+	var answerNode exprNode
+	for _, candidate := range expressionCandidates(segment, variables) {
+		if node, err := parseExpr(candidate, variables); err == nil {
+			answerNode = node
+			detail["answer_expression"] = candidate
+			break
+		}
+	}
+	if answerNode == nil {
+		mode = "no_expression"
+		detail["mode_reason"] = "回答から式を読み取れない"
+		return
+	}
+
+	if len(variables) > 0 {
+		score, mode = compareSymbolic(correctNode, answerNode, variables, detail)
+		return
+	}
+
+	st := &exprState{}
+	correctVal, err := correctNode.eval(nil, st)
+	if err != nil {
+		mode = "invalid_spec"
+		detail["mode_reason"] = "正解の式を計算できない"
+		detail["parse_error"] = err.Error()
+		return
+	}
+	answerVal, err := answerNode.eval(nil, st)
+	if err != nil {
+		mode = "expression_error"
+		detail["mode_reason"] = "回答の式を計算できない"
+		detail["eval_error"] = err.Error()
+		return
+	}
+
+	correctF, _ := correctVal.Float64()
+	answerF, _ := answerVal.Float64()
+	detail["correct_numeric"] = correctF
+	detail["extracted_numeric"] = answerF
+	detail["expression_precision"] = "big"
+	if st.approx {
+		detail["expression_precision"] = "float64"
+	}
+	valueCopy := answerF
+	extracted = &valueCopy
+
+	correctStr := correctVal.Text('g', 60)
+	answerStr := answerVal.Text('g', 60)
+	if exprClose(correctVal, answerVal, st.approx) {
+		// 計算順序による末尾の桁のずれは誤差として扱わない。
+		answerStr = correctStr
+	}
+	detail["extracted_text"] = answerStr
+	score, mode = scoreNumeric(correctStr, correctF, answerStr, answerF, opts.Scoring.withDefaults(), detail)
+	return
+}
+
+// compareSymbolic は変数を含む 2 つの式を、exprSamplePoints の値を代入して比較する。
+// 定義域外（0 除算・負の平方根）になる代入点は飛ばし、計算できた点がすべて一致すれば同値とみなす。
+func compareSymbolic(correctNode, answerNode exprNode, variables map[string]bool, detail map[string]any) (score int, mode string) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	detail["variables"] = names
+
+	var checked int
+	var mismatches []map[string]any
+	for i := range exprSamplePoints {
+		env := make(map[string]*big.Float, len(names))
+		point := make(map[string]float64, len(names))
+		for k, name := range names {
+			// 変数ごとに別の代入値を使い、x と y を取り違えた式が一致しないようにする。
+			v := exprSamplePoints[(i+k)%len(exprSamplePoints)]
+			env[name] = new(big.Float).SetPrec(exprPrec).SetFloat64(v)
+			point[name] = v
+		}
+		st := &exprState{}
+		want, err := correctNode.eval(env, st)
+		if err != nil {
+			continue
+		}
+		got, err := answerNode.eval(env, st)
+		if err != nil {
+			continue
+		}
+		checked++
+		if !exprClose(want, got, st.approx) {
+			wantF, _ := want.Float64()
+			gotF, _ := got.Float64()
+			mismatches = append(mismatches, map[string]any{"point": point, "expected": wantF, "got": gotF})
+		}
+	}
+	detail["sample_points_checked"] = checked
+
+	switch {
+	case checked < exprMinSamplePoints:
+		mode = "expression_error"
+		detail["mode_reason"] = "比較できる代入点が足りない"
+	case len(mismatches) == 0:
+		score = 100
+		mode = "expression_equivalent"
+		detail["mode_reason"] = "すべての代入点で正解の式と値が一致"
+		detail["normalized_score"] = score
+	default:
+		mode = "expression_mismatch"
+		detail["sample_mismatches"] = mismatches
+		detail["mode_reason"] = "代入点で正解の式と値が一致しない"
+	}
+	return
+}
+
+// exprClose は a と b が許容誤差内で等しいかを判定する。許容誤差は max(|a|, 1) に対する相対値。
+func exprClose(a, b *big.Float, approx bool) bool {
+	tol := exprExactTolerance
+	if approx {
+		tol = exprApproxTolerance
+	}
+	diff := new(big.Float).SetPrec(exprPrec).Sub(a, b)
+	diff.Abs(diff)
+	scale := new(big.Float).SetPrec(exprPrec).Abs(a)
+	if scale.Cmp(big.NewFloat(1)) < 0 {
+		scale.SetInt64(1)
+	}
+	limit := new(big.Float).SetPrec(exprPrec).Mul(scale, big.NewFloat(tol))
+	return diff.Cmp(limit) <= 0
+}
@@ -0,0 +1,176 @@
+// expr_test.go は数式の回答（√・π・累乗・分数・変数）の解析と、値や同値性に基づく採点を確認する単体テスト。
+package eval
+
+import (
+	"testing"
+)
+
+// TestEvaluateExpression は正解の式と回答の式の組み合わせごとにスコアとモードを検証する。
+func TestEvaluateExpression(t *testing.T) {
+	testcases := []struct {
+		name        string
+		answer      string
+		correct     string
+		expectScore int
+		expectMode  string
+	}{
+		{
+			name:        "SameForm",
+			answer:      "最終回答: 2√3",
+			correct:     "2√3",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "DifferentFormSameValue",
+			answer:      "最終回答: √12",
+			correct:     "2√3",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "SqrtFunctionAndFraction",
+			answer:      "答えは sqrt(27)/3 です。",
+			correct:     "√3",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "PiFraction",
+			answer:      "最終回答：π/2",
+			correct:     "pi/2",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "FullwidthAndUnicodeOperators",
+			answer:      "最終回答: （１＋√５）÷２",
+			correct:     "(1+√5)/2",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "DecimalApproximationUsesIntegerCurve",
+			answer:      "最終回答: 3.5",
+			correct:     "2√3",
+			expectScore: computeIntegerScaleScore(0.0358983848622454, 3.4641016151377544),
+			expectMode:  "numeric_score_integer",
+		},
+		{
+			name:        "WrongValue",
+			answer:      "最終回答: 3√2",
+			correct:     "2√3",
+			expectScore: computeIntegerScaleScore(0.7785902855, 3.4641016151377544),
+			expectMode:  "numeric_score_integer",
+		},
+		{
+			name:        "SuperscriptPower",
+			answer:      "最終回答: 2³ − 1",
+			correct:     "7",
+			expectScore: 100,
+			expectMode:  "numeric_exact",
+		},
+		{
+			name:        "VariableExpanded",
+			answer:      "展開すると\n最終回答: x^2 + 2x + 1",
+			correct:     "(x+1)^2",
+			expectScore: 100,
+			expectMode:  "expression_equivalent",
+		},
+		{
+			name:        "VariableImplicitProduct",
+			answer:      "最終回答: (x-1)(x+1)",
+			correct:     "x²-1",
+			expectScore: 100,
+			expectMode:  "expression_equivalent",
+		},
+		{
+			name:        "VariableMismatch",
+			answer:      "最終回答: x^2 + 1",
+			correct:     "(x+1)^2",
+			expectScore: 0,
+			expectMode:  "expression_mismatch",
+		},
+		{
+			name:        "UnknownVariableIsNotAnExpression",
+			answer:      "最終回答: y",
+			correct:     "x+1",
+			expectScore: 0,
+			expectMode:  "no_expression",
+		},
+		{
+			name:        "DivisionByZero",
+			answer:      "最終回答: 1/0",
+			correct:     "2",
+			expectScore: 0,
+			expectMode:  "expression_error",
+		},
+		{
+			// big.Float でも表せない大きさになった値同士の引き算で panic しないこと。
+			name:        "OverflowIsAnError",
+			answer:      "最終回答: ((10^1000)^1000)^1000 - ((10^1000)^1000)^1000",
+			correct:     "2",
+			expectScore: 0,
+			expectMode:  "expression_error",
+		},
+		{
+			name:        "NoExpression",
+			answer:      "わかりません",
+			correct:     "2√3",
+			expectScore: 0,
+			expectMode:  "no_expression",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, _, mode, detail := EvaluateSpec(tc.answer, AnswerSpec{Type: SpecExpression, Value: tc.correct}, Options{})
+			if score != tc.expectScore {
+				t.Fatalf("score mismatch: got %d, want %d (detail=%v)", score, tc.expectScore, detail)
+			}
+			if mode != tc.expectMode {
+				t.Fatalf("mode mismatch: got %s, want %s (detail=%v)", mode, tc.expectMode, detail)
+			}
+		})
+	}
+}
+
+// TestParseExpr は演算子の優先順位・結合性と、不正な式の検出を確認する。
+func TestParseExpr(t *testing.T) {
+	testcases := []struct {
+		expr    string
+		expect  float64
+		wantErr bool
+	}{
+		{expr: "1 + 2 * 3", expect: 7},
+		{expr: "-2^2", expect: -4},
+		{expr: "2^3^2", expect: 512},
+		{expr: "2^-1", expect: 0.5},
+		{expr: "4^0.5", expect: 2},
+		{expr: "6/2(1+2)", expect: 9},
+		{expr: "(1+2", wantErr: true},
+		{expr: "2 3", wantErr: true},
+		{expr: "x + 1", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		node, err := parseExpr(tc.expr, nil)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("parseExpr(%q) error = %v, wantErr %v", tc.expr, err, tc.wantErr)
+		}
+		if tc.wantErr {
+			continue
+		}
+		v, err := node.eval(nil, &exprState{})
+		if err != nil {
+			t.Fatalf("eval(%q) error = %v", tc.expr, err)
+		}
+		if got, _ := v.Float64(); got != tc.expect {
+			t.Errorf("eval(%q) = %v, want %v", tc.expr, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// golf.go は「プロンプトゴルフ」モードの採点をまとめたファイル。
+// 回答の正確さ（通常の採点結果）にプロンプトの短さを掛け合わせ、短く的確な指示ほど高いスコアになるようにする。
+// 長さの数え方（文字数・トークン数）と曲線は問題のレベルごとに設定できる。
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GolfMeasure はプロンプトの長さの数え方。
+type GolfMeasure string
+
+const (
+	// GolfMeasureChars は文字数（rune 数）で数える。
+	GolfMeasureChars GolfMeasure = "chars"
+	// GolfMeasureTokens は EstimateTokens による推定トークン数で数える。
+	GolfMeasureTokens GolfMeasure = "tokens"
+)
+
+// GolfCurve はレベルごとの長さスコアの曲線。
+//
+//	長さ <= Target          : 長さスコア 100
+//	長さ  = Target + Scale  : 長さスコア 50
+//	それ以上                 : 100 / (1 + ((長さ - Target) / Scale)^p) で減衰
+//
+// 最終的なゴルフスコアは 正確さ × (CorrectnessWeight + (1 - CorrectnessWeight) × 長さスコア / 100)。
+// 不正解（正確さ 0）はどれだけ短くても 0 点になる。
+type GolfCurve struct {
+	Measure           GolfMeasure `json:"measure"`
+	Target            int         `json:"target"`
+	Scale             int         `json:"scale"`
+	CorrectnessWeight float64     `json:"correctness_weight"`
+}
+
+// defaultGolfCurves はレベルごとの既定の曲線。難しい問題ほど長めのプロンプトを許容する。
+var defaultGolfCurves = map[int]GolfCurve{
+	1: {Measure: GolfMeasureChars, Target: 20, Scale: 30, CorrectnessWeight: 0.5},
+	2: {Measure: GolfMeasureChars, Target: 30, Scale: 40, CorrectnessWeight: 0.5},
+	3: {Measure: GolfMeasureChars, Target: 40, Scale: 60, CorrectnessWeight: 0.5},
+	4: {Measure: GolfMeasureChars, Target: 60, Scale: 80, CorrectnessWeight: 0.5},
+	5: {Measure: GolfMeasureChars, Target: 80, Scale: 100, CorrectnessWeight: 0.5},
+}
+
+// fallbackGolfCurve は表に無いレベルで使う曲線。
+var fallbackGolfCurve = GolfCurve{Measure: GolfMeasureChars, Target: 40, Scale: 60, CorrectnessWeight: 0.5}
+
+// GolfCurves はレベルごとの曲線の設定。
+type GolfCurves map[int]GolfCurve
+
+// DefaultGolfCurves は既定の曲線を返す。
+func DefaultGolfCurves() GolfCurves {
+	curves := make(GolfCurves, len(defaultGolfCurves))
+	for level, curve := range defaultGolfCurves {
+		curves[level] = curve
+	}
+	return curves
+}
+
+// ParseGolfCurves は {"1": {"measure": "tokens", "target": 10, "scale": 15}} 形式の JSON を読み、既定の曲線に上書きする。
+// 指定しなかった項目は既定値のまま残る。環境変数 GOLF_CURVES からの設定に使う。
+func ParseGolfCurves(raw string) (GolfCurves, error) {
+	curves := DefaultGolfCurves()
+	if raw == "" {
+		return curves, nil
+	}
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal golf curves: %w", err)
+	}
+	for key, body := range overrides {
+		level, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("golf curves: invalid level %q", key)
+		}
+		curve := curves.For(level)
+		if err := json.Unmarshal(body, &curve); err != nil {
+			return nil, fmt.Errorf("golf curves: level %d: %w", level, err)
+		}
+		if err := curve.validate(); err != nil {
+			return nil, fmt.Errorf("golf curves: level %d: %w", level, err)
+		}
+		curves[level] = curve
+	}
+	return curves, nil
+}
+
+// For はレベルに対応する曲線を返す。設定が無いレベルは fallbackGolfCurve を使う。
+func (c GolfCurves) For(level int) GolfCurve {
+	if curve, ok := c[level]; ok {
+		return curve
+	}
+	return fallbackGolfCurve
+}
+
+func (c GolfCurve) validate() error {
+	switch c.Measure {
+	case GolfMeasureChars, GolfMeasureTokens:
+	default:
+		return fmt.Errorf("unknown measure %q", c.Measure)
+	}
+	if c.Target < 0 || c.Scale <= 0 {
+		return fmt.Errorf("target must be >= 0 and scale must be > 0")
+	}
+	if c.CorrectnessWeight < 0 || c.CorrectnessWeight > 1 {
+		return fmt.Errorf("correctness_weight must be between 0 and 1")
+	}
+	return nil
+}
+
+// EstimateTokens はプロンプトのトークン数を推定する。
+// 英数字の連続は 4 文字で 1 トークン、日本語などそれ以外の文字は 1 文字 1 トークン、空白は数えない。
+// 実際のトークナイザとは一致しないが、モデルに依存しない安定した目安として使う。
+func EstimateTokens(prompt string) int {
+	tokens := 0
+	asciiRun := 0
+	flush := func() {
+		tokens += (asciiRun + 3) / 4
+		asciiRun = 0
+	}
+	for _, r := range prompt {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			asciiRun++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// GolfScore は正確さのスコアとプロンプトからゴルフスコアを計算し、内訳を返す。
+func GolfScore(correctness int, prompt string, curve GolfCurve) (int, map[string]any) {
+	chars := utf8.RuneCountInString(prompt)
+	tokens := EstimateTokens(prompt)
+	length := chars
+	if curve.Measure == GolfMeasureTokens {
+		length = tokens
+	}
+
+	lengthScore := 100.0
+	if over := length - curve.Target; over > 0 {
+		lengthScore = 100.0 / (1.0 + math.Pow(float64(over)/float64(curve.Scale), p))
+	}
+	factor := curve.CorrectnessWeight + (1-curve.CorrectnessWeight)*lengthScore/100
+	golf := int(math.Round(float64(correctness) * factor))
+
+	breakdown := map[string]any{
+		"correctness":        correctness,
+		"prompt_chars":       chars,
+		"prompt_tokens":      tokens,
+		"measure":            string(curve.Measure),
+		"measured_length":    length,
+		"target":             curve.Target,
+		"scale":              curve.Scale,
+		"correctness_weight": curve.CorrectnessWeight,
+		"length_score":       int(math.Round(lengthScore)),
+		"golf_score":         golf,
+	}
+	return golf, breakdown
+}
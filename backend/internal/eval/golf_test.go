@@ -0,0 +1,90 @@
+// golf_test.go はプロンプトゴルフの長さスコア・正確さとの合成・曲線設定の読み込みを確認する単体テスト。
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGolfScore は長さと正確さの組み合わせごとのゴルフスコアを検証する。
+func TestGolfScore(t *testing.T) {
+	curve := GolfCurve{Measure: GolfMeasureChars, Target: 10, Scale: 10, CorrectnessWeight: 0.5}
+
+	testcases := []struct {
+		name        string
+		correctness int
+		prompt      string
+		curve       GolfCurve
+		expect      int
+	}{
+		{name: "ShortAndCorrect", correctness: 100, prompt: "数えて", curve: curve, expect: 100},
+		{name: "AtTarget", correctness: 100, prompt: strings.Repeat("あ", 10), curve: curve, expect: 100},
+		{name: "OneScaleOver", correctness: 100, prompt: strings.Repeat("あ", 20), curve: curve, expect: 75},
+		{name: "VeryLongKeepsCorrectnessFloor", correctness: 100, prompt: strings.Repeat("あ", 1000), curve: curve, expect: 50},
+		{name: "WrongAnswerIsZero", correctness: 0, prompt: "短い", curve: curve, expect: 0},
+		{name: "PartialCorrectness", correctness: 60, prompt: strings.Repeat("a", 20), curve: curve, expect: 45},
+		{
+			name:        "TokensMeasure",
+			correctness: 100,
+			prompt:      "count the letters carefully",
+			curve:       GolfCurve{Measure: GolfMeasureTokens, Target: 8, Scale: 4, CorrectnessWeight: 0.5},
+			expect:      100,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, breakdown := GolfScore(tc.correctness, tc.prompt, tc.curve)
+			if got != tc.expect {
+				t.Fatalf("GolfScore() = %d, want %d (breakdown=%v)", got, tc.expect, breakdown)
+			}
+			if breakdown["golf_score"] != got {
+				t.Fatalf("breakdown golf_score mismatch: %v", breakdown)
+			}
+		})
+	}
+}
+
+// TestEstimateTokens は英数字と日本語の混在したプロンプトのトークン数の目安を確認する。
+func TestEstimateTokens(t *testing.T) {
+	testcases := map[string]int{
+		"":             0,
+		"count":        2,
+		"step by step": 3,
+		"一文字ずつ数えて":     8,
+		"rを1文字ずつ数える":   10,
+		"Think 手順で。":   6,
+	}
+	for prompt, want := range testcases {
+		if got := EstimateTokens(prompt); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", prompt, got, want)
+		}
+	}
+}
+
+// TestParseGolfCurves は設定 JSON で一部のレベルだけ上書きできることと、不正な設定の検出を確認する。
+func TestParseGolfCurves(t *testing.T) {
+	curves, err := ParseGolfCurves(`{"2": {"measure": "tokens", "target": 12}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := curves.For(2)
+	if got.Measure != GolfMeasureTokens || got.Target != 12 || got.Scale != defaultGolfCurves[2].Scale {
+		t.Fatalf("unexpected curve for level 2: %+v", got)
+	}
+	if curves.For(1) != defaultGolfCurves[1] {
+		t.Fatalf("level 1 should keep default curve: %+v", curves.For(1))
+	}
+	if curves.For(99) != fallbackGolfCurve {
+		t.Fatalf("unknown level should use fallback curve: %+v", curves.For(99))
+	}
+
+	for _, raw := range []string{`{"x": {}}`, `{"1": {"measure": "words"}}`, `{"1": {"scale": 0}}`, `{`} {
+		if _, err := ParseGolfCurves(raw); err == nil {
+			t.Errorf("expected error for %s", raw)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// judge_fake.go は外部 API を呼ばずに決定的な判定を返す FakeJudge をまとめたファイル。テストやローカル開発で LLMJudge の代わりに差し込む。
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FakeJudge は参考解答との文字列比較だけで判定する Judge 実装。
+//   - 正規化した回答が参考解答を含めば correct（100 点）
+//   - 参考解答の語（空白区切り）の半分以上を含めば partial（50 点）
+//   - それ以外は incorrect（0 点）
+//
+// Err を設定すると、採点者の障害を再現するためにそのエラーを返す。
+type FakeJudge struct {
+	Err error
+}
+
+// Grade は決定的な判定を返す。同じ入力には常に同じ結果になる。
+func (f FakeJudge) Grade(_ context.Context, req JudgeRequest) (Verdict, error) {
+	if f.Err != nil {
+		return Verdict{}, f.Err
+	}
+	answer := NormalizeText(req.Answer)
+	reference := NormalizeText(req.ReferenceAnswer)
+	if reference == "" {
+		return Verdict{Verdict: VerdictIncorrect, Rationale: "fake judge: 参考解答が無いため判定できない"}, nil
+	}
+	if strings.Contains(answer, reference) {
+		return Verdict{Verdict: VerdictCorrect, Score: 100, Rationale: "fake judge: 回答が参考解答を含む"}, nil
+	}
+
+	words := strings.Fields(reference)
+	hits := 0
+	for _, w := range words {
+		if strings.Contains(answer, w) {
+			hits++
+		}
+	}
+	if len(words) > 1 && hits*2 >= len(words) {
+		return Verdict{
+			Verdict:   VerdictPartial,
+			Score:     50,
+			Rationale: fmt.Sprintf("fake judge: 参考解答の語を %d/%d 含む", hits, len(words)),
+		}, nil
+	}
+	return Verdict{Verdict: VerdictIncorrect, Rationale: "fake judge: 参考解答と一致しない"}, nil
+}
@@ -0,0 +1,213 @@
+// judge_test.go は LLM 採点者の判定パース・キャッシュ・エラー時の扱いと、決定的な FakeJudge の挙動を確認する単体テスト。
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/ai"
+)
+
+// stubJudgeClient は固定のテキストを返す ai.Client。呼び出し回数を数えてキャッシュの効き具合を確認する。
+type stubJudgeClient struct {
+	text       string
+	err        error
+	calls      atomic.Int32
+	lastPrompt atomic.Value
+}
+
+func (s *stubJudgeClient) Generate(ctx context.Context, prompt string) (ai.Response, error) {
+	text, err := s.GenerateAnswer(ctx, prompt)
+	return ai.Response{RawText: text}, err
+}
+
+func (s *stubJudgeClient) GenerateAnswer(_ context.Context, prompt string) (string, error) {
+	s.calls.Add(1)
+	s.lastPrompt.Store(prompt)
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.text, nil
+}
+
+// TestParseVerdict はモデル出力の揺れ（コードフェンス・score 欠落・範囲外）に対する読み取りを確認する。
+func TestParseVerdict(t *testing.T) {
+	testcases := []struct {
+		name          string
+		raw           string
+		expectVerdict string
+		expectScore   int
+		wantErr       bool
+	}{
+		{
+			name:          "PlainJSON",
+			raw:           `{"verdict":"correct","score":95,"rationale":"要点を満たす"}`,
+			expectVerdict: VerdictCorrect,
+			expectScore:   95,
+		},
+		{
+			name:          "CodeFenceWithProse",
+			raw:           "採点結果です。\n```json\n{\"verdict\": \"Partial\", \"score\": 60.4, \"rationale\": \"一部不足\"}\n```",
+			expectVerdict: VerdictPartial,
+			expectScore:   60,
+		},
+		{
+			name:          "MissingScoreDerivedFromVerdict",
+			raw:           `{"verdict":"partial","rationale":"半分"}`,
+			expectVerdict: VerdictPartial,
+			expectScore:   50,
+		},
+		{
+			name:          "ScoreClamped",
+			raw:           `{"verdict":"correct","score":150}`,
+			expectVerdict: VerdictCorrect,
+			expectScore:   100,
+		},
+		{name: "NoJSON", raw: "正解です", wantErr: true},
+		{name: "UnknownVerdict", raw: `{"verdict":"maybe","score":10}`, wantErr: true},
+		{name: "BrokenJSON", raw: `{"verdict": }`, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v, err := parseVerdict(tc.raw)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseVerdict() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if v.Verdict != tc.expectVerdict || v.Score != tc.expectScore {
+				t.Fatalf("unexpected verdict: got %+v, want %s/%d", v, tc.expectVerdict, tc.expectScore)
+			}
+		})
+	}
+}
+
+// TestLLMJudge_CachesPerQuestionAndNormalizedAnswer は正規化後に同じ回答なら 2 回目以降モデルを呼ばず、問題や採点基準が変われば呼び直すことを確認する。
+func TestLLMJudge_CachesPerQuestionAndNormalizedAnswer(t *testing.T) {
+	client := &stubJudgeClient{text: `{"verdict":"correct","score":100,"rationale":"ok"}`}
+	judge := NewLLMJudge(client)
+	ctx := context.Background()
+
+	req := JudgeRequest{QuestionID: 1, Rubric: "首都を答えていれば正解", Answer: "東京です。"}
+	if _, err := judge.Grade(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Answer = "　東京"
+	if _, err := judge.Grade(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.calls.Load(); got != 1 {
+		t.Fatalf("expected 1 model call thanks to cache, got %d", got)
+	}
+
+	// 別の問題なら同じ回答でもキャッシュは効かない。
+	req.QuestionID = 2
+	if _, err := judge.Grade(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.calls.Load(); got != 2 {
+		t.Fatalf("expected 2 model calls for a different question, got %d", got)
+	}
+
+	// 採点基準や参考解答を編集した後は、古い基準での判定を使わない。
+	req.Rubric = "首都を漢字で答えていれば正解"
+	if _, err := judge.Grade(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.ReferenceAnswer = "東京"
+	if _, err := judge.Grade(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.calls.Load(); got != 4 {
+		t.Fatalf("expected 4 model calls after editing the rubric and reference answer, got %d", got)
+	}
+
+	prompt, _ := client.lastPrompt.Load().(string)
+	if !strings.Contains(prompt, "首都を漢字で答えていれば正解") {
+		t.Fatal("expected rubric to be included in judge prompt")
+	}
+}
+
+// TestLLMJudge_ErrorIsNotCached はモデル呼び出しの失敗をキャッシュしないことを確認する。
+func TestLLMJudge_ErrorIsNotCached(t *testing.T) {
+	client := &stubJudgeClient{err: errors.New("boom")}
+	judge := NewLLMJudge(client)
+	req := JudgeRequest{QuestionID: 1, Answer: "x"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := judge.Grade(context.Background(), req); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if got := client.calls.Load(); got != 2 {
+		t.Fatalf("expected failures not to be cached, got %d calls", got)
+	}
+}
+
+// TestEvaluateWithJudge は判定が detail に記録されることと、採点者の不在・障害をエラーとして返すことを確認する。
+func TestEvaluateWithJudge(t *testing.T) {
+	ctx := context.Background()
+	req := JudgeRequest{
+		QuestionID:      1,
+		Rubric:          "日本の首都",
+		ReferenceAnswer: "東京",
+		Answer:          "日本の首都について考えます。\n最終回答: 東京",
+	}
+
+	score, mode, detail, err := EvaluateWithJudge(ctx, FakeJudge{}, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if score != 100 || mode != "judge_correct" {
+		t.Fatalf("unexpected result: score=%d mode=%s", score, mode)
+	}
+	if detail["judge_rationale"] == "" || detail["answer_segment"] != "東京" {
+		t.Fatalf("unexpected detail: %v", detail)
+	}
+
+	if _, mode, _, err := EvaluateWithJudge(ctx, nil, req); err == nil || mode != "judge_unavailable" {
+		t.Fatalf("expected judge_unavailable error, got mode=%s err=%v", mode, err)
+	}
+	if _, mode, detail, err := EvaluateWithJudge(ctx, FakeJudge{Err: errors.New("down")}, req); err == nil || mode != "judge_error" || detail["judge_error"] != "down" {
+		t.Fatalf("expected judge_error, got mode=%s err=%v", mode, err)
+	}
+}
+
+// TestFakeJudge は FakeJudge が決定的に correct / partial / incorrect を返すことを確認する。
+func TestFakeJudge(t *testing.T) {
+	testcases := []struct {
+		answer    string
+		reference string
+		expect    string
+	}{
+		{answer: "東京", reference: "東京", expect: VerdictCorrect},
+		{answer: "it needs light and air", reference: "light and water", expect: VerdictPartial},
+		{answer: "大阪", reference: "東京", expect: VerdictIncorrect},
+		{answer: "何か", reference: "", expect: VerdictIncorrect},
+	}
+	for _, tc := range testcases {
+		v, err := FakeJudge{}.Grade(context.Background(), JudgeRequest{Answer: tc.answer, ReferenceAnswer: tc.reference})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v.Verdict != tc.expect {
+			t.Errorf("FakeJudge(%q, %q) = %s, want %s", tc.answer, tc.reference, v.Verdict, tc.expect)
+		}
+	}
+}
+
+// TestEvaluateSpec_JudgeRequired は同期評価の EvaluateSpec が judge 仕様を採点せず印を付けて返すことを確認する。
+func TestEvaluateSpec_JudgeRequired(t *testing.T) {
+	score, _, mode, _ := EvaluateSpec("何か", AnswerSpec{Type: SpecJudge}, Options{})
+	if score != 0 || mode != "judge_required" {
+		t.Fatalf("unexpected result: score=%d mode=%s", score, mode)
+	}
+}
@@ -0,0 +1,120 @@
+// scoring.go は数値の誤差からスコアを決める曲線のパラメータ（questions.scoring_params）をまとめたファイル。
+// 既定値は evaluator.go の定数と同じで、問題ごとに「高精度が必要な計算は厳しめ」「概算は緩め」のように調整できる。
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ScoringParams は数値問題の採点曲線のパラメータ。0 の項目は既定値を使う。
+//
+//	整数スケール（|正解| <= 1000）: score = 100 / (1 + (絶対誤差 / BaseError)^P)
+//	大きな数                      : score = 100 / (1 + (相対誤差 / R0)^P)、相対誤差の分母は max(|正解|, TolAbsHint)
+type ScoringParams struct {
+	R0         float64 `json:"r0,omitempty"`           // 相対誤差がこの値のとき 50 点
+	P          float64 `json:"p,omitempty"`            // 曲率。大きいほど減衰が急
+	TolAbsHint float64 `json:"tol_abs_hint,omitempty"` // 相対誤差の分母の下限
+	BaseError  float64 `json:"base_error,omitempty"`   // 整数スケールの問題で 50 点になる絶対誤差（|正解| < 1 の問題には使わない）
+}
+
+// maxScoringP は曲率の上限。大きすぎると 1 つのずれで 0 点になり、連続的な採点の意味が無くなる。
+const maxScoringP = 10
+
+// DefaultScoringParams は既定のパラメータを返す。
+func DefaultScoringParams() ScoringParams {
+	return ScoringParams{R0: r0, P: p, TolAbsHint: tolAbsHint, BaseError: integerBaseError}
+}
+
+// ParseScoringParams は questions.scoring_params 列の JSON を読み込む。NULL の場合はゼロ値（すべて既定値）を返す。
+func ParseScoringParams(raw []byte) (ScoringParams, error) {
+	var params ScoringParams
+	if len(raw) == 0 || string(raw) == "null" {
+		return params, nil
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return ScoringParams{}, fmt.Errorf("failed to unmarshal scoring_params: %w", err)
+	}
+	if err := params.Validate(); err != nil {
+		return ScoringParams{}, err
+	}
+	return params, nil
+}
+
+// IsZero はすべての項目が未設定（既定値を使う）かどうかを返す。
+func (s ScoringParams) IsZero() bool {
+	return s == ScoringParams{}
+}
+
+// Validate は設定された項目が採点に使える範囲かを確認する。問題登録時のバリデーションにも使う。
+func (s ScoringParams) Validate() error {
+	if s.R0 < 0 || s.TolAbsHint < 0 || s.BaseError < 0 {
+		return errors.New("scoring_params: r0, tol_abs_hint and base_error must not be negative")
+	}
+	if s.P < 0 || s.P > maxScoringP {
+		return fmt.Errorf("scoring_params: p must be between 0 and %d", maxScoringP)
+	}
+	return nil
+}
+
+// withDefaults は未設定の項目を既定値で埋めたパラメータを返す。
+func (s ScoringParams) withDefaults() ScoringParams {
+	def := DefaultScoringParams()
+	if s.R0 == 0 {
+		s.R0 = def.R0
+	}
+	if s.P == 0 {
+		s.P = def.P
+	}
+	if s.TolAbsHint == 0 {
+		s.TolAbsHint = def.TolAbsHint
+	}
+	if s.BaseError == 0 {
+		s.BaseError = def.BaseError
+	}
+	return s
+}
+
+// relativeError は絶対誤差と正解値から相対誤差を計算する。
+func (s ScoringParams) relativeError(absoluteDiff float64, correctVal float64) float64 {
+	denominator := math.Max(math.Abs(correctVal), s.TolAbsHint)
+	return absoluteDiff / denominator
+}
+
+// integerScore は整数スケールの問題のスコアを絶対誤差から計算する。
+// 正解が極小（絶対値1未満）の場合は、BaseError ではなく smallValueBaseError でより厳しく評価する。
+func (s ScoringParams) integerScore(absoluteDiff float64, correctVal float64) int {
+	if absoluteDiff <= 0 {
+		return 100
+	}
+	base := s.BaseError
+	if math.Abs(correctVal) < 1.0 {
+		base = smallValueBaseError
+	}
+	return logisticScore(absoluteDiff/base, s.P)
+}
+
+// relativeScore は大きな数の問題のスコアを相対誤差から計算する。
+func (s ScoringParams) relativeScore(relativeError float64) int {
+	if relativeError <= 0 {
+		return 100
+	}
+	return logisticScore(relativeError/s.R0, s.P)
+}
+
+// logisticScore は 100 / (1 + ratio^p) を 0〜100 の整数に丸める。
+func logisticScore(ratio, p float64) int {
+	raw := 100.0 / (1.0 + math.Pow(ratio, p))
+
+	// スコアを0-100の範囲に制限
+	if raw < 0 {
+		raw = 0
+	}
+	if raw > 100 {
+		raw = 100
+	}
+
+	return int(math.Round(raw))
+}
@@ -0,0 +1,59 @@
+// scoring_test.go は問題ごとの採点曲線のパラメータが数値問題のスコアに反映されることを確認する単体テスト。
+package eval
+
+import "testing"
+
+// TestEvaluateSpecWithScoringParams は同じ回答でもパラメータによってスコアが変わること、ゼロ値なら従来どおりであることを確認する。
+func TestEvaluateSpecWithScoringParams(t *testing.T) {
+	value := func(v string) AnswerSpec { return AnswerSpec{Type: SpecValue, Value: v} }
+
+	testcases := []struct {
+		name        string
+		answer      string
+		spec        AnswerSpec
+		scoring     ScoringParams
+		expectScore int
+	}{
+		{name: "DefaultsUnchanged", answer: "最終回答: 31", spec: value("32"), expectScore: computeIntegerScaleScore(1, 32)},
+		{name: "StricterBaseError", answer: "最終回答: 31", spec: value("32"), scoring: ScoringParams{BaseError: 1}, expectScore: 50},
+		{name: "LooserR0", answer: "最終回答: 11000", spec: value("10000"), scoring: ScoringParams{R0: 0.1}, expectScore: 50},
+		{name: "DefaultR0", answer: "最終回答: 11000", spec: value("10000"), expectScore: 20},
+		{name: "SteeperCurve", answer: "最終回答: 30", spec: value("32"), scoring: ScoringParams{P: 4}, expectScore: 50},
+		{name: "ExactIgnoresParams", answer: "32", spec: value("32"), scoring: ScoringParams{P: 10, BaseError: 0.1}, expectScore: 100},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, _, _, detail := EvaluateSpec(tc.answer, tc.spec, Options{Scoring: tc.scoring})
+			if score != tc.expectScore {
+				t.Fatalf("score = %d, want %d (detail=%v)", score, tc.expectScore, detail)
+			}
+		})
+	}
+}
+
+// TestParseScoringParams は JSON の読み込みと範囲外の値の検出を確認する。
+func TestParseScoringParams(t *testing.T) {
+	params, err := ParseScoringParams([]byte(`{"r0": 0.01, "p": 3}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.R0 != 0.01 || params.P != 3 || params.TolAbsHint != 0 {
+		t.Fatalf("unexpected params: %+v", params)
+	}
+	if got := params.withDefaults(); got.TolAbsHint != tolAbsHint || got.BaseError != integerBaseError {
+		t.Fatalf("defaults not filled: %+v", got)
+	}
+
+	if params, err := ParseScoringParams(nil); err != nil || !params.IsZero() {
+		t.Fatalf("NULL should be zero params: %+v, %v", params, err)
+	}
+	for _, raw := range []string{`{"r0": -1}`, `{"p": 11}`, `{"base_error": -0.5}`, `{`} {
+		if _, err := ParseScoringParams([]byte(raw)); err == nil {
+			t.Errorf("expected error for %s", raw)
+		}
+	}
+}
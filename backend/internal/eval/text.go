@@ -0,0 +1,99 @@
+// text.go は文字列の回答を比較する前の正規化（全角半角の統一・句読点や語尾の除去）と、最終回答部分の切り出しをまとめたファイル。
+package eval
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// finalAnswerMarkers は AI に出力させている「最終回答」の区切り表記。
+var finalAnswerMarkers = []string{"最終回答：", "最終回答:", "最終回答"}
+
+// trailingCopulas は日本語の回答末尾によく付く語尾。比較前に取り除く。
+var trailingCopulas = []string{"でございます", "である", "です", "だ"}
+
+// quotedPattern は「」や "" で囲まれた部分を拾う。説明文の中で答えだけ括弧に入れるケースに対応する。
+var quotedPattern = regexp.MustCompile(`[「『"“]([^」』"”]+)[」』"”]`)
+
+// FinalAnswerSegment は回答テキストのうち、最後の「最終回答」マーカー以降を返す。
+// マーカーが無い場合は全体を返す。複数行続く場合は最初の空でない行だけを採用する。
+func FinalAnswerSegment(text string) string {
+	segment := text
+	for _, marker := range finalAnswerMarkers {
+		if idx := strings.LastIndex(text, marker); idx != -1 {
+			segment = text[idx+len(marker):]
+			break
+		}
+	}
+	segment = strings.TrimSpace(segment)
+	if segment != text {
+		if line, _, found := strings.Cut(segment, "\n"); found {
+			segment = strings.TrimSpace(line)
+		}
+	}
+	return segment
+}
+
+// NormalizeText は文字列比較のための正規化を行う。
+// 全角英数字・記号を半角に寄せ、小文字化し、前後の句読点・括弧・語尾（です/だ）を取り除き、連続する空白を 1 つにまとめる。
+func NormalizeText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return unicode.ToLower(r - 0xFEE0)
+		case r == '　':
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+
+	// 語尾と句読点は交互に現れることがある（例: "の です。"）ので、変化しなくなるまで削る。
+	for {
+		before := s
+		s = strings.TrimFunc(s, isTrimmablePunct)
+		for _, suffix := range trailingCopulas {
+			if strings.HasSuffix(s, suffix) && len(s) > len(suffix) {
+				s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+				break
+			}
+		}
+		if s == before {
+			return s
+		}
+	}
+}
+
+// isTrimmablePunct は NormalizeText が前後から取り除く文字かどうかを判定する。
+func isTrimmablePunct(r rune) bool {
+	if unicode.IsSpace(r) {
+		return true
+	}
+	return strings.ContainsRune(`。、.,!?！？:;「」『』【】()（）[]"'“”‘’`+"`", r)
+}
+
+// textCandidates は回答テキストから文字列比較の候補を作る。
+// 最終回答部分そのものと、その中の括弧書き（最後のものを優先）を正規化して返す。
+func textCandidates(answerText string) []string {
+	segment := FinalAnswerSegment(answerText)
+	candidates := []string{NormalizeText(segment)}
+	quoted := quotedPattern.FindAllStringSubmatch(segment, -1)
+	for i := len(quoted) - 1; i >= 0; i-- {
+		candidates = append(candidates, NormalizeText(quoted[i][1]))
+	}
+	return candidates
+}
+
+// ParseNumbers は文字列に含まれる数値を出現順に有理数として返す。全角数字も半角に寄せてから読む。
+// 回答以外（プロンプトなど）に正解の数値が含まれているかを調べる用途にも使う。
+func ParseNumbers(s string) []*big.Rat {
+	var numbers []*big.Rat
+	for _, match := range numberPattern.FindAllString(normalizeDigits(s), -1) {
+		if v, ok := new(big.Rat).SetString(match); ok {
+			numbers = append(numbers, v)
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,327 @@
+// units.go は「120 km」「2時間30分」のような単位付きの回答を、問題が期待する単位へ換算するための正規化レイヤーをまとめたファイル。
+// 換算は math/big の有理数で行い、calculatePreciseDiff にそのまま渡せる精度を保つ。
+package eval
+
+import (
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UnitPolicy は回答の単位が期待する単位と次元ごと食い違った場合（例: 期待 km に対して 分）の扱いを表す。
+type UnitPolicy string
+
+const (
+	// UnitPolicyReject は次元の異なる単位を誤答として 0 点にする（デフォルト）。
+	UnitPolicyReject UnitPolicy = "reject"
+	// UnitPolicyPenalize は数値だけで採点したうえで unitMismatchPenalty を掛けて減点する。
+	UnitPolicyPenalize UnitPolicy = "penalize"
+)
+
+// ValidUnitPolicy は問題登録時に questions.unit_policy の値を確認する。
+func ValidUnitPolicy(p UnitPolicy) bool {
+	return p == UnitPolicyReject || p == UnitPolicyPenalize
+}
+
+// unitMismatchPenalty は UnitPolicyPenalize のときにスコアへ掛ける係数。
+const unitMismatchPenalty = 0.5
+
+// unitDimension は単位の次元（長さ・時間など）。同じ次元同士でのみ換算できる。
+type unitDimension string
+
+const (
+	dimLength      unitDimension = "length"       // 基準: m
+	dimTime        unitDimension = "time"         // 基準: 秒
+	dimMass        unitDimension = "mass"         // 基準: g
+	dimCurrencyJPY unitDimension = "currency_jpy" // 基準: 円
+	dimCurrencyUSD unitDimension = "currency_usd" // 基準: ドル（為替は固定できないため円とは別次元として扱う）
+	dimCount       unitDimension = "count"        // 基準: 個（日本語の助数詞はすべて同じ次元とみなす）
+)
+
+// unitDef は 1 単位が基準単位のいくつ分かを有理数で保持する。
+type unitDef struct {
+	dim    unitDimension
+	factor *big.Rat
+}
+
+// ratOf は "1/1000" のような文字列から *big.Rat を作る。テーブル定義を読みやすくするための小道具。
+func ratOf(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("eval: invalid unit factor " + s)
+	}
+	return r
+}
+
+// unitTable は表記ゆれを含む単位名から定義へのマッピング。
+// 英字の単位は大文字小文字を区別する（"m" と "M" を混同しないため）。
+var unitTable = map[string]unitDef{
+	// 長さ
+	"mm": {dimLength, ratOf("1/1000")}, "ミリメートル": {dimLength, ratOf("1/1000")}, "ミリ": {dimLength, ratOf("1/1000")},
+	"cm": {dimLength, ratOf("1/100")}, "センチメートル": {dimLength, ratOf("1/100")}, "センチ": {dimLength, ratOf("1/100")},
+	"m": {dimLength, ratOf("1")}, "meter": {dimLength, ratOf("1")}, "meters": {dimLength, ratOf("1")}, "メートル": {dimLength, ratOf("1")},
+	"km": {dimLength, ratOf("1000")}, "kilometer": {dimLength, ratOf("1000")}, "kilometers": {dimLength, ratOf("1000")}, "キロメートル": {dimLength, ratOf("1000")},
+	"inch": {dimLength, ratOf("254/10000")}, "inches": {dimLength, ratOf("254/10000")}, "インチ": {dimLength, ratOf("254/10000")},
+	"ft": {dimLength, ratOf("3048/10000")}, "feet": {dimLength, ratOf("3048/10000")}, "フィート": {dimLength, ratOf("3048/10000")},
+	"mile": {dimLength, ratOf("1609344/1000")}, "miles": {dimLength, ratOf("1609344/1000")}, "マイル": {dimLength, ratOf("1609344/1000")},
+
+	// 時間
+	"ms": {dimTime, ratOf("1/1000")}, "ミリ秒": {dimTime, ratOf("1/1000")},
+	"s": {dimTime, ratOf("1")}, "sec": {dimTime, ratOf("1")}, "second": {dimTime, ratOf("1")}, "seconds": {dimTime, ratOf("1")}, "秒": {dimTime, ratOf("1")},
+	"min": {dimTime, ratOf("60")}, "minute": {dimTime, ratOf("60")}, "minutes": {dimTime, ratOf("60")}, "分": {dimTime, ratOf("60")}, "分間": {dimTime, ratOf("60")},
+	"h": {dimTime, ratOf("3600")}, "hr": {dimTime, ratOf("3600")}, "hour": {dimTime, ratOf("3600")}, "hours": {dimTime, ratOf("3600")}, "時間": {dimTime, ratOf("3600")},
+	"day": {dimTime, ratOf("86400")}, "days": {dimTime, ratOf("86400")}, "日": {dimTime, ratOf("86400")}, "日間": {dimTime, ratOf("86400")},
+	"week": {dimTime, ratOf("604800")}, "weeks": {dimTime, ratOf("604800")}, "週間": {dimTime, ratOf("604800")},
+	"year": {dimTime, ratOf("31536000")}, "years": {dimTime, ratOf("31536000")}, "年": {dimTime, ratOf("31536000")}, "年間": {dimTime, ratOf("31536000")},
+
+	// 質量
+	"mg": {dimMass, ratOf("1/1000")}, "ミリグラム": {dimMass, ratOf("1/1000")},
+	"g": {dimMass, ratOf("1")}, "gram": {dimMass, ratOf("1")}, "grams": {dimMass, ratOf("1")}, "グラム": {dimMass, ratOf("1")},
+	"kg": {dimMass, ratOf("1000")}, "キログラム": {dimMass, ratOf("1000")},
+	"t": {dimMass, ratOf("1000000")}, "ton": {dimMass, ratOf("1000000")}, "tons": {dimMass, ratOf("1000000")}, "トン": {dimMass, ratOf("1000000")},
+
+	// 通貨
+	"円": {dimCurrencyJPY, ratOf("1")}, "yen": {dimCurrencyJPY, ratOf("1")}, "JPY": {dimCurrencyJPY, ratOf("1")},
+	"ドル": {dimCurrencyUSD, ratOf("1")}, "USD": {dimCurrencyUSD, ratOf("1")}, "dollars": {dimCurrencyUSD, ratOf("1")}, "dollar": {dimCurrencyUSD, ratOf("1")},
+
+	// 助数詞
+	"個": {dimCount, ratOf("1")}, "つ": {dimCount, ratOf("1")}, "人": {dimCount, ratOf("1")}, "本": {dimCount, ratOf("1")},
+	"枚": {dimCount, ratOf("1")}, "匹": {dimCount, ratOf("1")}, "頭": {dimCount, ratOf("1")}, "羽": {dimCount, ratOf("1")},
+	"台": {dimCount, ratOf("1")}, "冊": {dimCount, ratOf("1")}, "回": {dimCount, ratOf("1")}, "件": {dimCount, ratOf("1")},
+	"文字": {dimCount, ratOf("1")}, "字": {dimCount, ratOf("1")}, "通り": {dimCount, ratOf("1")}, "歳": {dimCount, ratOf("1")},
+}
+
+// magnitudeTable は数値と単位の間に入る漢数字の桁（例: 3万円, 1.2億人）。
+var magnitudeTable = map[string]*big.Rat{
+	"千": ratOf("1000"),
+	"万": ratOf("10000"),
+	"億": ratOf("100000000"),
+	"兆": ratOf("1000000000000"),
+}
+
+// quantityPattern は「数値 + (桁) + (単位) + (半)」を 1 つの量として切り出す正規表現。
+// 英字の単位には \b を付け、"5 sheep" の "s" のような誤検出を防ぐ。
+var quantityPattern = buildQuantityPattern()
+
+func buildQuantityPattern() *regexp.Regexp {
+	var ascii, others []string
+	for name := range unitTable {
+		if isASCIIWord(name) {
+			ascii = append(ascii, regexp.QuoteMeta(name))
+		} else {
+			others = append(others, regexp.QuoteMeta(name))
+		}
+	}
+	// 長い表記から順に試すことで "minutes" が "min" や "m" に食われないようにする。
+	byLength := func(list []string) {
+		sort.Slice(list, func(i, j int) bool {
+			if len(list[i]) != len(list[j]) {
+				return len(list[i]) > len(list[j])
+			}
+			return list[i] < list[j]
+		})
+	}
+	byLength(ascii)
+	byLength(others)
+
+	return regexp.MustCompile(`([-+]?(?:\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?)\s*([千万億兆])?\s*(?:((?:` +
+		strings.Join(ascii, "|") + `)\b|` + strings.Join(others, "|") + `)(半)?)?`)
+}
+
+func isASCIIWord(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// quantity は回答から読み取った 1 つの量（複合表記はまとめて 1 つ）を表す。
+type quantity struct {
+	text  string           // 元の表記（例: "2時間30分"）
+	value *big.Rat         // 基準単位に換算した値（単位なしの場合は数値そのもの）
+	raw   *big.Rat         // 最初の部品を元の単位のまま読んだ値（万・億や「半」は適用済み）
+	dim   unitDimension    // 単位の次元。単位なしの場合は空文字
+	parts []map[string]any // 換算ログ。detail にそのまま載せる
+	start int              // 回答テキスト中の開始位置（隣接判定用）
+	end   int              // 回答テキスト中の終了位置（隣接判定用）
+}
+
+// parseQuantities は回答テキストに含まれる量をすべて取り出す。
+// 「2時間30分」のように同じ次元の単位が空白のみを挟んで続く場合は 1 つの量として合算する。
+func parseQuantities(text string) []quantity {
+	text = normalizeDigits(text)
+	var result []quantity
+	for _, loc := range quantityPattern.FindAllStringSubmatchIndex(text, -1) {
+		numText := strings.ReplaceAll(text[loc[2]:loc[3]], ",", "")
+		value, ok := new(big.Rat).SetString(numText)
+		if !ok {
+			continue
+		}
+		part := map[string]any{"text": text[loc[0]:loc[1]], "value": numText}
+
+		if loc[4] != -1 {
+			mag := text[loc[4]:loc[5]]
+			value.Mul(value, magnitudeTable[mag])
+			part["magnitude"] = mag
+		}
+
+		q := quantity{text: text[loc[0]:loc[1]], value: value, start: loc[0], end: loc[1]}
+		if loc[6] != -1 {
+			name := text[loc[6]:loc[7]]
+			def := unitTable[name]
+			if loc[8] != -1 {
+				// 「1時間半」は 1.5 時間として扱う。
+				value.Add(value, big.NewRat(1, 2))
+			}
+			q.raw = new(big.Rat).Set(value)
+			value.Mul(value, def.factor)
+			q.dim = def.dim
+			part["unit"] = name
+			part["dimension"] = string(def.dim)
+		}
+		q.parts = []map[string]any{part}
+
+		// 直前の量と同じ次元で、間に空白しかなければ複合表記として合算する。
+		if n := len(result); n > 0 && q.dim != "" && result[n-1].dim == q.dim &&
+			strings.TrimSpace(text[result[n-1].end:q.start]) == "" {
+			prev := &result[n-1]
+			prev.value.Add(prev.value, q.value)
+			prev.parts = append(prev.parts, part)
+			prev.text = text[prev.start:q.end]
+			prev.end = q.end
+			continue
+		}
+		result = append(result, q)
+	}
+	return result
+}
+
+// normalizeDigits は全角数字・全角ピリオド・全角カンマを半角に揃える。
+func normalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			return '0' + (r - '０')
+		case r == '．':
+			return '.'
+		case r == '，':
+			return ','
+		}
+		return r
+	}, s)
+}
+
+// lookupUnit は期待単位の文字列から定義を引く。前後の空白は無視する。
+func lookupUnit(name string) (unitDef, bool) {
+	def, ok := unitTable[strings.TrimSpace(name)]
+	return def, ok
+}
+
+// KnownUnit は単位名が単位表に登録されているかを返す。問題登録時の expected_unit の確認に使う。
+func KnownUnit(name string) bool {
+	_, ok := lookupUnit(name)
+	return ok
+}
+
+// toUnit は基準単位の値を指定単位の値へ換算する。
+func toUnit(base *big.Rat, def unitDef) *big.Rat {
+	return new(big.Rat).Quo(base, def.factor)
+}
+
+// evaluateWithUnit は期待単位が設定された問題の採点を行う。
+// 回答と正解の両方を期待単位へ換算してから scoreNumeric に渡し、換算過程を detail に残す。
+func evaluateWithUnit(trimmedAnswer, trimmedCorrect string, opts Options, detail map[string]any) (score int, extracted *float64, mode string) {
+	detail["expected_unit"] = opts.ExpectedUnit
+	expected, ok := lookupUnit(opts.ExpectedUnit)
+	if !ok {
+		// 問題側の設定ミス。単位を無視して通常の数値評価に任せる。
+		detail["unit_error"] = "期待単位が単位表に存在しないため単位を無視して評価"
+		return evaluatePlainNumeric(trimmedAnswer, trimmedCorrect, opts.Scoring.withDefaults(), detail)
+	}
+
+	// 正解側: 素の数値なら期待単位の値とみなし、単位付きなら換算する。
+	correctRat, ok := new(big.Rat).SetString(trimmedCorrect)
+	if !ok {
+		quantities := parseQuantities(trimmedCorrect)
+		if len(quantities) != 1 || (quantities[0].dim != "" && quantities[0].dim != expected.dim) {
+			detail["unit_error"] = "正解を期待単位の数値として解釈できないため単位を無視して評価"
+			return evaluatePlainNumeric(trimmedAnswer, trimmedCorrect, opts.Scoring.withDefaults(), detail)
+		}
+		correctRat = quantities[0].value
+		if quantities[0].dim != "" {
+			correctRat = toUnit(correctRat, expected)
+		}
+	}
+	correctVal, _ := correctRat.Float64()
+	detail["correct_numeric"] = correctVal
+
+	quantities := parseQuantities(trimmedAnswer)
+	if len(quantities) == 0 {
+		mode = "no_numeric"
+		score = 0
+		detail["mode_reason"] = "回答に数値が含まれていない"
+		return
+	}
+	q := quantities[len(quantities)-1] // 数値と同様、最後の量を最終回答として採用
+	if len(quantities) > 1 {
+		texts := make([]string, len(quantities))
+		for i, item := range quantities {
+			texts[i] = item.text
+		}
+		detail["all_quantities_found"] = texts
+		detail["extraction_note"] = "複数の量が見つかったため、最後のものを最終回答として採用"
+	}
+	detail["unit_conversions"] = q.parts
+
+	answerRat := q.value
+	mismatch := false
+	switch {
+	case q.dim == "":
+		// 単位が書かれていなければ期待単位で答えたものとみなす。
+		detail["unit_assumed"] = true
+	case q.dim != expected.dim:
+		mismatch = true
+		detail["unit_mismatch"] = map[string]any{
+			"expected_dimension": string(expected.dim),
+			"answer_dimension":   string(q.dim),
+			"policy":             string(opts.policy()),
+		}
+		// 換算できないので、最初の部品を元の単位のまま読んだ値を使う。
+		answerRat = q.raw
+	default:
+		answerRat = toUnit(answerRat, expected)
+	}
+
+	answerVal, _ := answerRat.Float64()
+	detail["extracted_text"] = q.text
+	detail["extracted_numeric"] = answerVal
+	detail["converted_value"] = answerRat.RatString()
+	valueCopy := answerVal
+	extracted = &valueCopy
+
+	if mismatch && opts.policy() == UnitPolicyReject {
+		mode = "unit_mismatch"
+		score = 0
+		detail["mode_reason"] = "回答の単位が期待単位と次元が異なるため不正解"
+		detail["normalized_score"] = score
+		return
+	}
+
+	score, mode = scoreNumeric(correctRat.RatString(), correctVal, answerRat.RatString(), answerVal, opts.Scoring.withDefaults(), detail)
+	if mismatch {
+		score = penalizeUnitMismatch(score, detail)
+	}
+	return
+}
+
+// penalizeUnitMismatch は UnitPolicyPenalize で次元の異なる単位の回答を採点したスコアに unitMismatchPenalty を掛け、detail に記録する。
+func penalizeUnitMismatch(score int, detail map[string]any) int {
+	score = int(float64(score) * unitMismatchPenalty)
+	detail["unit_penalty"] = unitMismatchPenalty
+	detail["mode_reason"] = "回答の単位が期待単位と次元が異なるため減点"
+	detail["normalized_score"] = score
+	return score
+}
@@ -0,0 +1,224 @@
+// units_test.go は単位付き回答の換算と、期待単位との次元不一致時の扱いを確認する単体テスト。
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+// TestEvaluateWithOptions_Units は単位換算の代表的なケースをテーブルで検証する。
+func TestEvaluateWithOptions_Units(t *testing.T) {
+	testcases := []struct {
+		name            string
+		answer          string
+		correct         string
+		opts            Options
+		expectScore     int
+		expectMode      string
+		expectExtracted *float64
+	}{
+		{
+			name:            "SameUnit",
+			answer:          "最終回答: 120 km",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(120),
+		},
+		{
+			name:            "MetersToKilometers",
+			answer:          "答えは120000mです",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(120),
+		},
+		{
+			name:            "CompoundJapaneseTime",
+			answer:          "所要時間は2時間30分",
+			correct:         "150",
+			opts:            Options{ExpectedUnit: "分"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(150),
+		},
+		{
+			name:            "HalfHour",
+			answer:          "1時間半かかります",
+			correct:         "90",
+			opts:            Options{ExpectedUnit: "分"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(90),
+		},
+		{
+			name:            "CorrectWithUnit",
+			answer:          "9000秒",
+			correct:         "2時間30分",
+			opts:            Options{ExpectedUnit: "分"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(150),
+		},
+		{
+			name:            "MassKilogramsToGrams",
+			answer:          "1.5kg",
+			correct:         "1500",
+			opts:            Options{ExpectedUnit: "g"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(1500),
+		},
+		{
+			name:            "CurrencyWithMagnitudeAndComma",
+			answer:          "合計 1,2万円 ではなく 3万円",
+			correct:         "30,000円",
+			opts:            Options{ExpectedUnit: "円"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(30000),
+		},
+		{
+			name:            "JapaneseCounter",
+			answer:          "rは3個あります",
+			correct:         "3",
+			opts:            Options{ExpectedUnit: "個"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(3),
+		},
+		{
+			name:            "MissingUnitAssumesExpected",
+			answer:          "150",
+			correct:         "150",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     100,
+			expectMode:      "exact_match",
+			expectExtracted: floatPtr(150),
+		},
+		{
+			name:            "FullWidthDigits",
+			answer:          "１２０ｋｍ ではなく １２０ km",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     100,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(120),
+		},
+		{
+			name:            "IncompatibleUnitRejected",
+			answer:          "120分",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     0,
+			expectMode:      "unit_mismatch",
+			expectExtracted: floatPtr(120),
+		},
+		{
+			name:            "IncompatibleUnitPenalized",
+			answer:          "120分",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km", UnitPolicy: UnitPolicyPenalize},
+			expectScore:     50,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(120),
+		},
+		{
+			name:            "IncompatibleUnitKeepsMagnitude",
+			answer:          "1.2万分",
+			correct:         "12000",
+			opts:            Options{ExpectedUnit: "km", UnitPolicy: UnitPolicyPenalize},
+			expectScore:     50,
+			expectMode:      "numeric_exact",
+			expectExtracted: floatPtr(12000),
+		},
+		{
+			name:            "IncompatibleUnitKeepsHalf",
+			answer:          "1時間半",
+			correct:         "1.5",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     0,
+			expectMode:      "unit_mismatch",
+			expectExtracted: floatPtr(1.5),
+		},
+		{
+			name:            "ConvertedValueStillUsesCurve",
+			answer:          "121000 m",
+			correct:         "120",
+			opts:            Options{ExpectedUnit: "km"},
+			expectScore:     computeIntegerScaleScore(1, 120),
+			expectMode:      "numeric_score_integer",
+			expectExtracted: floatPtr(121),
+		},
+		{
+			name:        "NoQuantity",
+			answer:      "わかりません",
+			correct:     "120",
+			opts:        Options{ExpectedUnit: "km"},
+			expectScore: 0,
+			expectMode:  "no_numeric",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, extracted, mode, detail := EvaluateWithOptions(tc.answer, tc.correct, tc.opts)
+			if score != tc.expectScore {
+				t.Fatalf("score mismatch: got %d, want %d (detail=%v)", score, tc.expectScore, detail)
+			}
+			if mode != tc.expectMode {
+				t.Fatalf("mode mismatch: got %s, want %s", mode, tc.expectMode)
+			}
+			if tc.expectExtracted == nil {
+				if extracted != nil {
+					t.Fatalf("expected nil extracted, got %v", *extracted)
+				}
+				return
+			}
+			if extracted == nil {
+				t.Fatal("expected extracted value, got nil")
+			}
+			if math.Abs(*extracted-*tc.expectExtracted) > 1e-9 {
+				t.Fatalf("extracted mismatch: got %f, want %f", *extracted, *tc.expectExtracted)
+			}
+		})
+	}
+}
+
+// TestEvaluateWithOptions_UnitDetail は換算ログが detail に残ることを確認する。
+func TestEvaluateWithOptions_UnitDetail(t *testing.T) {
+	_, _, _, detail := EvaluateWithOptions("2時間30分", "150", Options{ExpectedUnit: "分"})
+
+	parts, ok := detail["unit_conversions"].([]map[string]any)
+	if !ok {
+		t.Fatalf("expected unit_conversions detail, got %T", detail["unit_conversions"])
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 conversion parts, got %d", len(parts))
+	}
+	if parts[0]["unit"] != "時間" || parts[1]["unit"] != "分" {
+		t.Fatalf("unexpected units in conversion log: %v", parts)
+	}
+	if detail["converted_value"] != "150" {
+		t.Fatalf("converted_value mismatch: got %v", detail["converted_value"])
+	}
+	if detail["expected_unit"] != "分" {
+		t.Fatalf("expected_unit mismatch: got %v", detail["expected_unit"])
+	}
+}
+
+// TestEvaluateWithOptions_UnknownExpectedUnit は期待単位が単位表に無い場合、通常の数値評価にフォールバックすることを確認する。
+func TestEvaluateWithOptions_UnknownExpectedUnit(t *testing.T) {
+	score, _, mode, detail := EvaluateWithOptions("答えは42", "42", Options{ExpectedUnit: "furlong"})
+	if score != 100 || mode != "numeric_exact" {
+		t.Fatalf("expected fallback numeric_exact 100, got %s %d", mode, score)
+	}
+	if _, ok := detail["unit_error"]; !ok {
+		t.Fatal("expected unit_error detail")
+	}
+}
@@ -0,0 +1,111 @@
+// version.go は採点ロジックの版を明示的に管理するファイル。
+// scores.evaluator_version に採点時の版を保存し、過去の版も呼び出せる形で残しておくことで、
+// 曲線や仕様の修正後に保存済みの ai_response を再採点（cmd/rescore）して差分を確認できるようにする。
+package eval
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Version は採点ロジックの版。scores.evaluator_version に保存する。
+type Version int
+
+const (
+	// VersionV3 は correct_answer だけを使う数値誤差ベースの採点（Evaluate）。版管理を始める前のスコアはこの版で付いている。
+	VersionV3 Version = 3
+	// VersionV4 は answer_spec（別解・集合・区間・数式）と期待単位に対応した採点（EvaluateSpec）。曲線は常に既定のパラメータを使う。
+	VersionV4 Version = 4
+	// VersionV5 は v4 に加えて、問題ごとの採点曲線のパラメータ（questions.scoring_params）と
+	// 単位の食い違いの扱い（questions.unit_policy）を使う採点。
+	VersionV5 Version = 5
+
+	// CurrentVersion は新しく保存するスコアに使う版。採点結果が変わる修正を入れたら版を増やし、evaluators に登録する。
+	CurrentVersion = VersionV5
+)
+
+// AnswerKey は採点に必要な問題側の情報。版ごとに使う項目が異なる。
+type AnswerKey struct {
+	CorrectAnswer string
+	Spec          AnswerSpec
+	ExpectedUnit  string
+	Scoring       ScoringParams // questions.scoring_params。v5 以降で使う
+	UnitPolicy    UnitPolicy    // questions.unit_policy。v5 以降で使い、空なら UnitPolicyReject
+}
+
+// Result は採点結果。Evaluate の戻り値をまとめたもの。
+type Result struct {
+	Score     int
+	Extracted *float64
+	Mode      string
+	Detail    map[string]any
+}
+
+// versionedEvaluator は 1 つの版の採点関数。
+type versionedEvaluator func(answerText string, key AnswerKey) Result
+
+// evaluators は版ごとの採点関数。過去の版は再採点時の比較用に削除せず残す。
+var evaluators = map[Version]versionedEvaluator{
+	VersionV3: func(answerText string, key AnswerKey) Result {
+		score, extracted, mode, detail := Evaluate(answerText, key.CorrectAnswer)
+		return Result{Score: score, Extracted: extracted, Mode: mode, Detail: detail}
+	},
+	VersionV4: func(answerText string, key AnswerKey) Result {
+		score, extracted, mode, detail := EvaluateSpec(answerText, key.Spec, Options{ExpectedUnit: key.ExpectedUnit})
+		return Result{Score: score, Extracted: extracted, Mode: mode, Detail: detail}
+	},
+	VersionV5: func(answerText string, key AnswerKey) Result {
+		score, extracted, mode, detail := EvaluateSpec(answerText, key.Spec, Options{ExpectedUnit: key.ExpectedUnit, UnitPolicy: key.UnitPolicy, Scoring: key.Scoring})
+		return Result{Score: score, Extracted: extracted, Mode: mode, Detail: detail}
+	},
+}
+
+// EvaluateVersion は指定した版の採点ロジックで回答を採点する。
+// detail には evaluator_version と mode を記録し、保存済みのスコアからどの版・どの経路で採点したか分かるようにする。
+func EvaluateVersion(v Version, answerText string, key AnswerKey) (Result, error) {
+	evaluate, ok := evaluators[v]
+	if !ok {
+		return Result{}, fmt.Errorf("eval: unknown evaluator version %d", v)
+	}
+	result := evaluate(answerText, key)
+	StampVersion(result.Detail, v, result.Mode)
+	return result, nil
+}
+
+// StampVersion は detail に採点の版とモードを書き込む。EvaluateWithJudge のように EvaluateVersion を通らない経路でも使う。
+func StampVersion(detail map[string]any, v Version, mode string) {
+	if detail == nil {
+		return
+	}
+	detail["evaluator_version"] = int(v)
+	detail["mode"] = mode
+}
+
+// aiTextKeys は detail のうち AI の出力（全文や抜き出した一部）をそのまま持つキー。
+var aiTextKeys = []string{
+	"answer_raw", "answer_trimmed", "answer_segment", "answer_candidates", "answer_items", "answer_expression",
+	"matched_text", "extracted_text", "all_numbers_found", "all_quantities_found", "unit_conversions", "judge_rationale",
+}
+
+// StripAIText は detail から AI の出力を持つキーを取り除き、取り除いたキーを返す。
+// AI の出力に非公開の問題文が含まれていた回答で、detail を経由して問題文が漏れないようにするために使う。
+func StripAIText(detail map[string]any) []string {
+	removed := []string{}
+	for _, key := range aiTextKeys {
+		if _, ok := detail[key]; ok {
+			delete(detail, key)
+			removed = append(removed, key)
+		}
+	}
+	return removed
+}
+
+// KnownVersions は登録済みの版を昇順で返す。
+func KnownVersions() []Version {
+	versions := make([]Version, 0, len(evaluators))
+	for v := range evaluators {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
@@ -0,0 +1,91 @@
+// version_test.go は版ごとの採点関数が呼び分けられることと、detail への版の記録を確認する単体テスト。
+package eval
+
+import (
+	"testing"
+)
+
+// TestEvaluateVersion は同じ回答でも版によって採点結果が変わり得ることと、未知の版がエラーになることを確認する。
+func TestEvaluateVersion(t *testing.T) {
+	key := AnswerKey{
+		CorrectAnswer: "150",
+		Spec:          AnswerSpec{Type: SpecValue, Value: "150"},
+		ExpectedUnit:  "km",
+	}
+	answer := "最終回答: 150000 m"
+
+	testcases := []struct {
+		version     Version
+		expectScore int
+		expectMode  string
+	}{
+		// v3 は単位を知らないため 150000 をそのまま比較する。
+		{version: VersionV3, expectScore: computeIntegerScaleScore(149850, 150), expectMode: "numeric_score_integer"},
+		{version: VersionV4, expectScore: 100, expectMode: "numeric_exact"},
+	}
+
+	for _, tc := range testcases {
+		result, err := EvaluateVersion(tc.version, answer, key)
+		if err != nil {
+			t.Fatalf("v%d: unexpected error: %v", tc.version, err)
+		}
+		if result.Mode != tc.expectMode {
+			t.Errorf("v%d: mode mismatch: got %s, want %s", tc.version, result.Mode, tc.expectMode)
+		}
+		if result.Score != tc.expectScore {
+			t.Errorf("v%d: score mismatch: got %d, want %d", tc.version, result.Score, tc.expectScore)
+		}
+		if result.Detail["evaluator_version"] != int(tc.version) || result.Detail["mode"] != result.Mode {
+			t.Errorf("v%d: version not stamped: %v", tc.version, result.Detail)
+		}
+	}
+
+	if _, err := EvaluateVersion(Version(99), answer, key); err == nil {
+		t.Fatal("expected error for unknown version")
+	}
+}
+
+// TestEvaluateVersion_ScoringParams は問題ごとの採点曲線のパラメータを v5 だけが使い、v4 で採点済みのスコアの意味が変わらないことを確認する。
+func TestEvaluateVersion_ScoringParams(t *testing.T) {
+	answer := "最終回答: 90"
+	key := AnswerKey{CorrectAnswer: "100", Spec: AnswerSpec{Type: SpecValue, Value: "100"}}
+	strict := key
+	strict.Scoring = ScoringParams{BaseError: 1}
+
+	defaultV5, _ := EvaluateVersion(VersionV5, answer, key)
+	strictV4, _ := EvaluateVersion(VersionV4, answer, strict)
+	strictV5, _ := EvaluateVersion(VersionV5, answer, strict)
+
+	if strictV4.Score != defaultV5.Score {
+		t.Errorf("v4 should ignore scoring params: got %d, want %d", strictV4.Score, defaultV5.Score)
+	}
+	if strictV5.Score >= defaultV5.Score {
+		t.Errorf("v5 should apply the stricter curve: got %d, default %d", strictV5.Score, defaultV5.Score)
+	}
+	if _, ok := strictV5.Detail["scoring_params"]; !ok {
+		t.Errorf("v5 should record scoring params: %v", strictV5.Detail)
+	}
+}
+
+// TestEvaluateVersion_UnitPolicy は問題ごとの単位の食い違いの扱いを v5 だけが使い、v4 では常に不正解にすることを確認する。
+func TestEvaluateVersion_UnitPolicy(t *testing.T) {
+	answer := "最終回答: 120分"
+	key := AnswerKey{CorrectAnswer: "120", Spec: AnswerSpec{Type: SpecValue, Value: "120"}, ExpectedUnit: "km", UnitPolicy: UnitPolicyPenalize}
+
+	v4, _ := EvaluateVersion(VersionV4, answer, key)
+	v5, _ := EvaluateVersion(VersionV5, answer, key)
+	if v4.Score != 0 || v4.Mode != "unit_mismatch" {
+		t.Errorf("v4 should reject incompatible units: got %d (%s)", v4.Score, v4.Mode)
+	}
+	if v5.Score != 50 || v5.Detail["unit_penalty"] != unitMismatchPenalty {
+		t.Errorf("v5 should apply the penalize policy: got %d, detail %v", v5.Score, v5.Detail)
+	}
+}
+
+// TestKnownVersions は現在の版が登録済みであることを確認する。
+func TestKnownVersions(t *testing.T) {
+	versions := KnownVersions()
+	if len(versions) == 0 || versions[len(versions)-1] != CurrentVersion {
+		t.Fatalf("CurrentVersion must be the newest registered version: %v", versions)
+	}
+}
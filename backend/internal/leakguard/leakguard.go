@@ -0,0 +1,145 @@
+// Package leakguard は AI の出力に非公開の問題文がそのまま含まれていないかを調べ、該当部分を伏せ字にする仕組みをまとめたパッケージ。
+// 「問題をそのまま繰り返して」のようなプロンプトや、最終回答マーカーが無く出力全体を返すケースで問題文が漏れるのを防ぐ。
+// 比較は文字 n-gram で行い、空白・句読点・全角半角・大文字小文字の違いは無視する。
+package leakguard
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// ngramSize は比較に使う文字 n-gram の長さ。日本語の文では 5 文字程度の一致から偶然とは言いにくくなる。
+	ngramSize = 5
+
+	// coverageThreshold は問題文の n-gram のうち出力に現れた割合がこれ以上なら漏洩とみなす。
+	coverageThreshold = 0.5
+
+	// ReviewReason は漏洩を検出したスコアに付ける scores.review_reason の値。
+	ReviewReason = "problem_statement_leak"
+
+	// Redaction は伏せ字にした部分を置き換える文字列。
+	Redaction = "［問題文のため非表示］"
+)
+
+// Result は 1 件の出力に対する検査結果。
+type Result struct {
+	Flagged      bool    // 問題文の大部分が出力に含まれているか
+	Coverage     float64 // 問題文の n-gram のうち出力に現れた割合（0〜1）
+	LongestMatch int     // 問題文と一致した最長の区間の長さ（正規化後の文字数）
+	Redacted     string  // 一致部分を伏せ字にした出力。Flagged でなければ元の出力のまま
+	Spans        int     // 伏せ字にした区間の数
+}
+
+// Detail は evaluation_detail に保存する形に変換する。
+func (r Result) Detail() map[string]any {
+	return map[string]any{
+		"flagged":        r.Flagged,
+		"coverage":       r.Coverage,
+		"longest_match":  r.LongestMatch,
+		"redacted_spans": r.Spans,
+	}
+}
+
+// keptRune は正規化後の 1 文字と、元の文字列中のバイト位置の組。
+type keptRune struct {
+	r      rune
+	offset int
+	width  int
+}
+
+// normalize は比較に使う文字（文字・数字）だけを残し、全角英数字を半角に寄せて小文字化する。
+// 元の位置を残しておき、一致した区間を元の文字列で伏せ字にできるようにする。
+func normalize(s string) []keptRune {
+	var kept []keptRune
+	for offset, r := range s {
+		width := utf8.RuneLen(r)
+		if r >= '！' && r <= '～' {
+			r -= 0xFEE0
+		}
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			continue
+		}
+		kept = append(kept, keptRune{r: unicode.ToLower(r), offset: offset, width: width})
+	}
+	return kept
+}
+
+func ngramAt(runes []keptRune, i, n int) string {
+	var b strings.Builder
+	for _, k := range runes[i : i+n] {
+		b.WriteRune(k.r)
+	}
+	return b.String()
+}
+
+// Check は出力と問題文の重なりを調べる。重なりが大きい場合は一致した区間を Redaction に置き換えた出力を返す。
+func Check(output, problemStatement string) Result {
+	result := Result{Redacted: output}
+	statement := normalize(problemStatement)
+	out := normalize(output)
+
+	// 問題文が n-gram より短い場合は、問題文全体を 1 つの n-gram として扱う。
+	n := ngramSize
+	if len(statement) < n {
+		n = len(statement)
+	}
+	if n == 0 || len(out) < n {
+		return result
+	}
+
+	statementGrams := make(map[string]bool, len(statement)-n+1)
+	for i := 0; i+n <= len(statement); i++ {
+		statementGrams[ngramAt(statement, i, n)] = true
+	}
+
+	// 出力側で問題文の n-gram と一致した文字に印を付ける。
+	covered := make([]bool, len(out))
+	found := make(map[string]bool)
+	for i := 0; i+n <= len(out); i++ {
+		gram := ngramAt(out, i, n)
+		if statementGrams[gram] {
+			found[gram] = true
+			for j := i; j < i+n; j++ {
+				covered[j] = true
+			}
+		}
+	}
+	result.Coverage = float64(len(found)) / float64(len(statementGrams))
+
+	// 印の付いた連続区間を求める。
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(out); i++ {
+		if !covered[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(out) && covered[j+1] {
+			j++
+		}
+		spans = append(spans, span{start: i, end: j})
+		if length := j - i + 1; length > result.LongestMatch {
+			result.LongestMatch = length
+		}
+		i = j
+	}
+
+	if result.Coverage < coverageThreshold {
+		return result
+	}
+	result.Flagged = true
+	result.Spans = len(spans)
+
+	// 後ろの区間から置き換えて、前の区間のバイト位置がずれないようにする。
+	redacted := output
+	for i := len(spans) - 1; i >= 0; i-- {
+		start := out[spans[i].start].offset
+		last := out[spans[i].end]
+		end := last.offset + last.width
+		redacted = redacted[:start] + Redaction + redacted[end:]
+	}
+	result.Redacted = redacted
+	return result
+}
@@ -0,0 +1,74 @@
+// leakguard_test.go は AI 出力と問題文の重なりの検出と、伏せ字への置き換えを確認する単体テスト。
+package leakguard
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheck は問題文の丸写し・言い換え・無関係な出力をテーブルで検証する。
+func TestCheck(t *testing.T) {
+	statement := "「すもももももももものうち」の中に「も」は何個ある？"
+
+	testcases := []struct {
+		name           string
+		output         string
+		statement      string
+		expectFlagged  bool
+		expectRedacted bool
+		mustNotContain string
+	}{
+		{
+			name:           "VerbatimRepeat",
+			output:         "問題: 「すもももももももものうち」の中に「も」は何個ある？\n最終回答: 8",
+			statement:      statement,
+			expectFlagged:  true,
+			expectRedacted: true,
+			mustNotContain: "何個ある",
+		},
+		{
+			name:           "RepeatWithDifferentPunctuationAndWidth",
+			output:         "Ｓｔｒａｗｂｅｒｒｙ の 中に r は 何個 ある",
+			statement:      "strawberryの中にrは何個ある？",
+			expectFlagged:  true,
+			expectRedacted: true,
+			mustNotContain: "何個",
+		},
+		{
+			name:      "AnswerOnly",
+			output:    "8",
+			statement: statement,
+		},
+		{
+			name:      "PartialMentionIsNotALeak",
+			output:    "strawberryにはrが3個あります",
+			statement: "strawberryの中にrは何個ある？1文字ずつ数えて答えてください。",
+		},
+		{
+			name:      "EmptyStatement",
+			output:    "何か",
+			statement: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := Check(tc.output, tc.statement)
+			if result.Flagged != tc.expectFlagged {
+				t.Fatalf("Flagged = %v, want %v (coverage=%.2f)", result.Flagged, tc.expectFlagged, result.Coverage)
+			}
+			if redacted := result.Redacted != tc.output; redacted != tc.expectRedacted {
+				t.Fatalf("redacted = %v, want %v: %q", redacted, tc.expectRedacted, result.Redacted)
+			}
+			if tc.mustNotContain != "" && strings.Contains(result.Redacted, tc.mustNotContain) {
+				t.Fatalf("redacted output still contains %q: %q", tc.mustNotContain, result.Redacted)
+			}
+			if tc.expectRedacted && !strings.Contains(result.Redacted, Redaction) {
+				t.Fatalf("expected redaction marker in %q", result.Redacted)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package progression はレベルの解放（プログレッション）の規則をまとめたパッケージ。
+// level 1 の問題は最初から挑戦でき、それより上のレベルは次のどちらかを満たすと解放される。
+//
+//   - 問題ごとの自己ベストの合計が Points × (level - 1) 点以上
+//   - 1 つ下のレベルが解放済みで、そのレベルの問題を Clears 問以上満点で解いている
+//     （そのレベルの問題が Clears 問に満たなければ全問）
+//
+// 解放済みのレベルはユーザーごとに user_progress に保存し、再採点や問題の level の変更で条件を満たさなくなっても戻さない。
+// 集計と保存は repository.ProgressRepository、ここでは DB に依存しない計算だけを扱う。
+package progression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GuestLevel はゲスト（進捗を保存できない）が挑戦できる最も高いレベル。
+const GuestLevel = 1
+
+// Rules はレベルを解放する条件。
+type Rules struct {
+	Clears int // 1 つ下のレベルで満点を取る必要がある問題の数（1 以上）
+	Points int // 1 レベルあたりに必要な自己ベストの合計点。0 ならこの条件では解放しない
+}
+
+// DefaultRules は既定の条件。1 つ下のレベルを 2 問クリアするか、300 点 × (level - 1) を稼ぐと解放する。
+func DefaultRules() Rules {
+	return Rules{Clears: 2, Points: 300}
+}
+
+// ParseRules は環境変数 PROGRESSION の値を読み取る。
+// 空なら既定の条件、"off" ならプログレッションを使わない（enabled が false）、
+// それ以外は "clears=2,points=300" の形で、書かなかった項目は既定値のまま。
+func ParseRules(raw string) (rules Rules, enabled bool, err error) {
+	rules = DefaultRules()
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return rules, true, nil
+	}
+	if strings.EqualFold(raw, "off") {
+		return rules, false, nil
+	}
+
+	for _, part := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Rules{}, false, fmt.Errorf("%q は name=value の形で指定してください", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return Rules{}, false, fmt.Errorf("%s の値 %q は整数で指定してください", name, value)
+		}
+		switch strings.TrimSpace(name) {
+		case "clears":
+			if n < 1 {
+				return Rules{}, false, fmt.Errorf("clears は 1 以上で指定してください: %d", n)
+			}
+			rules.Clears = n
+		case "points":
+			if n < 0 {
+				return Rules{}, false, fmt.Errorf("points は 0 以上で指定してください: %d", n)
+			}
+			rules.Points = n
+		default:
+			return Rules{}, false, fmt.Errorf("未知の項目です: %q", name)
+		}
+	}
+	return rules, true, nil
+}
+
+// LevelStats は 1 つのレベルについてのユーザーの成績。
+type LevelStats struct {
+	Level     int  `json:"level"`
+	Questions int  `json:"questions"` // そのレベルの（削除されていない）問題の数
+	Cleared   int  `json:"cleared"`   // そのうちユーザーが満点を取った問題の数
+	Unlocked  bool `json:"unlocked"`
+}
+
+// Requirement は次のレベルを解放する条件と、今の達成状況。
+type Requirement struct {
+	Level         int  `json:"level"`           // 次に解放されるレベル
+	Points        *int `json:"points"`          // 必要な自己ベストの合計点。点数では解放しない設定なら null
+	Clears        int  `json:"clears"`          // 1 つ下のレベルで満点を取る必要がある問題の数
+	ClearedAtPrev int  `json:"cleared_at_prev"` // 1 つ下のレベルで満点を取った問題の数
+}
+
+// Progress はユーザーの進捗。
+type Progress struct {
+	UnlockedLevel int          `json:"unlocked_level"` // 挑戦できる最も高いレベル。これ以下のレベルは全て解放済み
+	TotalPoints   int          `json:"total_points"`   // 問題ごとの自己ベストの合計
+	Levels        []LevelStats `json:"levels"`         // 問題があるレベルの成績。レベル順
+	Next          *Requirement `json:"next"`           // 次のレベルの解放条件。全て解放済みなら null
+}
+
+// Evaluate はレベルごとの成績（レベル順、問題の無いレベルは省いてよい）と保存済みの解放レベルから進捗を計算する。
+// 解放済みのレベルは stored より下がらない。
+func (r Rules) Evaluate(levels []LevelStats, totalPoints, stored int) Progress {
+	byLevel := make(map[int]LevelStats, len(levels))
+	maxLevel := GuestLevel
+	for _, l := range levels {
+		byLevel[l.Level] = l
+		if l.Level > maxLevel {
+			maxLevel = l.Level
+		}
+	}
+
+	unlocked := GuestLevel
+	for level := GuestLevel + 1; level <= maxLevel; level++ {
+		if !r.unlocks(level, byLevel[level-1], totalPoints, unlocked == level-1) {
+			break
+		}
+		unlocked = level
+	}
+	if stored > unlocked {
+		unlocked = stored
+	}
+
+	p := Progress{UnlockedLevel: unlocked, TotalPoints: totalPoints, Levels: make([]LevelStats, 0, len(levels))}
+	for _, l := range levels {
+		l.Unlocked = l.Level <= unlocked
+		p.Levels = append(p.Levels, l)
+	}
+	if unlocked < maxLevel {
+		next := unlocked + 1
+		prev := byLevel[next-1]
+		req := &Requirement{Level: next, Clears: r.clearsNeeded(prev), ClearedAtPrev: prev.Cleared}
+		if r.Points > 0 {
+			points := r.Points * (next - 1)
+			req.Points = &points
+		}
+		p.Next = req
+	}
+	return p
+}
+
+// unlocks は level が解放されるかを返す。prevUnlocked は 1 つ下のレベルが解放済みかどうか。
+func (r Rules) unlocks(level int, prev LevelStats, totalPoints int, prevUnlocked bool) bool {
+	if r.Points > 0 && totalPoints >= r.Points*(level-1) {
+		return true
+	}
+	return prevUnlocked && prev.Cleared >= r.clearsNeeded(prev)
+}
+
+// clearsNeeded は 1 つ下のレベル prev で満点を取る必要がある問題の数。問題が少なければ全問。
+func (r Rules) clearsNeeded(prev LevelStats) int {
+	if prev.Questions < r.Clears {
+		return prev.Questions
+	}
+	return r.Clears
+}
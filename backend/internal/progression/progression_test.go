@@ -0,0 +1,101 @@
+// progression_test.go はレベルの解放条件（点数・1 つ下のレベルのクリア数・保存済みの解放レベル）と、設定の読み取りを確認する単体テスト。
+package progression
+
+import "testing"
+
+func TestRules_Evaluate(t *testing.T) {
+	rules := Rules{Clears: 2, Points: 300}
+	// 初期データと同じく、level 1 は 1 問しかない構成。
+	levels := func(cleared ...int) []LevelStats {
+		questions := []int{1, 4, 3, 2}
+		out := make([]LevelStats, len(questions))
+		for i, n := range questions {
+			out[i] = LevelStats{Level: i + 1, Questions: n}
+			if i < len(cleared) {
+				out[i].Cleared = cleared[i]
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		levels     []LevelStats
+		points     int
+		stored     int
+		want       int
+		wantNext   int // 0 なら next は null
+		wantClears int
+	}{
+		{name: "何も解いていない", levels: levels(), want: 1, wantNext: 2, wantClears: 1},
+		{name: "問題が少ないレベルは全問で解放", levels: levels(1), points: 100, want: 2, wantNext: 3, wantClears: 2},
+		{name: "クリア数で続けて解放", levels: levels(1, 2, 2), points: 500, want: 4},
+		{name: "クリア数が足りない", levels: levels(1, 1), points: 250, want: 2, wantNext: 3, wantClears: 2},
+		{name: "点数で飛び級", levels: levels(0, 0, 1), points: 900, want: 4},
+		{name: "保存済みの解放レベルは下がらない", levels: levels(), stored: 3, want: 3, wantNext: 4, wantClears: 2},
+		{name: "問題の無いレベルは素通り", levels: []LevelStats{{Level: 1, Questions: 2, Cleared: 2}, {Level: 3, Questions: 1}}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules.Evaluate(tt.levels, tt.points, tt.stored)
+			if got.UnlockedLevel != tt.want {
+				t.Errorf("UnlockedLevel = %d, want %d", got.UnlockedLevel, tt.want)
+			}
+			for _, l := range got.Levels {
+				if l.Unlocked != (l.Level <= tt.want) {
+					t.Errorf("level %d unlocked = %v", l.Level, l.Unlocked)
+				}
+			}
+			if tt.wantNext == 0 {
+				if got.Next != nil {
+					t.Errorf("Next = %+v, want nil", got.Next)
+				}
+				return
+			}
+			if got.Next == nil || got.Next.Level != tt.wantNext || got.Next.Clears != tt.wantClears ||
+				got.Next.Points == nil || *got.Next.Points != 300*(tt.wantNext-1) {
+				t.Errorf("Next = %+v, want level %d clears %d", got.Next, tt.wantNext, tt.wantClears)
+			}
+		})
+	}
+
+	// 点数の条件を使わない設定では、クリア数だけで判断します。
+	if got := (Rules{Clears: 1}).Evaluate(levels(1), 10000, 0); got.UnlockedLevel != 2 || got.Next == nil || got.Next.Points != nil {
+		t.Errorf("clears only: %+v", got)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		raw         string
+		want        Rules
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{raw: "", want: DefaultRules(), wantEnabled: true},
+		{raw: "off", want: DefaultRules()},
+		{raw: "clears=3", want: Rules{Clears: 3, Points: 300}, wantEnabled: true},
+		{raw: " clears = 1 , points = 0 ", want: Rules{Clears: 1, Points: 0}, wantEnabled: true},
+		{raw: "clears=0", wantErr: true},
+		{raw: "points=-1", wantErr: true},
+		{raw: "points=many", wantErr: true},
+		{raw: "levels=3", wantErr: true},
+		{raw: "clears", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, enabled, err := ParseRules(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || enabled != tt.wantEnabled {
+				t.Errorf("ParseRules(%q) = %+v, %v, want %+v, %v", tt.raw, got, enabled, tt.want, tt.wantEnabled)
+			}
+		})
+	}
+}
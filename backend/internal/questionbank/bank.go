@@ -0,0 +1,280 @@
+// Package questionbank は問題をファイル（YAML / JSON）で管理するための問題バンクの読み書きと、取り込み前の検証を提供する。
+// SQL の INSERT で問題を書く代わりに、slug をキーにした問題の一覧をファイルで持ち、cmd/questions や管理 API から取り込む。
+//
+// ファイルの形式（YAML の例）:
+//
+//	questions:
+//	  - slug: strawberry-r
+//	    level: 3
+//	    tags: [character_counting, text_analysis]
+//	    problem_statement: strawberryの中にrは何個ある？
+//	    correct_answer: "3"
+//	    answer_spec: {type: value, value: "3"}
+//	    scoring_params: {p: 4}
+package questionbank
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// Format は問題バンクのファイル形式。
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat は "yaml" / "yml" / "json" を Format に変換する。
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown question bank format %q (yaml or json)", s)
+	}
+}
+
+// FormatFromPath はファイルの拡張子から形式を判定する。.json 以外は YAML として扱う。
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Bank は問題バンクのファイル全体。
+type Bank struct {
+	Questions []Entry `json:"questions" yaml:"questions"`
+}
+
+// Entry は問題バンクの 1 問。models.Question から ID や日時などの環境ごとに変わる項目を除き、slug で識別する。
+// YAML でも answer_spec / scoring_params を入れ子で書けるよう、JSON 列は構造体で持つ。
+type Entry struct {
+	Slug             string              `json:"slug" yaml:"slug"`
+	Level            int                 `json:"level" yaml:"level"`
+	Tags             []string            `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ProblemStatement string              `json:"problem_statement" yaml:"problem_statement"`
+	CorrectAnswer    string              `json:"correct_answer" yaml:"correct_answer"`
+	ExpectedUnit     string              `json:"expected_unit,omitempty" yaml:"expected_unit,omitempty"`
+	UnitPolicy       string              `json:"unit_policy,omitempty" yaml:"unit_policy,omitempty"`
+	AnswerSpec       *eval.AnswerSpec    `json:"answer_spec,omitempty" yaml:"answer_spec,omitempty"`
+	GradingRubric    string              `json:"grading_rubric,omitempty" yaml:"grading_rubric,omitempty"`
+	TemplateKey      string              `json:"template_key,omitempty" yaml:"template_key,omitempty"`
+	ScoringParams    *eval.ScoringParams `json:"scoring_params,omitempty" yaml:"scoring_params,omitempty"`
+}
+
+// Decode は問題バンクを読み込む。未知の項目は書き間違いの可能性が高いためエラーにする。
+func Decode(r io.Reader, format Format) (Bank, error) {
+	var bank Bank
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return bank, fmt.Errorf("failed to read question bank: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&bank); err != nil {
+			return Bank{}, fmt.Errorf("failed to decode question bank json: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.UnmarshalWithOptions(data, &bank, yaml.DisallowUnknownField()); err != nil {
+			return Bank{}, fmt.Errorf("failed to decode question bank yaml: %w", err)
+		}
+	default:
+		return Bank{}, fmt.Errorf("unknown question bank format %q", format)
+	}
+	return bank, nil
+}
+
+// Encode は問題バンクを書き出す。YAML では複数行の問題文をリテラルブロック（|）で出力し、差分を読みやすくする。
+func Encode(w io.Writer, bank Bank, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(bank); err != nil {
+			return fmt.Errorf("failed to encode question bank json: %w", err)
+		}
+	case FormatYAML:
+		data, err := yaml.MarshalWithOptions(bank, yaml.UseLiteralStyleIfMultiline(true), yaml.IndentSequence(true))
+		if err != nil {
+			return fmt.Errorf("failed to encode question bank yaml: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write question bank: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown question bank format %q", format)
+	}
+	return nil
+}
+
+// ToQuestion は Entry を保存用の models.Question に変換する。検証は Validate でまとめて行う。
+func (e Entry) ToQuestion() (*models.Question, error) {
+	q := &models.Question{
+		Slug:             stringOrNil(e.Slug),
+		Level:            e.Level,
+		ProblemStatement: e.ProblemStatement,
+		CorrectAnswer:    e.CorrectAnswer,
+		ExpectedUnit:     stringOrNil(e.ExpectedUnit),
+		UnitPolicy:       stringOrNil(e.UnitPolicy),
+		GradingRubric:    stringOrNil(e.GradingRubric),
+		TemplateKey:      stringOrNil(e.TemplateKey),
+		Tags:             e.Tags,
+	}
+	if e.AnswerSpec != nil {
+		raw, err := json.Marshal(e.AnswerSpec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal answer_spec: %w", err)
+		}
+		q.AnswerSpec = raw
+	}
+	if e.ScoringParams != nil {
+		raw, err := json.Marshal(e.ScoringParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal scoring_params: %w", err)
+		}
+		q.ScoringParams = raw
+	}
+	return q, nil
+}
+
+// FromQuestion は保存済みの問題を Entry に変換する。書き出しに使う。
+func FromQuestion(q models.Question) (Entry, error) {
+	e := Entry{
+		Slug:             derefString(q.Slug),
+		Level:            q.Level,
+		Tags:             q.Tags,
+		ProblemStatement: q.ProblemStatement,
+		CorrectAnswer:    q.CorrectAnswer,
+		ExpectedUnit:     derefString(q.ExpectedUnit),
+		UnitPolicy:       derefString(q.UnitPolicy),
+		GradingRubric:    derefString(q.GradingRubric),
+		TemplateKey:      derefString(q.TemplateKey),
+	}
+	if e.Slug == "" {
+		// 通常は登録時に割り当てられるが、移行前の行でも取り込み直せるよう登録時と同じ規則で補う。
+		e.Slug = fmt.Sprintf("q-%d", q.ID)
+	}
+	if len(q.AnswerSpec) > 0 {
+		spec, err := eval.ParseAnswerSpec(q.AnswerSpec, q.CorrectAnswer)
+		if err != nil {
+			return Entry{}, fmt.Errorf("question %d: %w", q.ID, err)
+		}
+		e.AnswerSpec = &spec
+	}
+	scoring, err := eval.ParseScoringParams(q.ScoringParams)
+	if err != nil {
+		return Entry{}, fmt.Errorf("question %d: %w", q.ID, err)
+	}
+	if !scoring.IsZero() {
+		e.ScoringParams = &scoring
+	}
+	return e, nil
+}
+
+// Problem は問題バンクの検証で見つかった 1 件の誤り。
+type Problem struct {
+	Index   int    `json:"index"` // ファイル内の位置（0 始まり）
+	Slug    string `json:"slug"`
+	Message string `json:"message"`
+}
+
+// ValidationError は問題バンクの検証エラー。1 件目で止めず、全ての誤りをまとめて返す。
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, fmt.Sprintf("#%d %s: %s", p.Index, p.Slug, p.Message))
+	}
+	return fmt.Sprintf("question bank has %d problem(s):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// Validate は全ての問題を検証し（slug の必須・重複、Normalize、SelfCheck）、保存用の問題に変換する。
+// 誤りがあれば *ValidationError を返す。
+func (b Bank) Validate() ([]*models.Question, error) {
+	var problems []Problem
+	questions := make([]*models.Question, 0, len(b.Questions))
+	seen := make(map[string]int, len(b.Questions))
+
+	for i, entry := range b.Questions {
+		fail := func(err error) {
+			problems = append(problems, Problem{Index: i, Slug: entry.Slug, Message: err.Error()})
+		}
+		if strings.TrimSpace(entry.Slug) == "" {
+			fail(errors.New("slug は必須です"))
+			continue
+		}
+		if first, dup := seen[entry.Slug]; dup {
+			fail(fmt.Errorf("slug が #%d と重複しています", first))
+			continue
+		}
+		seen[entry.Slug] = i
+
+		q, err := entry.ToQuestion()
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if err := Normalize(q); err != nil {
+			fail(err)
+			continue
+		}
+		if err := SelfCheck(q); err != nil {
+			fail(err)
+			continue
+		}
+		questions = append(questions, q)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return questions, nil
+}
+
+// BankFromQuestions は保存済みの問題から問題バンクを組み立てる。
+func BankFromQuestions(questions []models.Question) (Bank, error) {
+	bank := Bank{Questions: make([]Entry, 0, len(questions))}
+	for _, q := range questions {
+		entry, err := FromQuestion(q)
+		if err != nil {
+			return Bank{}, err
+		}
+		bank.Questions = append(bank.Questions, entry)
+	}
+	return bank, nil
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,143 @@
+// bank_test.go は問題バンクの読み書きと、取り込み前の検証（自己採点を含む）を確認する単体テスト。
+package questionbank
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+func valueSpec(v string) *eval.AnswerSpec {
+	return &eval.AnswerSpec{Type: eval.SpecValue, Value: v}
+}
+
+const sampleYAML = `
+questions:
+  - slug: strawberry-r
+    level: 3
+    tags: [character_counting, text_analysis]
+    problem_statement: strawberryの中にrは何個ある？
+    correct_answer: "3"
+  - slug: tokyo-osaka
+    level: 2
+    tags: [calculation]
+    problem_statement: |
+      東京から大阪まで 500 km を時速 100 km で走ると何時間？
+    correct_answer: "5"
+    expected_unit: 時間
+    unit_policy: penalize
+    scoring_params: {p: 4}
+  - slug: primes
+    level: 2
+    problem_statement: 10 以下の素数を全て答えよ
+    correct_answer: 2, 3, 5, 7
+    answer_spec:
+      type: set
+      items: ["2", "3", "5", "7"]
+`
+
+// TestBankRoundTrip は YAML と JSON の両方で、書き出した問題バンクを読み込み直すと同じ内容になることを確認する。
+func TestBankRoundTrip(t *testing.T) {
+	bank, err := Decode(strings.NewReader(sampleYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	questions, err := bank.Validate()
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(questions) != 3 || *questions[1].Slug != "tokyo-osaka" || string(questions[1].ScoringParams) != `{"p":4}` {
+		t.Fatalf("unexpected questions: %+v", questions)
+	}
+
+	stored := make([]models.Question, len(questions))
+	for i, q := range questions {
+		stored[i] = *q
+	}
+	exported, err := BankFromQuestions(stored)
+	if err != nil {
+		t.Fatalf("BankFromQuestions failed: %v", err)
+	}
+
+	for _, format := range []Format{FormatYAML, FormatJSON} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, exported, format); err != nil {
+			t.Fatalf("%s: Encode failed: %v", format, err)
+		}
+		again, err := Decode(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: Decode failed: %v\n%s", format, err, buf.String())
+		}
+		reimported, err := again.Validate()
+		if err != nil {
+			t.Fatalf("%s: Validate failed: %v", format, err)
+		}
+		for i, q := range reimported {
+			if q.ProblemStatement != questions[i].ProblemStatement || string(q.AnswerSpec) != string(questions[i].AnswerSpec) ||
+				derefString(q.UnitPolicy) != derefString(questions[i].UnitPolicy) {
+				t.Errorf("%s: question %d changed after round trip: %+v", format, i, q)
+			}
+		}
+	}
+}
+
+// TestBankValidate は誤りのある問題が全てまとめて報告されることを確認する。
+func TestBankValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   Entry
+		wantMsg string
+	}{
+		{name: "slug が無い", entry: Entry{Level: 1, ProblemStatement: "1+1", CorrectAnswer: "2"}, wantMsg: "slug は必須"},
+		{name: "slug の文字", entry: Entry{Slug: "Bad Slug", Level: 1, ProblemStatement: "1+1", CorrectAnswer: "2"}, wantMsg: "slug は英小文字"},
+		{name: "未定義のタグ", entry: Entry{Slug: "a", Level: 1, Tags: []string{"nope"}, ProblemStatement: "1+1", CorrectAnswer: "2"}, wantMsg: "未定義のタグ"},
+		{name: "レベル", entry: Entry{Slug: "b", ProblemStatement: "1+1", CorrectAnswer: "2"}, wantMsg: "level"},
+		{name: "unit_policy", entry: Entry{Slug: "d", Level: 1, ProblemStatement: "1+1", CorrectAnswer: "2", ExpectedUnit: "km", UnitPolicy: "ignore"}, wantMsg: "unit_policy は reject か penalize"},
+		{
+			name:    "自己採点で満点にならない",
+			entry:   Entry{Slug: "c", Level: 1, ProblemStatement: "1+1", CorrectAnswer: "3", AnswerSpec: valueSpec("2")},
+			wantMsg: "点になります",
+		},
+	}
+
+	entries := make([]Entry, 0, len(tests)+2)
+	for _, tt := range tests {
+		entries = append(entries, tt.entry)
+	}
+	// 正しい問題と、slug が重複する問題も混ぜる。
+	entries = append(entries,
+		Entry{Slug: "ok", Level: 1, ProblemStatement: "1+1", CorrectAnswer: "2"},
+		Entry{Slug: "ok", Level: 1, ProblemStatement: "2+2", CorrectAnswer: "4"},
+	)
+
+	_, err := Bank{Questions: entries}.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != len(tests)+1 {
+		t.Fatalf("len(problems) = %d, want %d: %v", len(verr.Problems), len(tests)+1, verr)
+	}
+	for i, tt := range tests {
+		if !strings.Contains(verr.Problems[i].Message, tt.wantMsg) {
+			t.Errorf("%s: message = %q, want to contain %q", tt.name, verr.Problems[i].Message, tt.wantMsg)
+		}
+	}
+	if last := verr.Problems[len(verr.Problems)-1]; last.Index != len(entries)-1 || !strings.Contains(last.Message, "重複") {
+		t.Errorf("duplicate slug not reported: %+v", last)
+	}
+}
+
+// TestDecodeRejectsUnknownFields は書き間違えた項目名を黙って無視しないことを確認する。
+func TestDecodeRejectsUnknownFields(t *testing.T) {
+	if _, err := Decode(strings.NewReader("questions:\n  - slug: a\n    levle: 1\n"), FormatYAML); err == nil {
+		t.Error("expected error for unknown yaml field")
+	}
+	if _, err := Decode(strings.NewReader(`{"questions":[{"slug":"a","levle":1}]}`), FormatJSON); err == nil {
+		t.Error("expected error for unknown json field")
+	}
+}
@@ -0,0 +1,148 @@
+// validate.go は問題を保存する前の検証をまとめたファイル。管理 API の登録・更新と問題バンクの取り込みで同じ規則を使う。
+package questionbank
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/internal/variant"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// slugPattern は slug に使える文字列。URL やファイル名にそのまま使えるよう英小文字・数字・ハイフン・アンダースコアに限る。
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Normalize は問題の内容を検証し、保存する形に整える。
+// answer_spec を省略した問題には correct_answer を単一の正解にした value 仕様を補い、scoring_params は既定値だけなら NULL にする。
+// エラーメッセージは管理画面や CLI でそのまま表示できるよう日本語で返す。
+func Normalize(q *models.Question) error {
+	q.ProblemStatement = strings.TrimSpace(q.ProblemStatement)
+	q.CorrectAnswer = strings.TrimSpace(q.CorrectAnswer)
+	q.ExpectedUnit = trimmedOrNil(q.ExpectedUnit)
+	q.UnitPolicy = trimmedOrNil(q.UnitPolicy)
+	q.GradingRubric = trimmedOrNil(q.GradingRubric)
+	q.TemplateKey = trimmedOrNil(q.TemplateKey)
+	q.Slug = trimmedOrNil(q.Slug)
+
+	if q.Level < 1 {
+		return errors.New("level は 1 以上を指定してください")
+	}
+	if q.ProblemStatement == "" || q.CorrectAnswer == "" {
+		return errors.New("problem_statement と correct_answer は空にできません")
+	}
+	if len(q.CorrectAnswer) > 255 {
+		return errors.New("correct_answer は 255 バイト以内にしてください")
+	}
+	if q.Slug != nil && !slugPattern.MatchString(*q.Slug) {
+		return fmt.Errorf("slug は英小文字・数字・-・_ の 64 文字以内にしてください: %s", *q.Slug)
+	}
+
+	if q.Tags == nil {
+		q.Tags = []string{}
+	}
+	seen := make(map[string]bool, len(q.Tags))
+	for _, tag := range q.Tags {
+		if _, ok := models.GetTagByID(tag); !ok {
+			return fmt.Errorf("未定義のタグです: %s", tag)
+		}
+		if seen[tag] {
+			return fmt.Errorf("タグが重複しています: %s", tag)
+		}
+		seen[tag] = true
+	}
+
+	spec, err := eval.ParseAnswerSpec(q.AnswerSpec, q.CorrectAnswer)
+	if err != nil {
+		return fmt.Errorf("answer_spec が不正です: %w", err)
+	}
+	if spec.Type == eval.SpecJudge && q.GradingRubric == nil {
+		return errors.New("answer_spec.type が judge の問題には grading_rubric が必要です")
+	}
+	// 省略時も仕様を明示的に保存し、後から correct_answer だけを書き換えても採点が食い違わないようにする。
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("answer_spec の変換に失敗しました: %w", err)
+	}
+	q.AnswerSpec = specJSON
+
+	scoring, err := eval.ParseScoringParams(q.ScoringParams)
+	if err != nil {
+		return fmt.Errorf("scoring_params が不正です: %w", err)
+	}
+	q.ScoringParams = nil
+	if !scoring.IsZero() {
+		scoringJSON, err := json.Marshal(scoring)
+		if err != nil {
+			return fmt.Errorf("scoring_params の変換に失敗しました: %w", err)
+		}
+		q.ScoringParams = scoringJSON
+	}
+
+	if q.TemplateKey != nil {
+		if _, ok := variant.Lookup(*q.TemplateKey); !ok {
+			return fmt.Errorf("未登録の問題テンプレートです: %s", *q.TemplateKey)
+		}
+	}
+	if q.ExpectedUnit != nil && !eval.KnownUnit(*q.ExpectedUnit) {
+		return fmt.Errorf("未対応の単位です: %s", *q.ExpectedUnit)
+	}
+	if q.UnitPolicy != nil {
+		if !eval.ValidUnitPolicy(eval.UnitPolicy(*q.UnitPolicy)) {
+			return fmt.Errorf("unit_policy は reject か penalize を指定してください: %s", *q.UnitPolicy)
+		}
+		if q.ExpectedUnit == nil {
+			return errors.New("unit_policy は expected_unit を指定した問題にだけ設定できます")
+		}
+	}
+	return nil
+}
+
+// SelfCheck は correct_answer をそのまま回答として採点し、満点になることを確認する。
+// answer_spec と correct_answer が食い違っている（例: 区間の外の代表値、別解に含まれない表記）問題を取り込み前に見つけるためのもの。
+// LLM 採点（judge）の問題は外部呼び出しが必要なため確認しない。Normalize の後に呼ぶこと。
+func SelfCheck(q *models.Question) error {
+	spec, err := eval.ParseAnswerSpec(q.AnswerSpec, q.CorrectAnswer)
+	if err != nil {
+		return fmt.Errorf("answer_spec が不正です: %w", err)
+	}
+	if spec.Type == eval.SpecJudge {
+		return nil
+	}
+	scoring, err := eval.ParseScoringParams(q.ScoringParams)
+	if err != nil {
+		return fmt.Errorf("scoring_params が不正です: %w", err)
+	}
+	key := eval.AnswerKey{CorrectAnswer: q.CorrectAnswer, Spec: spec, Scoring: scoring}
+	if q.ExpectedUnit != nil {
+		key.ExpectedUnit = *q.ExpectedUnit
+	}
+	if q.UnitPolicy != nil {
+		key.UnitPolicy = eval.UnitPolicy(*q.UnitPolicy)
+	}
+
+	// 出題時と同じ版で採点し、実際の採点経路で満点になることを確かめる。
+	result, err := eval.EvaluateVersion(eval.CurrentVersion, q.CorrectAnswer, key)
+	if err != nil {
+		return err
+	}
+	if result.Score != 100 {
+		return fmt.Errorf("correct_answer %q を回答として採点すると %d 点になります（mode=%s）。answer_spec と correct_answer を見直してください", q.CorrectAnswer, result.Score, result.Mode)
+	}
+	return nil
+}
+
+// trimmedOrNil は前後の空白を除き、空なら nil を返す。
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
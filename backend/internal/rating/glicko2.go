@@ -0,0 +1,131 @@
+// Package rating は挑戦を「プレイヤー対問題」の対戦とみなし、双方の強さを Glicko-2 で推定する仕組みをまとめたパッケージ。
+// 生のスコアは問題の難しさを考慮しないため、難しい問題で良い点を取ったプレイヤーほどレーティングが上がり、
+// 多くのプレイヤーが満点を取れない問題ほど問題側のレーティング（データに基づく難易度）が上がる。
+//
+// 計算は Glickman, "Example of the Glicko-2 system"（2013）に従う。1 回の挑戦を 1 レーティング期間として、挑戦のたびに更新する。
+package rating
+
+import "math"
+
+const (
+	// DefaultRating は未対戦のレーティング。
+	DefaultRating = 1500.0
+	// DefaultRD は未対戦のレーティング偏差（RD）。値が大きいほど推定が不確か。
+	DefaultRD = 350.0
+	// DefaultVolatility は未対戦のボラティリティ（強さの変わりやすさ）。
+	DefaultVolatility = 0.06
+	// DefaultTau はボラティリティの変化を抑える系の定数。論文の推奨範囲は 0.3〜1.2。
+	DefaultTau = 0.5
+
+	// scale は Glicko の尺度（1500 中心）と Glicko-2 の内部尺度の換算係数。
+	scale = 173.7178
+	// convergence はボラティリティの反復計算を打ち切る精度。
+	convergence = 0.000001
+)
+
+// Rating は 1 人のプレイヤーまたは 1 問の強さの推定値。Glicko の尺度（1500 中心）で持つ。
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	RD         float64 `json:"rd"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Default は未対戦のレーティングを返す。
+func Default() Rating {
+	return Rating{Rating: DefaultRating, RD: DefaultRD, Volatility: DefaultVolatility}
+}
+
+// Result は 1 回の対戦の結果。Score は勝ち 1、引き分け 0.5、負け 0 で、その間の値も取れる。
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+// Outcome はゲームのスコア（0〜100）を対戦の結果（0〜1）に変換する。範囲外の値は丸める。
+func Outcome(score int) float64 {
+	return math.Min(math.Max(float64(score)/100, 0), 1)
+}
+
+// Match は 1 回の挑戦の結果から、プレイヤーと問題の新しいレーティングを返す。
+// outcome はプレイヤー側の結果で、問題側は 1-outcome として更新する。どちらも対戦前の相手の値を使う。
+func Match(player, question Rating, outcome, tau float64) (Rating, Rating) {
+	newPlayer := Update(player, []Result{{Opponent: question, Score: outcome}}, tau)
+	newQuestion := Update(question, []Result{{Opponent: player, Score: 1 - outcome}}, tau)
+	return newPlayer, newQuestion
+}
+
+// Update は 1 レーティング期間の対戦結果から新しいレーティングを返す。
+// results が空のときは RD だけがボラティリティの分だけ大きくなる（対戦しない間に推定が不確かになる）。
+func Update(r Rating, results []Result, tau float64) Rating {
+	mu := (r.Rating - DefaultRating) / scale
+	phi := r.RD / scale
+	sigma := r.Volatility
+
+	if len(results) == 0 {
+		return Rating{Rating: r.Rating, RD: math.Sqrt(phi*phi+sigma*sigma) * scale, Volatility: sigma}
+	}
+
+	// 推定の分散 v と、結果から見込まれる改善量 delta
+	var invV, sum float64
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - DefaultRating) / scale
+		g := gFactor(res.Opponent.RD / scale)
+		e := expected(mu, muJ, g)
+		invV += g * g * e * (1 - e)
+		sum += g * (res.Score - e)
+	}
+	v := 1 / invV
+	delta := v * sum
+
+	newSigma := newVolatility(phi, sigma, v, delta, tau)
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*sum
+
+	return Rating{Rating: newMu*scale + DefaultRating, RD: newPhi * scale, Volatility: newSigma}
+}
+
+// gFactor は相手の RD が大きいほど結果の影響を小さくする係数。
+func gFactor(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// expected は相手に対する期待スコア。
+func expected(mu, muJ, g float64) float64 {
+	return 1 / (1 + math.Exp(-g*(mu-muJ)))
+}
+
+// newVolatility は論文の手順 5 に従い、Illinois 法で新しいボラティリティを求める。
+func newVolatility(phi, sigma, v, delta, tau float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
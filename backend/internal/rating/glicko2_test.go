@@ -0,0 +1,78 @@
+// glicko2_test.go は Glickman の論文の計算例と一致すること、挑戦の結果に応じてプレイヤーと問題のレーティングが逆向きに動くことを確認する単体テスト。
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdate_PaperExample(t *testing.T) {
+	// Glickman, "Example of the Glicko-2 system" の例。1500/200 のプレイヤーが 3 人と対戦する。
+	player := Rating{Rating: 1500, RD: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, RD: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, RD: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, RD: 300}, Score: 0},
+	}
+	got := Update(player, results, 0.5)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+		tol  float64
+	}{
+		{name: "rating", got: got.Rating, want: 1464.06, tol: 0.01},
+		{name: "rd", got: got.RD, want: 151.52, tol: 0.01},
+		{name: "volatility", got: got.Volatility, want: 0.05999, tol: 0.00001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > tt.tol {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdate_NoResults(t *testing.T) {
+	got := Update(Rating{Rating: 1600, RD: 50, Volatility: 0.06}, nil, DefaultTau)
+	if got.Rating != 1600 || got.RD <= 50 || got.Volatility != 0.06 {
+		t.Errorf("Update(no results) = %+v, want only RD to grow", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		outcome      float64
+		wantPlayerUp bool
+	}{
+		{name: "満点", outcome: Outcome(100), wantPlayerUp: true},
+		{name: "0 点", outcome: Outcome(0), wantPlayerUp: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, question := Match(Default(), Default(), tt.outcome, DefaultTau)
+			if (player.Rating > DefaultRating) != tt.wantPlayerUp {
+				t.Errorf("player rating = %v", player.Rating)
+			}
+			// 問題側は逆向きに同じだけ動き、どちらも RD が小さくなる。
+			if math.Abs((player.Rating-DefaultRating)+(question.Rating-DefaultRating)) > 1e-9 {
+				t.Errorf("ratings not symmetric: player %v, question %v", player.Rating, question.Rating)
+			}
+			if player.RD >= DefaultRD || question.RD >= DefaultRD {
+				t.Errorf("RD should shrink: player %v, question %v", player.RD, question.RD)
+			}
+		})
+	}
+
+	// 引き分け（50 点）なら同じ強さ同士のレーティングは変わらない。
+	player, _ := Match(Default(), Default(), Outcome(50), DefaultTau)
+	if math.Abs(player.Rating-DefaultRating) > 1e-9 {
+		t.Errorf("draw changed rating: %v", player.Rating)
+	}
+	if Outcome(150) != 1 || Outcome(-10) != 0 {
+		t.Error("Outcome should clamp to [0, 1]")
+	}
+}
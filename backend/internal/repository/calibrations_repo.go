@@ -0,0 +1,220 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// calibrations_repo.go は scores から問題ごとの解かれ方を集計して question_calibrations に保存し、
+// 管理者が提案を受け入れたときに questions.level を書き換える処理をまとめます。提案の規則は internal/calibration にあります。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/internal/calibration"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// ErrNoLevelSuggestion は受け入れようとした問題に level の提案が無い（未集計または挑戦者が少ない）ときに返します。
+var ErrNoLevelSuggestion = errors.New("no level suggestion")
+
+// QuestionCalibration は question_calibrations の 1 行に、比較用の今の level を付けたものです。
+type QuestionCalibration struct {
+	calibration.Stats
+	Level          int       `json:"level"`           // 今の questions.level
+	SuggestedLevel *int      `json:"suggested_level"` // 挑戦者が少なく判断できない場合は null
+	ComputedAt     time.Time `json:"computed_at"`
+}
+
+// CalibrationsRepository は難易度の集計と提案の受け入れを定義するインターフェースです。
+type CalibrationsRepository interface {
+	// Refresh は削除されていない全問題の解かれ方を集計し直して保存し、保存した問題の数を返します。
+	// 回答が無くなった問題の集計は消します。calibration.Refresher を満たします。
+	Refresh(ctx context.Context) (int, error)
+
+	// FindAll は削除されていない問題の集計を問題 ID 順に返します。未集計の問題は含みません。
+	FindAll(ctx context.Context) ([]QuestionCalibration, error)
+
+	// AcceptSuggestion は問題の level を提案の値に書き換え、変更履歴に残します。
+	// 提案が無ければ ErrNoLevelSuggestion、問題が無い（削除済み）なら ErrQuestionNotFound を返します。
+	// 既に提案どおりの level なら何も書き込まずに今の問題を返します。
+	AcceptSuggestion(ctx context.Context, questionID int, actor string) (*models.Question, error)
+}
+
+// calibrationsRepo は CalibrationsRepository の実装です。
+type calibrationsRepo struct {
+	db *sql.DB
+}
+
+// NewCalibrationsRepository は CalibrationsRepository の新しいインスタンスを作成します。
+func NewCalibrationsRepository(db *sql.DB) CalibrationsRepository {
+	return &calibrationsRepo{db: db}
+}
+
+// Refresh は集計を計算してから 1 トランザクションで置き換えます。
+// 満点までの挑戦回数は、ユーザーごとに回答を古い順に並べて初めて 100 点になった回の番号から求めます。
+func (r *calibrationsRepo) Refresh(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH ordered AS (
+			SELECT s.question_id, s.user_id, s.score,
+			       ROW_NUMBER() OVER (PARTITION BY s.question_id, s.user_id ORDER BY s.created_at, s.id) AS n
+			FROM scores s
+			JOIN questions q ON q.id = s.question_id AND q.deleted_at IS NULL
+			WHERE s.user_id IS NOT NULL AND NOT s.needs_review
+		),
+		per_player AS (
+			SELECT question_id, user_id, COUNT(*) AS attempts, SUM(score) AS total,
+			       MIN(n) FILTER (WHERE score = 100) AS first_100
+			FROM ordered
+			GROUP BY question_id, user_id
+		)
+		SELECT question_id,
+		       COUNT(*) AS players,
+		       SUM(attempts) AS attempts,
+		       COUNT(first_100)::float8 / COUNT(*) AS solve_rate,
+		       SUM(total)::float8 / SUM(attempts) AS mean_score,
+		       AVG(first_100)::float8 AS attempts_to_first_100
+		FROM per_player
+		GROUP BY question_id
+		ORDER BY question_id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query solve stats: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var stats []calibration.Stats
+	for rows.Next() {
+		var s calibration.Stats
+		if err := rows.Scan(&s.QuestionID, &s.Players, &s.Attempts, &s.SolveRate, &s.MeanScore, &s.AttemptsToFirst100); err != nil {
+			return 0, fmt.Errorf("failed to scan solve stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("solve stats rows iteration error: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	ids := make([]int64, 0, len(stats))
+	for _, s := range stats {
+		var suggested *int
+		if level, ok := calibration.SuggestLevel(s); ok {
+			suggested = &level
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO question_calibrations
+				(question_id, players, attempts, solve_rate, mean_score, attempts_to_first_100, suggested_level, computed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+			ON CONFLICT (question_id) DO UPDATE
+			SET players = EXCLUDED.players,
+			    attempts = EXCLUDED.attempts,
+			    solve_rate = EXCLUDED.solve_rate,
+			    mean_score = EXCLUDED.mean_score,
+			    attempts_to_first_100 = EXCLUDED.attempts_to_first_100,
+			    suggested_level = EXCLUDED.suggested_level,
+			    computed_at = EXCLUDED.computed_at
+		`, s.QuestionID, s.Players, s.Attempts, s.SolveRate, s.MeanScore, s.AttemptsToFirst100, suggested); err != nil {
+			return 0, fmt.Errorf("failed to save calibration of question %d: %w", s.QuestionID, err)
+		}
+		ids = append(ids, int64(s.QuestionID))
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM question_calibrations WHERE question_id <> ALL($1)`, pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to delete stale calibrations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit calibrations: %w", err)
+	}
+	return len(stats), nil
+}
+
+// FindAll は削除されていない問題の集計を今の level と並べて返します。
+func (r *calibrationsRepo) FindAll(ctx context.Context) ([]QuestionCalibration, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.question_id, c.players, c.attempts, c.solve_rate, c.mean_score, c.attempts_to_first_100,
+		       q.level, c.suggested_level, c.computed_at
+		FROM question_calibrations c
+		JOIN questions q ON q.id = c.question_id AND q.deleted_at IS NULL
+		ORDER BY c.question_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibrations: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	calibrations := []QuestionCalibration{}
+	for rows.Next() {
+		var c QuestionCalibration
+		if err := rows.Scan(&c.QuestionID, &c.Players, &c.Attempts, &c.SolveRate, &c.MeanScore, &c.AttemptsToFirst100,
+			&c.Level, &c.SuggestedLevel, &c.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration: %w", err)
+		}
+		calibrations = append(calibrations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("calibrations rows iteration error: %w", err)
+	}
+	return calibrations, nil
+}
+
+// AcceptSuggestion は問題を行ロックしてから提案を読み、level だけを書き換えます。
+// 管理 API の更新と同じく、変更と履歴は同じトランザクションで書き込みます。
+func (r *calibrationsRepo) AcceptSuggestion(ctx context.Context, questionID int, actor string) (*models.Question, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	before, err := scanQuestion(tx.QueryRowContext(ctx,
+		`SELECT `+questionColumns+` FROM questions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, questionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, fmt.Errorf("failed to lock question: %w", err)
+	}
+
+	var suggested *int
+	err = tx.QueryRowContext(ctx, `SELECT suggested_level FROM question_calibrations WHERE question_id = $1`, questionID).Scan(&suggested)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && suggested == nil) {
+		return nil, ErrNoLevelSuggestion
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibration: %w", err)
+	}
+	if *suggested == before.Level {
+		return before, nil
+	}
+
+	after := *before
+	after.Level = *suggested
+	if err := tx.QueryRowContext(ctx,
+		`UPDATE questions SET level = $2, updated_at = NOW() WHERE id = $1 RETURNING updated_at`,
+		questionID, after.Level,
+	).Scan(&after.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update question level: %w", err)
+	}
+	if err := insertAuditLog(ctx, tx, questionID, actor, AuditActionCalibrate, questionDiff(before, &after)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit level calibration: %w", err)
+	}
+	return &after, nil
+}
@@ -0,0 +1,112 @@
+// calibrations_repo_test.go は scores から問題ごとの解かれ方を集計して提案を保存し、受け入れると level が変わって履歴に残ることを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCalibrationsRepo_RefreshAndAccept(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	// 5 人（MinPlayers）が挑戦し、全員が 1 回目で満点を取った問題です。
+	userIDs := []int{999950, 999951, 999952, 999953, 999954}
+	for _, id := range userIDs {
+		cleanupTestData(t, db, id)
+		ensureTestUser(t, db, id)
+		defer cleanupTestData(t, db, id)
+	}
+
+	var questionID int
+	if err := db.QueryRow(`
+		INSERT INTO questions (level, problem_statement, correct_answer)
+		VALUES (4, 'calibration test', '1')
+		RETURNING id
+	`).Scan(&questionID); err != nil {
+		t.Fatalf("failed to insert question: %v", err)
+	}
+	defer func() {
+		_, _ = db.Exec("DELETE FROM scores WHERE question_id = $1", questionID)
+		_, _ = db.Exec("DELETE FROM question_calibrations WHERE question_id = $1", questionID)
+		_, _ = db.Exec("DELETE FROM question_audit_logs WHERE question_id = $1", questionID)
+		_, _ = db.Exec("DELETE FROM questions WHERE id = $1", questionID)
+	}()
+
+	repo := NewCalibrationsRepository(db)
+	if _, err := repo.AcceptSuggestion(ctx, questionID, "alice"); !errors.Is(err, ErrNoLevelSuggestion) {
+		t.Fatalf("AcceptSuggestion() before refresh error = %v, want ErrNoLevelSuggestion", err)
+	}
+
+	for i, id := range userIDs {
+		// 最初のユーザーだけは 2 回目で満点を取ります。
+		scores := []int{100}
+		if i == 0 {
+			scores = []int{40, 100}
+		}
+		for _, score := range scores {
+			if _, err := db.Exec(`
+				INSERT INTO scores (user_id, question_id, prompt, ai_response, score)
+				VALUES ($1, $2, 'p', 'r', $3)
+			`, id, questionID, score); err != nil {
+				t.Fatalf("failed to insert score: %v", err)
+			}
+		}
+	}
+
+	if _, err := repo.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	calibrations, err := repo.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll() error = %v", err)
+	}
+	var found *QuestionCalibration
+	for i := range calibrations {
+		if calibrations[i].QuestionID == questionID {
+			found = &calibrations[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("calibration of question %d was not saved", questionID)
+	}
+	if found.Players != 5 || found.Attempts != 6 || found.SolveRate != 1 || found.MeanScore != 90 ||
+		found.AttemptsToFirst100 == nil || *found.AttemptsToFirst100 != 1.2 {
+		t.Errorf("stats = %+v", found.Stats)
+	}
+	if found.Level != 4 || found.SuggestedLevel == nil || *found.SuggestedLevel != 1 {
+		t.Errorf("level = %d, suggested = %v, want 4 and 1", found.Level, found.SuggestedLevel)
+	}
+
+	q, err := repo.AcceptSuggestion(ctx, questionID, "alice")
+	if err != nil {
+		t.Fatalf("AcceptSuggestion() error = %v", err)
+	}
+	if q.Level != 1 {
+		t.Errorf("level after accept = %d, want 1", q.Level)
+	}
+	logs, err := NewQuestionsRepository(db).FindAuditLogs(ctx, questionID, 10)
+	if err != nil {
+		t.Fatalf("FindAuditLogs() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].Action != AuditActionCalibrate || logs[0].Actor != "alice" || logs[0].Changes["level"] == nil {
+		t.Errorf("audit logs = %+v", logs)
+	}
+
+	// 提案どおりになった後は何も書き込みません。
+	if _, err := repo.AcceptSuggestion(ctx, questionID, "alice"); err != nil {
+		t.Fatalf("second AcceptSuggestion() error = %v", err)
+	}
+	if logs, _ := NewQuestionsRepository(db).FindAuditLogs(ctx, questionID, 10); len(logs) != 1 {
+		t.Errorf("audit logs after second accept = %d, want 1", len(logs))
+	}
+}
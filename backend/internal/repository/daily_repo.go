@@ -0,0 +1,304 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// daily_repo.go は今日の一問（daily_challenges / daily_entries）の操作をまとめます。
+// 日付の区切りと問題の選び方は internal/daily にあり、ここでは出題の保存・挑戦回数の上限・日付ごとのランキングを扱います。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/internal/daily"
+)
+
+// ErrDailyNotFound は指定した日付にまだ出題が無い（誰も参照していない）ときに返します。
+var ErrDailyNotFound = errors.New("daily challenge not found")
+
+// ErrNoDailyQuestion は出題できる問題が 1 問も無いときに返します。
+var ErrNoDailyQuestion = errors.New("no question available for the daily challenge")
+
+// ErrDailyAttemptsExhausted はその日の挑戦回数を使い切っているときに返します。
+var ErrDailyAttemptsExhausted = errors.New("daily attempts exhausted")
+
+// DailyChallenge はある日付の出題です。問題文と正解は含めません。
+type DailyChallenge struct {
+	Date       string   `json:"date"` // daily.DateLayout の日付
+	QuestionID int      `json:"question_id"`
+	Level      int      `json:"level"`
+	Tags       []string `json:"tags"`
+	Seed       int64    `json:"-"` // テンプレート問題のバリアントのシード。出題を推測できないようクライアントには返しません。
+}
+
+// DailyEntry は日付ごとのランキングの 1 行（ユーザー × 日付の成績）です。
+type DailyEntry struct {
+	Rank      *int       `json:"rank"` // まだ採点された挑戦が無ければ null
+	UserID    int        `json:"user_id"`
+	Username  string     `json:"username"`
+	Attempts  int        `json:"attempts"`   // その日に使った挑戦回数
+	BestScore *int       `json:"best_score"` // その日の最高点
+	BestAt    *time.Time `json:"best_at"`    // 最高点を初めて取った時刻。同点なら早い方が上位です。
+}
+
+// DailyArchive は過去の日の出題と勝者です。
+type DailyArchive struct {
+	Date       string      `json:"date"`
+	QuestionID int         `json:"question_id"`
+	Players    int         `json:"players"` // 採点された挑戦があるユーザーの数
+	Winner     *DailyEntry `json:"winner"`  // 1 位のユーザー。誰も挑戦しなかった日は null
+}
+
+// DailyRepository は今日の一問に対する操作を定義するインターフェースです。
+type DailyRepository interface {
+	// FindOrCreate は日付の出題を返します。まだ無ければ削除されていない問題から daily.Pick で選んで保存します。
+	// 問題が 1 問も無ければ ErrNoDailyQuestion を返します。
+	FindOrCreate(ctx context.Context, date string) (*DailyChallenge, error)
+
+	// Find は日付の出題を返します。無ければ ErrDailyNotFound を返します（新しく選びません）。
+	Find(ctx context.Context, date string) (*DailyChallenge, error)
+
+	// ReserveAttempt は挑戦回数を 1 つ使い、使った後の回数を返します。limit 回を使い切っていれば ErrDailyAttemptsExhausted を返します。
+	// 同じユーザーの挑戦が同時に届いても limit を超えません。出題（FindOrCreate）が先に保存されている必要があります。
+	ReserveAttempt(ctx context.Context, date string, userID, limit int) (int, error)
+
+	// ReleaseAttempt は ReserveAttempt で使った挑戦回数を 1 つ戻します。AI の呼び出しに失敗した場合に使います。
+	ReleaseAttempt(ctx context.Context, date string, userID int) error
+
+	// RecordResult は採点結果をその日の最高点に反映します。
+	RecordResult(ctx context.Context, date string, userID, score int) error
+
+	// FindEntry はユーザーのその日の成績を順位付きで返します。まだ挑戦していなければ nil を返します。
+	FindEntry(ctx context.Context, date string, userID int) (*DailyEntry, error)
+
+	// FindLeaderboard はその日のランキングを最高点の高い順（同点なら先に取った順）に limit 件返します。
+	FindLeaderboard(ctx context.Context, date string, limit int) ([]DailyEntry, error)
+
+	// FindHistory は before より前の日の出題と勝者を新しい順に limit 件返します。
+	FindHistory(ctx context.Context, before string, limit int) ([]DailyArchive, error)
+}
+
+// dailyRepo は DailyRepository の実装です。
+type dailyRepo struct {
+	db *sql.DB
+}
+
+// NewDailyRepository は DailyRepository の新しいインスタンスを作成します。
+func NewDailyRepository(db *sql.DB) DailyRepository {
+	return &dailyRepo{db: db}
+}
+
+// dailyRankOrder はその日のランキングの並び順です。FindLeaderboard・FindEntry・FindHistory で揃えてください。
+const dailyRankOrder = `e.best_score DESC, e.best_at ASC, e.user_id ASC`
+
+// FindOrCreate は出題が無ければ選んで保存します。
+// 同じ日付で同時に呼ばれても、ON CONFLICT で先に保存された方に揃えます（同じ候補なら選ぶ問題も同じです）。
+func (r *dailyRepo) FindOrCreate(ctx context.Context, date string) (*DailyChallenge, error) {
+	challenge, err := r.Find(ctx, date)
+	if !errors.Is(err, ErrDailyNotFound) {
+		return challenge, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM questions WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily candidates: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan daily candidate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("daily candidates rows iteration error: %w", err)
+	}
+
+	questionID, ok := daily.Pick(date, ids)
+	if !ok {
+		return nil, ErrNoDailyQuestion
+	}
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO daily_challenges (challenge_date, question_id, seed)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (challenge_date) DO NOTHING
+	`, date, questionID, daily.Seed(date)); err != nil {
+		return nil, fmt.Errorf("failed to insert daily challenge: %w", err)
+	}
+	return r.Find(ctx, date)
+}
+
+// Find は日付の出題を問題のレベル・タグと一緒に読みます。
+func (r *dailyRepo) Find(ctx context.Context, date string) (*DailyChallenge, error) {
+	var c DailyChallenge
+	err := r.db.QueryRowContext(ctx, `
+		SELECT to_char(c.challenge_date, 'YYYY-MM-DD'), c.question_id, q.level, q.tags, c.seed
+		FROM daily_challenges c
+		JOIN questions q ON q.id = c.question_id
+		WHERE c.challenge_date = $1
+	`, date).Scan(&c.Date, &c.QuestionID, &c.Level, pq.Array(&c.Tags), &c.Seed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDailyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily challenge: %w", err)
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return &c, nil
+}
+
+// ReserveAttempt は回数が上限未満のときだけ増やす UPSERT で、数えることと増やすことを 1 文で行います。
+func (r *dailyRepo) ReserveAttempt(ctx context.Context, date string, userID, limit int) (int, error) {
+	var used int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO daily_entries (challenge_date, user_id, attempts)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (challenge_date, user_id) DO UPDATE
+		SET attempts = daily_entries.attempts + 1
+		WHERE daily_entries.attempts < $3
+		RETURNING attempts
+	`, date, userID, limit).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDailyAttemptsExhausted
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve daily attempt: %w", err)
+	}
+	return used, nil
+}
+
+// ReleaseAttempt は挑戦回数を 1 つ戻します。
+func (r *dailyRepo) ReleaseAttempt(ctx context.Context, date string, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE daily_entries
+		SET attempts = attempts - 1
+		WHERE challenge_date = $1 AND user_id = $2 AND attempts > 0
+	`, date, userID); err != nil {
+		return fmt.Errorf("failed to release daily attempt: %w", err)
+	}
+	return nil
+}
+
+// RecordResult は最高点を更新したときだけ best_at を進めます（SET の右辺は更新前の値を参照します）。
+func (r *dailyRepo) RecordResult(ctx context.Context, date string, userID, score int) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE daily_entries
+		SET best_at = CASE WHEN best_score IS NULL OR $3 > best_score THEN NOW() ELSE best_at END,
+		    best_score = GREATEST(COALESCE(best_score, $3), $3)
+		WHERE challenge_date = $1 AND user_id = $2
+	`, date, userID, score); err != nil {
+		return fmt.Errorf("failed to record daily result: %w", err)
+	}
+	return nil
+}
+
+// FindEntry はその日の採点済みの成績に順位を付けてから、ユーザーの行を取り出します。
+func (r *dailyRepo) FindEntry(ctx context.Context, date string, userID int) (*DailyEntry, error) {
+	var e DailyEntry
+	err := r.db.QueryRowContext(ctx, `
+		WITH ranked AS (
+			SELECT e.user_id, ROW_NUMBER() OVER (ORDER BY `+dailyRankOrder+`) AS rank
+			FROM daily_entries e
+			WHERE e.challenge_date = $1 AND e.best_score IS NOT NULL
+		)
+		SELECT r.rank, e.user_id, u.username, e.attempts, e.best_score, e.best_at
+		FROM daily_entries e
+		JOIN users u ON u.id = e.user_id
+		LEFT JOIN ranked r ON r.user_id = e.user_id
+		WHERE e.challenge_date = $1 AND e.user_id = $2
+	`, date, userID).Scan(&e.Rank, &e.UserID, &e.Username, &e.Attempts, &e.BestScore, &e.BestAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily entry: %w", err)
+	}
+	return &e, nil
+}
+
+// FindLeaderboard はその日の採点済みの成績を順位付きで返します。
+func (r *dailyRepo) FindLeaderboard(ctx context.Context, date string, limit int) ([]DailyEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ROW_NUMBER() OVER (ORDER BY `+dailyRankOrder+`) AS rank,
+		       e.user_id, u.username, e.attempts, e.best_score, e.best_at
+		FROM daily_entries e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.challenge_date = $1 AND e.best_score IS NOT NULL
+		ORDER BY `+dailyRankOrder+`
+		LIMIT $2
+	`, date, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily leaderboard: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entries []DailyEntry
+	for rows.Next() {
+		var e DailyEntry
+		if err := rows.Scan(&e.Rank, &e.UserID, &e.Username, &e.Attempts, &e.BestScore, &e.BestAt); err != nil {
+			return nil, fmt.Errorf("failed to scan daily leaderboard row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("daily leaderboard rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// FindHistory は日付ごとに 1 位の行だけを LATERAL で引きます。
+func (r *dailyRepo) FindHistory(ctx context.Context, before string, limit int) ([]DailyArchive, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT to_char(c.challenge_date, 'YYYY-MM-DD'), c.question_id,
+		       (SELECT COUNT(*) FROM daily_entries p WHERE p.challenge_date = c.challenge_date AND p.best_score IS NOT NULL),
+		       w.user_id, w.username, w.attempts, w.best_score, w.best_at
+		FROM daily_challenges c
+		LEFT JOIN LATERAL (
+			SELECT e.user_id, u.username, e.attempts, e.best_score, e.best_at
+			FROM daily_entries e
+			JOIN users u ON u.id = e.user_id
+			WHERE e.challenge_date = c.challenge_date AND e.best_score IS NOT NULL
+			ORDER BY `+dailyRankOrder+`
+			LIMIT 1
+		) w ON TRUE
+		WHERE c.challenge_date < $1
+		ORDER BY c.challenge_date DESC
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily history: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var history []DailyArchive
+	for rows.Next() {
+		var a DailyArchive
+		var winnerID, attempts *int
+		var username *string
+		var w DailyEntry
+		if err := rows.Scan(&a.Date, &a.QuestionID, &a.Players, &winnerID, &username, &attempts, &w.BestScore, &w.BestAt); err != nil {
+			return nil, fmt.Errorf("failed to scan daily history row: %w", err)
+		}
+		if winnerID != nil {
+			first := 1
+			w.Rank, w.UserID, w.Username, w.Attempts = &first, *winnerID, *username, *attempts
+			a.Winner = &w
+		}
+		history = append(history, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("daily history rows iteration error: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,126 @@
+// daily_repo_test.go は今日の一問の出題が日付ごとに固定されること、挑戦回数の上限、日付ごとのランキングと過去の勝者を結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// cleanupDailyData はテストで使った日付の出題と成績を削除します。
+func cleanupDailyData(t *testing.T, db *sql.DB, dates ...string) {
+	t.Helper()
+	for _, date := range dates {
+		_, _ = db.Exec("DELETE FROM daily_entries WHERE challenge_date = $1", date)
+		_, _ = db.Exec("DELETE FROM daily_challenges WHERE challenge_date = $1", date)
+	}
+}
+
+func TestDailyRepo(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	var questions int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM questions WHERE deleted_at IS NULL`).Scan(&questions); err != nil || questions == 0 {
+		t.Skipf("問題が用意されていません（スキップ）: %v", err)
+	}
+
+	ctx := context.Background()
+	// 本物の出題と重ならないよう、遠い過去の日付を使います。
+	const date, nextDate = "2000-01-01", "2000-01-02"
+	firstUserID, secondUserID := 999930, 999931
+	cleanupDailyData(t, db, date, nextDate)
+	for _, id := range []int{firstUserID, secondUserID} {
+		cleanupTestData(t, db, id)
+		ensureTestUser(t, db, id)
+	}
+	defer func() {
+		cleanupDailyData(t, db, date, nextDate)
+		cleanupTestData(t, db, firstUserID)
+		cleanupTestData(t, db, secondUserID)
+	}()
+
+	repo := NewDailyRepository(db)
+
+	if _, err := repo.Find(ctx, date); !errors.Is(err, ErrDailyNotFound) {
+		t.Fatalf("Find() before creation error = %v, want ErrDailyNotFound", err)
+	}
+	challenge, err := repo.FindOrCreate(ctx, date)
+	if err != nil {
+		t.Fatalf("FindOrCreate() error = %v", err)
+	}
+	again, err := repo.FindOrCreate(ctx, date)
+	if err != nil {
+		t.Fatalf("FindOrCreate() error = %v", err)
+	}
+	if again.QuestionID != challenge.QuestionID || again.Seed != challenge.Seed {
+		t.Errorf("FindOrCreate() changed the challenge: %+v -> %+v", challenge, again)
+	}
+
+	// 上限 2 回まで予約でき、3 回目は断られます。戻した分はもう一度使えます。
+	for want := 1; want <= 2; want++ {
+		used, err := repo.ReserveAttempt(ctx, date, firstUserID, 2)
+		if err != nil || used != want {
+			t.Fatalf("ReserveAttempt() = %d, %v, want %d", used, err, want)
+		}
+	}
+	if _, err := repo.ReserveAttempt(ctx, date, firstUserID, 2); !errors.Is(err, ErrDailyAttemptsExhausted) {
+		t.Fatalf("ReserveAttempt() over the limit error = %v, want ErrDailyAttemptsExhausted", err)
+	}
+	if err := repo.ReleaseAttempt(ctx, date, firstUserID); err != nil {
+		t.Fatalf("ReleaseAttempt() error = %v", err)
+	}
+	if used, err := repo.ReserveAttempt(ctx, date, firstUserID, 2); err != nil || used != 2 {
+		t.Fatalf("ReserveAttempt() after release = %d, %v, want 2", used, err)
+	}
+
+	// 採点前は順位が付かず、ランキングにも出ません。
+	entry, err := repo.FindEntry(ctx, date, firstUserID)
+	if err != nil || entry == nil || entry.Rank != nil || entry.Attempts != 2 {
+		t.Fatalf("FindEntry() before a result = %+v, %v", entry, err)
+	}
+
+	if _, err := repo.ReserveAttempt(ctx, date, secondUserID, 2); err != nil {
+		t.Fatalf("ReserveAttempt() error = %v", err)
+	}
+	for _, r := range []struct{ userID, score int }{
+		{firstUserID, 70},
+		{firstUserID, 50}, // 低い点では最高点は下がりません
+		{secondUserID, 90},
+	} {
+		if err := repo.RecordResult(ctx, date, r.userID, r.score); err != nil {
+			t.Fatalf("RecordResult() error = %v", err)
+		}
+	}
+
+	board, err := repo.FindLeaderboard(ctx, date, 10)
+	if err != nil {
+		t.Fatalf("FindLeaderboard() error = %v", err)
+	}
+	if len(board) != 2 || board[0].UserID != secondUserID || *board[0].BestScore != 90 || *board[1].BestScore != 70 {
+		t.Fatalf("FindLeaderboard() = %+v", board)
+	}
+	if entry, err = repo.FindEntry(ctx, date, firstUserID); err != nil || entry.Rank == nil || *entry.Rank != 2 {
+		t.Errorf("FindEntry() = %+v, %v, want rank 2", entry, err)
+	}
+	if entry, err = repo.FindEntry(ctx, nextDate, firstUserID); err != nil || entry != nil {
+		t.Errorf("FindEntry() on another day = %+v, %v, want nil", entry, err)
+	}
+
+	history, err := repo.FindHistory(ctx, nextDate, 1)
+	if err != nil {
+		t.Fatalf("FindHistory() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Date != date || history[0].Players != 2 ||
+		history[0].Winner == nil || history[0].Winner.UserID != secondUserID {
+		t.Errorf("FindHistory() = %+v", history)
+	}
+}
@@ -0,0 +1,88 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// progress_repo.go はレベルの解放（user_progress）の操作をまとめます。
+// 解放の条件は internal/progression にあり、ここでは user_question_best からレベルごとの成績を集計し、解放が進んだときに保存します。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shiv/CoT_game/backend/internal/progression"
+)
+
+// ProgressRepository はレベルの解放に対する操作を定義するインターフェースです。
+type ProgressRepository interface {
+	// Sync はユーザーの成績から進捗を計算し直し、解放済みのレベルが上がっていれば保存してから返します。
+	// 解放済みのレベルは下がりません。
+	Sync(ctx context.Context, userID int) (*progression.Progress, error)
+}
+
+// progressRepo は ProgressRepository の実装です。
+type progressRepo struct {
+	db    *sql.DB
+	rules progression.Rules
+}
+
+// NewProgressRepository は rules で解放を判断する ProgressRepository の新しいインスタンスを作成します。
+func NewProgressRepository(db *sql.DB, rules progression.Rules) ProgressRepository {
+	return &progressRepo{db: db, rules: rules}
+}
+
+// Sync はレベルごとの問題数・満点を取った問題数と自己ベストの合計を集計し、保存済みの解放レベルと合わせて進捗を作ります。
+// 同時に呼ばれても解放レベルが下がらないよう、書き込みは GREATEST で今の値と比べます。
+func (r *progressRepo) Sync(ctx context.Context, userID int) (*progression.Progress, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT q.level,
+		       COUNT(*) AS questions,
+		       COUNT(*) FILTER (WHERE b.best_score = 100) AS cleared,
+		       COALESCE(SUM(b.best_score), 0) AS points
+		FROM questions q
+		LEFT JOIN user_question_best b ON b.question_id = q.id AND b.user_id = $1
+		WHERE q.deleted_at IS NULL
+		GROUP BY q.level
+		ORDER BY q.level
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query level stats: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var levels []progression.LevelStats
+	totalPoints := 0
+	for rows.Next() {
+		var l progression.LevelStats
+		var points int
+		if err := rows.Scan(&l.Level, &l.Questions, &l.Cleared, &points); err != nil {
+			return nil, fmt.Errorf("failed to scan level stats: %w", err)
+		}
+		levels = append(levels, l)
+		totalPoints += points
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("level stats rows iteration error: %w", err)
+	}
+
+	stored := progression.GuestLevel
+	err = r.db.QueryRowContext(ctx, `SELECT unlocked_level FROM user_progress WHERE user_id = $1`, userID).Scan(&stored)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query user progress: %w", err)
+	}
+
+	progress := r.rules.Evaluate(levels, totalPoints, stored)
+	if progress.UnlockedLevel > stored {
+		if _, err := r.db.ExecContext(ctx, `
+			INSERT INTO user_progress (user_id, unlocked_level, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (user_id) DO UPDATE
+			SET unlocked_level = GREATEST(user_progress.unlocked_level, EXCLUDED.unlocked_level),
+			    updated_at = EXCLUDED.updated_at
+		`, userID, progress.UnlockedLevel); err != nil {
+			return nil, fmt.Errorf("failed to save user progress: %w", err)
+		}
+	}
+	return &progress, nil
+}
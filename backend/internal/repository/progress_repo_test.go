@@ -0,0 +1,70 @@
+// progress_repo_test.go は自己ベストからレベルの解放を計算して保存し、一度解放したレベルが下がらないことを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/progression"
+)
+
+func TestProgressRepo_Sync(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	testUserID := 999940
+	cleanupTestData(t, db, testUserID)
+	ensureTestUser(t, db, testUserID)
+	defer cleanupTestData(t, db, testUserID)
+
+	var questionID, maxLevel int
+	if err := db.QueryRow(`SELECT MIN(id), MAX(level) FROM questions WHERE deleted_at IS NULL`).Scan(&questionID, &maxLevel); err != nil {
+		t.Skipf("問題が用意されていません（スキップ）: %v", err)
+	}
+
+	// 1 点でも稼げば全レベルが解放される条件で、点数による解放を確認します。
+	repo := NewProgressRepository(db, progression.Rules{Clears: 100, Points: 1})
+	progress, err := repo.Sync(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if progress.UnlockedLevel != progression.GuestLevel || progress.TotalPoints != 0 {
+		t.Errorf("initial progress = %+v", progress)
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO user_question_best (user_id, question_id, best_score, attempts, best_at, last_at)
+		VALUES ($1, $2, 100, 1, NOW(), NOW())
+	`, testUserID, questionID); err != nil {
+		t.Fatalf("failed to insert best: %v", err)
+	}
+	if progress, err = repo.Sync(ctx, testUserID); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if progress.UnlockedLevel != maxLevel || progress.TotalPoints != 100 || progress.Next != nil {
+		t.Errorf("progress after a clear = %+v, want unlocked %d", progress, maxLevel)
+	}
+
+	// 自己ベストが消えても（再採点など）、保存した解放レベルは下がりません。
+	if _, err := db.Exec(`DELETE FROM user_question_best WHERE user_id = $1`, testUserID); err != nil {
+		t.Fatalf("failed to delete best: %v", err)
+	}
+	if progress, err = repo.Sync(ctx, testUserID); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	var stored int
+	if err := db.QueryRow(`SELECT unlocked_level FROM user_progress WHERE user_id = $1`, testUserID).Scan(&stored); err != nil {
+		t.Fatalf("failed to read user_progress: %v", err)
+	}
+	if progress.UnlockedLevel != maxLevel || stored != maxLevel {
+		t.Errorf("unlocked = %d, stored = %d, want %d", progress.UnlockedLevel, stored, maxLevel)
+	}
+}
@@ -0,0 +1,560 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// questions_repo.go は管理 API から使う questions テーブルの CRUD と、変更履歴（question_audit_logs）の記録をまとめます。
+// 問題の変更とその履歴は必ず同じトランザクションで書き込み、履歴の無い変更が残らないようにしています。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// ErrQuestionNotFound は指定した問題が存在しない（または削除済みで対象外の）ときに返します。
+var ErrQuestionNotFound = errors.New("question not found")
+
+// ErrQuestionSlugTaken は登録・更新しようとした slug が別の問題で使われているときに返します。
+var ErrQuestionSlugTaken = errors.New("question slug already exists")
+
+// uniqueViolation は PostgreSQL の一意制約違反のエラーコードです。
+const uniqueViolation = "23505"
+
+// 変更履歴の操作の種類です。
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	// AuditActionCalibrate は集計に基づく level の提案を受け入れたときの記録です（CalibrationsRepository.AcceptSuggestion）。
+	AuditActionCalibrate = "calibrate"
+)
+
+// QuestionAuditLog は question_audit_logs テーブルの 1 行です。
+type QuestionAuditLog struct {
+	ID         int                    `json:"id"`
+	QuestionID int                    `json:"question_id"`
+	Actor      string                 `json:"actor"`   // 操作した管理者の名前
+	Action     string                 `json:"action"`  // create / update / delete / restore / calibrate
+	Changes    map[string]interface{} `json:"changes"` // 変更した項目ごとの {"before": ..., "after": ...}
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// QuestionsRepository は questions テーブルに対する管理操作を定義するインターフェースです。
+type QuestionsRepository interface {
+	// Create は問題を登録し、ID と作成日時を q にセットします。
+	Create(ctx context.Context, q *models.Question, actor string) error
+
+	// FindByID は問題を 1 件取得します。includeDeleted が false なら削除済みの問題は ErrQuestionNotFound になります。
+	FindByID(ctx context.Context, id int, includeDeleted bool) (*models.Question, error)
+
+	// FindAll は問題を ID 順に全件取得します。includeDeleted が true なら削除済みの問題も含めます。
+	FindAll(ctx context.Context, includeDeleted bool) ([]models.Question, error)
+
+	// Update は問題の内容を q で置き換えます。削除済みの問題は更新できません。
+	Update(ctx context.Context, q *models.Question, actor string) error
+
+	// SoftDelete は問題を論理削除します。過去のスコアが参照しているため行は消しません。
+	SoftDelete(ctx context.Context, id int, actor string) error
+
+	// Restore は論理削除した問題を元に戻します。
+	Restore(ctx context.Context, id int, actor string) error
+
+	// FindAuditLogs は問題の変更履歴を新しい順に取得します。
+	FindAuditLogs(ctx context.Context, questionID int, limit int) ([]QuestionAuditLog, error)
+
+	// Import は slug をキーに問題をまとめて登録・更新します。全件が 1 トランザクションで、途中で失敗すると何も反映しません。
+	// 内容が同じ問題は書き込まず履歴も残さないため、同じファイルを何度取り込んでも結果は変わりません。
+	// dryRun が true の場合は最後にロールバックし、何が作成・更新されるかだけを返します。
+	Import(ctx context.Context, questions []*models.Question, actor string, dryRun bool) ([]ImportResult, error)
+}
+
+// 取り込み結果の種類です。
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+)
+
+// ImportResult は取り込んだ 1 問の結果です。
+type ImportResult struct {
+	Slug       string   `json:"slug"`
+	QuestionID int      `json:"question_id"`       // dry-run で新規登録になる問題では、ロールバックされる仮の ID です。
+	Result     string   `json:"result"`            // created / updated / unchanged
+	Changed    []string `json:"changed,omitempty"` // updated の場合に変わった項目
+	Deleted    bool     `json:"deleted,omitempty"` // 論理削除済みの問題。内容は更新しますが復元はしません。
+}
+
+// questionsRepo は QuestionsRepository の実装です。
+type questionsRepo struct {
+	db *sql.DB
+}
+
+// NewQuestionsRepository は QuestionsRepository の新しいインスタンスを作成します。
+func NewQuestionsRepository(db *sql.DB) QuestionsRepository {
+	return &questionsRepo{db: db}
+}
+
+// questionColumns は問題を読み出すときの列の並びです。scanQuestion と揃えてください。
+const questionColumns = `
+	id, slug, level, problem_statement, correct_answer, answer_spec, expected_unit, unit_policy, grading_rubric,
+	template_key, scoring_params, tags, created_at, updated_at, deleted_at
+`
+
+// rowScanner は *sql.Row と *sql.Rows の共通部分です。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQuestion(row rowScanner) (*models.Question, error) {
+	var q models.Question
+	var answerSpec, scoringParams []byte
+	err := row.Scan(
+		&q.ID,
+		&q.Slug,
+		&q.Level,
+		&q.ProblemStatement,
+		&q.CorrectAnswer,
+		&answerSpec,
+		&q.ExpectedUnit,
+		&q.UnitPolicy,
+		&q.GradingRubric,
+		&q.TemplateKey,
+		&scoringParams,
+		pq.Array(&q.Tags),
+		&q.CreatedAt,
+		&q.UpdatedAt,
+		&q.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// JSONB は NULL のとき空になるので、そのまま json.RawMessage に入れると null 扱いになります。
+	if len(answerSpec) > 0 {
+		q.AnswerSpec = answerSpec
+	}
+	if len(scoringParams) > 0 {
+		q.ScoringParams = scoringParams
+	}
+	if q.Tags == nil {
+		q.Tags = []string{}
+	}
+	return &q, nil
+}
+
+// nullableJSON は空の json.RawMessage を SQL の NULL として渡します。
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return []byte(raw)
+}
+
+// Create は問題を登録し、変更履歴に登録内容を残します。
+func (r *questionsRepo) Create(ctx context.Context, q *models.Question, actor string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Commit 済みなら Rollback は sql.ErrTxDone を返すだけなので無視して構いません。
+		_ = tx.Rollback()
+	}()
+
+	if err := createQuestionTx(ctx, tx, q, actor); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit question: %w", err)
+	}
+	return nil
+}
+
+// createQuestionTx はトランザクション内で問題を登録し、履歴を残します。
+// slug が未指定なら "q-<id>" を割り当て、どの問題も問題バンクとして書き出せるようにします。
+func createQuestionTx(ctx context.Context, tx *sql.Tx, q *models.Question, actor string) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO questions (
+			slug, level, problem_statement, correct_answer, answer_spec, expected_unit, unit_policy, grading_rubric,
+			template_key, scoring_params, tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`,
+		q.Slug,
+		q.Level,
+		q.ProblemStatement,
+		q.CorrectAnswer,
+		nullableJSON(q.AnswerSpec),
+		q.ExpectedUnit,
+		q.UnitPolicy,
+		q.GradingRubric,
+		q.TemplateKey,
+		nullableJSON(q.ScoringParams),
+		pq.Array(q.Tags),
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrQuestionSlugTaken
+		}
+		return fmt.Errorf("failed to insert question: %w", err)
+	}
+	if q.Slug == nil {
+		err := tx.QueryRowContext(ctx, `UPDATE questions SET slug = 'q-' || id WHERE id = $1 RETURNING slug`, q.ID).Scan(&q.Slug)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrQuestionSlugTaken
+			}
+			return fmt.Errorf("failed to assign question slug: %w", err)
+		}
+	}
+
+	return insertAuditLog(ctx, tx, q.ID, actor, AuditActionCreate, questionDiff(nil, q))
+}
+
+// FindByID は問題を 1 件取得します。
+func (r *questionsRepo) FindByID(ctx context.Context, id int, includeDeleted bool) (*models.Question, error) {
+	query := `SELECT ` + questionColumns + ` FROM questions WHERE id = $1 AND ($2 OR deleted_at IS NULL)`
+	q, err := scanQuestion(r.db.QueryRowContext(ctx, query, id, includeDeleted))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, fmt.Errorf("failed to query question: %w", err)
+	}
+	return q, nil
+}
+
+// FindAll は問題を ID 順に全件取得します。
+func (r *questionsRepo) FindAll(ctx context.Context, includeDeleted bool) ([]models.Question, error) {
+	query := `SELECT ` + questionColumns + ` FROM questions WHERE ($1 OR deleted_at IS NULL) ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query questions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			// rows.Close のエラーは通常無視しても問題ないが、linter 対策のためログ出力を想定
+			_ = closeErr
+		}
+	}()
+
+	var results []models.Question
+	for rows.Next() {
+		q, err := scanQuestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan question row: %w", err)
+		}
+		results = append(results, *q)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("questions rows iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// Update は問題の内容を置き換え、変わった項目だけを変更履歴に残します。
+// q.Slug が nil の場合は今の slug をそのまま使います。
+func (r *questionsRepo) Update(ctx context.Context, q *models.Question, actor string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// 変更前の内容を行ロック付きで読み、同時に更新されても差分が正しく残るようにします。
+	before, err := scanQuestion(tx.QueryRowContext(ctx,
+		`SELECT `+questionColumns+` FROM questions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, q.ID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrQuestionNotFound
+		}
+		return fmt.Errorf("failed to lock question: %w", err)
+	}
+	if q.Slug == nil {
+		q.Slug = before.Slug
+	}
+
+	if err := updateQuestionTx(ctx, tx, before, q, actor); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit question update: %w", err)
+	}
+	return nil
+}
+
+// updateQuestionTx はトランザクション内で問題を q の内容に置き換え、before との差分を履歴に残します。
+// 呼び出し元で before を SELECT ... FOR UPDATE で読んでおく必要があります。
+func updateQuestionTx(ctx context.Context, tx *sql.Tx, before, q *models.Question, actor string) error {
+	err := tx.QueryRowContext(ctx, `
+		UPDATE questions
+		SET slug = $2,
+		    level = $3,
+		    problem_statement = $4,
+		    correct_answer = $5,
+		    answer_spec = $6,
+		    expected_unit = $7,
+		    unit_policy = $8,
+		    grading_rubric = $9,
+		    template_key = $10,
+		    scoring_params = $11,
+		    tags = $12,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at, deleted_at
+	`,
+		q.ID,
+		q.Slug,
+		q.Level,
+		q.ProblemStatement,
+		q.CorrectAnswer,
+		nullableJSON(q.AnswerSpec),
+		q.ExpectedUnit,
+		q.UnitPolicy,
+		q.GradingRubric,
+		q.TemplateKey,
+		nullableJSON(q.ScoringParams),
+		pq.Array(q.Tags),
+	).Scan(&q.CreatedAt, &q.UpdatedAt, &q.DeletedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrQuestionSlugTaken
+		}
+		return fmt.Errorf("failed to update question: %w", err)
+	}
+
+	return insertAuditLog(ctx, tx, q.ID, actor, AuditActionUpdate, questionDiff(before, q))
+}
+
+// Import は slug をキーに問題をまとめて登録・更新します。
+func (r *questionsRepo) Import(ctx context.Context, questions []*models.Question, actor string, dryRun bool) ([]ImportResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// dry-run ではここで全ての変更を取り消します。
+		_ = tx.Rollback()
+	}()
+
+	results := make([]ImportResult, 0, len(questions))
+	for _, q := range questions {
+		if q.Slug == nil || *q.Slug == "" {
+			return nil, errors.New("import requires a slug for every question")
+		}
+		result := ImportResult{Slug: *q.Slug}
+
+		before, err := scanQuestion(tx.QueryRowContext(ctx,
+			`SELECT `+questionColumns+` FROM questions WHERE slug = $1 FOR UPDATE`, *q.Slug))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := createQuestionTx(ctx, tx, q, actor); err != nil {
+				return nil, fmt.Errorf("failed to import %s: %w", *q.Slug, err)
+			}
+			result.Result = ImportCreated
+		case err != nil:
+			return nil, fmt.Errorf("failed to lock question %s: %w", *q.Slug, err)
+		default:
+			q.ID = before.ID
+			result.Deleted = before.DeletedAt != nil
+			changes := questionDiff(before, q)
+			if len(changes) == 0 {
+				result.Result = ImportUnchanged
+				break
+			}
+			if err := updateQuestionTx(ctx, tx, before, q, actor); err != nil {
+				return nil, fmt.Errorf("failed to import %s: %w", *q.Slug, err)
+			}
+			result.Result = ImportUpdated
+			for name := range changes {
+				result.Changed = append(result.Changed, name)
+			}
+			sort.Strings(result.Changed)
+		}
+		result.QuestionID = q.ID
+		results = append(results, result)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return results, nil
+}
+
+// SoftDelete は問題を論理削除します。
+func (r *questionsRepo) SoftDelete(ctx context.Context, id int, actor string) error {
+	return r.setDeleted(ctx, id, actor, true)
+}
+
+// Restore は論理削除した問題を元に戻します。
+func (r *questionsRepo) Restore(ctx context.Context, id int, actor string) error {
+	return r.setDeleted(ctx, id, actor, false)
+}
+
+// setDeleted は deleted_at を設定または解除し、履歴を残します。既に目的の状態なら ErrQuestionNotFound を返します。
+func (r *questionsRepo) setDeleted(ctx context.Context, id int, actor string, deleted bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `UPDATE questions SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`
+	action := AuditActionDelete
+	if !deleted {
+		query = `UPDATE questions SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL RETURNING deleted_at`
+		action = AuditActionRestore
+	}
+
+	var deletedAt *time.Time
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrQuestionNotFound
+		}
+		return fmt.Errorf("failed to %s question: %w", action, err)
+	}
+
+	changes := map[string]interface{}{"deleted_at": map[string]interface{}{"after": deletedAt}}
+	if err := insertAuditLog(ctx, tx, id, actor, action, changes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit question %s: %w", action, err)
+	}
+	return nil
+}
+
+// FindAuditLogs は問題の変更履歴を新しい順に取得します。
+func (r *questionsRepo) FindAuditLogs(ctx context.Context, questionID int, limit int) ([]QuestionAuditLog, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, question_id, actor, action, changes, created_at
+		FROM question_audit_logs
+		WHERE question_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, questionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			// rows.Close のエラーは通常無視しても問題ないが、linter 対策のためログ出力を想定
+			_ = closeErr
+		}
+	}()
+
+	var results []QuestionAuditLog
+	for rows.Next() {
+		var log QuestionAuditLog
+		var changesJSON []byte
+		if err := rows.Scan(&log.ID, &log.QuestionID, &log.Actor, &log.Action, &changesJSON, &log.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		if len(changesJSON) > 0 {
+			if err := json.Unmarshal(changesJSON, &log.Changes); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit log changes: %w", err)
+			}
+		}
+		results = append(results, log)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit logs rows iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// insertAuditLog は変更履歴を 1 件書き込みます。呼び出し元のトランザクション内で実行します。
+func insertAuditLog(ctx context.Context, tx *sql.Tx, questionID int, actor, action string, changes map[string]interface{}) error {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log changes: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO question_audit_logs (question_id, actor, action, changes)
+		VALUES ($1, $2, $3, $4)
+	`, questionID, actor, action, changesJSON)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit log: %w", err)
+	}
+	return nil
+}
+
+// questionDiff は編集できる項目のうち値が変わったものを {"項目": {"before": 変更前, "after": 変更後}} の形で返します。
+// before が nil（新規登録）の場合は全項目の after だけを記録します。
+func questionDiff(before, after *models.Question) map[string]interface{} {
+	fields := func(q *models.Question) map[string]interface{} {
+		return map[string]interface{}{
+			"slug":              q.Slug,
+			"level":             q.Level,
+			"problem_statement": q.ProblemStatement,
+			"correct_answer":    q.CorrectAnswer,
+			"answer_spec":       rawOrNil(q.AnswerSpec),
+			"expected_unit":     q.ExpectedUnit,
+			"unit_policy":       q.UnitPolicy,
+			"grading_rubric":    q.GradingRubric,
+			"template_key":      q.TemplateKey,
+			"scoring_params":    rawOrNil(q.ScoringParams),
+			"tags":              q.Tags,
+		}
+	}
+
+	changes := make(map[string]interface{})
+	afterFields := fields(after)
+	if before == nil {
+		for name, value := range afterFields {
+			changes[name] = map[string]interface{}{"after": value}
+		}
+		return changes
+	}
+	for name, beforeValue := range fields(before) {
+		afterValue := afterFields[name]
+		if !sameValue(beforeValue, afterValue) {
+			changes[name] = map[string]interface{}{"before": beforeValue, "after": afterValue}
+		}
+	}
+	return changes
+}
+
+// isUniqueViolation は一意制約違反のエラーかどうかを返します。
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// rawOrNil は JSON の列を比較・保存しやすいようにデコードした値へ変換します。
+func rawOrNil(raw json.RawMessage) interface{} {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return v
+}
+
+// sameValue はポインタの中身や JSON のキー順の違いを無視して 2 つの値を比較します。
+func sameValue(a, b interface{}) bool {
+	aj, aErr := json.Marshal(a)
+	bj, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(aj) == string(bj)
+}
@@ -0,0 +1,176 @@
+// questions_repo_test.go は管理 API 用の questions リポジトリが、変更と同じトランザクションで変更履歴を残すことを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+// TestQuestionsRepo_Lifecycle は登録→更新→論理削除→復元の流れと、各操作の変更履歴を確認します。
+func TestQuestionsRepo_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewQuestionsRepository(db)
+	ctx := context.Background()
+
+	q := &models.Question{
+		Level:            1,
+		ProblemStatement: "テスト用: 1+1は？",
+		CorrectAnswer:    "2",
+		AnswerSpec:       json.RawMessage(`{"type":"value","value":"2"}`),
+		Tags:             []string{"calculation"},
+	}
+	if err := repo.Create(ctx, q, "tester"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// 変更履歴が外部キーで問題を参照するため、履歴から先に削除します。
+	defer func() {
+		if _, err := db.Exec("DELETE FROM question_audit_logs WHERE question_id = $1", q.ID); err != nil {
+			t.Logf("cleanup audit logs: %v", err)
+		}
+		if _, err := db.Exec("DELETE FROM questions WHERE id = $1", q.ID); err != nil {
+			t.Logf("cleanup question: %v", err)
+		}
+	}()
+
+	q.CorrectAnswer = "3"
+	q.AnswerSpec = json.RawMessage(`{"type":"value","value":"3"}`)
+	q.ScoringParams = json.RawMessage(`{"p":4}`)
+	if err := repo.Update(ctx, q, "tester"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if err := repo.SoftDelete(ctx, q.ID, "tester"); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, q.ID, false); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("deleted question should be hidden, got err=%v", err)
+	}
+	if err := repo.SoftDelete(ctx, q.ID, "tester"); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("second delete should return ErrQuestionNotFound, got %v", err)
+	}
+	if err := repo.Update(ctx, q, "tester"); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("updating a deleted question should fail, got %v", err)
+	}
+
+	if err := repo.Restore(ctx, q.ID, "tester"); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	got, err := repo.FindByID(ctx, q.ID, false)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if got.CorrectAnswer != "3" || got.DeletedAt != nil || got.UpdatedAt == nil {
+		t.Errorf("unexpected question after restore: %+v", got)
+	}
+
+	logs, err := repo.FindAuditLogs(ctx, q.ID, 10)
+	if err != nil {
+		t.Fatalf("FindAuditLogs failed: %v", err)
+	}
+	wantActions := []string{AuditActionRestore, AuditActionDelete, AuditActionUpdate, AuditActionCreate}
+	if len(logs) != len(wantActions) {
+		t.Fatalf("len(logs) = %d, want %d", len(logs), len(wantActions))
+	}
+	for i, want := range wantActions {
+		if logs[i].Action != want || logs[i].Actor != "tester" {
+			t.Errorf("logs[%d] = %s by %s, want %s by tester", i, logs[i].Action, logs[i].Actor, want)
+		}
+	}
+
+	// 更新の履歴には変わった項目だけが残ります。
+	update := logs[2].Changes
+	for _, field := range []string{"correct_answer", "answer_spec", "scoring_params"} {
+		if _, ok := update[field]; !ok {
+			t.Errorf("update log should contain %s: %v", field, update)
+		}
+	}
+	if _, ok := update["level"]; ok {
+		t.Errorf("update log should not contain unchanged level: %v", update)
+	}
+}
+
+// TestQuestionsRepo_Import は slug による upsert が冪等であること、dry-run では何も残らないことを確認します。
+func TestQuestionsRepo_Import(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewQuestionsRepository(db)
+	ctx := context.Background()
+	slug := "test-import-question"
+	newBank := func(answer string) []*models.Question {
+		return []*models.Question{{
+			Slug:             &slug,
+			Level:            1,
+			ProblemStatement: "テスト用: 取り込み",
+			CorrectAnswer:    answer,
+			Tags:             []string{},
+		}}
+	}
+	cleanup := func() {
+		if _, err := db.Exec("DELETE FROM question_audit_logs WHERE question_id IN (SELECT id FROM questions WHERE slug = $1)", slug); err != nil {
+			t.Logf("cleanup audit logs: %v", err)
+		}
+		if _, err := db.Exec("DELETE FROM questions WHERE slug = $1", slug); err != nil {
+			t.Logf("cleanup question: %v", err)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	// dry-run は結果だけを返し、行を残しません。
+	results, err := repo.Import(ctx, newBank("1"), "tester", true)
+	if err != nil || len(results) != 1 || results[0].Result != ImportCreated {
+		t.Fatalf("dry-run import = %+v, %v", results, err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM questions WHERE slug = $1", slug).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("dry-run should not write: count=%d err=%v", count, err)
+	}
+
+	steps := []struct {
+		answer string
+		want   string
+	}{
+		{answer: "1", want: ImportCreated},
+		{answer: "1", want: ImportUnchanged},
+		{answer: "2", want: ImportUpdated},
+	}
+	var questionID int
+	for i, step := range steps {
+		results, err := repo.Import(ctx, newBank(step.answer), "tester", false)
+		if err != nil {
+			t.Fatalf("step %d: Import failed: %v", i, err)
+		}
+		if results[0].Result != step.want {
+			t.Errorf("step %d: result = %s, want %s", i, results[0].Result, step.want)
+		}
+		if questionID == 0 {
+			questionID = results[0].QuestionID
+		} else if results[0].QuestionID != questionID {
+			t.Errorf("step %d: question id changed %d -> %d", i, questionID, results[0].QuestionID)
+		}
+	}
+
+	// 変更なしの取り込みは履歴を残しません。
+	logs, err := repo.FindAuditLogs(ctx, questionID, 10)
+	if err != nil {
+		t.Fatalf("FindAuditLogs failed: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("len(logs) = %d, want 2 (create and update)", len(logs))
+	}
+}
@@ -0,0 +1,100 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// ratings_repo.go はプレイヤーと問題の Glicko-2 レーティング（player_ratings / question_ratings）の操作をまとめます。
+// 計算そのものは internal/rating に任せ、ここでは同じ挑戦で両方の行を同時に更新することに責任を持ちます。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shiv/CoT_game/backend/internal/rating"
+)
+
+// RatingsRepository はレーティングに対する操作を定義するインターフェースです。
+type RatingsRepository interface {
+	// RecordMatch は 1 回の挑戦をプレイヤーと問題の対戦として記録し、更新後のレーティングを返します。
+	// outcome はプレイヤー側の結果（0〜1、rating.Outcome でスコアから変換）です。未対戦の側は既定値から始めます。
+	RecordMatch(ctx context.Context, userID, questionID int, outcome float64) (player rating.Rating, question rating.Rating, err error)
+}
+
+// ratingsRepo は RatingsRepository の実装です。
+type ratingsRepo struct {
+	db  *sql.DB
+	tau float64
+}
+
+// NewRatingsRepository は RatingsRepository の新しいインスタンスを作成します。ボラティリティの定数は rating.DefaultTau を使います。
+func NewRatingsRepository(db *sql.DB) RatingsRepository {
+	return &ratingsRepo{db: db, tau: rating.DefaultTau}
+}
+
+// RecordMatch は両方のレーティングを 1 トランザクションで更新します。
+// 同じプレイヤーや同じ問題の挑戦が同時に届いても更新を取りこぼさないよう、行ロックを取ってから計算します。
+// ロックは常にプレイヤー → 問題の順に取り、デッドロックしないようにします。
+func (r *ratingsRepo) RecordMatch(ctx context.Context, userID, questionID int, outcome float64) (rating.Rating, rating.Rating, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return rating.Rating{}, rating.Rating{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Commit 済みなら Rollback は sql.ErrTxDone を返すだけなので無視して構いません。
+		_ = tx.Rollback()
+	}()
+
+	player, err := lockRating(ctx, tx, "player_ratings", "user_id", userID)
+	if err != nil {
+		return rating.Rating{}, rating.Rating{}, err
+	}
+	question, err := lockRating(ctx, tx, "question_ratings", "question_id", questionID)
+	if err != nil {
+		return rating.Rating{}, rating.Rating{}, err
+	}
+
+	newPlayer, newQuestion := rating.Match(player, question, outcome, r.tau)
+	if err := saveRating(ctx, tx, "player_ratings", "user_id", userID, newPlayer); err != nil {
+		return rating.Rating{}, rating.Rating{}, err
+	}
+	if err := saveRating(ctx, tx, "question_ratings", "question_id", questionID, newQuestion); err != nil {
+		return rating.Rating{}, rating.Rating{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rating.Rating{}, rating.Rating{}, fmt.Errorf("failed to commit ratings: %w", err)
+	}
+	return newPlayer, newQuestion, nil
+}
+
+// lockRating は未対戦なら既定値の行を作ってから、行ロックを取って現在のレーティングを読みます。
+// table と key は RecordMatch が渡す固定の文字列だけです。
+func lockRating(ctx context.Context, tx *sql.Tx, table, key string, id int) (rating.Rating, error) {
+	def := rating.Default()
+	insert := fmt.Sprintf(`
+		INSERT INTO %s (%s, rating, rd, volatility)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (%s) DO NOTHING
+	`, table, key, key)
+	if _, err := tx.ExecContext(ctx, insert, id, def.Rating, def.RD, def.Volatility); err != nil {
+		return rating.Rating{}, fmt.Errorf("failed to insert %s: %w", table, err)
+	}
+
+	var r rating.Rating
+	query := fmt.Sprintf(`SELECT rating, rd, volatility FROM %s WHERE %s = $1 FOR UPDATE`, table, key)
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&r.Rating, &r.RD, &r.Volatility); err != nil {
+		return rating.Rating{}, fmt.Errorf("failed to lock %s: %w", table, err)
+	}
+	return r, nil
+}
+
+// saveRating は更新後のレーティングを書き戻し、対戦数を 1 つ進めます。
+func saveRating(ctx context.Context, tx *sql.Tx, table, key string, id int, r rating.Rating) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET rating = $2, rd = $3, volatility = $4, matches = matches + 1, updated_at = NOW()
+		WHERE %s = $1
+	`, table, key)
+	if _, err := tx.ExecContext(ctx, query, id, r.Rating, r.RD, r.Volatility); err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// ratings_repo_test.go は挑戦のたびにプレイヤーと問題のレーティングが逆向きに更新され、対戦数が進むことを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/internal/rating"
+)
+
+func TestRatingsRepo_RecordMatch(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	testUserID := 999960
+	cleanupTestData(t, db, testUserID)
+	ensureTestUser(t, db, testUserID)
+	defer cleanupTestData(t, db, testUserID)
+
+	// 他のテストの挑戦と混ざらないよう、専用の問題で確認します。
+	var questionID int
+	if err := db.QueryRow(`
+		INSERT INTO questions (level, problem_statement, correct_answer)
+		VALUES (3, 'rating test', '1')
+		RETURNING id
+	`).Scan(&questionID); err != nil {
+		t.Fatalf("failed to insert question: %v", err)
+	}
+	defer func() {
+		_, _ = db.Exec("DELETE FROM question_ratings WHERE question_id = $1", questionID)
+		_, _ = db.Exec("DELETE FROM questions WHERE id = $1", questionID)
+	}()
+
+	repo := NewRatingsRepository(db)
+	player, question, err := repo.RecordMatch(ctx, testUserID, questionID, rating.Outcome(100))
+	if err != nil {
+		t.Fatalf("RecordMatch() error = %v", err)
+	}
+	wantPlayer, wantQuestion := rating.Match(rating.Default(), rating.Default(), 1, rating.DefaultTau)
+	if player != wantPlayer || question != wantQuestion {
+		t.Errorf("RecordMatch() = %+v, %+v, want %+v, %+v", player, question, wantPlayer, wantQuestion)
+	}
+
+	// 2 回目は保存したレーティングから続けて計算します。
+	player2, question2, err := repo.RecordMatch(ctx, testUserID, questionID, rating.Outcome(0))
+	if err != nil {
+		t.Fatalf("RecordMatch() error = %v", err)
+	}
+	if player2.Rating >= player.Rating || question2.Rating <= question.Rating {
+		t.Errorf("after a loss: player %v -> %v, question %v -> %v", player.Rating, player2.Rating, question.Rating, question2.Rating)
+	}
+
+	var matches int
+	if err := db.QueryRow("SELECT matches FROM question_ratings WHERE question_id = $1", questionID).Scan(&matches); err != nil || matches != 2 {
+		t.Errorf("question matches = %d, %v, want 2", matches, err)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Score は scores テーブルに対応する構造体です。
@@ -25,16 +28,47 @@ type Score struct {
 	AnswerNumber     *float64               `json:"answer_number"`
 	LatencyMs        int                    `json:"latency_ms"`        // AI 応答までの時間（ミリ秒）。体験の快適さを可視化するために保存します。
 	EvaluationDetail map[string]interface{} `json:"evaluation_detail"` // 採点結果の詳細メモ。採点ロジックが増えても柔軟に持てるように JSONB で保存。
+	EvaluatorVersion *int                   `json:"evaluator_version"` // 採点に使ったロジックの版（eval.Version）。版管理を始める前のスコアは null です。
+	NeedsReview      bool                   `json:"needs_review"`      // 問題文の漏洩などで運営の確認が必要な回答かどうか。
+	ReviewReason     *string                `json:"review_reason"`     // 確認が必要な理由（例: problem_statement_leak）。
+	ScoringMode      string                 `json:"scoring_mode"`      // 採点モード（standard / golf）。空の場合は standard として保存します。
+	GolfScore        *int                   `json:"golf_score"`        // ゴルフモードのスコア（正確さ × プロンプトの短さ）。standard モードでは null です。
+	VariantParams    map[string]interface{} `json:"variant_params"`    // テンプレート問題で出題したバリアントのテンプレート・シード・変数。固定の問題では null です。
+	VariantAnswer    *string                `json:"variant_answer"`    // 出題したバリアントの正解。採点はこの値に対して行われています。
 	CreatedAt        time.Time              `json:"created_at"`        // DB 側で決まる投稿時刻。履歴ソートや期間集計に必須です。
 }
 
 // LeaderboardRow はランキング結果の1行を表します。
 type LeaderboardRow struct {
-	UserID    *int      `json:"user_id"`
-	Username  string    `json:"username"`
-	BestScore int       `json:"best_score"`
-	Attempts  int       `json:"attempts"`
-	LastAt    time.Time `json:"last_at"`
+	Rank       int       `json:"rank"` // 1 始まりの順位。同点でも並び順が毎回変わらないよう、一意に決まる順位を振ります。
+	UserID     *int      `json:"user_id"`
+	Username   string    `json:"username"`
+	TotalScore int       `json:"total_score"` // 問題ごとの自己ベストの合計（レベルで重み付けした場合は best × level の合計）。順位はこの値で決まります。
+	BestScore  int       `json:"best_score"`  // 1 問あたりの最高スコア。
+	Questions  int       `json:"questions"`   // 挑戦した問題の数。
+	Attempts   int       `json:"attempts"`
+	LastAt     time.Time `json:"last_at"`
+	Rating     *float64  `json:"rating"`    // Glicko-2 のレーティング（player_ratings）。まだ無ければ null。
+	RatingRD   *float64  `json:"rating_rd"` // レーティング偏差。値が大きいほど推定が不確かです。
+}
+
+// LeaderboardScope はランキングの集計対象を絞り込む条件です。どちらも nil なら全問題が対象です。
+type LeaderboardScope struct {
+	QuestionID    *int    // 指定した問題のスコアだけで集計します。
+	Tag           *string // 指定したタグが付いた問題のスコアだけで集計します。
+	WeightByLevel bool    // true なら自己ベストに問題のレベルを掛けてから合計し、難しい問題ほど順位に効くようにします。
+}
+
+// GolfLeaderboardRow はプロンプトゴルフのランキングの1行を表します。
+type GolfLeaderboardRow struct {
+	UserID         *int      `json:"user_id"`
+	Username       string    `json:"username"`
+	TotalGolfScore int       `json:"total_golf_score"` // 問題ごとのゴルフスコアの自己ベストの合計。順位はこの値で決まります。
+	BestGolfScore  int       `json:"best_golf_score"`  // 1 問あたりの最高ゴルフスコア。
+	PromptChars    int       `json:"prompt_chars"`     // 各問題で自己ベストを出したプロンプトの文字数の合計。問題を指定した場合はその 1 本の文字数です。
+	Questions      int       `json:"questions"`        // ゴルフモードで挑戦した問題の数。
+	Attempts       int       `json:"attempts"`
+	LastAt         time.Time `json:"last_at"`
 }
 
 // ScoresRepository は scores テーブルに対する操作を定義するインターフェースです。
@@ -42,16 +76,105 @@ type LeaderboardRow struct {
 type ScoresRepository interface {
 	// Create は新しいスコアレコードをデータベースに保存します。
 	// HTTP ハンドラなど上位レイヤーから呼ばれ、1 回のプレイ結果を 1 行として登録します。
+	// ログイン中のユーザーの回答なら、同じトランザクションで user_question_best（問題ごとの自己ベスト）も更新します。
 	Create(ctx context.Context, record *Score) error
 
-	// FindLeaderboard は指定された期間と上限数でランキングを取得します。
+	// FindLeaderboard は指定された期間・集計対象・上限数でランキングを取得します。
 	// period: "day", "week", "all" のいずれか
 	// 期間によって SQL の WHERE 条件を差し替え、上位 n 件だけ返します。
-	FindLeaderboard(ctx context.Context, period string, limit int) ([]LeaderboardRow, error)
+	// 順位は問題ごとの自己ベストの合計（total_score）で決め、1 問だけの満点が全問クリアと並ばないようにします。
+	// 並び順は total_score の降順、同点ならその合計に先に達した順（自己ベストを取った日時のうち最も遅いものの古い順）、それも同じなら user_id の昇順です。
+	// ゲストの回答は 1 人のプレイヤーとして合計できないため集計しません。
+	FindLeaderboard(ctx context.Context, period string, scope LeaderboardScope, limit int) ([]LeaderboardRow, error)
+
+	// FindLeaderboardRank は FindLeaderboard と同じ条件・並び順での、指定ユーザーの順位と成績を返します。
+	// 上位 n 件の外にいるユーザーにも自分の順位を見せるために使います。対象期間にスコアが無ければ nil を返します。
+	FindLeaderboardRank(ctx context.Context, period string, scope LeaderboardScope, userID int) (*LeaderboardRow, error)
+
+	// FindGolfLeaderboard はゴルフモードの回答だけを対象に、問題ごとの golf_score の自己ベストの合計でランキングを取得します。
+	// 並び順は合計の降順、同点ならその合計に先に達した順、それも同じなら user_id の昇順で、FindLeaderboard と揃えています。
+	// FindLeaderboard と同じくゲスト（user_id が NULL）の回答は含めません。questionID を指定するとその問題だけのランキングになります。period は FindLeaderboard と同じです。
+	FindGolfLeaderboard(ctx context.Context, period string, questionID *int, limit int) ([]GolfLeaderboardRow, error)
+
+	// FindUserScores は指定されたユーザーのスコア履歴を、新しい順（created_at, id の降順）に取得します。
+	// マイページなどで最新の解答履歴を表示する用途を想定しています。filter.Before を指定すると、その位置より古い行から limit 件を返します。
+	FindUserScores(ctx context.Context, userID int, filter UserScoresFilter, limit int) ([]Score, error)
+
+	// FindScoreDetail は 1 件のスコアを、伏せ字の判定に使う問題文と一緒に取得します。存在しなければ ErrScoreNotFound を返します。
+	FindScoreDetail(ctx context.Context, scoreID int) (*ScoreDetail, error)
+
+	// FindRescoreTargets は再採点の対象になるスコアを、問題側の正解情報と一緒に ID 昇順で取得します。
+	// afterID より大きい ID から limit 件ずつ読むことで、件数が多くてもバッチに分けて処理できます。
+	FindRescoreTargets(ctx context.Context, filter RescoreFilter, afterID int, limit int) ([]RescoreTarget, error)
+
+	// UpdateEvaluations は再採点の結果をまとめて書き戻します。1 回の呼び出しが 1 トランザクションです。
+	// 書き戻したスコアのユーザー × 問題の自己ベストも、同じトランザクションで scores から計算し直します。
+	UpdateEvaluations(ctx context.Context, updates []EvaluationUpdate) error
+
+	// RebuildQuestionBest は user_question_best を scores から全て計算し直し、書き込んだ行数を返します。
+	// 導入前の回答の取り込みや、scores を直接編集した後の修復に使います（cmd/backfill_best）。
+	RebuildQuestionBest(ctx context.Context) (int64, error)
+}
+
+// ErrScoreNotFound は指定した ID のスコアが存在しないことを表します。
+var ErrScoreNotFound = errors.New("score not found")
+
+// UserScoresFilter はスコア履歴の絞り込み条件です。nil の項目は条件に含めません。
+type UserScoresFilter struct {
+	QuestionID *int
+	Tag        *string    // 指定したタグが付いた問題のスコアだけを返します。
+	ModelName  *string    // scores.model_name が一致するスコアだけを返します。
+	From       *time.Time // この時刻以降（含む）に回答したスコアだけを返します。
+	To         *time.Time // この時刻より前（含まない）に回答したスコアだけを返します。
+	Before     *ScoreCursor
+}
+
+// ScoreCursor はスコア履歴のページの区切り位置です。前のページの最後の行の created_at と id を持ちます。
+type ScoreCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// ScoreDetail はスコア 1 件と、その回答で出題した問題文です。
+type ScoreDetail struct {
+	Score
+	ProblemStatement string // questions.problem_statement。テンプレート問題では代表例の問題文です。
+	TemplateKey      *string
+}
+
+// RescoreFilter は再採点の対象を絞り込む条件です。
+type RescoreFilter struct {
+	QuestionID   *int // 指定した問題のスコアだけを対象にします。nil なら全問題。
+	BelowVersion *int // evaluator_version がこの値未満（または null）のスコアだけを対象にします。nil なら版を問いません。
+}
+
+// RescoreTarget は再採点に必要なスコアと問題の情報を 1 行にまとめたものです。
+type RescoreTarget struct {
+	ScoreID          int
+	QuestionID       int
+	Prompt           string
+	AIResponse       string
+	Score            int
+	EvaluatorVersion *int
+	EvaluationDetail map[string]interface{}
+	CorrectAnswer    string
+	AnswerSpec       []byte  // questions.answer_spec の JSON。null の場合は空です。
+	ExpectedUnit     *string // questions.expected_unit
+	UnitPolicy       *string // questions.unit_policy
+	VariantAnswer    *string // scores.variant_answer。テンプレート問題ではこちらが正解です。
+	ScoringParams    []byte  // questions.scoring_params の JSON。null の場合は空です。
+	ScoringMode      string  // scores.scoring_mode。golf ならゴルフスコアも計算し直します。
+	Level            int     // questions.level。ゴルフモードの曲線の選択に使います。
+}
 
-	// FindUserScores は指定されたユーザーのスコア履歴を取得します。
-	// マイページなどで最新の解答履歴を表示する用途を想定しています。
-	FindUserScores(ctx context.Context, userID int, limit int) ([]Score, error)
+// EvaluationUpdate は 1 件のスコアに書き戻す再採点結果です。
+type EvaluationUpdate struct {
+	ScoreID          int
+	Score            int
+	AnswerNumber     *float64
+	EvaluationDetail map[string]interface{}
+	EvaluatorVersion int
+	GolfScore        *int // ゴルフモードの回答だけ。standard モードでは nil（NULL）です。
 }
 
 // scoresRepo は ScoresRepository の実装です。
@@ -89,20 +212,47 @@ func (r *scoresRepo) Create(ctx context.Context, record *Score) error {
 		detailJSON = nil
 	}
 
+	// バリアントの変数も同じく JSONB に変換します。固定の問題では NULL です。
+	var variantJSON interface{}
+	if record.VariantParams != nil {
+		jsonBytes, err := json.Marshal(record.VariantParams)
+		if err != nil {
+			return fmt.Errorf("failed to marshal variant_params: %w", err)
+		}
+		variantJSON = jsonBytes
+	}
+
+	// 採点モードが未指定なら通常モードとして保存します。
+	scoringMode := record.ScoringMode
+	if scoringMode == "" {
+		scoringMode = "standard"
+	}
+
 	// SQL はヒアドキュメントで書くと列の並びが視覚的に追いやすくなります。
 	// このVALUES部分には、後で実際の値を渡す。
 	query := `
 		INSERT INTO scores (
 			user_id, question_id, prompt, ai_response, score,
 			model_vendor, model_name, answer_number, latency_ms, evaluation_detail,
-			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			evaluator_version, needs_review, review_reason, scoring_mode, golf_score,
+			variant_params, variant_answer, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at
 	`
 
+	// スコアと自己ベストの更新は 1 トランザクションにまとめ、片方だけ反映された状態を残しません。
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Commit 済みなら Rollback は sql.ErrTxDone を返すだけなので無視して構いません。
+		_ = tx.Rollback()
+	}()
+
 	// QueryRowContext は 1 行だけ返るクエリを実行し、Scan で構造体に詰めます。
 	// returning で ID と created_at を受け取ることで、呼び出し側が直後に利用できます。
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		record.UserID,
@@ -115,6 +265,13 @@ func (r *scoresRepo) Create(ctx context.Context, record *Score) error {
 		record.AnswerNumber,
 		record.LatencyMs,
 		detailJSON,
+		record.EvaluatorVersion,
+		record.NeedsReview,
+		record.ReviewReason,
+		scoringMode,
+		record.GolfScore,
+		variantJSON,
+		record.VariantAnswer,
 		time.Now(), // Go 側で現在時刻をセットしておくと、呼び出しが終わった時点で値が分かります。
 	).Scan(&record.ID, &record.CreatedAt)
 	if err != nil {
@@ -122,43 +279,163 @@ func (r *scoresRepo) Create(ctx context.Context, record *Score) error {
 		return fmt.Errorf("failed to insert score: %w", err)
 	}
 
+	// ゲストの回答は自己ベストを持たないので scores だけに保存します。
+	if record.UserID != nil {
+		// 自己ベストを更新したときだけ best_score と best_at を差し替え、挑戦回数と最終回答日時は毎回進めます。
+		// SET の右辺の user_question_best.* は更新前の値です。
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_question_best (user_id, question_id, best_score, attempts, best_at, last_at)
+			VALUES ($1, $2, $3, 1, $4, $4)
+			ON CONFLICT (user_id, question_id) DO UPDATE SET
+				best_score = GREATEST(user_question_best.best_score, EXCLUDED.best_score),
+				best_at = CASE
+					WHEN EXCLUDED.best_score > user_question_best.best_score THEN EXCLUDED.best_at
+					ELSE user_question_best.best_at
+				END,
+				attempts = user_question_best.attempts + 1,
+				last_at = GREATEST(user_question_best.last_at, EXCLUDED.last_at)
+		`, *record.UserID, record.QuestionID, record.Score, record.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert user_question_best: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit score: %w", err)
+	}
 	return nil
 }
 
-// FindLeaderboard は指定された期間と上限数でランキングを取得します。
-func (r *scoresRepo) FindLeaderboard(ctx context.Context, period string, limit int) ([]LeaderboardRow, error) {
-	// 期間条件を決定
-	// SQL の断片を直接差し込むため、switch で許可する文字列のみ選ぶと安全です。
-	var whereClause string
+// questionBestFromScores は scores から user_question_best の行を計算して書き込む INSERT 文です。
+// %s には scores s に対する追加の WHERE 条件を差し込みます。同点の自己ベストは最初に取った日時を best_at にします。
+const questionBestFromScores = `
+	INSERT INTO user_question_best (user_id, question_id, best_score, attempts, best_at, last_at)
+	SELECT
+		s.user_id,
+		s.question_id,
+		MAX(s.score),
+		COUNT(*),
+		(ARRAY_AGG(s.created_at ORDER BY s.score DESC, s.created_at ASC))[1],
+		MAX(s.created_at)
+	FROM scores s
+	WHERE s.user_id IS NOT NULL AND s.question_id IS NOT NULL %s
+	GROUP BY s.user_id, s.question_id
+	ON CONFLICT (user_id, question_id) DO UPDATE SET
+		best_score = EXCLUDED.best_score,
+		attempts = EXCLUDED.attempts,
+		best_at = EXCLUDED.best_at,
+		last_at = EXCLUDED.last_at
+`
+
+// RebuildQuestionBest は user_question_best を scores から全て計算し直します。
+func (r *scoresRepo) RebuildQuestionBest(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(questionBestFromScores, ""))
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild user_question_best: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// periodClause は期間指定を scores s に対する WHERE 条件の断片に変換します。
+// SQL の断片を直接差し込むため、switch で許可する文字列のみ選ぶと安全です。
+func periodClause(period string) (string, error) {
 	switch period {
 	case "day":
-		whereClause = "AND s.created_at >= NOW() - INTERVAL '1 day'"
+		return "AND s.created_at >= NOW() - INTERVAL '1 day'", nil
 	case "week":
-		whereClause = "AND s.created_at >= NOW() - INTERVAL '7 days'"
+		return "AND s.created_at >= NOW() - INTERVAL '7 days'", nil
 	case "all":
-		whereClause = ""
+		return "", nil
 	default:
-		return nil, fmt.Errorf("invalid period: %s (must be day, week, or all)", period)
+		return "", fmt.Errorf("invalid period: %s (must be day, week, or all)", period)
 	}
+}
 
-	// 期間条件は whereClause に差し込み、ランキングのスコアと最新回答日時で並べ替えます。
-	query := fmt.Sprintf(`
+// rankedLeaderboardQuery は順位付きのランキング全体を返す SELECT 文を組み立てます。
+// $1 が問題 ID、$2 がタグの絞り込みで、NULL を渡すと無効になります。$3 はレベルで重み付けするかどうかです。
+// 呼び出し側はこれを副問い合わせにして上位 n 件や特定ユーザーを取り出します。
+func rankedLeaderboardQuery(period string) (string, error) {
+	// best はユーザー × 問題ごとの自己ベストです。
+	// 全期間なら user_question_best をそのまま使い、期間を区切る場合はその期間の scores から集計します（idx_scores_created_at）。
+	var best string
+	if period == "all" {
+		best = `
+			SELECT
+				b.user_id,
+				b.question_id,
+				b.best_score,
+				b.attempts,
+				b.best_at,
+				b.last_at,
+				CASE WHEN $3::bool THEN q.level ELSE 1 END as weight
+			FROM user_question_best b
+			JOIN questions q ON b.question_id = q.id
+			WHERE ($1::int IS NULL OR b.question_id = $1)
+			  AND ($2::text IS NULL OR $2 = ANY(q.tags))`
+	} else {
+		whereClause, err := periodClause(period)
+		if err != nil {
+			return "", err
+		}
+		best = fmt.Sprintf(`
+			SELECT
+				s.user_id,
+				s.question_id,
+				MAX(s.score) as best_score,
+				COUNT(*) as attempts,
+				(ARRAY_AGG(s.created_at ORDER BY s.score DESC, s.created_at ASC))[1] as best_at,
+				MAX(s.created_at) as last_at,
+				CASE WHEN $3::bool THEN COALESCE(MAX(q.level), 1) ELSE 1 END as weight
+			FROM scores s
+			LEFT JOIN questions q ON s.question_id = q.id
+			WHERE s.user_id IS NOT NULL
+			  AND ($1::int IS NULL OR s.question_id = $1)
+			  AND ($2::text IS NULL OR $2 = ANY(q.tags)) %s
+			GROUP BY s.user_id, s.question_id`, whereClause)
+	}
+
+	// totals でユーザーごとに合計してから順位を振ります。
+	// 同点は合計に達した時刻（各問題の自己ベストを取った時刻のうち最も遅いもの）が早い方を上位にします。
+	// 最終回答日時で比べると、点にならない回答を送るだけで同点の相手を抜けてしまうためです。
+	return fmt.Sprintf(`
+		WITH best AS (%s
+		), totals AS (
+			SELECT
+				b.user_id,
+				u.username,
+				SUM(b.best_score * b.weight)::int as total_score,
+				MAX(b.best_score) as best_score,
+				COUNT(*)::int as questions,
+				SUM(b.attempts)::int as attempts,
+				MAX(b.best_at) as achieved_at,
+				MAX(b.last_at) as last_at
+			FROM best b
+			JOIN users u ON b.user_id = u.id
+			GROUP BY b.user_id, u.username
+		)
 		SELECT
-			s.user_id,
-			COALESCE(u.username, 'guest') as username,
-			MAX(s.score) as best_score,
-			COUNT(*) as attempts,
-			MAX(s.created_at) as last_at
-		FROM scores s
-		LEFT JOIN users u ON s.user_id = u.id
-		WHERE 1=1 %s
-		GROUP BY s.user_id, u.username
-		ORDER BY best_score DESC, last_at DESC
-		LIMIT $1
-	`, whereClause)
+			ROW_NUMBER() OVER (ORDER BY t.total_score DESC, t.achieved_at ASC, t.user_id ASC) as rank,
+			t.user_id, t.username, t.total_score, t.best_score, t.questions, t.attempts, t.last_at,
+			pr.rating, pr.rd as rating_rd
+		FROM totals t
+		LEFT JOIN player_ratings pr ON pr.user_id = t.user_id
+	`, best), nil
+}
+
+// FindLeaderboard は指定された期間・集計対象と上限数でランキングを取得します。
+func (r *scoresRepo) FindLeaderboard(ctx context.Context, period string, scope LeaderboardScope, limit int) ([]LeaderboardRow, error) {
+	ranked, err := rankedLeaderboardQuery(period)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT * FROM (%s) ranked ORDER BY rank LIMIT $4`, ranked)
 
 	// QueryContext で複数行取得。limit はバインド変数で渡すことで SQL インジェクションを防ぎます。
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	rows, err := r.db.QueryContext(ctx, query, scope.QuestionID, scope.Tag, scope.WeightByLevel, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
 	}
@@ -173,11 +450,16 @@ func (r *scoresRepo) FindLeaderboard(ctx context.Context, period string, limit i
 	for rows.Next() {
 		var row LeaderboardRow
 		err := rows.Scan(
+			&row.Rank,
 			&row.UserID,
 			&row.Username,
+			&row.TotalScore,
 			&row.BestScore,
+			&row.Questions,
 			&row.Attempts,
 			&row.LastAt,
+			&row.Rating,
+			&row.RatingRD,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan leaderboard row: %w", err)
@@ -194,22 +476,197 @@ func (r *scoresRepo) FindLeaderboard(ctx context.Context, period string, limit i
 	return results, nil
 }
 
-// FindUserScores は指定されたユーザーのスコア履歴を取得します。
-func (r *scoresRepo) FindUserScores(ctx context.Context, userID int, limit int) ([]Score, error) {
-	// ORDER BY created_at DESC で最新回答から順に並べ替えます。
-	query := `
+// FindLeaderboardRank は指定ユーザーの順位と成績を取得します。
+func (r *scoresRepo) FindLeaderboardRank(ctx context.Context, period string, scope LeaderboardScope, userID int) (*LeaderboardRow, error) {
+	ranked, err := rankedLeaderboardQuery(period)
+	if err != nil {
+		return nil, err
+	}
+	// 順位は全員分を並べてから振る必要があるため、絞り込みは副問い合わせの外側で行います。
+	query := fmt.Sprintf(`SELECT * FROM (%s) ranked WHERE user_id = $4`, ranked)
+
+	var row LeaderboardRow
+	err = r.db.QueryRowContext(ctx, query, scope.QuestionID, scope.Tag, scope.WeightByLevel, userID).Scan(
+		&row.Rank,
+		&row.UserID,
+		&row.Username,
+		&row.TotalScore,
+		&row.BestScore,
+		&row.Questions,
+		&row.Attempts,
+		&row.LastAt,
+		&row.Rating,
+		&row.RatingRD,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard rank: %w", err)
+	}
+	return &row, nil
+}
+
+// FindGolfLeaderboard はゴルフモードのランキングを取得します。
+func (r *scoresRepo) FindGolfLeaderboard(ctx context.Context, period string, questionID *int, limit int) ([]GolfLeaderboardRow, error) {
+	// 期間条件は FindLeaderboard と同じく、許可した文字列だけを SQL に差し込みます。
+	whereClause, err := periodClause(period)
+	if err != nil {
+		return nil, err
+	}
+
+	// best はユーザー × 問題ごとの自己ベストで、文字数と日時は自己ベストを最初に出した回答のものを使います。
+	// 簡単な問題 1 問だけの高いゴルフスコアで上位に立てないよう、順位は問題ごとの自己ベストを合計して決めます。
+	// 問題の絞り込みは NULL を渡すと無効になる形にして、バインド変数のまま扱います。
+	query := fmt.Sprintf(`
+		WITH best AS (
+			SELECT
+				s.user_id,
+				s.question_id,
+				MAX(s.golf_score) as best_golf_score,
+				(ARRAY_AGG(char_length(s.prompt) ORDER BY s.golf_score DESC, s.created_at ASC))[1] as prompt_chars,
+				(ARRAY_AGG(s.created_at ORDER BY s.golf_score DESC, s.created_at ASC))[1] as best_at,
+				COUNT(*) as attempts,
+				MAX(s.created_at) as last_at
+			FROM scores s
+			WHERE s.user_id IS NOT NULL
+			  AND s.scoring_mode = 'golf'
+			  AND s.golf_score IS NOT NULL
+			  AND ($1::int IS NULL OR s.question_id = $1) %s
+			GROUP BY s.user_id, s.question_id
+		)
 		SELECT
-			id, user_id, question_id, prompt, ai_response, score,
-			model_vendor, model_name, answer_number, latency_ms,
-			evaluation_detail, created_at
-		FROM scores
-		WHERE user_id = $1
-		ORDER BY created_at DESC
+			b.user_id,
+			u.username,
+			SUM(b.best_golf_score)::int as total_golf_score,
+			MAX(b.best_golf_score) as best_golf_score,
+			SUM(b.prompt_chars)::int as prompt_chars,
+			COUNT(*)::int as questions,
+			SUM(b.attempts)::int as attempts,
+			MAX(b.last_at) as last_at
+		FROM best b
+		JOIN users u ON b.user_id = u.id
+		GROUP BY b.user_id, u.username
+		ORDER BY total_golf_score DESC, MAX(b.best_at) ASC, b.user_id ASC
 		LIMIT $2
+	`, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, questionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query golf leaderboard: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			// rows.Close のエラーは通常無視しても問題ないが、linter 対策のためログ出力を想定
+			_ = closeErr
+		}
+	}()
+
+	var results []GolfLeaderboardRow
+	for rows.Next() {
+		var row GolfLeaderboardRow
+		err := rows.Scan(
+			&row.UserID,
+			&row.Username,
+			&row.TotalGolfScore,
+			&row.BestGolfScore,
+			&row.PromptChars,
+			&row.Questions,
+			&row.Attempts,
+			&row.LastAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan golf leaderboard row: %w", err)
+		}
+		results = append(results, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("golf leaderboard rows iteration error: %w", err)
+	}
+
+	return results, nil
+}
+
+// scoreColumns は scores s から Score を読み出すときの列の並びです。scanScore と順番を揃えます。
+const scoreColumns = `
+	s.id, s.user_id, s.question_id, s.prompt, s.ai_response, s.score,
+	s.model_vendor, s.model_name, s.answer_number, s.latency_ms,
+	s.evaluation_detail, s.evaluator_version, s.needs_review, s.review_reason,
+	s.scoring_mode, s.golf_score, s.variant_params, s.variant_answer, s.created_at`
+
+// scanScore は scoreColumns の並びの 1 行を Score に読み込みます。extra は続けて SELECT した列の読み込み先です。
+func scanScore(row rowScanner, s *Score, extra ...any) error {
+	var detailJSON, variantJSON []byte
+	dest := []any{
+		&s.ID,
+		&s.UserID,
+		&s.QuestionID,
+		&s.Prompt,
+		&s.AIResponse,
+		&s.Score,
+		&s.ModelVendor,
+		&s.ModelName,
+		&s.AnswerNumber,
+		&s.LatencyMs,
+		&detailJSON,
+		&s.EvaluatorVersion,
+		&s.NeedsReview,
+		&s.ReviewReason,
+		&s.ScoringMode,
+		&s.GolfScore,
+		&variantJSON,
+		&s.VariantAnswer,
+		&s.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	// JSONB を map に変換
+	if len(detailJSON) > 0 {
+		// DB から返る JSONB は []byte なので、構造体の map に戻します。
+		// エラーがあると詳細表示ができないため、そのまま上位に返します。
+		if err := json.Unmarshal(detailJSON, &s.EvaluationDetail); err != nil {
+			return fmt.Errorf("failed to unmarshal evaluation_detail: %w", err)
+		}
+	}
+	if len(variantJSON) > 0 {
+		if err := json.Unmarshal(variantJSON, &s.VariantParams); err != nil {
+			return fmt.Errorf("failed to unmarshal variant_params: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindUserScores は指定されたユーザーのスコア履歴を取得します。
+func (r *scoresRepo) FindUserScores(ctx context.Context, userID int, filter UserScoresFilter, limit int) ([]Score, error) {
+	// ORDER BY created_at DESC で最新回答から順に並べ替え、同時刻の行は id で順序を決めてカーソルが一意になるようにします。
+	// 任意条件は NULL を渡すと無効になる形にして、クエリ文字列を組み立てずに済ませます。
+	query := `
+		SELECT ` + scoreColumns + `
+		FROM scores s
+		LEFT JOIN questions q ON q.id = s.question_id
+		WHERE s.user_id = $1
+		  AND ($2::int IS NULL OR s.question_id = $2)
+		  AND ($3::text IS NULL OR $3 = ANY(q.tags))
+		  AND ($4::text IS NULL OR s.model_name = $4)
+		  AND ($5::timestamptz IS NULL OR s.created_at >= $5)
+		  AND ($6::timestamptz IS NULL OR s.created_at < $6)
+		  AND ($7::timestamptz IS NULL OR (s.created_at, s.id) < ($7, $8::int))
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $9
 	`
 
+	var beforeAt *time.Time
+	var beforeID *int
+	if filter.Before != nil {
+		beforeAt, beforeID = &filter.Before.CreatedAt, &filter.Before.ID
+	}
+
 	// userID と limit は必ずプレースホルダを使って安全に渡します。
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query,
+		userID, filter.QuestionID, filter.Tag, filter.ModelName, filter.From, filter.To, beforeAt, beforeID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user scores: %w", err)
 	}
@@ -223,42 +680,162 @@ func (r *scoresRepo) FindUserScores(ctx context.Context, userID int, limit int)
 	var results []Score
 	for rows.Next() {
 		var s Score
-		var detailJSON []byte
+		if err := scanScore(rows, &s); err != nil {
+			return nil, fmt.Errorf("failed to scan score row: %w", err)
+		}
+		// 1 レコードずつ結果スライスに詰めていきます。limit が小さければメモリ消費も抑えられます。
+		results = append(results, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("user scores rows iteration error: %w", err)
+	}
+
+	return results, nil
+}
+
+// FindScoreDetail は 1 件のスコアを問題文と一緒に取得します。
+func (r *scoresRepo) FindScoreDetail(ctx context.Context, scoreID int) (*ScoreDetail, error) {
+	// 論理削除した問題のスコアも履歴としては見られるよう、deleted_at では絞り込みません。
+	query := `
+		SELECT ` + scoreColumns + `, COALESCE(q.problem_statement, ''), q.template_key
+		FROM scores s
+		LEFT JOIN questions q ON q.id = s.question_id
+		WHERE s.id = $1
+	`
+
+	var d ScoreDetail
+	err := scanScore(r.db.QueryRowContext(ctx, query, scoreID), &d.Score, &d.ProblemStatement, &d.TemplateKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScoreNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query score %d: %w", scoreID, err)
+	}
+	return &d, nil
+}
+
+// FindRescoreTargets は再採点の対象になるスコアを ID 昇順で取得します。
+func (r *scoresRepo) FindRescoreTargets(ctx context.Context, filter RescoreFilter, afterID int, limit int) ([]RescoreTarget, error) {
+	// 任意条件は NULL を渡すと無効になる形にして、クエリ文字列を組み立てずに済ませます。
+	query := `
+		SELECT
+			s.id, s.question_id, s.prompt, s.ai_response, s.score, s.evaluator_version, s.evaluation_detail,
+			q.correct_answer, q.answer_spec, q.expected_unit, q.unit_policy, s.variant_answer, q.scoring_params,
+			s.scoring_mode, q.level
+		FROM scores s
+		JOIN questions q ON q.id = s.question_id
+		WHERE s.id > $1
+		  AND ($2::int IS NULL OR s.question_id = $2)
+		  AND ($3::int IS NULL OR s.evaluator_version IS NULL OR s.evaluator_version < $3)
+		ORDER BY s.id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, filter.QuestionID, filter.BelowVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rescore targets: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			// rows.Close のエラーは通常無視しても問題ないが、linter 対策のためログ出力を想定
+			_ = closeErr
+		}
+	}()
 
+	var results []RescoreTarget
+	for rows.Next() {
+		var t RescoreTarget
+		var detailJSON []byte
 		err := rows.Scan(
-			&s.ID,
-			&s.UserID,
-			&s.QuestionID,
-			&s.Prompt,
-			&s.AIResponse,
-			&s.Score,
-			&s.ModelVendor,
-			&s.ModelName,
-			&s.AnswerNumber,
-			&s.LatencyMs,
+			&t.ScoreID,
+			&t.QuestionID,
+			&t.Prompt,
+			&t.AIResponse,
+			&t.Score,
+			&t.EvaluatorVersion,
 			&detailJSON,
-			&s.CreatedAt,
+			&t.CorrectAnswer,
+			&t.AnswerSpec,
+			&t.ExpectedUnit,
+			&t.UnitPolicy,
+			&t.VariantAnswer,
+			&t.ScoringParams,
+			&t.ScoringMode,
+			&t.Level,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan score row: %w", err)
+			return nil, fmt.Errorf("failed to scan rescore target: %w", err)
 		}
-
-		// JSONB を map に変換
 		if len(detailJSON) > 0 {
-			// DB から返る JSONB は []byte なので、構造体の map に戻します。
-			// エラーがあると詳細表示ができないため、そのまま上位に返します。
-			if err := json.Unmarshal(detailJSON, &s.EvaluationDetail); err != nil {
+			if err := json.Unmarshal(detailJSON, &t.EvaluationDetail); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal evaluation_detail: %w", err)
 			}
 		}
-
-		// 1 レコードずつ結果スライスに詰めていきます。limit が小さければメモリ消費も抑えられます。
-		results = append(results, s)
+		results = append(results, t)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("user scores rows iteration error: %w", err)
+		return nil, fmt.Errorf("rescore targets rows iteration error: %w", err)
 	}
 
 	return results, nil
 }
+
+// UpdateEvaluations は再採点の結果を 1 トランザクションで書き戻します。
+// 途中で失敗した場合はバッチ全体をロールバックし、一部だけ新しい版になった状態を残しません。
+func (r *scoresRepo) UpdateEvaluations(ctx context.Context, updates []EvaluationUpdate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Commit 済みなら Rollback は sql.ErrTxDone を返すだけなので無視して構いません。
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE scores
+		SET score = $2,
+		    answer_number = $3,
+		    evaluation_detail = $4,
+		    evaluator_version = $5,
+		    golf_score = $6,
+		    rescored_at = NOW()
+		WHERE id = $1
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare rescore update: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for _, u := range updates {
+		detailJSON, err := json.Marshal(u.EvaluationDetail)
+		if err != nil {
+			return fmt.Errorf("failed to marshal evaluation_detail: %w", err)
+		}
+		if _, err := stmt.ExecContext(ctx, u.ScoreID, u.Score, u.AnswerNumber, detailJSON, u.EvaluatorVersion, u.GolfScore); err != nil {
+			return fmt.Errorf("failed to update score %d: %w", u.ScoreID, err)
+		}
+	}
+
+	// 再採点でスコアが下がると自己ベストが別の回答に移ることがあるため、差分ではなく scores から計算し直します。
+	scoreIDs := make([]int64, 0, len(updates))
+	for _, u := range updates {
+		scoreIDs = append(scoreIDs, int64(u.ScoreID))
+	}
+	refresh := fmt.Sprintf(questionBestFromScores, `
+		AND (s.user_id, s.question_id) IN (
+			SELECT user_id, question_id FROM scores WHERE id = ANY($1) AND user_id IS NOT NULL
+		)`)
+	if _, err := tx.ExecContext(ctx, refresh, pq.Array(scoreIDs)); err != nil {
+		return fmt.Errorf("failed to refresh user_question_best: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rescore updates: %w", err)
+	}
+	return nil
+}
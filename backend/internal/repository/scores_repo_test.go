@@ -5,8 +5,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -216,7 +218,7 @@ func TestScoresRepo_FindLeaderboard(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rows, err := repo.FindLeaderboard(ctx, tt.period, tt.limit)
+			rows, err := repo.FindLeaderboard(ctx, tt.period, LeaderboardScope{}, tt.limit)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FindLeaderboard() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -230,9 +232,13 @@ func TestScoresRepo_FindLeaderboard(t *testing.T) {
 					t.Logf("FindLeaderboard() returned %d rows", len(rows))
 					// 最初の行が最高スコアであることを確認
 					for i := 1; i < len(rows); i++ {
-						if rows[i-1].BestScore < rows[i].BestScore {
-							t.Errorf("Leaderboard not sorted: row %d score=%d < row %d score=%d",
-								i-1, rows[i-1].BestScore, i, rows[i].BestScore)
+						if rows[i-1].TotalScore < rows[i].TotalScore {
+							t.Errorf("Leaderboard not sorted: row %d total=%d < row %d total=%d",
+								i-1, rows[i-1].TotalScore, i, rows[i].TotalScore)
+						}
+						if rows[i].Rank != rows[i-1].Rank+1 {
+							t.Errorf("Leaderboard rank not sequential: row %d rank=%d, row %d rank=%d",
+								i-1, rows[i-1].Rank, i, rows[i].Rank)
 						}
 					}
 				}
@@ -241,6 +247,260 @@ func TestScoresRepo_FindLeaderboard(t *testing.T) {
 	}
 }
 
+// TestScoresRepo_FindLeaderboardRank は問題・タグで絞ったランキングでの自分の順位が、上位一覧と同じ並び順で求まることを確認します。
+func TestScoresRepo_FindLeaderboardRank(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	// 他のテストのデータと混ざらないよう、専用の問題を作ってその問題だけで集計します。
+	var questionID int
+	if err := db.QueryRow(`
+		INSERT INTO questions (level, problem_statement, correct_answer, tags)
+		VALUES (1, 'leaderboard rank test', '1', ARRAY['calculation'])
+		RETURNING id
+	`).Scan(&questionID); err != nil {
+		t.Fatalf("failed to insert question: %v", err)
+	}
+	defer func() {
+		_, _ = db.Exec("DELETE FROM scores WHERE question_id = $1", questionID)
+		_, _ = db.Exec("DELETE FROM questions WHERE id = $1", questionID)
+	}()
+
+	userIDs := []int{999981, 999982, 999983}
+	scores := []int{60, 90, 60}
+	for i, id := range userIDs {
+		userID := id
+		ensureTestUser(t, db, userID)
+		defer cleanupTestData(t, db, userID)
+		if err := repo.Create(ctx, &Score{UserID: &userID, QuestionID: questionID, Prompt: "p", AIResponse: "a", Score: scores[i], ModelVendor: "gemini"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	scope := LeaderboardScope{QuestionID: &questionID}
+	rows, err := repo.FindLeaderboard(ctx, "all", scope, 2)
+	if err != nil {
+		t.Fatalf("FindLeaderboard() error = %v", err)
+	}
+	// 同点の 2 人は先にその点を取った 999981 が先に並びます。
+	if len(rows) != 2 || *rows[0].UserID != 999982 || *rows[1].UserID != 999981 || rows[1].Rank != 2 {
+		t.Fatalf("FindLeaderboard() = %+v", rows)
+	}
+
+	// 後から同点になった 999983 が点にならない回答を送っても、順位は入れ替わりません。
+	late := 999983
+	if err := repo.Create(ctx, &Score{UserID: &late, QuestionID: questionID, Prompt: "p", AIResponse: "a", Score: 0, ModelVendor: "gemini"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	me, err := repo.FindLeaderboardRank(ctx, "all", scope, late)
+	if err != nil || me == nil || me.Rank != 3 || me.BestScore != 60 {
+		t.Errorf("FindLeaderboardRank() = %+v, %v, want rank 3", me, err)
+	}
+	if me, err := repo.FindLeaderboardRank(ctx, "all", scope, 999999); err != nil || me != nil {
+		t.Errorf("FindLeaderboardRank(no scores) = %+v, %v, want nil", me, err)
+	}
+
+	tag := "calculation"
+	if rows, err := repo.FindLeaderboard(ctx, "all", LeaderboardScope{QuestionID: &questionID, Tag: &tag}, 10); err != nil || len(rows) != 3 {
+		t.Errorf("FindLeaderboard(tag) = %d rows, %v, want 3", len(rows), err)
+	}
+	other := "general_knowledge"
+	if rows, err := repo.FindLeaderboard(ctx, "all", LeaderboardScope{QuestionID: &questionID, Tag: &other}, 10); err != nil || len(rows) != 0 {
+		t.Errorf("FindLeaderboard(other tag) = %d rows, %v, want 0", len(rows), err)
+	}
+}
+
+// TestScoresRepo_FindLeaderboardSumsBestPerQuestion は順位が問題ごとの自己ベストの合計で決まり、
+// 簡単な問題 1 問だけの満点が全問に挑戦したプレイヤーを上回らないこと、レベルでの重み付けが効くことを確認します。
+func TestScoresRepo_FindLeaderboardSumsBestPerQuestion(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	// 他のテストのデータと混ざらないよう、このテスト専用のタグを付けた問題だけで集計します。
+	tag := "leaderboard_sum_test"
+	questionIDs := map[int]int{} // level → question_id
+	for _, level := range []int{1, 3, 5} {
+		var id int
+		if err := db.QueryRow(`
+			INSERT INTO questions (level, problem_statement, correct_answer, tags)
+			VALUES ($1, 'leaderboard sum test', '1', ARRAY[$2])
+			RETURNING id
+		`, level, tag).Scan(&id); err != nil {
+			t.Fatalf("failed to insert question: %v", err)
+		}
+		questionIDs[level] = id
+	}
+
+	lucky, grinder, hard := 999971, 999972, 999973
+	for _, id := range []int{lucky, grinder, hard} {
+		cleanupTestData(t, db, id)
+		ensureTestUser(t, db, id)
+	}
+	defer func() {
+		for _, id := range []int{lucky, grinder, hard} {
+			cleanupTestData(t, db, id)
+		}
+		for _, id := range questionIDs {
+			_, _ = db.Exec("DELETE FROM questions WHERE id = $1", id)
+		}
+	}()
+
+	attempts := []struct {
+		userID int
+		level  int
+		score  int
+	}{
+		{lucky, 1, 100},
+		{lucky, 1, 20}, // 自己ベストより低い再挑戦は合計に影響しません。
+		{grinder, 1, 80},
+		{grinder, 3, 80},
+		{grinder, 5, 80},
+		{hard, 5, 100},
+	}
+	for _, a := range attempts {
+		userID := a.userID
+		if err := repo.Create(ctx, &Score{UserID: &userID, QuestionID: questionIDs[a.level], Prompt: "p", AIResponse: "a", Score: a.score, ModelVendor: "gemini"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		weighted  bool
+		wantUsers []int
+		wantTotal []int
+	}{
+		// lucky と hard は同点のため、先に 100 点を取った lucky が先に並びます（lucky の後からの低い再挑戦は関係しません）。
+		{name: "自己ベストの合計", weighted: false, wantUsers: []int{grinder, lucky, hard}, wantTotal: []int{240, 100, 100}},
+		{name: "レベルで重み付け", weighted: true, wantUsers: []int{grinder, hard, lucky}, wantTotal: []int{720, 500, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := LeaderboardScope{Tag: &tag, WeightByLevel: tt.weighted}
+			rows, err := repo.FindLeaderboard(ctx, "all", scope, 10)
+			if err != nil {
+				t.Fatalf("FindLeaderboard() error = %v", err)
+			}
+			if len(rows) != len(tt.wantUsers) {
+				t.Fatalf("FindLeaderboard() returned %d rows, want %d: %+v", len(rows), len(tt.wantUsers), rows)
+			}
+			for i, row := range rows {
+				if *row.UserID != tt.wantUsers[i] || row.TotalScore != tt.wantTotal[i] || row.Rank != i+1 {
+					t.Errorf("rows[%d] = user %d total %d rank %d, want user %d total %d", i, *row.UserID, row.TotalScore, row.Rank, tt.wantUsers[i], tt.wantTotal[i])
+				}
+			}
+
+			wantRank := slices.Index(tt.wantUsers, lucky) + 1
+			me, err := repo.FindLeaderboardRank(ctx, "all", scope, lucky)
+			if err != nil || me == nil || me.Rank != wantRank || me.Questions != 1 || me.Attempts != 2 || me.BestScore != 100 {
+				t.Errorf("FindLeaderboardRank(lucky) = %+v, %v", me, err)
+			}
+		})
+	}
+}
+
+// TestScoresRepo_QuestionBest は user_question_best が回答の保存・再採点のたびに更新され、RebuildQuestionBest で scores から作り直せることを確認します。
+func TestScoresRepo_QuestionBest(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	testUserID := 999970
+	cleanupTestData(t, db, testUserID)
+	ensureTestUser(t, db, testUserID)
+	defer cleanupTestData(t, db, testUserID)
+
+	type best struct {
+		score    int
+		attempts int
+		bestAt   time.Time
+	}
+	readBest := func() best {
+		t.Helper()
+		var b best
+		if err := db.QueryRow(`
+			SELECT best_score, attempts, best_at FROM user_question_best WHERE user_id = $1 AND question_id = 1
+		`, testUserID).Scan(&b.score, &b.attempts, &b.bestAt); err != nil {
+			t.Fatalf("failed to read user_question_best: %v", err)
+		}
+		return b
+	}
+
+	var created []Score
+	for _, score := range []int{50, 90, 70} {
+		record := Score{UserID: &testUserID, QuestionID: 1, Prompt: "p", AIResponse: "a", Score: score, ModelVendor: "gemini"}
+		if err := repo.Create(ctx, &record); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		created = append(created, record)
+	}
+	// ゲストの回答は自己ベストに含まれません。
+	guest := Score{QuestionID: 1, Prompt: "p", AIResponse: "a", Score: 100, ModelVendor: "gemini"}
+	if err := repo.Create(ctx, &guest); err != nil {
+		t.Fatalf("Create(guest) error = %v", err)
+	}
+	defer func() {
+		_, _ = db.Exec("DELETE FROM scores WHERE id = $1", guest.ID)
+	}()
+
+	if b := readBest(); b.score != 90 || b.attempts != 3 || !b.bestAt.Equal(created[1].CreatedAt) {
+		t.Errorf("after Create: %+v, want best 90 at %v, 3 attempts", b, created[1].CreatedAt)
+	}
+
+	// 再採点で自己ベストの回答が下がると、次に良い回答が自己ベストになります。
+	if err := repo.UpdateEvaluations(ctx, []EvaluationUpdate{{
+		ScoreID:          created[1].ID,
+		Score:            10,
+		EvaluationDetail: map[string]interface{}{"mode": "rescore_test"},
+		EvaluatorVersion: 4,
+	}}); err != nil {
+		t.Fatalf("UpdateEvaluations() error = %v", err)
+	}
+	if b := readBest(); b.score != 70 || b.attempts != 3 || !b.bestAt.Equal(created[2].CreatedAt) {
+		t.Errorf("after rescore: %+v, want best 70 at %v", b, created[2].CreatedAt)
+	}
+
+	// 行が消えても scores から作り直せます。
+	if _, err := db.Exec("DELETE FROM user_question_best WHERE user_id = $1", testUserID); err != nil {
+		t.Fatalf("failed to delete user_question_best: %v", err)
+	}
+	if n, err := repo.RebuildQuestionBest(ctx); err != nil || n == 0 {
+		t.Fatalf("RebuildQuestionBest() = %d, %v", n, err)
+	}
+	if b := readBest(); b.score != 70 || b.attempts != 3 {
+		t.Errorf("after rebuild: %+v, want best 70, 3 attempts", b)
+	}
+}
+
 // TestScoresRepo_FindUserScores はユーザー別スコア履歴が新しい順で返ることと、存在しないユーザーでもエラーにしないことをテストします。
 func TestScoresRepo_FindUserScores(t *testing.T) {
 	db := setupTestDB(t)
@@ -319,7 +579,7 @@ func TestScoresRepo_FindUserScores(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			scores, err := repo.FindUserScores(ctx, tt.userID, tt.limit)
+			scores, err := repo.FindUserScores(ctx, tt.userID, UserScoresFilter{}, tt.limit)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FindUserScores() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -341,12 +601,309 @@ func TestScoresRepo_FindUserScores(t *testing.T) {
 	}
 }
 
+// TestScoresRepo_FindUserScoresFilter はスコア履歴の絞り込みとカーソルでのページ送り、1 件の詳細の取得を確認します。
+func TestScoresRepo_FindUserScoresFilter(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	testUserID := 999980
+	cleanupTestData(t, db, testUserID)
+	ensureTestUser(t, db, testUserID)
+	defer cleanupTestData(t, db, testUserID)
+
+	// 問題 1 は character_counting、問題 3 は pattern_recognition のタグが付いたシードデータです。
+	flash, pro := "gemini-2.5-flash", "gemini-2.5-pro"
+	inputs := []struct {
+		questionID int
+		model      *string
+	}{{1, &flash}, {3, &flash}, {1, &pro}}
+	var created []Score
+	for _, in := range inputs {
+		record := Score{UserID: &testUserID, QuestionID: in.questionID, Prompt: "p", AIResponse: "a", Score: 50, ModelVendor: "gemini", ModelName: in.model}
+		if err := repo.Create(ctx, &record); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		created = append(created, record)
+	}
+
+	questionID := 1
+	tag := "pattern_recognition"
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		filter  UserScoresFilter
+		wantIDs []int
+	}{
+		{name: "全件（新しい順）", filter: UserScoresFilter{}, wantIDs: []int{created[2].ID, created[1].ID, created[0].ID}},
+		{name: "問題", filter: UserScoresFilter{QuestionID: &questionID}, wantIDs: []int{created[2].ID, created[0].ID}},
+		{name: "タグ", filter: UserScoresFilter{Tag: &tag}, wantIDs: []int{created[1].ID}},
+		{name: "モデル", filter: UserScoresFilter{ModelName: &pro}, wantIDs: []int{created[2].ID}},
+		{name: "期間", filter: UserScoresFilter{From: &future}, wantIDs: nil},
+		{name: "カーソル", filter: UserScoresFilter{Before: &ScoreCursor{CreatedAt: created[1].CreatedAt, ID: created[1].ID}}, wantIDs: []int{created[0].ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, err := repo.FindUserScores(ctx, testUserID, tt.filter, 10)
+			if err != nil {
+				t.Fatalf("FindUserScores() error = %v", err)
+			}
+			var gotIDs []int
+			for _, s := range scores {
+				gotIDs = append(gotIDs, s.ID)
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("FindUserScores() ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+
+	detail, err := repo.FindScoreDetail(ctx, created[0].ID)
+	if err != nil || detail.UserID == nil || *detail.UserID != testUserID || detail.ProblemStatement == "" {
+		t.Errorf("FindScoreDetail() = %+v, %v", detail, err)
+	}
+	if _, err := repo.FindScoreDetail(ctx, -1); !errors.Is(err, ErrScoreNotFound) {
+		t.Errorf("FindScoreDetail(missing) error = %v, want ErrScoreNotFound", err)
+	}
+}
+
+// TestScoresRepo_FindGolfLeaderboard はログイン中のゴルフモードの回答だけが集計され、問題ごとの自己ベストの合計の降順、
+// 同点なら先にその合計に達した順で並ぶこと、文字数が自己ベストを出した回答のものであることを確認します。
+func TestScoresRepo_FindGolfLeaderboard(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	// 他のテストのデータと混ざらないよう、専用の問題を 2 つ作ります。
+	questionIDs := make([]int, 2)
+	for i := range questionIDs {
+		if err := db.QueryRow(`
+			INSERT INTO questions (level, problem_statement, correct_answer)
+			VALUES (1, 'golf leaderboard test', '3')
+			RETURNING id
+		`).Scan(&questionIDs[i]); err != nil {
+			t.Fatalf("failed to insert question: %v", err)
+		}
+	}
+	defer func() {
+		for _, id := range questionIDs {
+			_, _ = db.Exec("DELETE FROM scores WHERE question_id = $1", id)
+			_, _ = db.Exec("DELETE FROM questions WHERE id = $1", id)
+		}
+	}()
+	easy, other := questionIDs[0], questionIDs[1]
+
+	golfer, standard, steady, late := 999994, 999993, 999992, 999991
+	for _, id := range []int{golfer, standard, steady, late} {
+		cleanupTestData(t, db, id)
+		ensureTestUser(t, db, id)
+		defer cleanupTestData(t, db, id)
+	}
+
+	create := func(userID *int, questionID int, prompt string, golfScore int) {
+		t.Helper()
+		if err := repo.Create(ctx, &Score{
+			UserID: userID, QuestionID: questionID, Prompt: prompt, AIResponse: "3", Score: 100,
+			ModelVendor: "gemini", ScoringMode: "golf", GolfScore: &golfScore,
+		}); err != nil {
+			t.Fatalf("Failed to create golf score: %v", err)
+		}
+	}
+	// golfer の自己ベストは 2 回目の 80 点で、文字数はそのプロンプトのものを使います（短い 1 回目の文字数ではありません）。
+	create(&golfer, easy, "短", 70)
+	create(&golfer, easy, "数えてね", 80)
+	// steady は 1 問あたりでは golfer より低いものの、2 問の合計で上回ります。
+	create(&steady, easy, "p", 60)
+	create(&steady, other, "p", 60)
+	// late は steady と同点に後から達し、さらに低い回答を送っても steady を抜けません。
+	create(&late, other, "p", 60)
+	create(&late, other, "p", 10)
+
+	if err := repo.Create(ctx, &Score{
+		UserID: &standard, QuestionID: easy, Prompt: "通常モード", AIResponse: "3", Score: 100,
+		ModelVendor: "gemini",
+	}); err != nil {
+		t.Fatalf("Failed to create standard score: %v", err)
+	}
+	// ゲストの回答は 1 つの "guest" 行にまとめず、ランキングから除きます。
+	create(nil, easy, "golf leaderboard guest", 100)
+
+	rows, err := repo.FindGolfLeaderboard(ctx, "all", nil, 100)
+	if err != nil {
+		t.Fatalf("FindGolfLeaderboard() error = %v", err)
+	}
+	users := make([]int, 0, len(rows))
+	for i, row := range rows {
+		if i > 0 && rows[i-1].TotalGolfScore < row.TotalGolfScore {
+			t.Errorf("golf leaderboard not sorted at row %d", i)
+		}
+		if row.UserID == nil {
+			t.Errorf("guest row must not appear in golf leaderboard: %+v", row)
+			continue
+		}
+		users = append(users, *row.UserID)
+		switch *row.UserID {
+		case standard:
+			t.Error("standard mode score must not appear in golf leaderboard")
+		case golfer:
+			if row.TotalGolfScore != 80 || row.BestGolfScore != 80 || row.PromptChars != 4 || row.Questions != 1 || row.Attempts != 2 {
+				t.Errorf("unexpected golfer row: %+v", row)
+			}
+		case steady:
+			if row.TotalGolfScore != 120 || row.BestGolfScore != 60 || row.Questions != 2 {
+				t.Errorf("unexpected steady row: %+v", row)
+			}
+		}
+	}
+	if g, s := slices.Index(users, golfer), slices.Index(users, steady); g < 0 || s < 0 || s > g {
+		t.Errorf("steady (total 120) must rank above golfer (best 80): %v", users)
+	}
+
+	tests := []struct {
+		name       string
+		questionID int
+		wantUsers  []int
+		wantTotal  []int
+	}{
+		{name: "1 問のベスト", questionID: easy, wantUsers: []int{golfer, steady}, wantTotal: []int{80, 60}},
+		{name: "同点は先に達した順", questionID: other, wantUsers: []int{steady, late}, wantTotal: []int{60, 60}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := repo.FindGolfLeaderboard(ctx, "all", &tt.questionID, 10)
+			if err != nil {
+				t.Fatalf("FindGolfLeaderboard() error = %v", err)
+			}
+			if len(rows) != len(tt.wantUsers) {
+				t.Fatalf("FindGolfLeaderboard() returned %d rows, want %d: %+v", len(rows), len(tt.wantUsers), rows)
+			}
+			for i, row := range rows {
+				if *row.UserID != tt.wantUsers[i] || row.TotalGolfScore != tt.wantTotal[i] {
+					t.Errorf("rows[%d] = user %d total %d, want user %d total %d", i, *row.UserID, row.TotalGolfScore, tt.wantUsers[i], tt.wantTotal[i])
+				}
+			}
+		})
+	}
+
+	if _, err := repo.FindGolfLeaderboard(ctx, "invalid", nil, 10); err == nil {
+		t.Error("expected error for invalid period")
+	}
+}
+
+// TestScoresRepo_Rescore は版の古いスコアが再採点対象として取得でき、書き戻すと版が更新されて対象から外れることを確認します。
+func TestScoresRepo_Rescore(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewScoresRepository(db)
+	ctx := context.Background()
+
+	testUserID := 999995
+	cleanupTestData(t, db, testUserID)
+	ensureTestUser(t, db, testUserID)
+	defer cleanupTestData(t, db, testUserID)
+
+	oldVersion := 3
+	record := &Score{
+		UserID:           &testUserID,
+		QuestionID:       1,
+		Prompt:           "rescore",
+		AIResponse:       "最終回答: 3",
+		Score:            40,
+		ModelVendor:      "gemini",
+		EvaluatorVersion: &oldVersion,
+		ScoringMode:      "golf",
+		GolfScore:        intPtr(10),
+	}
+	if err := repo.Create(ctx, record); err != nil {
+		t.Fatalf("Failed to create score: %v", err)
+	}
+
+	questionID := 1
+	below := 4
+	filter := RescoreFilter{QuestionID: &questionID, BelowVersion: &below}
+	targets, err := repo.FindRescoreTargets(ctx, filter, record.ID-1, 10)
+	if err != nil {
+		t.Fatalf("FindRescoreTargets() error = %v", err)
+	}
+	if len(targets) == 0 || targets[0].ScoreID != record.ID {
+		t.Fatalf("FindRescoreTargets() did not return the created score: %+v", targets)
+	}
+	if targets[0].CorrectAnswer == "" || targets[0].Level == 0 {
+		t.Error("FindRescoreTargets() did not join question answer and level")
+	}
+	if targets[0].ScoringMode != "golf" {
+		t.Errorf("FindRescoreTargets() scoring mode = %q, want golf", targets[0].ScoringMode)
+	}
+
+	err = repo.UpdateEvaluations(ctx, []EvaluationUpdate{{
+		ScoreID:          record.ID,
+		Score:            100,
+		AnswerNumber:     float64Ptr(3),
+		EvaluationDetail: map[string]interface{}{"mode": "numeric_exact"},
+		EvaluatorVersion: 4,
+		GolfScore:        intPtr(90),
+	}})
+	if err != nil {
+		t.Fatalf("UpdateEvaluations() error = %v", err)
+	}
+
+	targets, err = repo.FindRescoreTargets(ctx, filter, record.ID-1, 10)
+	if err != nil {
+		t.Fatalf("FindRescoreTargets() error = %v", err)
+	}
+	for _, target := range targets {
+		if target.ScoreID == record.ID {
+			t.Fatal("rescored score should no longer be a target")
+		}
+	}
+
+	scores, err := repo.FindUserScores(ctx, testUserID, UserScoresFilter{}, 10)
+	if err != nil || len(scores) != 1 {
+		t.Fatalf("FindUserScores() = %v, %v", scores, err)
+	}
+	if scores[0].Score != 100 || scores[0].EvaluatorVersion == nil || *scores[0].EvaluatorVersion != 4 ||
+		scores[0].GolfScore == nil || *scores[0].GolfScore != 90 {
+		t.Errorf("score not updated: %+v", scores[0])
+	}
+}
+
 // stringPtr はリテラル文字列のポインタを返し、テストデータで *string フィールドへ簡潔に値を入れるための小道具です。
 func stringPtr(s string) *string {
 	// Go では文字列リテラルのポインタを直接取れないため、簡単なヘルパーを用意しておくとテストが読みやすくなります。
 	return &s
 }
 
+// intPtr は *int フィールド（GolfScore など）に入れる値のポインタを返します。
+func intPtr(n int) *int {
+	return &n
+}
+
 // float64Ptr は数値リテラルのポインタ版ヘルパーで、AnswerNumber など *float64 フィールドの値定義を簡潔にします。
 func float64Ptr(f float64) *float64 {
 	// ↑ と同じ理由で float64 版も用意しています。
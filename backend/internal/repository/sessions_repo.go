@@ -0,0 +1,207 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// sessions_repo.go はログインセッション（user_sessions）とリフレッシュトークン（user_session_tokens）の操作をまとめます。
+// リフレッシュトークンは 1 回使うと新しいものに交換し、使用済みのトークンが再び使われたらセッションごと失効させます。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid は未知・失効済み・期限切れのセッションのリフレッシュトークンを表します。
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	// ErrRefreshTokenReused は使用済みのリフレッシュトークンが再び使われたことを表します。このときセッションは失効させています。
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+// セッションを失効させた理由です。
+const (
+	RevokeReasonLogout    = "logout"
+	RevokeReasonLogoutAll = "logout_all"
+	RevokeReasonReuse     = "refresh_token_reuse"
+)
+
+// Session は user_sessions テーブルの 1 行です。
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int       `json:"user_id"`
+	UserAgent  *string   `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SessionsRepository はログインセッションに対する操作を定義するインターフェースです。
+type SessionsRepository interface {
+	// Create はセッションと最初のリフレッシュトークン（ハッシュ）を登録し、採番された ID と日時を session に設定します。
+	Create(ctx context.Context, session *Session, tokenHash string) error
+
+	// Rotate はリフレッシュトークンを使用済みにして新しいトークンに交換し、セッションの期限を expiresAt まで延ばします。
+	// 使用済みのトークンなら、同じトークンを持つ誰かがいるとみなしてセッションを失効させ、ErrRefreshTokenReused を返します。
+	Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*Session, error)
+
+	// RevokeByToken はリフレッシュトークンのセッションを失効させます。未知のトークンや失効済みのセッションでもエラーにしません。
+	RevokeByToken(ctx context.Context, tokenHash, reason string) error
+
+	// RevokeAllForUser はユーザーの有効なセッションを全て失効させ、失効させた数を返します。
+	RevokeAllForUser(ctx context.Context, userID int, reason string) (int64, error)
+
+	// IsActive はセッションが失効しておらず、期限内かどうかを返します。認証ミドルウェアがリクエストごとに確認します。
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+}
+
+// sessionsRepo は SessionsRepository の実装です。
+type sessionsRepo struct {
+	db *sql.DB
+}
+
+// NewSessionsRepository は SessionsRepository の新しいインスタンスを作成します。
+func NewSessionsRepository(db *sql.DB) SessionsRepository {
+	return &sessionsRepo{db: db}
+}
+
+func (r *sessionsRepo) Create(ctx context.Context, session *Session, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_sessions (user_id, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, last_used_at
+	`, session.UserID, session.UserAgent, session.IPAddress, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO user_session_tokens (token_hash, session_id) VALUES ($1, $2)`, tokenHash, session.ID); err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionsRepo) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*Session, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 同じトークンで同時に交換されても 1 回しか成功しないよう、セッションの行をロックします。
+	var s Session
+	var usedAt sql.NullTime
+	var revoked bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT s.id, s.user_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, s.expires_at,
+		       s.revoked_at IS NOT NULL, t.used_at
+		FROM user_session_tokens t
+		JOIN user_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1
+		FOR UPDATE OF s, t
+	`, oldHash).Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &revoked, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	if revoked || !s.ExpiresAt.After(time.Now()) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if usedAt.Valid {
+		// 失効はロールバックせずに残します。正規の利用者も攻撃者も、このセッションでは以後更新できなくなります。
+		if err := revokeSession(ctx, tx, s.ID, RevokeReasonReuse); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit session revocation: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE user_session_tokens SET used_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO user_session_tokens (token_hash, session_id) VALUES ($1, $2)`, newHash, s.ID); err != nil {
+		return nil, fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_sessions SET last_used_at = NOW(), expires_at = $2
+		WHERE id = $1
+		RETURNING last_used_at, expires_at
+	`, s.ID, expiresAt).Scan(&s.LastUsedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *sessionsRepo) RevokeByToken(ctx context.Context, tokenHash, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW(), revoke_reason = $2
+		WHERE id = (SELECT session_id FROM user_session_tokens WHERE token_hash = $1)
+		  AND revoked_at IS NULL
+	`, tokenHash, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionsRepo) RevokeAllForUser(ctx context.Context, userID int, reason string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW(), revoke_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count revoked sessions: %w", err)
+	}
+	return n, nil
+}
+
+func (r *sessionsRepo) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`, sessionID).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
+
+// revokeSession はトランザクションの中でセッションを失効させます。
+func revokeSession(ctx context.Context, tx *sql.Tx, sessionID int64, reason string) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE user_sessions SET revoked_at = NOW(), revoke_reason = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		sessionID, reason); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
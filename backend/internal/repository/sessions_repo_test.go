@@ -0,0 +1,88 @@
+// sessions_repo_test.go はリフレッシュトークンのローテーション、使用済みトークンの再利用によるセッションの失効、ログアウトを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+func TestSessionsRepo_RotateAndRevoke(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	cleanup := func() {
+		// セッションとトークンは users の削除に合わせて消えます（ON DELETE CASCADE）。
+		if _, err := db.Exec("DELETE FROM users WHERE username = 'session_test_user'"); err != nil {
+			t.Logf("cleanup users: %v", err)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	user := &models.User{Username: "session_test_user", Email: "session-test@example.com", PasswordHash: "hash"}
+	if err := NewUsersRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+
+	repo := NewSessionsRepository(db)
+	expiresAt := time.Now().Add(time.Hour)
+	session := &Session{UserID: user.ID, ExpiresAt: expiresAt}
+	if err := repo.Create(ctx, session, "hash-1"); err != nil {
+		t.Fatalf("Create session failed: %v", err)
+	}
+	if active, err := repo.IsActive(ctx, session.ID); err != nil || !active {
+		t.Fatalf("new session should be active: %v, %v", active, err)
+	}
+
+	// 交換した新しいトークンは使え、古いトークンは使用済みになります。
+	rotated, err := repo.Rotate(ctx, "hash-1", "hash-2", expiresAt.Add(time.Hour))
+	if err != nil || rotated.ID != session.ID || rotated.UserID != user.ID {
+		t.Fatalf("Rotate = %+v, %v", rotated, err)
+	}
+	if _, err := repo.Rotate(ctx, "unknown", "hash-x", expiresAt); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Rotate(unknown) = %v, want ErrRefreshTokenInvalid", err)
+	}
+
+	// 使用済みのトークンを再び使うとセッションごと失効し、最新のトークンも使えなくなります。
+	if _, err := repo.Rotate(ctx, "hash-1", "hash-3", expiresAt); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Rotate(reused) = %v, want ErrRefreshTokenReused", err)
+	}
+	if active, _ := repo.IsActive(ctx, session.ID); active {
+		t.Error("session should be revoked after reuse")
+	}
+	if _, err := repo.Rotate(ctx, "hash-2", "hash-4", expiresAt); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Rotate(after revoke) = %v, want ErrRefreshTokenInvalid", err)
+	}
+
+	// ログアウトと全端末からのログアウト
+	second := &Session{UserID: user.ID, ExpiresAt: expiresAt}
+	third := &Session{UserID: user.ID, ExpiresAt: expiresAt}
+	if err := repo.Create(ctx, second, "hash-5"); err != nil {
+		t.Fatalf("Create session failed: %v", err)
+	}
+	if err := repo.Create(ctx, third, "hash-6"); err != nil {
+		t.Fatalf("Create session failed: %v", err)
+	}
+	if err := repo.RevokeByToken(ctx, "hash-5", RevokeReasonLogout); err != nil {
+		t.Fatalf("RevokeByToken failed: %v", err)
+	}
+	if active, _ := repo.IsActive(ctx, second.ID); active {
+		t.Error("logged out session should be revoked")
+	}
+	n, err := repo.RevokeAllForUser(ctx, user.ID, RevokeReasonLogoutAll)
+	if err != nil || n != 1 {
+		t.Errorf("RevokeAllForUser = %d, %v, want 1", n, err)
+	}
+	if active, _ := repo.IsActive(ctx, third.ID); active {
+		t.Error("all sessions should be revoked")
+	}
+}
@@ -0,0 +1,93 @@
+// Package repository はデータベースアクセスとドメインロジックの間を仲介するリポジトリ層を提供します。
+// users_repo.go は users テーブルの登録と検索をまとめ、サインアップ・ログインから使います。
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+var (
+	// ErrUserNotFound は指定したユーザーが存在しないときに返します。
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUsernameTaken は登録しようとしたユーザー名が使われているときに返します。
+	ErrUsernameTaken = errors.New("username already exists")
+	// ErrEmailTaken は登録しようとしたメールアドレスが使われているときに返します。
+	ErrEmailTaken = errors.New("email already exists")
+)
+
+// usersEmailIndex はメールアドレスの一意インデックスの名前です。一意制約違反がどちらの列で起きたかを見分けるのに使います。
+const usersEmailIndex = "idx_users_email"
+
+// UsersRepository は users テーブルに対する操作を定義するインターフェースです。
+type UsersRepository interface {
+	// Create はユーザーを登録し、採番された ID と作成日時を user に設定します。
+	// ユーザー名・メールアドレスの重複は ErrUsernameTaken・ErrEmailTaken を返します。
+	Create(ctx context.Context, user *models.User) error
+
+	// FindByEmail はメールアドレス（大文字・小文字を区別しない）でユーザーを探します。見つからなければ ErrUserNotFound です。
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+
+	// FindByID は ID でユーザーを探します。見つからなければ ErrUserNotFound です。
+	FindByID(ctx context.Context, id int) (*models.User, error)
+}
+
+// usersRepo は UsersRepository の実装です。
+type usersRepo struct {
+	db *sql.DB
+}
+
+// NewUsersRepository は UsersRepository の新しいインスタンスを作成します。
+func NewUsersRepository(db *sql.DB) UsersRepository {
+	return &usersRepo{db: db}
+}
+
+const userColumns = `id, username, COALESCE(email, ''), password_hash, created_at`
+
+func (r *usersRepo) Create(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ($1, LOWER($2), $3)
+		RETURNING id, email, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, user.Username, user.Email, user.PasswordHash).
+		Scan(&user.ID, &user.Email, &user.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			if pqErr.Constraint == usersEmailIndex {
+				return ErrEmailTaken
+			}
+			return ErrUsernameTaken
+		}
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	return nil
+}
+
+func (r *usersRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	// 一意インデックスと同じ LOWER(email) で検索し、インデックスを使えるようにします。
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, email)
+	return scanUser(row)
+}
+
+func (r *usersRepo) FindByID(ctx context.Context, id int) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+	return scanUser(row)
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	var u models.User
+	if err := row.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,66 @@
+// users_repo_test.go は users リポジトリの登録・検索と、重複したユーザー名・メールアドレスの見分けを結合テストで確認します。
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shiv/CoT_game/backend/models"
+)
+
+func TestUsersRepo_CreateAndFind(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	repo := NewUsersRepository(db)
+	ctx := context.Background()
+	cleanup := func() {
+		if _, err := db.Exec("DELETE FROM users WHERE username LIKE 'repo_test_%'"); err != nil {
+			t.Logf("cleanup users: %v", err)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	user := &models.User{Username: "repo_test_alice", Email: "Alice@Example.com", PasswordHash: "hash"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if user.ID == 0 || user.Email != "alice@example.com" {
+		t.Errorf("unexpected user after create: %+v", user)
+	}
+
+	// メールアドレスは大文字・小文字を区別せずに検索できます。
+	found, err := repo.FindByEmail(ctx, "ALICE@example.com")
+	if err != nil || found.ID != user.ID || found.PasswordHash != "hash" {
+		t.Fatalf("FindByEmail = %+v, %v", found, err)
+	}
+	if _, err := repo.FindByID(ctx, user.ID); err != nil {
+		t.Errorf("FindByID failed: %v", err)
+	}
+	if _, err := repo.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("FindByEmail(missing) = %v, want ErrUserNotFound", err)
+	}
+
+	tests := []struct {
+		name string
+		user models.User
+		want error
+	}{
+		{name: "ユーザー名の重複", user: models.User{Username: "repo_test_alice", Email: "other@example.com", PasswordHash: "hash"}, want: ErrUsernameTaken},
+		{name: "メールアドレスの重複（大文字違い）", user: models.User{Username: "repo_test_bob", Email: "ALICE@EXAMPLE.COM", PasswordHash: "hash"}, want: ErrEmailTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.user
+			if err := repo.Create(ctx, &u); !errors.Is(err, tt.want) {
+				t.Errorf("Create() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// templates.go は登録済みの問題テンプレートを定義するファイル。
+// 新しいテンプレートは init で register し、questions.template_key にそのキーを設定すると出題に使われる。
+package variant
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Template{
+		Key:         "letter_count",
+		Description: "英単語の中に指定した文字が何個あるかを数える",
+		Generate:    generateLetterCount,
+	})
+	register(Template{
+		Key:         "geometric_next",
+		Description: "等比数列の次の項を答える",
+		Generate:    generateGeometricNext,
+	})
+}
+
+// letterCountWords は letter_count で使う単語。同じ文字を複数含むものを選んでいる。
+var letterCountWords = []string{
+	"strawberry", "banana", "mississippi", "bookkeeper", "committee",
+	"assessment", "possession", "independence", "parallel", "raspberry",
+	"engineering", "tomorrow", "successful", "cucumber", "referee",
+}
+
+// generateLetterCount は単語と、その単語に 2 回以上出てくる文字を選んで個数を問う。
+func generateLetterCount(rng *rand.Rand) (string, string, map[string]any) {
+	word := letterCountWords[rng.IntN(len(letterCountWords))]
+
+	var letters []string
+	for _, r := range word {
+		letter := string(r)
+		if strings.Count(word, letter) >= 2 && !containsString(letters, letter) {
+			letters = append(letters, letter)
+		}
+	}
+	letter := letters[rng.IntN(len(letters))]
+	count := strings.Count(word, letter)
+
+	statement := fmt.Sprintf("%sの中に%sは何個ある？", word, letter)
+	return statement, strconv.Itoa(count), map[string]any{"word": word, "letter": letter}
+}
+
+// generateGeometricNext は初項・公比・表示する項数を選び、次の項を問う。
+func generateGeometricNext(rng *rand.Rand) (string, string, map[string]any) {
+	first := 1 + rng.IntN(5) // 1〜5
+	ratio := 2 + rng.IntN(4) // 2〜5
+	shown := 4 + rng.IntN(2) // 4〜5 項
+
+	terms := make([]string, shown)
+	term := first
+	for i := range terms {
+		terms[i] = strconv.Itoa(term)
+		term *= ratio
+	}
+
+	statement := fmt.Sprintf("%s, ... 次に来る数は？", strings.Join(terms, ", "))
+	return statement, strconv.Itoa(term), map[string]any{"first": first, "ratio": ratio, "shown": shown}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Package variant は変数を含む問題テンプレートと、挑戦ごとに具体的な問題（バリアント）を生成する仕組みをまとめたパッケージ。
+// 固定の問題は正解がプレイヤー間で広まりやすいため、questions.template_key が設定された問題では
+// 挑戦のたびにテンプレートから問題文と正解を作り直し、その正解で採点する。
+// 同じテンプレートとシードからは必ず同じバリアントが得られるので、シードを保存しておけば後から再現できる。
+package variant
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+// maxSeed はシードの上限。JSON（float64）に保存しても丸められない範囲に収める。
+const maxSeed = 1 << 53
+
+// Variant はテンプレートから生成した 1 回分の問題。
+type Variant struct {
+	TemplateKey      string
+	Seed             int64
+	Params           map[string]any // 生成に使った変数（単語・公比など）
+	ProblemStatement string
+	CorrectAnswer    string
+}
+
+// Record は scores.variant_params に保存する形に変換する。
+func (v Variant) Record() map[string]any {
+	return map[string]any{
+		"template": v.TemplateKey,
+		"seed":     v.Seed,
+		"params":   v.Params,
+	}
+}
+
+// Generator は乱数から問題文・正解・使った変数を作る関数。
+// 乱数は引数の rng だけを使い、同じ rng の状態からは同じ結果を返すこと。
+type Generator func(rng *rand.Rand) (statement string, answer string, params map[string]any)
+
+// Template は登録済みのテンプレート。
+type Template struct {
+	Key         string
+	Description string
+	Generate    Generator
+}
+
+var registry = map[string]Template{}
+
+// register はテンプレートを登録する。キーの重複は起動時に気付けるよう panic にする。
+func register(t Template) {
+	if _, ok := registry[t.Key]; ok {
+		panic(fmt.Sprintf("variant: template %q is already registered", t.Key))
+	}
+	registry[t.Key] = t
+}
+
+// Lookup はキーに対応するテンプレートを返す。
+func Lookup(key string) (Template, bool) {
+	t, ok := registry[key]
+	return t, ok
+}
+
+// Keys は登録済みのテンプレートのキーを昇順で返す。
+func Keys() []string {
+	keys := make([]string, 0, len(registry))
+	for key := range registry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewSeed は新しいシードを返す。
+func NewSeed() int64 {
+	return rand.Int64N(maxSeed)
+}
+
+// Instantiate はテンプレートとシードからバリアントを生成する。
+func Instantiate(key string, seed int64) (Variant, error) {
+	t, ok := Lookup(key)
+	if !ok {
+		return Variant{}, fmt.Errorf("unknown template: %s", key)
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	statement, answer, params := t.Generate(rng)
+	return Variant{
+		TemplateKey:      key,
+		Seed:             seed,
+		Params:           params,
+		ProblemStatement: statement,
+		CorrectAnswer:    answer,
+	}, nil
+}
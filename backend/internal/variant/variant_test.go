@@ -0,0 +1,77 @@
+// variant_test.go はテンプレートからのバリアント生成が再現可能で、正解が問題文と矛盾しないことを確認する単体テスト。
+package variant
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestInstantiate は登録済みのテンプレートごとに、同じシードから同じバリアントが得られることを確認する。
+func TestInstantiate(t *testing.T) {
+	for _, key := range Keys() {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+
+			for seed := int64(0); seed < 50; seed++ {
+				a, err := Instantiate(key, seed)
+				if err != nil {
+					t.Fatalf("Instantiate(%q, %d) error = %v", key, seed, err)
+				}
+				b, _ := Instantiate(key, seed)
+				if a.ProblemStatement != b.ProblemStatement || a.CorrectAnswer != b.CorrectAnswer {
+					t.Fatalf("seed %d is not reproducible: %+v vs %+v", seed, a, b)
+				}
+				if a.ProblemStatement == "" || a.CorrectAnswer == "" {
+					t.Fatalf("empty variant for seed %d: %+v", seed, a)
+				}
+			}
+		})
+	}
+}
+
+// TestLetterCount は問題文の単語と文字から数え直した個数が正解と一致することを確認する。
+func TestLetterCount(t *testing.T) {
+	statements := map[string]bool{}
+	for seed := int64(0); seed < 100; seed++ {
+		v, err := Instantiate("letter_count", seed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		word, letter := v.Params["word"].(string), v.Params["letter"].(string)
+		if want := strconv.Itoa(strings.Count(word, letter)); v.CorrectAnswer != want {
+			t.Errorf("seed %d: answer = %s, want %s (%s)", seed, v.CorrectAnswer, want, v.ProblemStatement)
+		}
+		if !strings.Contains(v.ProblemStatement, word) {
+			t.Errorf("seed %d: statement does not contain word %q: %s", seed, word, v.ProblemStatement)
+		}
+		statements[v.ProblemStatement] = true
+	}
+	if len(statements) < 10 {
+		t.Errorf("too few distinct variants: %d", len(statements))
+	}
+}
+
+// TestGeometricNext は代表的なシードで次の項が公比倍になっていることを確認する。
+func TestGeometricNext(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		v, err := Instantiate("geometric_next", seed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		terms := strings.Split(strings.TrimSuffix(v.ProblemStatement, ", ... 次に来る数は？"), ", ")
+		last, _ := strconv.Atoi(terms[len(terms)-1])
+		answer, _ := strconv.Atoi(v.CorrectAnswer)
+		if ratio := v.Params["ratio"].(int); answer != last*ratio {
+			t.Errorf("seed %d: answer = %d, want %d (%s)", seed, answer, last*ratio, v.ProblemStatement)
+		}
+	}
+}
+
+// TestInstantiateUnknown は未登録のテンプレートでエラーになることを確認する。
+func TestInstantiateUnknown(t *testing.T) {
+	if _, err := Instantiate("no_such_template", 1); err == nil {
+		t.Fatal("expected error for unknown template")
+	}
+}
@@ -24,7 +24,15 @@ import (
 	_ "github.com/lib/pq" // docs code generation
 	"github.com/shiv/CoT_game/backend/handlers"
 	"github.com/shiv/CoT_game/backend/internal/ai"
+	"github.com/shiv/CoT_game/backend/internal/anticheat"
+	"github.com/shiv/CoT_game/backend/internal/auth"
+	"github.com/shiv/CoT_game/backend/internal/authoring"
+	"github.com/shiv/CoT_game/backend/internal/calibration"
+	"github.com/shiv/CoT_game/backend/internal/daily"
+	"github.com/shiv/CoT_game/backend/internal/eval"
+	"github.com/shiv/CoT_game/backend/internal/progression"
 	"github.com/shiv/CoT_game/backend/internal/repository"
+	"github.com/shiv/CoT_game/backend/middleware"
 	"github.com/shiv/CoT_game/backend/routes"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -71,6 +79,27 @@ func createDbPool(ctx context.Context) (*pgxpool.Pool, error) {
 	return dbpool, nil
 }
 
+// loadTokenIssuer は JWT_SECRET と JWT_ACCESS_TTL からアクセストークンの発行者を作ります。JWT_SECRET が未設定なら nil を返します。
+func loadTokenIssuer() (*auth.TokenIssuer, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	var ttl time.Duration
+	if raw := os.Getenv("JWT_ACCESS_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("JWT_ACCESS_TTL は正の期間（例: 15m）で指定してください: %q", raw)
+		}
+		ttl = d
+	}
+	tokens, err := auth.NewTokenIssuer(secret, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_SECRET の読み込みに失敗しました: %w", err)
+	}
+	return tokens, nil
+}
+
 // initの実行後にmainが実行される。
 func main() {
 	// アプリケーションのコンテキストを作成します。
@@ -89,6 +118,18 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("gemini クライアントの初期化に失敗しました: %w", err)
 	}
 
+	// 自由記述問題の採点用クライアントを初期化します。
+	// 回答生成とは別のモデルを使えるよう JUDGE_* 環境変数で設定し、失敗しても起動は続けます（その問題だけ採点不可になります）。
+	// 問題作成時の試走でも同じクライアントで自由記述問題を採点します。
+	var judge eval.Judge
+	var judgeClient ai.Client
+	if client, err := ai.NewJudgeClientFromEnv(); err != nil {
+		log.Printf("採点用クライアントの初期化に失敗しました（LLM 採点は無効）: %v", err)
+	} else {
+		judgeClient = client
+		judge = eval.NewLLMJudge(client)
+	}
+
 	// データベース接続プールを作成します。
 	dbpool, err := createDbPool(ctx)
 	if err != nil {
@@ -112,8 +153,73 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("sql.DB の疎通確認に失敗しました: %w", err)
 	}
 
+	// 問題に付いたタグが全て models.TagDefinitions に定義されているかを確認します。
+	// 未定義のタグはフロントエンドで表示できないため、起動は続けつつログで知らせます。
+	if err := handlers.CheckQuestionTags(ctx, dbpool); err != nil {
+		log.Printf("警告: 問題のタグの確認に失敗しました: %v", err)
+	}
+
 	// リポジトリ層の初期化
 	scoreRepo := repository.NewScoresRepository(sqlDB)
+	questionRepo := repository.NewQuestionsRepository(sqlDB)
+	userRepo := repository.NewUsersRepository(sqlDB)
+	sessionRepo := repository.NewSessionsRepository(sqlDB)
+	calibrationRepo := repository.NewCalibrationsRepository(sqlDB)
+
+	// 挑戦の記録から問題ごとの難易度を集計し、level の提案を作り直すジョブを CALIBRATION_INTERVAL（例: 1h、既定 1h）ごとに走らせます。
+	// 0 を指定すると定期実行はせず、管理 API（POST /api/v1/admin/calibrations/refresh）からだけ集計します。
+	calibrationInterval := calibration.DefaultInterval
+	if raw := os.Getenv("CALIBRATION_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < 0 {
+			return fmt.Errorf("CALIBRATION_INTERVAL は 0 以上の期間（例: 1h）で指定してください: %q", raw)
+		}
+		calibrationInterval = interval
+	}
+	if calibrationInterval > 0 {
+		go calibration.RunPeriodically(ctx, calibrationRepo, calibrationInterval)
+	}
+
+	// アクセストークン（JWT）の署名鍵は JWT_SECRET、有効期間は JWT_ACCESS_TTL（例: 15m）で設定します。
+	// 書式が不正なら起動を止め、JWT_SECRET が未設定ならサインアップ・ログインは 503 になり、全員ゲストとして遊べます。
+	tokens, err := loadTokenIssuer()
+	if err != nil {
+		return err
+	}
+	if tokens == nil {
+		log.Println("警告: JWT_SECRET が設定されていないため、サインアップ・ログインは利用できません。")
+	}
+
+	// 管理 API のトークンは ADMIN_API_TOKENS（name:token をカンマ区切り）で設定します。
+	// 書式が不正なら管理者を勝手に無効化しないよう起動を止め、未設定なら管理 API は全て 401 になります。
+	adminTokens, err := middleware.ParseAdminTokens(os.Getenv("ADMIN_API_TOKENS"))
+	if err != nil {
+		return fmt.Errorf("ADMIN_API_TOKENS の読み込みに失敗しました: %w", err)
+	}
+	if len(adminTokens) == 0 {
+		log.Println("警告: ADMIN_API_TOKENS が設定されていないため、管理 API は利用できません。")
+	}
+
+	// レベルの解放の条件は PROGRESSION（例: clears=2,points=300、off で無効）で設定します。書式が不正なら起動を止めます。
+	// 進捗はユーザーごとに保存するため、ログインが使えない（JWT_SECRET 未設定）場合は全ての問題を解放したままにします。
+	var progressRepo repository.ProgressRepository
+	progressionRules, progressionEnabled, err := progression.ParseRules(os.Getenv("PROGRESSION"))
+	if err != nil {
+		return fmt.Errorf("PROGRESSION の読み込みに失敗しました: %w", err)
+	}
+	if progressionEnabled && tokens == nil {
+		log.Println("警告: ログインが利用できないため、レベルの解放は無効です（全ての問題に挑戦できます）。")
+	} else if progressionEnabled {
+		progressRepo = repository.NewProgressRepository(sqlDB, progressionRules)
+	}
+
+	// 今日の一問の日付は DAILY_TIMEZONE（既定 Asia/Tokyo）の 0 時で切り替わり、1 日の挑戦回数は DAILY_ATTEMPTS（既定 3）で設定します。
+	// 書式が不正なら、ランキングの区切りが意図せず変わらないよう起動を止めます。
+	dailyConfig, err := daily.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	dailyRepo := repository.NewDailyRepository(sqlDB)
 
 	// デフォルトのミドルウェアを使用してGinルーターを初期化します。
 	router := gin.Default()
@@ -124,7 +230,7 @@ func run(ctx context.Context) error {
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", handlers.HeaderNextCursor, handlers.HeaderTotalCount},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -135,11 +241,57 @@ func run(ctx context.Context) error {
 
 	// データベースプールを使用してハンドラを初期化します。
 	questionHandler := handlers.NewQuestionHandler(dbpool)
+	questionHandler.Progress = progressRepo
 	solveHandler := handlers.NewSolveHandler(geminiClient, scoreRepo, sqlDB)
+	solveHandler.Judge = judge
+	solveHandler.Cheat = anticheat.PolicyFromEnv()
+	solveHandler.Ratings = repository.NewRatingsRepository(sqlDB)
+	solveHandler.Progress = progressRepo
+	solveHandler.Daily = dailyRepo
+	solveHandler.DailyConfig = dailyConfig
+	// プロンプトゴルフの曲線は GOLF_CURVES（JSON）でレベルごとに上書きできます。不正な値なら既定の曲線のまま起動します。
+	if golfCurves, err := eval.ParseGolfCurves(os.Getenv("GOLF_CURVES")); err != nil {
+		log.Printf("GOLF_CURVES の読み込みに失敗しました（既定の曲線を使用）: %v", err)
+	} else {
+		solveHandler.Golf = golfCurves
+	}
+
+	adminQuestionHandler := handlers.NewAdminQuestionHandler(questionRepo)
+	adminQuestionHandler.Calibrations = calibrationRepo
+	// 問題作成時の試走に使うモデルは DRYRUN_MODELS（カンマ区切り）で設定します。未設定なら挑戦と同じモデルだけを使います。
+	if dryRunModels, err := authoring.ModelsFromEnv(geminiClient); err != nil {
+		log.Printf("試走用クライアントの初期化に失敗しました（試走は無効）: %v", err)
+	} else {
+		adminQuestionHandler.DryRunner = &authoring.Runner{Models: dryRunModels, JudgeClient: judgeClient}
+	}
+
+	// プレイヤー向けの API はアクセストークンがあればユーザーとして、無ければゲストとして扱います。
+	// 管理 API は別のトークンを使うため、このミドルウェアはかけません。
+	userAuth := middleware.UserAuth(tokens, sessionRepo)
+
+	// サインアップ・ログインはフロントエンドの authService に合わせて /api 直下に登録します。
+	// ログインセッション（リフレッシュトークン）の有効期間は REFRESH_TOKEN_TTL（例: 720h）で設定します。
+	authHandler := handlers.NewAuthHandler(userRepo, sessionRepo, tokens)
+	if raw := os.Getenv("REFRESH_TOKEN_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("REFRESH_TOKEN_TTL は正の期間（例: 720h）で指定してください: %q", raw)
+		}
+		authHandler.RefreshTTL = ttl
+	}
+	routes.RegisterAuthRoutes(router.Group("/api"), userAuth, authHandler)
+
+	playerAPI := apiV1.Group("", userAuth)
 
 	// questions API のルートを登録します。
-	routes.RegisterQuestionRoutes(apiV1, questionHandler)
-	routes.RegisterSolveRoutes(apiV1, solveHandler)
+	routes.RegisterQuestionRoutes(playerAPI, questionHandler)
+	routes.RegisterSolveRoutes(playerAPI, solveHandler)
+	routes.RegisterLeaderboardRoutes(playerAPI, handlers.NewLeaderboardHandler(scoreRepo))
+	routes.RegisterScoreRoutes(playerAPI, handlers.NewScoreHistoryHandler(scoreRepo))
+	routes.RegisterProgressRoutes(playerAPI, handlers.NewProgressHandler(progressRepo))
+	routes.RegisterDailyRoutes(playerAPI, handlers.NewDailyHandler(dailyRepo, dailyConfig))
+	routes.RegisterTagRoutes(playerAPI, handlers.NewTagHandler(dbpool))
+	routes.RegisterAdminRoutes(apiV1, middleware.AdminAuth(adminTokens), adminQuestionHandler)
 
 	// シンプルなヘルスチェック用のエンドポイントです。
 	router.GET("/ping", func(c *gin.Context) {
@@ -0,0 +1,89 @@
+// Package middleware は複数のルートで共有する Gin のミドルウェアを提供します。
+// admin_auth.go は管理 API（/api/v1/admin）を保護する Bearer トークン認証です。
+// トークンは ADMIN_API_TOKENS に "名前:トークン" をカンマ区切りで並べて設定し、名前は変更履歴の actor として残ります。
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// AdminToken は管理 API の 1 つのトークンと、その持ち主の名前です。
+type AdminToken struct {
+	Name  string
+	Token string
+}
+
+// minAdminTokenLength は推測されにくさのためにトークンに求める最低の長さです。
+const minAdminTokenLength = 16
+
+// ParseAdminTokens は ADMIN_API_TOKENS（例: "alice:xxxx,bob:yyyy"）を読み込みます。
+// 空文字なら空のリストを返し、書式が不正・名前の重複・短すぎるトークンはエラーにします。
+func ParseAdminTokens(raw string) ([]AdminToken, error) {
+	var tokens []AdminToken
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(entry, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("admin token entry must be name:token: %q", entry)
+		}
+		if len(token) < minAdminTokenLength {
+			return nil, fmt.Errorf("admin token for %s must be at least %d characters", name, minAdminTokenLength)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate admin name: %s", name)
+		}
+		seen[name] = true
+		tokens = append(tokens, AdminToken{Name: name, Token: token})
+	}
+	return tokens, nil
+}
+
+// AdminAuth は Authorization: Bearer <token> を確認し、一致した管理者の名前を handlers.ContextKeyAdmin にセットします。
+// トークンが無い・一致しない場合は 401 を返して処理を打ち切ります。トークンが 1 つも無い場合は全て拒否します。
+func AdminAuth(tokens []AdminToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented, ok := bearerToken(c.GetHeader("Authorization"))
+		if ok {
+			if name, found := matchAdminToken(tokens, presented); found {
+				c.Set(handlers.ContextKeyAdmin, name)
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "管理者として認証されていません。"})
+	}
+}
+
+// bearerToken は Authorization ヘッダーから Bearer トークンを取り出します。
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// matchAdminToken は提示されたトークンに一致する管理者を探します。
+// 比較時間からトークンを推測されないよう、一致しても途中で打ち切らずに全件を定数時間で比較します。
+func matchAdminToken(tokens []AdminToken, presented string) (string, bool) {
+	var name string
+	found := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(presented)) == 1 && !found {
+			name, found = t.Name, true
+		}
+	}
+	return name, found
+}
@@ -0,0 +1,85 @@
+// admin_auth_test.go は管理者トークンの読み込みと、AdminAuth による認証の可否を確認する単体テストです。
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+func TestParseAdminTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{name: "未設定", raw: "", want: 0},
+		{name: "2 人", raw: "alice:0123456789abcdef, bob:fedcba9876543210", want: 2},
+		{name: "末尾のカンマ", raw: "alice:0123456789abcdef,", want: 1},
+		{name: "名前が無い", raw: ":0123456789abcdef", wantErr: true},
+		{name: "区切りが無い", raw: "0123456789abcdef", wantErr: true},
+		{name: "短すぎる", raw: "alice:short", wantErr: true},
+		{name: "名前の重複", raw: "alice:0123456789abcdef,alice:fedcba9876543210", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := ParseAdminTokens(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAdminTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(tokens) != tt.want {
+				t.Errorf("len(tokens) = %d, want %d", len(tokens), tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := []AdminToken{
+		{Name: "alice", Token: "0123456789abcdef"},
+		{Name: "bob", Token: "fedcba9876543210"},
+	}
+
+	router := gin.New()
+	router.GET("/admin", AdminAuth(tokens), func(c *gin.Context) {
+		name, _ := c.Get(handlers.ContextKeyAdmin)
+		c.String(http.StatusOK, "%v", name)
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantActor  string
+	}{
+		{name: "ヘッダー無し", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "Bearer 以外", header: "Basic 0123456789abcdef", wantStatus: http.StatusUnauthorized},
+		{name: "不一致", header: "Bearer 0123456789abcdeX", wantStatus: http.StatusUnauthorized},
+		{name: "alice", header: "Bearer 0123456789abcdef", wantStatus: http.StatusOK, wantActor: "alice"},
+		{name: "bob（小文字の scheme）", header: "bearer fedcba9876543210", wantStatus: http.StatusOK, wantActor: "bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantActor != "" && w.Body.String() != tt.wantActor {
+				t.Errorf("actor = %q, want %q", w.Body.String(), tt.wantActor)
+			}
+		})
+	}
+}
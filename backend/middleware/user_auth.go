@@ -0,0 +1,78 @@
+// Package middleware は複数のルートで共有する Gin のミドルウェアを提供します。
+// user_auth.go はプレイヤー向け API のアクセストークン（JWT）認証です。
+// ゲストでも遊べるよう、トークンが無いリクエストはそのまま通し、有効なトークンがあればユーザー ID を handlers.ContextKeyUserID にセットします。
+// トークンの署名が正しくても、ログアウトなどで失効させたセッションのトークンは受け付けません。
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+	"github.com/shiv/CoT_game/backend/internal/auth"
+)
+
+// SessionChecker はアクセストークンのセッションがまだ有効かを確認します。repository.SessionsRepository が満たします。
+type SessionChecker interface {
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+}
+
+// UserAuth は Authorization: Bearer <token> のアクセストークンを検証し、ユーザー ID を gin.Context にセットします。
+// ヘッダーが無ければゲストとして続行し、トークンが不正・期限切れ・セッション失効なら 401 を返します（黙ってゲスト扱いにすると、ログインしたつもりの挑戦が記録されないため）。
+// tokens が nil（JWT_SECRET 未設定）の場合は全てゲストとして扱います。
+func UserAuth(tokens *auth.TokenIssuer, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" || tokens == nil {
+			c.Next()
+			return
+		}
+
+		presented, ok := bearerToken(header)
+		if !ok {
+			abortInvalidToken(c, auth.ErrInvalidToken)
+			return
+		}
+		claims, err := tokens.Verify(presented)
+		if err != nil {
+			abortInvalidToken(c, err)
+			return
+		}
+		active, err := sessions.IsActive(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			log.Printf("セッションの確認に失敗しました (session_id=%d): %v", claims.SessionID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "ログイン状態の確認に失敗しました",
+			})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "session_revoked",
+				"message": "ログアウトされています。もう一度ログインしてください",
+			})
+			return
+		}
+		c.Set(handlers.ContextKeyUserID, claims.UserID)
+		c.Next()
+	}
+}
+
+// abortInvalidToken はトークンの検証エラーを 401 のレスポンスに変換します。期限切れは再ログインを促せるよう別のコードにします。
+func abortInvalidToken(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrTokenExpired) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "token_expired",
+			"message": "ログインの有効期限が切れました。もう一度ログインしてください",
+		})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error":   "invalid_token",
+		"message": "認証トークンが不正です",
+	})
+}
@@ -0,0 +1,75 @@
+// user_auth_test.go は UserAuth がゲストを通し、有効なトークンのユーザー ID をセットし、不正なトークンや失効したセッションを拒否することを確認する単体テストです。
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+	"github.com/shiv/CoT_game/backend/internal/auth"
+)
+
+// fakeSessions は有効なセッション ID の集合です。
+type fakeSessions map[int64]bool
+
+func (f fakeSessions) IsActive(_ context.Context, sessionID int64) (bool, error) {
+	return f[sessionID], nil
+}
+
+func TestUserAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens, err := auth.NewTokenIssuer("0123456789abcdef0123456789abcdef", time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+	token, _, err := tokens.Issue(7, 1)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	revoked, _, err := tokens.Issue(7, 2)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/me", UserAuth(tokens, fakeSessions{1: true}), func(c *gin.Context) {
+		userID, ok := c.Get(handlers.ContextKeyUserID)
+		c.String(http.StatusOK, fmt.Sprint(userID, ok))
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "ゲスト", header: "", wantStatus: http.StatusOK, wantBody: "<nil> false"},
+		{name: "有効なトークン", header: "Bearer " + token, wantStatus: http.StatusOK, wantBody: "7 true"},
+		{name: "不正なトークン", header: "Bearer " + token + "x", wantStatus: http.StatusUnauthorized},
+		{name: "Bearer 以外", header: "Basic " + token, wantStatus: http.StatusUnauthorized},
+		{name: "失効したセッション", header: "Bearer " + revoked, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
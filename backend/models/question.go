@@ -1,17 +1,29 @@
 // Package models はデータベースや外部入出力に対応するドメインオブジェクトを提供します。
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Question は内部処理用の完全な問題情報を表す構造体です。
 // データベースの questions テーブルに対応します。
 type Question struct {
-	ID               int       `json:"id"`
-	Level            int       `json:"level"`
-	ProblemStatement string    `json:"problem_statement"`
-	CorrectAnswer    string    `json:"correct_answer"`
-	Tags             []string  `json:"tags"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               int             `json:"id"`
+	Slug             *string         `json:"slug"` // 環境をまたいで問題を識別する安定したキー（問題バンクの取り込みで使う）。未指定で登録すると "q-<id>" になる。
+	Level            int             `json:"level"`
+	ProblemStatement string          `json:"problem_statement"`
+	CorrectAnswer    string          `json:"correct_answer"`
+	ExpectedUnit     *string         `json:"expected_unit"`  // 正解の単位（例: "km"）。単位を問わない問題では nil。
+	UnitPolicy       *string         `json:"unit_policy"`    // 回答の単位が期待単位と次元ごと異なる場合の扱い（"reject" または "penalize"）。nil なら "reject"。
+	AnswerSpec       json.RawMessage `json:"answer_spec"`    // 構造化された正解仕様（eval.AnswerSpec の JSON）。NULL なら CorrectAnswer を単一の正解として扱う。
+	GradingRubric    *string         `json:"grading_rubric"` // LLM 採点（answer_spec.type=judge）用の採点基準。
+	TemplateKey      *string         `json:"template_key"`   // 問題テンプレートのキー（internal/variant）。固定の問題では nil。
+	ScoringParams    json.RawMessage `json:"scoring_params"` // 数値問題の採点曲線のパラメータ（eval.ScoringParams の JSON）。NULL なら既定値。
+	Tags             []string        `json:"tags"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        *time.Time      `json:"updated_at"` // 管理 API で最後に更新した日時。一度も更新していなければ nil。
+	DeletedAt        *time.Time      `json:"deleted_at"` // 論理削除した日時。削除していなければ nil。
 }
 
 // QuestionResponse はクライアントに返す問題情報を表す構造体です。
@@ -22,4 +34,31 @@ type QuestionResponse struct {
 	Level     int       `json:"level"`
 	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
+	// Rating は挑戦の結果から推定した難しさ（Glicko-2、1500 中心で大きいほど難しい）。作問者が決めた level と並べて表示できます。
+	// ログインユーザーの挑戦がまだ 1 件も無い問題では null です。
+	Rating   *float64 `json:"rating"`
+	RatingRD *float64 `json:"rating_rd"` // Rating の偏差。値が大きいほど推定が不確かです。
+	// SuggestedLevel は挑戦の記録（solve rate・平均点・満点までの挑戦回数）から定期的に提案する level です。
+	// 管理者が受け入れるまで level は変わりません。挑戦者が少なく判断できない問題では null です。
+	SuggestedLevel *int `json:"suggested_level"`
+	// Locked はレベルが未解放でまだ挑戦できない問題かどうかです（internal/progression）。プログレッションを使わない設定では常に false です。
+	Locked bool `json:"locked"`
+}
+
+// QuestionDetailResponse は問題詳細ページ向けのレスポンスです。
+// QuestionResponse と同じく問題文と正解は含めず、タグの表示情報・挑戦の統計・呼び出したユーザーの自己ベストを加えます。
+type QuestionDetailResponse struct {
+	QuestionResponse
+	TagDetails  []Tag         `json:"tag_details"`   // Tags の各 ID を TagDefinitions で解決したもの。Tags と同じ順序です。
+	Stats       QuestionStats `json:"stats"`         // 全プレイヤーの挑戦の統計
+	MyBestScore *int          `json:"my_best_score"` // 呼び出したユーザーの最高点。未ログインまたは未挑戦なら null。
+}
+
+// QuestionStats は 1 問に対する挑戦の統計です。
+type QuestionStats struct {
+	Attempts     int      `json:"attempts"`      // 挑戦回数（ゲストを含む）
+	Players      int      `json:"players"`       // 挑戦したログインユーザーの人数
+	AverageScore *float64 `json:"average_score"` // 平均点。挑戦が無ければ null。
+	BestScore    *int     `json:"best_score"`    // 最高点。挑戦が無ければ null。
+	SolveRate    *float64 `json:"solve_rate"`    // 満点を取った挑戦の割合（0〜1）。挑戦が無ければ null。
 }
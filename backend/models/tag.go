@@ -81,6 +81,19 @@ var TagDefinitions = map[string]Tag{
 	},
 }
 
+// TagOrder はタグの表示順です。map の反復順は毎回変わるため、一覧を返すときはこの順序に従います。
+// TagDefinitions にタグを追加したときは、ここにも ID を追加してください。
+var TagOrder = []string{
+	"calculation",
+	"character_counting",
+	"text_analysis",
+	"text_problem",
+	"pattern_recognition",
+	"logic_puzzle",
+	"general_knowledge",
+	"estimation",
+}
+
 // GetTagByID はタグIDからタグメタデータを取得します。
 // タグが見つからない場合は空のTag構造体を返します。
 func GetTagByID(id string) (Tag, bool) {
@@ -88,11 +101,21 @@ func GetTagByID(id string) (Tag, bool) {
 	return tag, exists
 }
 
-// GetAllTags は全てのタグメタデータをスライスで返します。
+// GetAllTags は全てのタグメタデータを TagOrder の順で返します。
 func GetAllTags() []Tag {
-	tags := make([]Tag, 0, len(TagDefinitions))
-	for _, tag := range TagDefinitions {
-		tags = append(tags, tag)
+	tags := make([]Tag, 0, len(TagOrder))
+	for _, id := range TagOrder {
+		if tag, ok := TagDefinitions[id]; ok {
+			tags = append(tags, tag)
+		}
 	}
 	return tags
 }
+
+// TagSummary はタグ一覧・詳細 API のレスポンスです。タグの定義に、そのタグが付いた問題の集計を加えます。
+type TagSummary struct {
+	Tag
+	QuestionCount int      `json:"question_count"` // このタグが付いた問題の数
+	Attempts      int      `json:"attempts"`       // このタグが付いた問題への挑戦回数
+	AverageScore  *float64 `json:"average_score"`  // このタグが付いた問題の平均点。挑戦が無ければ null。
+}
@@ -0,0 +1,36 @@
+// tag_test.go はタグの定義と表示順が食い違っていないかを確認する単体テストです。
+package models
+
+import "testing"
+
+// TestTagOrderCoversDefinitions は TagOrder と TagDefinitions が同じタグを過不足なく持つことを確認します。
+func TestTagOrderCoversDefinitions(t *testing.T) {
+	seen := make(map[string]bool, len(TagOrder))
+	for _, id := range TagOrder {
+		if seen[id] {
+			t.Errorf("TagOrder に %q が重複しています", id)
+		}
+		seen[id] = true
+		if _, ok := TagDefinitions[id]; !ok {
+			t.Errorf("TagOrder の %q が TagDefinitions にありません", id)
+		}
+	}
+	for id := range TagDefinitions {
+		if !seen[id] {
+			t.Errorf("TagDefinitions の %q が TagOrder にありません", id)
+		}
+	}
+}
+
+// TestGetAllTagsOrder は GetAllTags が毎回同じ順序で返すことを確認します。
+func TestGetAllTagsOrder(t *testing.T) {
+	tags := GetAllTags()
+	if len(tags) != len(TagOrder) {
+		t.Fatalf("GetAllTags() returned %d tags, want %d", len(tags), len(TagOrder))
+	}
+	for i, tag := range tags {
+		if tag.ID != TagOrder[i] {
+			t.Errorf("tags[%d] = %s, want %s", i, tag.ID, TagOrder[i])
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Package models はデータベースや外部入出力に対応するドメインオブジェクトを提供します。
+package models
+
+import "time"
+
+// User は users テーブルに対応する構造体です。
+// PasswordHash は JSON に出さず、レスポンスにはフロントエンドの User 型（id・username・email）と同じ項目だけが載ります。
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"` // 小文字に揃えたメールアドレス。認証を導入する前のユーザーでは空文字
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// admin_routes.go は管理者向けのエンドポイントを /admin 配下にまとめ、グループ全体に管理者認証をかけます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterAdminRoutes は管理者向けのエンドポイントを登録します。auth は middleware.AdminAuth を想定しています。
+func RegisterAdminRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h *handlers.AdminQuestionHandler) {
+	// 例: /api/v1/admin
+	adminRoutes := api.Group("/admin", auth)
+	{
+		// /api/v1/admin/questions
+		// 問題文と正解を含む問題の一覧・登録。include_deleted=true で論理削除した問題も返します。
+		adminRoutes.GET("/questions", h.ListQuestions)
+		adminRoutes.POST("/questions", h.CreateQuestion)
+		// /api/v1/admin/questions/import, /api/v1/admin/questions/export
+		// YAML / JSON の問題バンクを slug をキーに取り込み（dry_run=true で確認のみ）、現在の問題を同じ形式で書き出します。
+		adminRoutes.POST("/questions/import", h.ImportQuestions)
+		adminRoutes.GET("/questions/export", h.ExportQuestions)
+		// POST /api/v1/admin/questions/dry-run
+		// 保存済みの問題や下書きを AI に複数回解かせ、正答率と提案する level を返します（scores には保存しません）。
+		adminRoutes.POST("/questions/dry-run", h.DryRunQuestion)
+		// /api/v1/admin/questions/:id
+		// 取得・全項目の更新・論理削除。
+		adminRoutes.GET("/questions/:id", h.GetQuestion)
+		adminRoutes.PUT("/questions/:id", h.UpdateQuestion)
+		adminRoutes.DELETE("/questions/:id", h.DeleteQuestion)
+		// POST /api/v1/admin/questions/:id/restore
+		// 論理削除した問題を元に戻します。
+		adminRoutes.POST("/questions/:id/restore", h.RestoreQuestion)
+		// GET /api/v1/admin/questions/:id/audit-logs
+		// 誰がいつ何を変えたかの履歴を新しい順に返します。
+		adminRoutes.GET("/questions/:id/audit-logs", h.GetAuditLogs)
+		// POST /api/v1/admin/questions/:id/accept-level
+		// 挑戦の記録から提案された level を受け入れます。
+		adminRoutes.POST("/questions/:id/accept-level", h.AcceptLevel)
+		// /api/v1/admin/calibrations
+		// 問題ごとの solve rate・平均点・満点までの挑戦回数と提案する level の一覧（pending=true で今の level と異なるものだけ）と、手動での集計。
+		adminRoutes.GET("/calibrations", h.ListCalibrations)
+		adminRoutes.POST("/calibrations/refresh", h.RefreshCalibrations)
+	}
+}
@@ -0,0 +1,33 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// auth_routes.go はサインアップ・ログインと、ログインセッションの更新・ログアウトのエンドポイントを登録します。
+// フロントエンドの authService に合わせ、バージョンを付けない /api 直下に置きます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterAuthRoutes は認証関連のエンドポイントを登録します。userAuth は middleware.UserAuth を想定しています。
+func RegisterAuthRoutes(api *gin.RouterGroup, userAuth gin.HandlerFunc, h *handlers.AuthHandler) {
+	// POST /api/signup
+	// ユーザーを登録し、{token, refresh_token, user} を返します。
+	api.POST("/signup", h.Signup)
+	// POST /api/login
+	// メールアドレスとパスワードでログインし、{token, refresh_token, user} を返します。
+	api.POST("/login", h.Login)
+
+	// 例: /api/auth
+	authRoutes := api.Group("/auth")
+	{
+		// POST /api/auth/refresh
+		// リフレッシュトークンを新しいものに交換し、新しいアクセストークンを返します。使用済みのトークンはセッションごと失効させます。
+		authRoutes.POST("/refresh", h.Refresh)
+		// POST /api/auth/logout
+		// リフレッシュトークンのセッションを失効させます。
+		authRoutes.POST("/logout", h.Logout)
+		// POST /api/auth/logout-all
+		// ログイン中のユーザーの全てのセッションを失効させます（全端末からログアウト）。
+		authRoutes.POST("/logout-all", userAuth, h.LogoutAll)
+	}
+}
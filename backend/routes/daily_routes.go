@@ -0,0 +1,26 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// daily_routes.go は今日の一問（デイリーチャレンジ）のエンドポイントを /daily 配下にまとめます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterDailyRoutes は今日の一問関連のエンドポイントを登録します。挑戦は POST /api/v1/solve に daily: true を付けて行います。
+// api にはユーザー認証（middleware.UserAuth）を通したグループを渡してください。
+func RegisterDailyRoutes(api *gin.RouterGroup, h *handlers.DailyHandler) {
+	// 例: /api/v1/daily
+	dailyRoutes := api.Group("/daily")
+	{
+		// GET /api/v1/daily
+		// 今日の出題と挑戦できる回数、次に切り替わる時刻を返します。
+		dailyRoutes.GET("", h.GetDaily)
+		// GET /api/v1/daily/leaderboard
+		// 日付ごとのランキング（date で過去の日も指定可）。
+		dailyRoutes.GET("/leaderboard", h.GetDailyLeaderboard)
+		// GET /api/v1/daily/history
+		// 過去の日の出題と勝者を新しい順に返します。
+		dailyRoutes.GET("/history", h.GetDailyHistory)
+	}
+}
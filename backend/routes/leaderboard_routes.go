@@ -0,0 +1,23 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// leaderboard_routes.go はランキング系のエンドポイントを /leaderboard 配下にまとめます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterLeaderboardRoutes はランキング関連のエンドポイントを登録します。
+func RegisterLeaderboardRoutes(api *gin.RouterGroup, h *handlers.LeaderboardHandler) {
+	// 例: /api/v1/leaderboard
+	leaderboardRoutes := api.Group("/leaderboard")
+	{
+		// GET /api/v1/leaderboard?period=day|week|all&limit=&question_id=&tag=
+		// 最高スコアのランキングと、ログイン中なら自分の順位を返します。
+		leaderboardRoutes.GET("", h.GetLeaderboard)
+
+		// GET /api/v1/leaderboard/golf
+		// プロンプトゴルフモードのランキングを返します。
+		leaderboardRoutes.GET("/golf", h.GetGolfLeaderboard)
+	}
+}
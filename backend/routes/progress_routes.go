@@ -0,0 +1,16 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// progress_routes.go はレベルの解放（プログレッション）の進捗のエンドポイントを /me/progress にまとめます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterProgressRoutes は進捗関連のエンドポイントを登録します。
+// api にはユーザー認証（middleware.UserAuth）を通したグループを渡してください。
+func RegisterProgressRoutes(api *gin.RouterGroup, h *handlers.ProgressHandler) {
+	// GET /api/v1/me/progress
+	// ログイン中のユーザーが挑戦できるレベルと、次のレベルの解放条件を返します。未ログインなら 401 です。
+	api.GET("/me/progress", h.GetMyProgress)
+}
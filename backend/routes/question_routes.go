@@ -18,5 +18,8 @@ func RegisterQuestionRoutes(api *gin.RouterGroup, h *handlers.QuestionHandler) {
 		// 最終的なパスは /api/v1/questions となります。
 		// このエンドポイントは、全ての問題のリストを取得します。
 		questionRoutes.GET("", h.GetQuestions)
+		// /api/v1/questions/:id に GET を登録。
+		// 1 問の詳細（タグ情報・統計・自己ベスト）を返します。問題文と正解は含みません。
+		questionRoutes.GET("/:id", h.GetQuestion)
 	}
 }
@@ -0,0 +1,20 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// scores_routes.go はスコア履歴のエンドポイントを /me/scores と /scores 配下にまとめます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterScoreRoutes はスコア履歴関連のエンドポイントを登録します。
+// api にはユーザー認証（middleware.UserAuth）を通したグループを渡してください。
+func RegisterScoreRoutes(api *gin.RouterGroup, h *handlers.ScoreHistoryHandler) {
+	// GET /api/v1/me/scores
+	// ログイン中のユーザー自身の挑戦履歴を返します。未ログインなら 401 です。
+	api.GET("/me/scores", h.GetMyScores)
+
+	// GET /api/v1/scores/:id
+	// 1 回の挑戦の詳細を返します。AI の出力は本人にだけ返します。
+	api.GET("/scores/:id", h.GetScore)
+}
@@ -0,0 +1,22 @@
+// Package routes は HTTP ルートのグルーピングとマッピングを管理します。
+// tag_routes.go はタグ定義を返すエンドポイントを /tags 配下にまとめます。
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shiv/CoT_game/backend/handlers"
+)
+
+// RegisterTagRoutes はタグ関連のエンドポイントを登録します。
+func RegisterTagRoutes(api *gin.RouterGroup, h *handlers.TagHandler) {
+	// 例: /api/v1/tags
+	tagRoutes := api.Group("/tags")
+	{
+		// GET /api/v1/tags
+		// 全てのタグを表示順に、問題数と平均点を付けて返します。
+		tagRoutes.GET("", h.GetTags)
+		// GET /api/v1/tags/:id
+		// 1 つのタグの定義と集計を返します。
+		tagRoutes.GET("/:id", h.GetTag)
+	}
+}
@@ -1,22 +1,71 @@
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) UNIQUE NOT NULL,
+    email VARCHAR(255) NULL,
     password_hash VARCHAR(255) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
+
+-- ログインセッション。アクセストークンの sid で参照し、失効したセッションのトークンは受け付けない
+CREATE TABLE user_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NULL,
+    ip_address TEXT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL,
+    revoke_reason TEXT NULL
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id) WHERE revoked_at IS NULL;
+
+-- リフレッシュトークンのハッシュ。1 回使うと新しいトークンに交換され、使用済みのトークンが再び使われたらセッションを失効させる
+CREATE TABLE user_session_tokens (
+    token_hash TEXT PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX idx_user_session_tokens_session ON user_session_tokens(session_id);
+
 CREATE TABLE questions (
     id SERIAL PRIMARY KEY,
+    slug TEXT NULL UNIQUE,
     level INT NOT NULL,
     problem_statement TEXT NOT NULL,
     correct_answer VARCHAR(255) NOT NULL,
+    answer_spec JSONB NULL,
+    expected_unit TEXT NULL,
+    unit_policy TEXT NULL CHECK (unit_policy IN ('reject', 'penalize')),
+    grading_rubric TEXT NULL,
+    template_key TEXT NULL,
+    scoring_params JSONB NULL,
     tags TEXT[] DEFAULT '{}',
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE NULL
 );
 
 -- タグカラムにインデックスを追加（検索の高速化）
 CREATE INDEX idx_questions_tags ON questions USING GIN(tags);
 
+-- 管理 API による問題の変更履歴（誰がいつ何を変えたか）
+CREATE TABLE question_audit_logs (
+    id SERIAL PRIMARY KEY,
+    question_id INT NOT NULL REFERENCES questions(id),
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    changes JSONB NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_question_audit_logs_question ON question_audit_logs(question_id, id DESC);
+
 CREATE TABLE scores (
     id SERIAL PRIMARY KEY,
     user_id INT REFERENCES users(id),
@@ -29,9 +78,103 @@ CREATE TABLE scores (
     answer_number NUMERIC NULL,
     latency_ms INT NOT NULL DEFAULT 0,
     evaluation_detail JSONB NULL,
+    evaluator_version INT NULL,
+    rescored_at TIMESTAMP WITH TIME ZONE NULL,
+    needs_review BOOLEAN NOT NULL DEFAULT FALSE,
+    review_reason TEXT NULL,
+    scoring_mode TEXT NOT NULL DEFAULT 'standard',
+    golf_score INT NULL,
+    variant_params JSONB NULL,
+    variant_answer TEXT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 再採点（cmd/rescore）で古い版のスコアを探すためのインデックス
+CREATE INDEX idx_scores_evaluator_version ON scores(evaluator_version);
+
+-- 運営の確認待ちの回答だけを素早く引くための部分インデックス
+CREATE INDEX idx_scores_needs_review ON scores(created_at) WHERE needs_review;
+
+-- ゴルフモードのランキング集計用の部分インデックス
+CREATE INDEX idx_scores_golf ON scores(question_id, golf_score DESC) WHERE scoring_mode = 'golf';
+
+-- ランキング（問題ごとの自己ベストの合計）の集計用の部分インデックス。ゲストの回答は集計しない
+CREATE INDEX idx_scores_user_question ON scores(user_id, question_id, score DESC) WHERE user_id IS NOT NULL;
+
+-- 期間を区切ったランキング（day / week）の集計用のインデックス
+CREATE INDEX idx_scores_created_at ON scores(created_at);
+
+-- ユーザー × 問題ごとの自己ベスト。scores への保存と同じトランザクションで更新する（ゲストは対象外）
+CREATE TABLE user_question_best (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question_id INT NOT NULL REFERENCES questions(id),
+    best_score INT NOT NULL,
+    attempts INT NOT NULL,
+    best_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, question_id)
+);
+
+CREATE INDEX idx_user_question_best_question ON user_question_best(question_id, best_score DESC);
+
+-- 挑戦を「プレイヤー対問題」の対戦とみなした Glicko-2 レーティング（backend/internal/rating）。問題側は値が大きいほど難しい
+CREATE TABLE player_ratings (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    rating DOUBLE PRECISION NOT NULL,
+    rd DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    matches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE question_ratings (
+    question_id INT PRIMARY KEY REFERENCES questions(id),
+    rating DOUBLE PRECISION NOT NULL,
+    rd DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    matches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 挑戦の記録から定期的に集計した問題ごとの解かれ方と、提案する level（backend/internal/calibration）。level の書き換えは管理者が受け入れたときだけ
+CREATE TABLE question_calibrations (
+    question_id INT PRIMARY KEY REFERENCES questions(id),
+    players INT NOT NULL,
+    attempts INT NOT NULL,
+    solve_rate DOUBLE PRECISION NOT NULL,
+    mean_score DOUBLE PRECISION NOT NULL,
+    attempts_to_first_100 DOUBLE PRECISION NULL,
+    suggested_level INT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ユーザーごとに挑戦できる最も高いレベル（backend/internal/progression）。上がったときだけ書き込み、下げない。行が無ければ level 1 まで
+CREATE TABLE user_progress (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    unlocked_level INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 今日の一問（backend/internal/daily）。日付は DAILY_TIMEZONE（既定 Asia/Tokyo）で区切り、その日に最初に参照されたときに問題を選んで保存する
+CREATE TABLE daily_challenges (
+    challenge_date DATE PRIMARY KEY,
+    question_id INT NOT NULL REFERENCES questions(id),
+    seed BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ユーザー × 日付ごとの挑戦回数と最高点。日付ごとのランキングの元データで、過去の日の勝者の閲覧のために消さずに残す
+CREATE TABLE daily_entries (
+    challenge_date DATE NOT NULL REFERENCES daily_challenges(challenge_date),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    best_score INT NULL,
+    best_at TIMESTAMP WITH TIME ZONE NULL,
+    PRIMARY KEY (challenge_date, user_id)
+);
+
+CREATE INDEX idx_daily_entries_rank ON daily_entries(challenge_date, best_score DESC, best_at) WHERE best_score IS NOT NULL;
+
 -- Insert some initial data for testing
 INSERT INTO users (username, password_hash) VALUES ('testuser', 'testhash');
 INSERT INTO questions (level, problem_statement, correct_answer, tags) VALUES 
@@ -40,6 +183,31 @@ INSERT INTO questions (level, problem_statement, correct_answer, tags) VALUES
   (1, '1, 2, 4, 8, 16, ... 次に来る数は？', '32', ARRAY['pattern_recognition', 'calculation']),
   (2, 'すもももももももものうちの右から３番目の文字は何？', 'の', ARRAY['text_analysis']),
   (4, '3 + 2 × 5 - 4 ÷ 2 の答えは？', '11', ARRAY['calculation']);
+INSERT INTO questions (level, problem_statement, correct_answer, expected_unit, tags) VALUES
+  (2, '時速60kmの車が2時間30分走ると、何km進む？', '150', 'km', ARRAY['calculation', 'text_problem']);
+INSERT INTO questions (level, problem_statement, correct_answer, answer_spec, tags) VALUES
+  (2, '10以下の素数をすべて挙げてください。', '2, 3, 5, 7', '{"type": "set", "items": ["2", "3", "5", "7"], "partial_credit": true}', ARRAY['calculation']);
+
+INSERT INTO questions (level, problem_statement, correct_answer, answer_spec, tags) VALUES
+  (3, '一辺の長さが4の正三角形の面積を求めてください。（根号のまま答えてよい）', '4√3', '{"type": "expression", "value": "4√3"}', ARRAY['calculation']);
+
+INSERT INTO questions (level, problem_statement, correct_answer, answer_spec, grading_rubric, tags) VALUES
+  (3, '空が青く見える理由を一文で説明してください。', '太陽光のうち波長の短い青い光が大気中の分子によって強く散乱されるため（レイリー散乱）', '{"type": "judge", "value": "太陽光のうち波長の短い青い光が大気中の分子によって強く散乱されるため（レイリー散乱）"}', '「散乱」に触れ、青い光（短い波長）が他の色より強く散乱されることを説明していれば正解。散乱に触れているが波長との関係が無ければ部分点。海の色の反射など誤った説明は不正解。', ARRAY['general_knowledge']);
+
+-- テンプレート問題。problem_statement と correct_answer は代表例で、実際の出題は挑戦ごとに backend/internal/variant で生成する
+INSERT INTO questions (level, problem_statement, correct_answer, template_key, tags) VALUES
+  (3, 'bananaの中にaは何個ある？', '3', 'letter_count', ARRAY['character_counting', 'text_analysis']),
+  (2, '3, 6, 12, 24, ... 次に来る数は？', '48', 'geometric_next', ARRAY['pattern_recognition', 'calculation']);
+
+-- answer_spec が未設定の問題は correct_answer を単一の正解とする仕様で埋める
+UPDATE questions
+SET answer_spec = jsonb_build_object('type', 'value', 'value', correct_answer)
+WHERE answer_spec IS NULL;
+
 
 
 
+-- 問題バンク（cmd/questions）で取り込み直せるよう、全ての問題に slug を割り当てる
+UPDATE questions
+SET slug = 'q-' || id
+WHERE slug IS NULL;
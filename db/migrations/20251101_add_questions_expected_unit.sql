@@ -0,0 +1,18 @@
+-- Migration: Add expected_unit column to questions table
+-- Created: 2025-11-01
+-- Purpose: Allow unit-aware evaluation ("120 km", "2時間30分") by storing the unit the correct answer is expressed in
+
+-- 正解の単位（例: km, 分, 円, 個）を保存する列を追加
+-- 単位を問わない既存の問題は NULL のままで、従来どおりの数値評価になる
+ALTER TABLE questions
+ADD COLUMN expected_unit TEXT NULL;
+
+COMMENT ON COLUMN questions.expected_unit IS 'Unit of correct_answer (e.g., km, 分, 円). NULL means unit-agnostic evaluation';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE questions
+DROP COLUMN expected_unit;
+*/
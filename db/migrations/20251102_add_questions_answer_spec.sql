@@ -0,0 +1,27 @@
+-- Migration: Add answer_spec column to questions table
+-- Created: 2025-11-02
+-- Purpose: Store a structured answer specification (alternatives, regex, sets, ordered lists, intervals) as JSON
+
+-- 構造化された正解仕様を保存する列を追加
+-- 形式は backend/internal/eval/answer_spec.go の AnswerSpec を参照
+-- 例: {"type": "alternatives", "alternatives": ["3", "three", "三"]}
+--     {"type": "set", "items": ["2", "3", "5", "7"], "partial_credit": true}
+--     {"type": "interval", "min": 350, "max": 420}
+ALTER TABLE questions
+ADD COLUMN answer_spec JSONB NULL;
+
+COMMENT ON COLUMN questions.answer_spec IS 'Structured answer specification (type: value/alternatives/regex/set/list/interval). NULL falls back to correct_answer';
+
+-- 既存の correct_answer を単一の正解（type=value）として移行する
+-- correct_answer は表示・後方互換用の代表解として残す
+UPDATE questions
+SET answer_spec = jsonb_build_object('type', 'value', 'value', correct_answer)
+WHERE answer_spec IS NULL;
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE questions
+DROP COLUMN answer_spec;
+*/
@@ -0,0 +1,18 @@
+-- Migration: Add grading_rubric column to questions table
+-- Created: 2025-11-03
+-- Purpose: Store the rubric used by the LLM-as-judge evaluator for open-ended questions (answer_spec.type = 'judge')
+
+-- 自由記述問題の採点基準を保存する列を追加
+-- answer_spec が {"type": "judge"} の問題だけが使用し、他の問題は NULL のまま
+ALTER TABLE questions
+ADD COLUMN grading_rubric TEXT NULL;
+
+COMMENT ON COLUMN questions.grading_rubric IS 'Grading rubric passed to the LLM judge when answer_spec.type is judge';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE questions
+DROP COLUMN grading_rubric;
+*/
@@ -0,0 +1,25 @@
+-- Migration: Add evaluator_version and rescored_at columns to scores table
+-- Created: 2025-11-04
+-- Purpose: Record which version of the scoring logic produced each score so that old rows can be re-scored (cmd/rescore)
+
+-- 採点ロジックの版（backend/internal/eval/version.go の Version）を保存する列を追加
+-- 版管理を始める前のスコアは NULL のままにし、cmd/rescore の対象とする
+ALTER TABLE scores
+ADD COLUMN evaluator_version INT NULL,
+ADD COLUMN rescored_at TIMESTAMP WITH TIME ZONE NULL;
+
+COMMENT ON COLUMN scores.evaluator_version IS 'Version of the scoring logic (eval.Version) used for this score. NULL for scores created before versioning';
+COMMENT ON COLUMN scores.rescored_at IS 'When the score was last re-evaluated by cmd/rescore. NULL if never re-scored';
+
+-- 古い版のスコアを探すためのインデックス
+CREATE INDEX idx_scores_evaluator_version ON scores(evaluator_version);
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_scores_evaluator_version;
+ALTER TABLE scores
+DROP COLUMN rescored_at,
+DROP COLUMN evaluator_version;
+*/
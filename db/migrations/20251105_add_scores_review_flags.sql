@@ -0,0 +1,25 @@
+-- Migration: Add needs_review and review_reason columns to scores table
+-- Created: 2025-11-05
+-- Purpose: Flag attempts whose AI output leaked the hidden problem statement so that operators can review them
+
+-- 確認が必要な回答の印と理由を保存する列を追加
+-- 理由の例: problem_statement_leak（backend/internal/leakguard を参照）
+ALTER TABLE scores
+ADD COLUMN needs_review BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN review_reason TEXT NULL;
+
+COMMENT ON COLUMN scores.needs_review IS 'TRUE when the attempt must be reviewed by an operator (e.g. problem statement leak)';
+COMMENT ON COLUMN scores.review_reason IS 'Why the attempt needs review (e.g. problem_statement_leak). NULL when needs_review is FALSE';
+
+-- 確認待ちの回答だけを素早く引くための部分インデックス
+CREATE INDEX idx_scores_needs_review ON scores(created_at) WHERE needs_review;
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_scores_needs_review;
+ALTER TABLE scores
+DROP COLUMN review_reason,
+DROP COLUMN needs_review;
+*/
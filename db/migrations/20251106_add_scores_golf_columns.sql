@@ -0,0 +1,26 @@
+-- Migration: Add scoring_mode and golf_score columns to scores table
+-- Created: 2025-11-06
+-- Purpose: Support the prompt golf scoring mode (correctness combined with prompt length) and its leaderboard
+
+-- 採点モードとゴルフスコアを保存する列を追加
+-- scoring_mode: 'standard'（正確さのみ）または 'golf'（プロンプトの短さも加味）
+-- golf_score: golf モードのときだけ値が入る（計算式は backend/internal/eval/golf.go を参照）
+ALTER TABLE scores
+ADD COLUMN scoring_mode TEXT NOT NULL DEFAULT 'standard',
+ADD COLUMN golf_score INT NULL;
+
+COMMENT ON COLUMN scores.scoring_mode IS 'Scoring mode of the attempt: standard or golf. Default: standard';
+COMMENT ON COLUMN scores.golf_score IS 'Golf score (correctness x prompt length curve). NULL unless scoring_mode is golf';
+
+-- ゴルフモードのランキング集計用の部分インデックス
+CREATE INDEX idx_scores_golf ON scores(question_id, golf_score DESC) WHERE scoring_mode = 'golf';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_scores_golf;
+ALTER TABLE scores
+DROP COLUMN golf_score,
+DROP COLUMN scoring_mode;
+*/
@@ -0,0 +1,31 @@
+-- Migration: Add template_key to questions and variant columns to scores
+-- Created: 2025-11-07
+-- Purpose: Serve a randomized variant of templated questions on every attempt and grade against the variant's answer
+
+-- 問題テンプレートのキーを追加
+-- 設定された問題は挑戦ごとに backend/internal/variant のテンプレートから問題文と正解を生成する
+-- problem_statement と correct_answer は一覧表示や代表例として残す
+ALTER TABLE questions
+ADD COLUMN template_key TEXT NULL;
+
+COMMENT ON COLUMN questions.template_key IS 'Key of the generator in backend/internal/variant (e.g. letter_count). NULL for fixed questions';
+
+-- 出題したバリアントを保存する列を追加
+-- variant_params: {"template": "letter_count", "seed": 42, "params": {"word": "banana", "letter": "a"}}
+-- variant_answer: そのバリアントの正解。再採点（cmd/rescore）でも questions.correct_answer の代わりに使う
+ALTER TABLE scores
+ADD COLUMN variant_params JSONB NULL,
+ADD COLUMN variant_answer TEXT NULL;
+
+COMMENT ON COLUMN scores.variant_params IS 'Template key, seed and generated parameters of the served variant. NULL for fixed questions';
+COMMENT ON COLUMN scores.variant_answer IS 'Correct answer of the served variant. Grading uses this instead of questions.correct_answer';
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE scores
+DROP COLUMN variant_answer,
+DROP COLUMN variant_params;
+ALTER TABLE questions
+DROP COLUMN template_key;
+*/
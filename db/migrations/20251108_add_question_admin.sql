@@ -0,0 +1,48 @@
+-- Migration: Add scoring params, soft delete and audit log for question management
+-- Created: 2025-11-08
+-- Purpose: Let admins create, edit, soft-delete and restore questions through the API with a record of who changed what
+
+-- 数値問題の採点曲線のパラメータを追加
+-- 例: {"r0": 0.01, "p": 3}（未指定の項目は backend/internal/eval の既定値を使う）
+-- NULL の場合は既定の曲線で採点する
+ALTER TABLE questions
+ADD COLUMN scoring_params JSONB NULL;
+
+COMMENT ON COLUMN questions.scoring_params IS 'Per-question scoring curve (eval.ScoringParams: r0, p, tol_abs_hint, base_error). NULL uses the defaults';
+
+-- 更新日時と論理削除の日時を追加
+-- 過去のスコアが question_id を参照しているため、問題は物理削除せず deleted_at で一覧と出題から外す
+ALTER TABLE questions
+ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NULL,
+ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE NULL;
+
+COMMENT ON COLUMN questions.updated_at IS 'Last time the question was changed through the admin API';
+COMMENT ON COLUMN questions.deleted_at IS 'Soft delete timestamp. Deleted questions are hidden from listings and cannot be solved';
+
+-- 問題の変更履歴テーブルを追加
+-- actor: ADMIN_API_TOKENS に設定した管理者の名前
+-- action: create / update / delete / restore
+-- changes: 変更した項目ごとの {"before": ..., "after": ...}
+CREATE TABLE question_audit_logs (
+    id SERIAL PRIMARY KEY,
+    question_id INT NOT NULL REFERENCES questions(id),
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    changes JSONB NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 問題ごとに新しい順で履歴を引くためのインデックス
+CREATE INDEX idx_question_audit_logs_question ON question_audit_logs(question_id, id DESC);
+
+COMMENT ON TABLE question_audit_logs IS 'Who changed which question fields through the admin API';
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP TABLE question_audit_logs;
+ALTER TABLE questions
+DROP COLUMN deleted_at,
+DROP COLUMN updated_at,
+DROP COLUMN scoring_params;
+*/
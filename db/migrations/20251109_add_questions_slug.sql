@@ -0,0 +1,23 @@
+-- Migration: Add slug to questions
+-- Created: 2025-11-09
+-- Purpose: Give every question a stable key so question banks (YAML/JSON) can be imported idempotently across environments
+
+-- 問題の slug を追加
+-- 環境ごとに異なる id の代わりに、問題バンクの取り込み（backend/cmd/questions）で同じ問題を見分けるためのキー
+-- 英小文字・数字・-・_ の 64 文字以内。管理 API で slug を指定せずに登録した問題には 'q-<id>' を割り当てる
+ALTER TABLE questions
+ADD COLUMN slug TEXT NULL UNIQUE;
+
+COMMENT ON COLUMN questions.slug IS 'Stable identifier used by question bank import/export. Defaults to q-<id>';
+
+-- 既存の問題に slug を割り当てる
+UPDATE questions
+SET slug = 'q-' || id
+WHERE slug IS NULL;
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE questions
+DROP COLUMN slug;
+*/
@@ -0,0 +1,22 @@
+-- Migration: Add email to users
+-- Created: 2025-11-10
+-- Purpose: Support signup/login by email (POST /api/signup, POST /api/login) with bcrypt password hashes
+
+-- ユーザーのメールアドレスを追加
+-- ログインに使うため小文字に揃えて保存し、大文字・小文字の違いだけの重複は一意インデックスで防ぐ
+-- 認証を導入する前に作られたユーザー（初期データの testuser など）はメールアドレスを持たないため NULL を許容する
+ALTER TABLE users
+ADD COLUMN email VARCHAR(255) NULL;
+
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
+
+COMMENT ON COLUMN users.email IS 'Login email address, stored lower-cased. NULL for users created before authentication was added';
+COMMENT ON COLUMN users.password_hash IS 'bcrypt hash of the password';
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users
+DROP COLUMN email;
+*/
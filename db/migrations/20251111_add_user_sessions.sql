@@ -0,0 +1,46 @@
+-- Migration: Add user sessions and refresh tokens
+-- Created: 2025-11-11
+-- Purpose: Long-lived, revocable login sessions with rotating refresh tokens (POST /api/auth/refresh, /api/auth/logout)
+
+-- ログインセッションのテーブルを追加
+-- サインアップ・ログインのたびに 1 行作る。アクセストークン（JWT）の sid にこの id を載せ、失効したセッションのトークンは認証ミドルウェアで拒否する
+-- expires_at: リフレッシュトークンを使うたびに延びる。過ぎたら再ログインが必要
+-- revoke_reason: logout / logout_all / refresh_token_reuse
+CREATE TABLE user_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NULL,
+    ip_address TEXT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL,
+    revoke_reason TEXT NULL
+);
+
+-- ユーザーの有効なセッションをまとめて失効させる（全端末からログアウト）ためのインデックス
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id) WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE user_sessions IS 'Login sessions. Access tokens carry the session id (sid) and are rejected once the session is revoked';
+
+-- リフレッシュトークンのテーブルを追加
+-- トークンそのものは保存せず SHA-256 のハッシュだけを持つ
+-- 使うたびに新しいトークンに交換し（ローテーション）、used_at を記録する
+-- 使用済みのトークンがもう一度使われたら盗まれたものとみなし、セッションごと失効させる
+CREATE TABLE user_session_tokens (
+    token_hash TEXT PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX idx_user_session_tokens_session ON user_session_tokens(session_id);
+
+COMMENT ON TABLE user_session_tokens IS 'SHA-256 hashes of refresh tokens. A token is single-use; presenting a used token revokes its session';
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP TABLE user_session_tokens;
+DROP TABLE user_sessions;
+*/
@@ -0,0 +1,15 @@
+-- Migration: Add an index on scores for per-user, per-question best scores
+-- Created: 2025-11-12
+-- Purpose: Rank the leaderboard by the sum of each user's best score per question (GET /api/v1/leaderboard)
+
+-- ランキングは「ユーザー × 問題ごとの自己ベスト」を合計して順位を決める
+-- user_id, question_id で GROUP BY して MAX(score) を取る集計を、scores 全体を並べ替えずに済ませるためのインデックス
+-- ゲスト（user_id が NULL）の回答はランキングに含めないため、部分インデックスにする
+CREATE INDEX idx_scores_user_question ON scores(user_id, question_id, score DESC) WHERE user_id IS NOT NULL;
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_scores_user_question;
+*/
@@ -0,0 +1,50 @@
+-- Migration: Add user_question_best table
+-- Created: 2025-11-13
+-- Purpose: Keep each user's best score per question up to date so leaderboards and "solved" flags are index lookups instead of scans of scores
+
+-- ユーザー × 問題ごとの自己ベストを保持するテーブルを追加
+-- scores に回答を保存するのと同じトランザクションで更新する（backend/internal/repository/scores_repo.go の Create）
+-- 再採点（cmd/rescore -apply）では書き戻した (user_id, question_id) の行を scores から計算し直す
+-- ゲスト（user_id が NULL）の回答は対象外
+-- best_at: 自己ベストを最初に記録した日時
+-- last_at: 最後に回答した日時
+CREATE TABLE user_question_best (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question_id INT NOT NULL REFERENCES questions(id),
+    best_score INT NOT NULL,
+    attempts INT NOT NULL,
+    best_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, question_id)
+);
+
+COMMENT ON TABLE user_question_best IS 'Best score per user and question, maintained on insert into scores. Guests are not included';
+COMMENT ON COLUMN user_question_best.best_at IS 'When the best score was first reached';
+COMMENT ON COLUMN user_question_best.last_at IS 'When the user last attempted the question';
+
+-- 問題ごとのランキング用のインデックス
+CREATE INDEX idx_user_question_best_question ON user_question_best(question_id, best_score DESC);
+
+-- 期間を区切ったランキング（day / week）は scores から集計するため、回答日時のインデックスを追加
+CREATE INDEX idx_scores_created_at ON scores(created_at);
+
+-- 既存の回答から自己ベストを埋める（go run ./cmd/backfill_best でも同じ計算をやり直せる）
+INSERT INTO user_question_best (user_id, question_id, best_score, attempts, best_at, last_at)
+SELECT
+    user_id,
+    question_id,
+    MAX(score),
+    COUNT(*),
+    (ARRAY_AGG(created_at ORDER BY score DESC, created_at ASC))[1],
+    MAX(created_at)
+FROM scores
+WHERE user_id IS NOT NULL AND question_id IS NOT NULL
+GROUP BY user_id, question_id;
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_scores_created_at;
+DROP TABLE IF EXISTS user_question_best;
+*/
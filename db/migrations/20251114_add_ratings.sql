@@ -0,0 +1,38 @@
+-- Migration: Add player_ratings and question_ratings tables
+-- Created: 2025-11-14
+-- Purpose: Glicko-2 skill ratings for players and data-driven difficulty for questions, updated after each POST /api/v1/solve
+
+-- 挑戦を「プレイヤー対問題」の対戦とみなし、双方の Glicko-2 レーティングを保持するテーブルを追加
+-- 計算は backend/internal/rating を参照。ログイン中のユーザーの挑戦だけで更新する（ゲストは対象外）
+-- rating: 1500 中心。問題側は値が大きいほど難しい
+-- rd: レーティング偏差。値が大きいほど推定が不確か（未対戦は 350）
+-- volatility: 強さの変わりやすさ
+-- matches: 更新に使った挑戦の回数
+CREATE TABLE player_ratings (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    rating DOUBLE PRECISION NOT NULL,
+    rd DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    matches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE question_ratings (
+    question_id INT PRIMARY KEY REFERENCES questions(id),
+    rating DOUBLE PRECISION NOT NULL,
+    rd DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    matches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE player_ratings IS 'Glicko-2 rating of each player, updated after each logged-in attempt';
+COMMENT ON TABLE question_ratings IS 'Glicko-2 rating of each question (higher is harder), updated after each logged-in attempt';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP TABLE IF EXISTS question_ratings;
+DROP TABLE IF EXISTS player_ratings;
+*/
@@ -0,0 +1,33 @@
+-- Migration: Add question_calibrations table
+-- Created: 2025-11-15
+-- Purpose: Store per-question solve statistics and a recalibrated level suggested from them (backend/internal/calibration)
+
+-- 挑戦の記録から定期的に集計した問題ごとの解かれ方と、それに基づく level の提案を保持するテーブルを追加
+-- ログイン中のユーザーの回答だけを数え、運営の確認待ち（needs_review）の回答は除く
+-- players: 挑戦したユーザーの数
+-- attempts: 回答の総数
+-- solve_rate: 一度でも満点を取ったユーザーの割合（0〜1）
+-- mean_score: 全回答の平均点
+-- attempts_to_first_100: 満点を取ったユーザーが初めて満点を取るまでの平均の挑戦回数。誰も満点を取っていなければ NULL
+-- suggested_level: 提案する level。挑戦者が少なく判断できない場合は NULL
+-- questions.level を書き換えるのは管理者が提案を受け入れたとき（POST /api/v1/admin/questions/:id/accept-level）だけ
+CREATE TABLE question_calibrations (
+    question_id INT PRIMARY KEY REFERENCES questions(id),
+    players INT NOT NULL,
+    attempts INT NOT NULL,
+    solve_rate DOUBLE PRECISION NOT NULL,
+    mean_score DOUBLE PRECISION NOT NULL,
+    attempts_to_first_100 DOUBLE PRECISION NULL,
+    suggested_level INT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE question_calibrations IS 'Per-question solve statistics and suggested level, recomputed periodically from scores';
+COMMENT ON COLUMN question_calibrations.suggested_level IS 'Level suggested from the statistics; NULL when there are too few players';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP TABLE IF EXISTS question_calibrations;
+*/
@@ -0,0 +1,22 @@
+-- Migration: Add user_progress table
+-- Created: 2025-11-16
+-- Purpose: Persist the highest unlocked question level per user for the level progression system (backend/internal/progression)
+
+-- ユーザーごとに挑戦できる最も高いレベルを保持するテーブルを追加
+-- 解放の条件（自己ベストの合計点・1 つ下のレベルのクリア数）は user_question_best から計算し、上がったときだけ書き込む
+-- 再採点や問題の level の変更で条件を満たさなくなっても、一度解放したレベルは戻さない
+-- 行の無いユーザーは level 1 だけが解放されている状態
+CREATE TABLE user_progress (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    unlocked_level INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE user_progress IS 'Highest unlocked question level per user; never decreases';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP TABLE IF EXISTS user_progress;
+*/
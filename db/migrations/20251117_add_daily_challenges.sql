@@ -0,0 +1,42 @@
+-- Migration: Add daily_challenges and daily_entries tables
+-- Created: 2025-11-17
+-- Purpose: Daily challenge (one question per day, limited attempts, per-day leaderboard kept as an archive of past winners)
+
+-- 日付ごとに出題する 1 問を保持するテーブルを追加
+-- 日付は DAILY_TIMEZONE（既定 Asia/Tokyo）で区切り、問題は日付から決まるように選ぶ（backend/internal/daily）
+-- その日に最初に参照されたときに選んで保存し、その日のうちに問題が追加・削除されても出題を変えない
+-- seed: テンプレート問題のバリアントを決めるシード。同じ日は全員に同じバリアントを出す
+CREATE TABLE daily_challenges (
+    challenge_date DATE PRIMARY KEY,
+    question_id INT NOT NULL REFERENCES questions(id),
+    seed BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ユーザー × 日付ごとの挑戦回数と最高点を保持するテーブルを追加
+-- 日付ごとのランキングの元データで、消さずに残すことで過去の日の勝者を閲覧できる（ログイン中のユーザーのみ）
+-- attempts: 使った挑戦回数。AI を呼ぶ前に DAILY_ATTEMPTS を上限として数え、AI の呼び出しに失敗したら戻す
+-- best_score: その日の最高点。まだ採点された挑戦が無ければ NULL（ランキングには出さない）
+-- best_at: 最高点を初めて取った時刻。同点なら早い方が上位
+CREATE TABLE daily_entries (
+    challenge_date DATE NOT NULL REFERENCES daily_challenges(challenge_date),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    best_score INT NULL,
+    best_at TIMESTAMP WITH TIME ZONE NULL,
+    PRIMARY KEY (challenge_date, user_id)
+);
+
+CREATE INDEX idx_daily_entries_rank ON daily_entries(challenge_date, best_score DESC, best_at) WHERE best_score IS NOT NULL;
+
+COMMENT ON TABLE daily_challenges IS 'Question chosen for each day of the daily challenge';
+COMMENT ON TABLE daily_entries IS 'Attempts and best score per user and day; also the archive of past daily leaderboards';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+DROP INDEX IF EXISTS idx_daily_entries_rank;
+DROP TABLE IF EXISTS daily_entries;
+DROP TABLE IF EXISTS daily_challenges;
+*/
@@ -0,0 +1,19 @@
+-- Migration: Add unit_policy column to questions table
+-- Created: 2025-11-18
+-- Purpose: Choose per question whether an answer in an incompatible unit (e.g. 分 for a km question) is rejected or scored with a penalty
+
+-- 回答の単位が期待単位と次元ごと異なる場合の扱いを問題ごとに保存する列を追加
+-- reject: 0 点（NULL も同じ扱い）、penalize: 数値だけで採点してから減点（backend/internal/eval/units.go）
+-- evaluator_version 5 以降の採点で使う
+ALTER TABLE questions
+ADD COLUMN unit_policy TEXT NULL CHECK (unit_policy IN ('reject', 'penalize'));
+
+COMMENT ON COLUMN questions.unit_policy IS 'Handling of answers whose unit has a different dimension from expected_unit: reject or penalize. NULL means reject';
+
+
+-- ロールバック用のコマンド:
+-- もとに戻す場合は以下のコマンドを実行する:
+/*
+ALTER TABLE questions
+DROP COLUMN unit_policy;
+*/